@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/services"
+	"github.com/qeunasd/coniven/storage"
+)
+
+const usage = `usage:
+  coniven                      start the web server
+  coniven migrate up           apply all pending migrations
+  coniven migrate down [n]     revert the last n migrations (default 1)
+  coniven migrate status       list migrations and whether they are applied
+  coniven recount              rebuild jumlah_ruangan and jumlah_barang counters
+  coniven trash purge          permanently delete records past the trash retention
+  coniven user create <username> <peran> [nama]
+                               create a user, reading the password from stdin`
+
+func RunCommand(ctx context.Context, repository *storage.Storage, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(ctx, repository, args[1:])
+	case "recount":
+		locations, rooms, err := repository.RecountCounters(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("recount done: %d lokasi and %d ruangan corrected\n", locations, rooms)
+		return nil
+	case "user":
+		return runUser(ctx, repository, args[1:])
+	case "trash":
+		if len(args) < 2 || args[1] != "purge" {
+			return fmt.Errorf("usage: coniven trash purge\n%s", usage)
+		}
+		purged, err := services.NewTrashService(repository).PurgeExpiredTrash(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("trash purge done: %d records deleted\n", purged)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+func runMigrate(ctx context.Context, repository *storage.Storage, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate action\n%s", usage)
+	}
+
+	switch args[0] {
+	case "up":
+		return repository.MigrateUp(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		return repository.MigrateDown(ctx, steps)
+	case "status":
+		statuses, err := repository.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range statuses {
+			status, appliedAt := "pending", "-"
+			if st.Applied {
+				status = "applied"
+				appliedAt = st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if st.Modified {
+				status = "modified"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", st.Version, st.Name, status, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate action %q\n%s", args[0], usage)
+	}
+}
+
+func runUser(ctx context.Context, repository *storage.Storage, args []string) error {
+	if len(args) < 3 || args[0] != "create" {
+		return fmt.Errorf("usage: coniven user create <username> <peran> [nama]\n%s", usage)
+	}
+
+	nama := args[1]
+	if len(args) > 3 {
+		nama = strings.Join(args[3:], " ")
+	}
+
+	fmt.Fprint(os.Stderr, "password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return fmt.Errorf("reading password: %w", err)
+	}
+
+	err = services.NewUserService(repository).CreateUser(ctx, entities.UserForm{
+		Username: args[1],
+		Nama:     nama,
+		Password: strings.TrimRight(password, "\r\n"),
+		Peran:    args[2],
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("user %s created\n", args[1])
+	return nil
+}
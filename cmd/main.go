@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"html/template"
 	"io/fs"
 	"log"
@@ -15,6 +16,7 @@ import (
 	"github.com/qeunasd/coniven/server"
 	"github.com/qeunasd/coniven/services"
 	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
 )
 
 func main() {
@@ -30,8 +32,24 @@ func main() {
 		log.Fatalf("error connecting to postgres: %v", err)
 	}
 
-	repository := storage.NewRepository(db, nil)
-	if err := repository.RunMigration(ctx); err != nil {
+	if len(os.Args) > 1 {
+		if err := RunCommand(ctx, storage.NewRepository(db, nil), os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	objects, err := NewObjectStore(ctx)
+	if err != nil {
+		log.Fatalf("error setting up object storage: %v", err)
+	}
+
+	repository := storage.NewRepository(db, objects)
+	if err := repository.MigrateUp(ctx); err != nil {
+		log.Fatalf("error migrating database: %v", err)
+	}
+
+	if err := repository.CheckSchema(ctx); err != nil {
 		log.Fatal(err)
 	}
 
@@ -43,16 +61,57 @@ func main() {
 	categoryService := services.NewCategoryService(repository)
 	locationService := services.NewLocationService(repository)
 	roomService := services.NewRoomService(repository)
-	itemService := services.NewRoomService(repository)
+	itemService := services.NewItemService(repository)
+	unitService := services.NewUnitService(repository)
+	pictureService := services.NewPictureService(repository)
+	transferService := services.NewTransferService(repository)
+	reportService := services.NewReportService(repository)
+	depreciationService := services.NewDepreciationService(repository)
+	authService := services.NewAuthService(repository)
+	userService := services.NewUserService(repository)
+	auditService := services.NewAuditService(repository)
+	opnameService := services.NewOpnameService(repository)
+	importService := services.NewImportService(repository)
+	exportService := services.NewExportService(repository)
+	bulkService := services.NewBulkService(repository)
+	trashService := services.NewTrashService(repository)
+	loanService := services.NewLoanService(repository)
+	repairService := services.NewRepairService(repository)
 
 	log.Println("listening to server at localhost:8080")
-	srv := server.NewServer(templates, categoryService, locationService, roomService, itemService)
+	srv := server.NewServer(templates, os.Getenv("PUBLIC_BASE_URL"), categoryService, locationService, roomService, itemService, unitService, pictureService, transferService, reportService, depreciationService, authService, userService, auditService, opnameService, importService, exportService, bulkService, trashService, loanService, repairService)
 
 	if err := srv.Run(); err != nil {
 		log.Fatalf("error listening to server: %v", err)
 	}
 }
 
+func NewObjectStore(ctx context.Context) (storage.ObjectStore, error) {
+	switch os.Getenv("OBJECT_STORE") {
+	case "local":
+		dir := os.Getenv("LOCAL_STORAGE_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		return storage.NewLocal(dir)
+	case "", "minio":
+		bucket := os.Getenv("MINIO_BUCKET")
+		if bucket == "" {
+			bucket = "coniven"
+		}
+		return storage.NewMinio(
+			ctx,
+			os.Getenv("MINIO_ENDPOINT"),
+			os.Getenv("MINIO_ACCESS_KEY"),
+			os.Getenv("MINIO_SECRET_KEY"),
+			bucket,
+			os.Getenv("MINIO_USE_SSL") == "true",
+		)
+	default:
+		return nil, fmt.Errorf("unknown OBJECT_STORE %q", os.Getenv("OBJECT_STORE"))
+	}
+}
+
 func ParseTemplate(dir string) (*template.Template, error) {
 	cleanRoot := filepath.Clean(dir)
 	tmpl := template.New("")
@@ -96,12 +155,16 @@ func ParseTemplate(dir string) (*template.Template, error) {
 		"sub": func(x, y int) int {
 			return x - y
 		},
+		"inc": func(x int) int {
+			return x + 1
+		},
 		"parseTime": func(date time.Time) string {
 			return date.Format("02-01-2006 15:04:05")
 		},
 		"uidStr": func(id uuid.UUID) string {
 			return id.String()
 		},
+		"rupiah": utils.FormatRupiah,
 	})
 
 	err := filepath.Walk(cleanRoot, func(path string, info fs.FileInfo, err error) error {
@@ -0,0 +1,227 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qeunasd/coniven/utils"
+)
+
+type TipeAtribut string
+
+const (
+	AtributTeks    TipeAtribut = "teks"
+	AtributAngka   TipeAtribut = "angka"
+	AtributTanggal TipeAtribut = "tanggal"
+	AtributPilihan TipeAtribut = "pilihan"
+)
+
+func TipeAtributs() []TipeAtribut {
+	return []TipeAtribut{AtributTeks, AtributAngka, AtributTanggal, AtributPilihan}
+}
+
+func (t TipeAtribut) IsValid() bool {
+	switch t {
+	case AtributTeks, AtributAngka, AtributTanggal, AtributPilihan:
+		return true
+	default:
+		return false
+	}
+}
+
+// AttributeField is a custom field a category asks of its items. Items fill in
+// the fields of their category and of every category above it.
+type AttributeField struct {
+	Kunci   string      `json:"kunci"`
+	Label   string      `json:"label"`
+	Tipe    TipeAtribut `json:"tipe"`
+	Wajib   bool        `json:"wajib"`
+	Pilihan []string    `json:"pilihan,omitempty"`
+}
+
+// AttributeFieldForm is one row of the field editor. Pilihan holds the enum
+// values separated by commas; rows without kunci and label are skipped.
+type AttributeFieldForm struct {
+	Kunci   string `form:"kunci"`
+	Label   string `form:"label"`
+	Tipe    string `form:"tipe"`
+	Wajib   bool   `form:"wajib"`
+	Pilihan string `form:"pilihan"`
+}
+
+type CategoryAttributeForm struct {
+	Fields []AttributeFieldForm `form:"atribut"`
+}
+
+var attributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+func NewAttributeFields(rows []AttributeFieldForm) ([]AttributeField, error) {
+	fields := make([]AttributeField, 0, len(rows))
+	seen := make(map[string]bool, len(rows))
+
+	for i, row := range rows {
+		kunci := strings.ToLower(strings.TrimSpace(row.Kunci))
+		label := strings.TrimSpace(row.Label)
+		if kunci == "" && label == "" {
+			continue
+		}
+
+		if !attributeKeyPattern.MatchString(kunci) {
+			return nil, utils.WebError{Field: "Atribut", Message: fmt.Sprintf("kunci baris %d harus diawali huruf dan hanya berisi huruf kecil, angka atau garis bawah", i+1)}
+		}
+		if seen[kunci] {
+			return nil, utils.WebError{Field: "Atribut", Message: fmt.Sprintf("kunci %s dipakai lebih dari sekali", kunci)}
+		}
+		seen[kunci] = true
+
+		if label == "" {
+			return nil, utils.WebError{Field: "Atribut", Message: fmt.Sprintf("label baris %d harus diisi", i+1)}
+		}
+
+		tipe := TipeAtribut(strings.TrimSpace(row.Tipe))
+		if !tipe.IsValid() {
+			return nil, utils.WebError{Field: "Atribut", Message: fmt.Sprintf("tipe baris %d tidak valid", i+1)}
+		}
+
+		field := AttributeField{Kunci: kunci, Label: label, Tipe: tipe, Wajib: row.Wajib}
+		if tipe == AtributPilihan {
+			for _, p := range strings.Split(row.Pilihan, ",") {
+				if p = strings.TrimSpace(p); p != "" && !field.HasPilihan(p) {
+					field.Pilihan = append(field.Pilihan, p)
+				}
+			}
+			if len(field.Pilihan) == 0 {
+				return nil, utils.WebError{Field: "Atribut", Message: fmt.Sprintf("pilihan untuk %s harus diisi", label)}
+			}
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+func (f AttributeField) HasPilihan(value string) bool {
+	for _, p := range f.Pilihan {
+		if p == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AttributeFormRows turns fields back into editor rows.
+func AttributeFormRows(fields []AttributeField) []AttributeFieldForm {
+	rows := make([]AttributeFieldForm, len(fields))
+	for i, f := range fields {
+		rows[i] = AttributeFieldForm{
+			Kunci: f.Kunci, Label: f.Label, Tipe: string(f.Tipe), Wajib: f.Wajib, Pilihan: strings.Join(f.Pilihan, ", "),
+		}
+	}
+	return rows
+}
+
+type AttributeValueForm struct {
+	Kunci string `form:"kunci"`
+	Nilai string `form:"nilai"`
+}
+
+// AttributeValues is the attribute document of an item, stored as JSONB.
+// Numbers are kept as numbers and dates as YYYY-MM-DD so they compare and
+// sort in queries.
+type AttributeValues map[string]any
+
+// ParseAttributeValues checks the submitted values against fields. Values for
+// keys outside fields are dropped.
+func ParseAttributeValues(fields []AttributeField, rows []AttributeValueForm) (AttributeValues, error) {
+	raw := make(map[string]string, len(rows))
+	for _, row := range rows {
+		raw[strings.TrimSpace(row.Kunci)] = strings.TrimSpace(row.Nilai)
+	}
+
+	values := make(AttributeValues, len(fields))
+	for _, f := range fields {
+		input := raw[f.Kunci]
+		if input == "" {
+			if f.Wajib {
+				return nil, utils.WebError{Field: "Atribut", Message: fmt.Sprintf("%s harus diisi", f.Label)}
+			}
+			continue
+		}
+
+		switch f.Tipe {
+		case AtributAngka:
+			n, err := strconv.ParseFloat(input, 64)
+			if err != nil {
+				return nil, utils.WebError{Field: "Atribut", Message: fmt.Sprintf("%s harus berupa angka", f.Label)}
+			}
+			values[f.Kunci] = n
+		case AtributTanggal:
+			if _, err := time.Parse("2006-01-02", input); err != nil {
+				return nil, utils.WebError{Field: "Atribut", Message: fmt.Sprintf("%s harus berupa tanggal", f.Label)}
+			}
+			values[f.Kunci] = input
+		case AtributPilihan:
+			if !f.HasPilihan(input) {
+				return nil, utils.WebError{Field: "Atribut", Message: fmt.Sprintf("%s harus salah satu dari %s", f.Label, strings.Join(f.Pilihan, ", "))}
+			}
+			values[f.Kunci] = input
+		default:
+			values[f.Kunci] = input
+		}
+	}
+
+	return values, nil
+}
+
+// AttributeFormValues keeps what was typed into the attribute inputs so a
+// rejected form shows it again.
+func AttributeFormValues(rows []AttributeValueForm) AttributeValues {
+	values := make(AttributeValues, len(rows))
+	for _, row := range rows {
+		values[row.Kunci] = row.Nilai
+	}
+	return values
+}
+
+// ParseAttributeJSON reads attribute values written as a JSON object, as in
+// the atribut column of an import file.
+func ParseAttributeJSON(raw string) ([]AttributeValueForm, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var values AttributeValues
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, utils.WebError{Field: "Atribut", Message: "atribut harus berupa objek JSON"}
+	}
+
+	return values.Form(), nil
+}
+
+// Text is the value of kunci as shown in a form or on a page.
+func (v AttributeValues) Text(kunci string) string {
+	switch value := v[kunci].(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Form turns the values back into form rows, for an edit that keeps them.
+func (v AttributeValues) Form() []AttributeValueForm {
+	rows := make([]AttributeValueForm, 0, len(v))
+	for kunci := range v {
+		rows = append(rows, AttributeValueForm{Kunci: kunci, Nilai: v.Text(kunci)})
+	}
+	return rows
+}
@@ -0,0 +1,171 @@
+package entities
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/qeunasd/coniven/utils"
+)
+
+func TestNewAttributeFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    []AttributeFieldForm
+		want    []AttributeField
+		wantErr bool
+	}{
+		{
+			name: "normalises key and splits choices",
+			rows: []AttributeFieldForm{
+				{Kunci: " RAM_GB ", Label: "RAM", Tipe: "angka", Wajib: true},
+				{Kunci: "warna", Label: "Warna", Tipe: "pilihan", Pilihan: "hitam, putih,, hitam"},
+			},
+			want: []AttributeField{
+				{Kunci: "ram_gb", Label: "RAM", Tipe: AtributAngka, Wajib: true},
+				{Kunci: "warna", Label: "Warna", Tipe: AtributPilihan, Pilihan: []string{"hitam", "putih"}},
+			},
+		},
+		{
+			name: "skips blank rows",
+			rows: []AttributeFieldForm{{}, {Kunci: "garansi", Label: "Garansi", Tipe: "tanggal"}},
+			want: []AttributeField{{Kunci: "garansi", Label: "Garansi", Tipe: AtributTanggal}},
+		},
+		{name: "key starting with a digit", rows: []AttributeFieldForm{{Kunci: "1ram", Label: "RAM", Tipe: "angka"}}, wantErr: true},
+		{name: "key with spaces", rows: []AttributeFieldForm{{Kunci: "no seri", Label: "No Seri", Tipe: "teks"}}, wantErr: true},
+		{name: "duplicate key", rows: []AttributeFieldForm{{Kunci: "ram", Label: "RAM", Tipe: "angka"}, {Kunci: "RAM", Label: "Memori", Tipe: "teks"}}, wantErr: true},
+		{name: "missing label", rows: []AttributeFieldForm{{Kunci: "ram", Tipe: "angka"}}, wantErr: true},
+		{name: "unknown type", rows: []AttributeFieldForm{{Kunci: "ram", Label: "RAM", Tipe: "desimal"}}, wantErr: true},
+		{name: "choice without options", rows: []AttributeFieldForm{{Kunci: "warna", Label: "Warna", Tipe: "pilihan", Pilihan: " , "}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewAttributeFields(tt.rows)
+			if tt.wantErr {
+				var webErr utils.WebError
+				if !errors.As(err, &webErr) {
+					t.Fatalf("got error %v, want a WebError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAttributeValues(t *testing.T) {
+	fields := []AttributeField{
+		{Kunci: "ram", Label: "RAM", Tipe: AtributAngka, Wajib: true},
+		{Kunci: "garansi", Label: "Garansi", Tipe: AtributTanggal},
+		{Kunci: "warna", Label: "Warna", Tipe: AtributPilihan, Pilihan: []string{"hitam", "putih"}},
+		{Kunci: "catatan", Label: "Catatan", Tipe: AtributTeks},
+	}
+
+	tests := []struct {
+		name    string
+		rows    []AttributeValueForm
+		want    AttributeValues
+		wantErr bool
+	}{
+		{
+			name: "typed values",
+			rows: []AttributeValueForm{
+				{Kunci: "ram", Nilai: " 16 "},
+				{Kunci: "garansi", Nilai: "2027-01-31"},
+				{Kunci: "warna", Nilai: "hitam"},
+				{Kunci: "catatan", Nilai: "bekas pameran"},
+			},
+			want: AttributeValues{"ram": 16.0, "garansi": "2027-01-31", "warna": "hitam", "catatan": "bekas pameran"},
+		},
+		{
+			name: "optional fields left empty and unknown keys dropped",
+			rows: []AttributeValueForm{{Kunci: "ram", Nilai: "8"}, {Kunci: "garansi"}, {Kunci: "lain", Nilai: "x"}},
+			want: AttributeValues{"ram": 8.0},
+		},
+		{name: "required field missing", rows: []AttributeValueForm{{Kunci: "warna", Nilai: "putih"}}, wantErr: true},
+		{name: "number not numeric", rows: []AttributeValueForm{{Kunci: "ram", Nilai: "banyak"}}, wantErr: true},
+		{name: "bad date", rows: []AttributeValueForm{{Kunci: "ram", Nilai: "8"}, {Kunci: "garansi", Nilai: "31-01-2027"}}, wantErr: true},
+		{name: "choice not offered", rows: []AttributeValueForm{{Kunci: "ram", Nilai: "8"}, {Kunci: "warna", Nilai: "merah"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAttributeValues(fields, tt.rows)
+			if tt.wantErr {
+				var webErr utils.WebError
+				if !errors.As(err, &webErr) {
+					t.Fatalf("got error %v, want a WebError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryAttributeInheritance(t *testing.T) {
+	elektronik := Category{Id: 1, Nama: "Elektronik", Atribut: []AttributeField{{Kunci: "garansi", Label: "Garansi", Tipe: AtributTanggal}}}
+	komputer := Category{Id: 2, Nama: "Komputer", IdInduk: &elektronik.Id, Atribut: []AttributeField{{Kunci: "ram", Label: "RAM", Tipe: AtributAngka}}}
+	laptop := Category{Id: 3, Nama: "Laptop", IdInduk: &komputer.Id, Atribut: []AttributeField{{Kunci: "layar", Label: "Layar", Tipe: AtributAngka}}}
+	categories := map[int]Category{1: elektronik, 2: komputer, 3: laptop}
+
+	tests := []struct {
+		name        string
+		category    Category
+		warisan     []string
+		semua       []string
+		namaLengkap string
+	}{
+		{"top level", elektronik, nil, []string{"garansi"}, "Elektronik"},
+		{"one level down", komputer, []string{"garansi"}, []string{"garansi", "ram"}, "Elektronik / Komputer"},
+		{"two levels down", laptop, []string{"garansi", "ram"}, []string{"garansi", "ram", "layar"}, "Elektronik / Komputer / Laptop"},
+	}
+
+	keys := func(fields []AttributeField) []string {
+		var out []string
+		for _, f := range fields {
+			out = append(out, f.Kunci)
+		}
+		return out
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.category
+			c.Jalur = CategoryPath(categories, c.Id)
+
+			if got := keys(c.AtributWarisan()); !reflect.DeepEqual(got, tt.warisan) {
+				t.Errorf("AtributWarisan() = %v, want %v", got, tt.warisan)
+			}
+			if got := keys(c.SemuaAtribut()); !reflect.DeepEqual(got, tt.semua) {
+				t.Errorf("SemuaAtribut() = %v, want %v", got, tt.semua)
+			}
+			if got := c.NamaLengkap(); got != tt.namaLengkap {
+				t.Errorf("NamaLengkap() = %q, want %q", got, tt.namaLengkap)
+			}
+		})
+	}
+}
+
+func TestCategoryPathStopsOnCycle(t *testing.T) {
+	a, b := 1, 2
+	categories := map[int]Category{
+		1: {Id: 1, IdInduk: &b},
+		2: {Id: 2, IdInduk: &a},
+	}
+
+	if path := CategoryPath(categories, 1); len(path) > len(categories) {
+		t.Errorf("CategoryPath walked %d levels in a two category cycle", len(path))
+	}
+}
@@ -0,0 +1,139 @@
+package entities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+func AuditActions() []AuditAction {
+	return []AuditAction{AuditCreate, AuditUpdate, AuditDelete}
+}
+
+// Entity types recorded in the audit log.
+const (
+	AuditKategori  = "kategori"
+	AuditLokasi    = "lokasi"
+	AuditRuangan   = "ruangan"
+	AuditBarang    = "barang"
+	AuditUnit      = "unit_barang"
+	AuditGambar    = "gambar_barang"
+	AuditMutasi    = "mutasi"
+	AuditPengguna  = "pengguna"
+	AuditOpname    = "stok_opname"
+	AuditPinjam    = "peminjaman"
+	AuditPerbaikan = "perbaikan"
+)
+
+func AuditEntityTypes() []string {
+	return []string{AuditKategori, AuditLokasi, AuditRuangan, AuditBarang, AuditUnit, AuditGambar, AuditMutasi, AuditPengguna, AuditOpname, AuditPinjam, AuditPerbaikan}
+}
+
+// AuditLog is one append-only record of a mutation. Aktor keeps the username
+// so the entry stays readable after the account is removed.
+type AuditLog struct {
+	Id           int64         `db:"id"`
+	IdPengguna   uuid.NullUUID `db:"id_pengguna"`
+	Aktor        string        `db:"aktor"`
+	JenisEntitas string        `db:"jenis_entitas"`
+	IdEntitas    string        `db:"id_entitas"`
+	Aksi         AuditAction   `db:"aksi"`
+	Sebelum      []byte        `db:"sebelum"`
+	Sesudah      []byte        `db:"sesudah"`
+	TglDibuat    time.Time     `db:"tgl_dibuat"`
+}
+
+// NewAuditLog snapshots before and after as JSON. Either may be nil, as for
+// a create or a delete.
+func NewAuditLog(ctx context.Context, jenis string, id any, aksi AuditAction, before, after any) (AuditLog, error) {
+	entry := AuditLog{
+		Aktor:        "system",
+		JenisEntitas: jenis,
+		IdEntitas:    fmt.Sprint(id),
+		Aksi:         aksi,
+		TglDibuat:    time.Now(),
+	}
+
+	if user, ok := ActorFrom(ctx); ok {
+		entry.IdPengguna = uuid.NullUUID{UUID: user.Id, Valid: true}
+		entry.Aktor = user.Username
+	}
+
+	var err error
+	if before != nil {
+		if entry.Sebelum, err = json.Marshal(before); err != nil {
+			return AuditLog{}, fmt.Errorf("encoding audit before: %w", err)
+		}
+	}
+	if after != nil {
+		if entry.Sesudah, err = json.Marshal(after); err != nil {
+			return AuditLog{}, fmt.Errorf("encoding audit after: %w", err)
+		}
+	}
+
+	return entry, nil
+}
+
+type AuditChange struct {
+	Field   string
+	Sebelum string
+	Sesudah string
+}
+
+// Changes lists the top-level fields whose value differs between the before
+// and after snapshots, sorted by field name.
+func (a AuditLog) Changes() []AuditChange {
+	before, after := decodeSnapshot(a.Sebelum), decodeSnapshot(a.Sesudah)
+
+	fields := make(map[string]bool, len(before)+len(after))
+	for k := range before {
+		fields[k] = true
+	}
+	for k := range after {
+		fields[k] = true
+	}
+
+	var changes []AuditChange
+	for k := range fields {
+		b, aft := string(before[k]), string(after[k])
+		if b == aft {
+			continue
+		}
+		changes = append(changes, AuditChange{Field: k, Sebelum: b, Sesudah: aft})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func decodeSnapshot(raw []byte) map[string]json.RawMessage {
+	var m map[string]json.RawMessage
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &m)
+	}
+	return m
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, actorKey{}, user)
+}
+
+// ActorFrom returns the user performing the request, if any. Commands run
+// from the CLI have no actor and are recorded as "system".
+func ActorFrom(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(actorKey{}).(User)
+	return user, ok
+}
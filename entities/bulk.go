@@ -0,0 +1,84 @@
+package entities
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/utils"
+)
+
+// BulkForm carries the rows checked on a list page. Konfirmasi is sent by the
+// summary panel once the user has seen what else the delete removes.
+type BulkForm struct {
+	Ids        []string `form:"ids"`
+	Konfirmasi bool     `form:"konfirmasi"`
+}
+
+type BulkMoveForm struct {
+	Ids    []string `form:"ids"`
+	Lokasi string   `form:"lokasi"`
+}
+
+type BulkConditionForm struct {
+	Units   []string `form:"units"`
+	Kondisi string   `form:"kondisi"`
+}
+
+// DeleteSummary describes a bulk delete before it runs: the selected records
+// and how many live rows go to the trash with them.
+type DeleteSummary struct {
+	Nama     []string
+	Kategori int
+	Lokasi   int
+	Ruangan  int
+	Barang   int
+	Unit     int
+}
+
+func (s DeleteSummary) Cascades() bool {
+	return s.Kategori+s.Lokasi+s.Ruangan+s.Barang+s.Unit > 0
+}
+
+// ParseBulkUUIDs parses the selected ids of a list, dropping duplicates.
+func ParseBulkUUIDs(field string, input []string) ([]uuid.UUID, error) {
+	if len(input) == 0 {
+		return nil, utils.WebError{Field: field, Message: "pilih minimal satu data"}
+	}
+
+	seen := make(map[uuid.UUID]bool, len(input))
+	ids := make([]uuid.UUID, 0, len(input))
+	for _, raw := range input {
+		id, err := uuid.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, utils.WebError{Field: field, Message: "data terpilih tidak valid"}
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func ParseBulkInts(field string, input []string) ([]int, error) {
+	if len(input) == 0 {
+		return nil, utils.WebError{Field: field, Message: "pilih minimal satu data"}
+	}
+
+	seen := make(map[int]bool, len(input))
+	ids := make([]int, 0, len(input))
+	for _, raw := range input {
+		id, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || id <= 0 {
+			return nil, utils.WebError{Field: field, Message: "data terpilih tidak valid"}
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
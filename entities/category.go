@@ -8,16 +8,29 @@ import (
 )
 
 type CategoryForm struct {
-	Code string `form:"kode_kategori"`
-	Name string `form:"nama_kategori"`
+	Code   string `form:"kode_kategori"`
+	Name   string `form:"nama_kategori"`
+	Parent string `form:"id_induk"`
+}
+
+// CategoryMoveForm moves a category with everything under it; an empty parent
+// makes it a top level category.
+type CategoryMoveForm struct {
+	Parent string `form:"id_induk"`
 }
 
 type Category struct {
-	Id        int       `db:"id"`
-	Kode      string    `db:"kode"`
-	Nama      string    `db:"nama"`
-	TglDibuat time.Time `db:"tgl_dibuat"`
-	TglUpdate time.Time `db:"tgl_update"`
+	Id        int              `db:"id" json:"id"`
+	Kode      string           `db:"kode" json:"kode"`
+	Nama      string           `db:"nama" json:"nama"`
+	TglDibuat time.Time        `db:"tgl_dibuat" json:"tgl_dibuat"`
+	TglUpdate time.Time        `db:"tgl_update" json:"tgl_update"`
+	IdInduk   *int             `db:"id_induk" json:"id_induk"`
+	Atribut   []AttributeField `db:"atribut" json:"atribut"`
+
+	// Jalur lists the ancestors from the top level down, with the fields
+	// each of them declares.
+	Jalur []Category `db:"-" json:"jalur,omitempty"`
 }
 
 func NewCategory(code, name string) *Category {
@@ -27,7 +40,56 @@ func NewCategory(code, name string) *Category {
 		Nama:      name,
 		TglDibuat: now,
 		TglUpdate: now,
+		Atribut:   []AttributeField{},
+	}
+}
+
+// NamaLengkap is the name prefixed with its ancestors, as shown in category
+// pickers.
+func (c Category) NamaLengkap() string {
+	names := make([]string, 0, len(c.Jalur)+1)
+	for _, anc := range c.Jalur {
+		names = append(names, anc.Nama)
+	}
+	return strings.Join(append(names, c.Nama), " / ")
+}
+
+// IsUnder reports whether id is one of the category's ancestors.
+func (c Category) IsUnder(id int) bool {
+	for _, anc := range c.Jalur {
+		if anc.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AtributWarisan lists the fields declared by the ancestors.
+func (c Category) AtributWarisan() []AttributeField {
+	var fields []AttributeField
+	for _, anc := range c.Jalur {
+		fields = append(fields, anc.Atribut...)
+	}
+	return fields
+}
+
+// SemuaAtribut lists every field an item of this category fills in, the
+// inherited ones first.
+func (c Category) SemuaAtribut() []AttributeField {
+	return append(c.AtributWarisan(), c.Atribut...)
+}
+
+// CategoryPath walks up from id through categories and returns its ancestors,
+// the top level first. It stops at a parent missing from categories.
+func CategoryPath(categories map[int]Category, id int) []Category {
+	var path []Category
+	c, ok := categories[id]
+	for ok && c.IdInduk != nil && len(path) < len(categories) {
+		if c, ok = categories[*c.IdInduk]; ok {
+			path = append([]Category{c}, path...)
+		}
 	}
+	return path
 }
 
 func (c *Category) Validate() error {
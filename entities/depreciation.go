@@ -0,0 +1,284 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/qeunasd/coniven/utils"
+)
+
+type MetodePenyusutan string
+
+const (
+	GarisLurus   MetodePenyusutan = "garis_lurus"
+	SaldoMenurun MetodePenyusutan = "saldo_menurun"
+)
+
+func MetodePenyusutans() []MetodePenyusutan {
+	return []MetodePenyusutan{GarisLurus, SaldoMenurun}
+}
+
+func (m MetodePenyusutan) IsValid() bool {
+	switch m {
+	case GarisLurus, SaldoMenurun:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m MetodePenyusutan) Label() string {
+	switch m {
+	case SaldoMenurun:
+		return "Saldo Menurun Ganda"
+	default:
+		return "Garis Lurus"
+	}
+}
+
+func ParseMetodePenyusutan(input string) (MetodePenyusutan, error) {
+	if input == "" {
+		return GarisLurus, nil
+	}
+
+	metode := MetodePenyusutan(input)
+	if !metode.IsValid() {
+		return "", utils.WebError{Field: "MetodePenyusutan", Message: "Metode penyusutan tidak valid"}
+	}
+	return metode, nil
+}
+
+// Depreciation computes the value of an asset over its useful life in whole
+// months, starting from the acquisition date.
+type Depreciation struct {
+	Metode MetodePenyusutan
+	Harga  int
+	Residu int
+	Umur   int
+	Mulai  time.Time
+}
+
+type DepreciationPeriod struct {
+	Tahun      int
+	Dari       time.Time
+	Sampai     time.Time
+	NilaiAwal  int
+	Penyusutan int
+	Akumulasi  int
+	NilaiAkhir int
+}
+
+func (d Depreciation) base() int {
+	return max(d.Harga-d.Residu, 0)
+}
+
+func (d Depreciation) monthsElapsed(at time.Time) int {
+	if d.Umur <= 0 || at.Before(d.Mulai) {
+		return 0
+	}
+
+	months := (at.Year()-d.Mulai.Year())*12 + int(at.Month()-d.Mulai.Month())
+	if at.Day() < d.Mulai.Day() {
+		months--
+	}
+	return min(max(months, 0), d.Umur*12)
+}
+
+// yearly returns the depreciation charged in each year of the useful life.
+// Declining balance uses double the straight-line rate and writes the rest
+// down to the residual value in the final year.
+func (d Depreciation) yearly() []int {
+	amounts := make([]int, d.Umur)
+	if d.Umur <= 0 {
+		return amounts
+	}
+
+	if d.Metode != SaldoMenurun {
+		for y := range amounts {
+			amounts[y] = d.base()*(y+1)/d.Umur - d.base()*y/d.Umur
+		}
+		return amounts
+	}
+
+	book := d.Harga
+	for y := range amounts {
+		amount := book * 2 / d.Umur
+		if y == d.Umur-1 || amount > book-d.Residu {
+			amount = book - d.Residu
+		}
+		amount = max(amount, 0)
+		amounts[y] = amount
+		book -= amount
+	}
+	return amounts
+}
+
+func (d Depreciation) AccumulatedAt(at time.Time) int {
+	months := d.monthsElapsed(at)
+	if months == 0 {
+		return 0
+	}
+
+	if d.Metode != SaldoMenurun {
+		return d.base() * months / (d.Umur * 12)
+	}
+
+	amounts := d.yearly()
+	accumulated := 0
+	for _, amount := range amounts[:months/12] {
+		accumulated += amount
+	}
+	if rest := months % 12; rest > 0 {
+		accumulated += amounts[months/12] * rest / 12
+	}
+	return accumulated
+}
+
+func (d Depreciation) BookValueAt(at time.Time) int {
+	return d.Harga - d.AccumulatedAt(at)
+}
+
+func (d Depreciation) Schedule() []DepreciationPeriod {
+	periods := make([]DepreciationPeriod, 0, d.Umur)
+
+	book, accumulated := d.Harga, 0
+	for y, amount := range d.yearly() {
+		accumulated += amount
+		periods = append(periods, DepreciationPeriod{
+			Tahun:      y + 1,
+			Dari:       d.Mulai.AddDate(y, 0, 0),
+			Sampai:     d.Mulai.AddDate(y+1, 0, -1),
+			NilaiAwal:  book,
+			Penyusutan: amount,
+			Akumulasi:  accumulated,
+			NilaiAkhir: book - amount,
+		})
+		book -= amount
+	}
+	return periods
+}
+
+// Depreciation covers every unit of the item, starting from the acquisition
+// date of the item.
+func (i Item) Depreciation() Depreciation {
+	return Depreciation{
+		Metode: i.MetodePenyusutan,
+		Harga:  i.HargaSatuan * i.Jumlah,
+		Residu: i.NilaiResidu * i.Jumlah,
+		Umur:   i.UmurEkonomis,
+		Mulai:  DateOf(i.TglPerolehan),
+	}
+}
+
+func (i Item) UnitDepreciation(unit ItemUnit) Depreciation {
+	return Depreciation{
+		Metode: i.MetodePenyusutan,
+		Harga:  i.HargaSatuan,
+		Residu: i.NilaiResidu,
+		Umur:   i.UmurEkonomis,
+		Mulai:  DateOf(unit.TglPerolehan),
+	}
+}
+
+type UnitBookValue struct {
+	Unit      ItemUnit
+	Akumulasi int
+	NilaiBuku int
+}
+
+type ItemDepreciation struct {
+	Item      Item
+	At        time.Time
+	Akumulasi int
+	NilaiBuku int
+	Schedule  []DepreciationPeriod
+	Units     []UnitBookValue
+}
+
+func NewItemDepreciation(item Item, units []ItemUnit, at time.Time) ItemDepreciation {
+	dep := item.Depreciation()
+	result := ItemDepreciation{
+		Item:      item,
+		At:        at,
+		Akumulasi: dep.AccumulatedAt(at),
+		NilaiBuku: dep.BookValueAt(at),
+		Schedule:  dep.Schedule(),
+	}
+
+	for _, unit := range units {
+		unitDep := item.UnitDepreciation(unit)
+		result.Units = append(result.Units, UnitBookValue{
+			Unit:      unit,
+			Akumulasi: unitDep.AccumulatedAt(at),
+			NilaiBuku: unitDep.BookValueAt(at),
+		})
+	}
+	return result
+}
+
+type DepreciationLine struct {
+	Kode           string
+	Nama           string
+	Kategori       string
+	HargaPerolehan int
+	AkumulasiAwal  int
+	Penyusutan     int
+	AkumulasiAkhir int
+	NilaiBuku      int
+}
+
+func (l *DepreciationLine) add(other DepreciationLine) {
+	l.HargaPerolehan += other.HargaPerolehan
+	l.AkumulasiAwal += other.AkumulasiAwal
+	l.Penyusutan += other.Penyusutan
+	l.AkumulasiAkhir += other.AkumulasiAkhir
+	l.NilaiBuku += other.NilaiBuku
+}
+
+// DepreciationSummary reports the depreciation charged during a fiscal year,
+// which runs from January to December.
+type DepreciationSummary struct {
+	Tahun      int
+	ByCategory []DepreciationLine
+	Items      []DepreciationLine
+	Total      DepreciationLine
+}
+
+func FiscalYearBounds(year int) (time.Time, time.Time) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, 0)
+}
+
+func NewDepreciationSummary(year int, items []Item) DepreciationSummary {
+	start, end := FiscalYearBounds(year)
+	summary := DepreciationSummary{Tahun: year, Total: DepreciationLine{Nama: "Total"}}
+
+	index := make(map[string]int)
+	for _, item := range items {
+		if !item.TglPerolehan.Before(end) {
+			continue
+		}
+
+		dep := item.Depreciation()
+		line := DepreciationLine{
+			Kode:           item.SKU,
+			Nama:           item.Nama,
+			Kategori:       item.Kategori.Nama,
+			HargaPerolehan: dep.Harga,
+			AkumulasiAwal:  dep.AccumulatedAt(start),
+			AkumulasiAkhir: dep.AccumulatedAt(end),
+		}
+		line.Penyusutan = line.AkumulasiAkhir - line.AkumulasiAwal
+		line.NilaiBuku = line.HargaPerolehan - line.AkumulasiAkhir
+		summary.Items = append(summary.Items, line)
+
+		i, ok := index[item.Kategori.Kode]
+		if !ok {
+			i = len(summary.ByCategory)
+			index[item.Kategori.Kode] = i
+			summary.ByCategory = append(summary.ByCategory, DepreciationLine{Kode: item.Kategori.Kode, Nama: item.Kategori.Nama})
+		}
+		summary.ByCategory[i].add(line)
+		summary.Total.add(line)
+	}
+	return summary
+}
@@ -0,0 +1,141 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDepreciationAccumulatedAt(t *testing.T) {
+	garisLurus := Depreciation{Metode: GarisLurus, Harga: 12_000_000, Residu: 0, Umur: 4, Mulai: date(2024, 1, 15)}
+	saldoMenurun := Depreciation{Metode: SaldoMenurun, Harga: 10_000_000, Residu: 1_000_000, Umur: 4, Mulai: date(2024, 1, 15)}
+
+	tests := []struct {
+		name string
+		dep  Depreciation
+		at   time.Time
+		want int
+	}{
+		{"garis lurus sebelum perolehan", garisLurus, date(2023, 12, 31), 0},
+		{"garis lurus hari perolehan", garisLurus, date(2024, 1, 15), 0},
+		{"garis lurus kurang sehari dari sebulan", garisLurus, date(2024, 2, 14), 0},
+		{"garis lurus satu bulan", garisLurus, date(2024, 2, 15), 250_000},
+		{"garis lurus sebelas bulan", garisLurus, date(2025, 1, 14), 2_750_000},
+		{"garis lurus satu tahun", garisLurus, date(2025, 1, 15), 3_000_000},
+		{"garis lurus habis umur", garisLurus, date(2030, 6, 1), 12_000_000},
+		{"garis lurus dengan residu", Depreciation{Metode: GarisLurus, Harga: 5_000_000, Residu: 1_000_000, Umur: 2, Mulai: date(2024, 1, 1)}, date(2025, 1, 1), 2_000_000},
+		{"residu melebihi harga", Depreciation{Metode: GarisLurus, Harga: 1_000, Residu: 2_000, Umur: 2, Mulai: date(2024, 1, 1)}, date(2025, 1, 1), 0},
+		{"tanpa umur ekonomis", Depreciation{Metode: GarisLurus, Harga: 1_000, Umur: 0, Mulai: date(2024, 1, 1)}, date(2025, 1, 1), 0},
+		{"saldo menurun tahun pertama", saldoMenurun, date(2025, 1, 15), 5_000_000},
+		{"saldo menurun delapan belas bulan", saldoMenurun, date(2025, 7, 15), 6_250_000},
+		{"saldo menurun tahun ketiga", saldoMenurun, date(2027, 1, 15), 8_750_000},
+		{"saldo menurun habis umur", saldoMenurun, date(2028, 1, 15), 9_000_000},
+		{"saldo menurun lewat umur", saldoMenurun, date(2035, 1, 1), 9_000_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dep.AccumulatedAt(tt.at); got != tt.want {
+				t.Errorf("AccumulatedAt(%s) = %d, want %d", tt.at.Format("2006-01-02"), got, tt.want)
+			}
+			if got := tt.dep.BookValueAt(tt.at); got != tt.dep.Harga-tt.want {
+				t.Errorf("BookValueAt(%s) = %d, want %d", tt.at.Format("2006-01-02"), got, tt.dep.Harga-tt.want)
+			}
+		})
+	}
+}
+
+func TestDepreciationSchedule(t *testing.T) {
+	tests := []struct {
+		name string
+		dep  Depreciation
+		want []int
+	}{
+		{"garis lurus merata", Depreciation{Metode: GarisLurus, Harga: 12_000_000, Umur: 4}, []int{3_000_000, 3_000_000, 3_000_000, 3_000_000}},
+		{"garis lurus sisa pembagian di akhir", Depreciation{Metode: GarisLurus, Harga: 1_000, Umur: 3}, []int{333, 333, 334}},
+		{"saldo menurun sampai residu", Depreciation{Metode: SaldoMenurun, Harga: 10_000_000, Residu: 1_000_000, Umur: 4}, []int{5_000_000, 2_500_000, 1_250_000, 250_000}},
+		{"saldo menurun berhenti di residu", Depreciation{Metode: SaldoMenurun, Harga: 10_000_000, Residu: 4_000_000, Umur: 4}, []int{5_000_000, 1_000_000, 0, 0}},
+		{"saldo menurun satu tahun", Depreciation{Metode: SaldoMenurun, Harga: 10_000_000, Residu: 1_000_000, Umur: 1}, []int{9_000_000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.dep.Mulai = date(2024, 3, 1)
+			schedule := tt.dep.Schedule()
+			if len(schedule) != len(tt.want) {
+				t.Fatalf("got %d periods, want %d", len(schedule), len(tt.want))
+			}
+
+			book := tt.dep.Harga
+			for i, p := range schedule {
+				if p.Penyusutan != tt.want[i] {
+					t.Errorf("year %d: penyusutan = %d, want %d", i+1, p.Penyusutan, tt.want[i])
+				}
+				if p.NilaiAwal != book || p.NilaiAkhir != book-p.Penyusutan {
+					t.Errorf("year %d: nilai %d -> %d, want %d -> %d", i+1, p.NilaiAwal, p.NilaiAkhir, book, book-p.Penyusutan)
+				}
+				book -= p.Penyusutan
+			}
+
+			last := schedule[len(schedule)-1]
+			if last.NilaiAkhir != tt.dep.Residu {
+				t.Errorf("final book value = %d, want residu %d", last.NilaiAkhir, tt.dep.Residu)
+			}
+			if end := date(2024+len(tt.want), 3, 1).AddDate(0, 0, -1); !last.Sampai.Equal(end) {
+				t.Errorf("final period ends %s, want %s", last.Sampai.Format("2006-01-02"), end.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestDepreciationStartsAtAcquisition(t *testing.T) {
+	item := Item{
+		HargaSatuan: 1_200_000, Jumlah: 2, UmurEkonomis: 2, MetodePenyusutan: GarisLurus,
+		TglDibuat:    time.Date(2025, 6, 10, 9, 30, 0, 0, time.UTC),
+		TglPerolehan: date(2024, 6, 1),
+	}
+	unit := ItemUnit{TglDibuat: item.TglDibuat, TglPerolehan: date(2024, 12, 1)}
+
+	if got := item.Depreciation().Mulai; !got.Equal(item.TglPerolehan) {
+		t.Errorf("item depreciation starts %s, want %s", got.Format(DateLayout), item.TglPerolehan.Format(DateLayout))
+	}
+	if got := item.Depreciation().AccumulatedAt(date(2025, 6, 1)); got != 1_200_000 {
+		t.Errorf("item accumulated after a year = %d, want 1200000", got)
+	}
+	if got := item.UnitDepreciation(unit).Mulai; !got.Equal(unit.TglPerolehan) {
+		t.Errorf("unit depreciation starts %s, want %s", got.Format(DateLayout), unit.TglPerolehan.Format(DateLayout))
+	}
+}
+
+func TestParseTglPerolehan(t *testing.T) {
+	now := time.Date(2026, 3, 15, 14, 0, 0, 0, time.UTC)
+	fallback := date(2026, 1, 2)
+
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", fallback, false},
+		{"  ", fallback, false},
+		{"2024-07-01", date(2024, 7, 1), false},
+		{" 2026-03-15 ", date(2026, 3, 15), false},
+		{"2026-03-16", time.Time{}, true},
+		{"15-03-2026", time.Time{}, true},
+		{"kemarin", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTglPerolehan(tt.input, fallback, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTglPerolehan(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTglPerolehan(%q) = %s, want %s", tt.input, got.Format(DateLayout), tt.want.Format(DateLayout))
+		}
+	}
+}
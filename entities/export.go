@@ -0,0 +1,46 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qeunasd/coniven/utils"
+)
+
+type FormatEkspor string
+
+const (
+	EksporCSV  FormatEkspor = "csv"
+	EksporXLSX FormatEkspor = "xlsx"
+	EksporJSON FormatEkspor = "json"
+)
+
+func FormatEkspors() []FormatEkspor {
+	return []FormatEkspor{EksporCSV, EksporXLSX, EksporJSON}
+}
+
+func ParseFormatEkspor(input string) (FormatEkspor, error) {
+	switch f := FormatEkspor(input); f {
+	case EksporCSV, EksporXLSX, EksporJSON:
+		return f, nil
+	case "":
+		return EksporCSV, nil
+	default:
+		return "", utils.WebError{Field: "Format", Message: "format harus csv, xlsx atau json"}
+	}
+}
+
+func (f FormatEkspor) ContentType() string {
+	switch f {
+	case EksporXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case EksporJSON:
+		return "application/json"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+func (f FormatEkspor) FileName(list string, now time.Time) string {
+	return fmt.Sprintf("%s-%s.%s", list, now.Format(DateLayout), f)
+}
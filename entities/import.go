@@ -0,0 +1,148 @@
+package entities
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/qeunasd/coniven/utils"
+)
+
+type JenisImpor string
+
+const (
+	ImporKategori JenisImpor = "kategori"
+	ImporLokasi   JenisImpor = "lokasi"
+	ImporRuangan  JenisImpor = "ruangan"
+	ImporBarang   JenisImpor = "barang"
+)
+
+// Import files are matched to these header names, case-insensitively and in
+// any order. Rooms refer to their location and items to their category by
+// kode.
+var importColumns = map[JenisImpor][]string{
+	ImporKategori: {"kode", "nama"},
+	ImporLokasi:   {"kode", "nama"},
+	ImporRuangan:  {"nama", "penanggung_jawab", "kode_lokasi"},
+	ImporBarang: {
+		"sku", "nama", "kode_kategori", "jumlah", "satuan", "harga_satuan",
+		"umur_ekonomis", "metode_penyusutan", "nilai_residu", "spesifikasi", "atribut", "tgl_perolehan",
+	},
+}
+
+// Columns that may be left out of the header entirely.
+var optionalImportColumns = map[string]bool{
+	"jumlah":            true,
+	"metode_penyusutan": true,
+	"nilai_residu":      true,
+	"spesifikasi":       true,
+	"atribut":           true,
+	"tgl_perolehan":     true,
+}
+
+func JenisImpors() []JenisImpor {
+	return []JenisImpor{ImporKategori, ImporLokasi, ImporRuangan, ImporBarang}
+}
+
+func ParseJenisImpor(input string) (JenisImpor, error) {
+	jenis := JenisImpor(strings.TrimSpace(input))
+	if _, ok := importColumns[jenis]; !ok {
+		return "", utils.WebError{Field: "Jenis", Message: "jenis data tidak valid"}
+	}
+	return jenis, nil
+}
+
+func (j JenisImpor) Columns() []string {
+	return importColumns[j]
+}
+
+// ImportRow is one data row of the file. Baris is the line number as the user
+// sees it in a spreadsheet, counting the header as line 1.
+type ImportRow struct {
+	Baris  int
+	Values map[string]string
+	Errors []string
+}
+
+func (r ImportRow) Get(column string) string {
+	return strings.TrimSpace(r.Values[column])
+}
+
+func (r *ImportRow) AddError(err error) {
+	var webErr utils.WebError
+	if errors.As(err, &webErr) {
+		r.Errors = append(r.Errors, webErr.Message)
+		return
+	}
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// ImportHeader maps the header of a file to the columns of jenis. It fails on
+// a missing required column so a wrong file is rejected before any row is
+// read.
+func ImportHeader(jenis JenisImpor, header []string) (map[string]int, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, dup := index[name]; name != "" && !dup {
+			index[name] = i
+		}
+	}
+
+	var missing []string
+	for _, column := range jenis.Columns() {
+		if _, ok := index[column]; !ok && !optionalImportColumns[column] {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, utils.WebError{Field: "File", Message: "kolom tidak ditemukan: " + strings.Join(missing, ", ")}
+	}
+
+	return index, nil
+}
+
+func NewImportRow(jenis JenisImpor, baris int, index map[string]int, record []string) ImportRow {
+	row := ImportRow{Baris: baris, Values: make(map[string]string, len(jenis.Columns()))}
+	for _, column := range jenis.Columns() {
+		if i, ok := index[column]; ok && i < len(record) {
+			row.Values[column] = record[i]
+		}
+	}
+	return row
+}
+
+// ImportPreview is the validated content of an import file. Nothing is saved
+// unless every row is valid; Disimpan reports that the rows were committed.
+type ImportPreview struct {
+	Jenis    JenisImpor
+	NamaFile string
+	Rows     []ImportRow
+	Disimpan bool
+}
+
+func (p ImportPreview) Columns() []string {
+	return p.Jenis.Columns()
+}
+
+func (p ImportPreview) ErrorCount() int {
+	count := 0
+	for _, row := range p.Rows {
+		if len(row.Errors) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func (p ImportPreview) HasErrors() bool {
+	return p.ErrorCount() > 0
+}
+
+// ImportBatch holds the records built from a valid file, saved together in
+// one transaction.
+type ImportBatch struct {
+	Categories []Category
+	Locations  []Location
+	Rooms      []Room
+	Items      []Item
+}
@@ -1,9 +1,14 @@
 package entities
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/utils"
 )
 
 type KondisiUnit string
@@ -16,53 +21,313 @@ const (
 	KondisiDigudangkan KondisiUnit = "digudangkan"
 )
 
+var kondisiTransitions = map[KondisiUnit][]KondisiUnit{
+	KondisiBaik:        {KondisiRusak, KondisiHilang, KondisiDigudangkan},
+	KondisiRusak:       {KondisiPerbaikan, KondisiHilang, KondisiDigudangkan},
+	KondisiPerbaikan:   {KondisiBaik, KondisiRusak, KondisiHilang},
+	KondisiDigudangkan: {KondisiBaik, KondisiRusak, KondisiHilang},
+	KondisiHilang:      {},
+}
+
+func KondisiUnits() []KondisiUnit {
+	return []KondisiUnit{KondisiBaik, KondisiRusak, KondisiPerbaikan, KondisiHilang, KondisiDigudangkan}
+}
+
 func (k KondisiUnit) IsValid() bool {
 	switch k {
-	case KondisiBaik, KondisiRusak, KondisiPerbaikan, KondisiHilang:
+	case KondisiBaik, KondisiRusak, KondisiPerbaikan, KondisiHilang, KondisiDigudangkan:
 		return true
 	default:
 		return false
 	}
 }
 
+func (k KondisiUnit) IsTerminal() bool {
+	return len(kondisiTransitions[k]) == 0
+}
+
+func (k KondisiUnit) NextKondisi() []KondisiUnit {
+	return kondisiTransitions[k]
+}
+
+func (k KondisiUnit) CanTransitionTo(next KondisiUnit) bool {
+	for _, allowed := range kondisiTransitions[k] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ManualKondisi are the conditions a unit may be set to by hand. Diperbaiki
+// is entered and left through repair tickets only.
+func (k KondisiUnit) ManualKondisi() []KondisiUnit {
+	if k == KondisiPerbaikan {
+		return nil
+	}
+
+	var next []KondisiUnit
+	for _, n := range kondisiTransitions[k] {
+		if n != KondisiPerbaikan {
+			next = append(next, n)
+		}
+	}
+	return next
+}
+
+func (k KondisiUnit) CanSetManually(next KondisiUnit) bool {
+	return k != KondisiPerbaikan && next != KondisiPerbaikan && k.CanTransitionTo(next)
+}
+
+// ManualKondisiUnits are the targets offered when changing conditions by hand.
+func ManualKondisiUnits() []KondisiUnit {
+	return []KondisiUnit{KondisiBaik, KondisiRusak, KondisiHilang, KondisiDigudangkan}
+}
+
+type ItemForm struct {
+	SKU              string               `form:"sku_barang"`
+	Name             string               `form:"nama_barang"`
+	Kategori         string               `form:"kategori_barang"`
+	Jumlah           string               `form:"jumlah_barang"`
+	Satuan           string               `form:"satuan_barang"`
+	HargaSatuan      string               `form:"harga_barang"`
+	UmurEkonomis     string               `form:"umur_barang"`
+	MetodePenyusutan string               `form:"metode_penyusutan"`
+	NilaiResidu      string               `form:"residu_barang"`
+	Spesifikasi      string               `form:"spesifikasi_barang"`
+	TglPerolehan     string               `form:"tgl_perolehan"`
+	Atribut          []AttributeValueForm `form:"atribut"`
+}
+
 type Item struct {
-	Id           uuid.UUID `db:"id"`
-	SKU          string    `db:"sku"`
-	Nama         string    `db:"nama"`
-	Jumlah       int       `db:"jumlah"`
-	Satuan       string    `db:"satuan"`
-	HargaSatuan  int       `db:"harga_satuan"`
-	UmurEkonomis int       `db:"umur_ekonomis"`
-	Spesifikasi  string    `db:"spesifikasi"`
-	Slug         string    `db:"slug"`
-	TotalHarga   int       `db:"-"`
-	TglDibuat    time.Time `db:"tgl_dibuat"`
-	IdKategori   uuid.UUID `db:"id_kategori"`
-	Kategori     Category  `db:"-"`
+	Id               uuid.UUID        `db:"id" json:"id"`
+	SKU              string           `db:"sku" json:"sku"`
+	Nama             string           `db:"nama" json:"nama"`
+	Jumlah           int              `db:"jumlah" json:"jumlah"`
+	Satuan           string           `db:"satuan" json:"satuan"`
+	HargaSatuan      int              `db:"harga_satuan" json:"harga_satuan"`
+	UmurEkonomis     int              `db:"umur_ekonomis" json:"umur_ekonomis"`
+	MetodePenyusutan MetodePenyusutan `db:"metode_penyusutan" json:"metode_penyusutan"`
+	NilaiResidu      int              `db:"nilai_residu" json:"nilai_residu"`
+	Spesifikasi      string           `db:"spesifikasi" json:"spesifikasi"`
+	Slug             string           `db:"slug" json:"slug"`
+	TotalHarga       int              `db:"-" json:"total_harga"`
+	TglDibuat        time.Time        `db:"tgl_dibuat" json:"tgl_dibuat"`
+	TglPerolehan     time.Time        `db:"tgl_perolehan" json:"tgl_perolehan"`
+	IdKategori       int              `db:"id_kategori" json:"id_kategori"`
+	Kategori         Category         `db:"-" json:"kategori,omitzero"`
+	Atribut          AttributeValues  `db:"atribut" json:"atribut"`
+}
+
+func NewItem(reqForm ItemForm) (*Item, error) {
+	if !validateString(reqForm.SKU) {
+		return nil, utils.WebError{Field: "SKU", Message: "SKU harus diisi"}
+	}
+
+	if !validateString(reqForm.Name) {
+		return nil, utils.WebError{Field: "Nama", Message: "Nama harus diisi"}
+	}
+
+	if !validateString(reqForm.Kategori) {
+		return nil, utils.WebError{Field: "Kategori", Message: "Kategori harus ditentukan"}
+	}
+
+	if !validateString(reqForm.Satuan) {
+		return nil, utils.WebError{Field: "Satuan", Message: "Satuan harus diisi"}
+	}
+
+	idKategori, err := strconv.Atoi(strings.TrimSpace(reqForm.Kategori))
+	if err != nil || idKategori <= 0 {
+		return nil, utils.WebError{Field: "Kategori", Message: "Kategori tidak valid"}
+	}
+
+	jumlah := 1
+	if validateString(reqForm.Jumlah) {
+		jumlah, err = ParsePositiveInt(reqForm.Jumlah)
+		if err != nil {
+			return nil, utils.WebError{Field: "Jumlah", Message: "Jumlah harus berupa angka lebih dari 0"}
+		}
+	}
+
+	harga, err := ParseNonNegativeInt(reqForm.HargaSatuan)
+	if err != nil {
+		return nil, utils.WebError{Field: "HargaSatuan", Message: "Harga satuan harus berupa angka"}
+	}
+
+	umur, err := ParsePositiveInt(reqForm.UmurEkonomis)
+	if err != nil {
+		return nil, utils.WebError{Field: "UmurEkonomis", Message: "Umur ekonomis harus berupa angka lebih dari 0"}
+	}
+
+	metode, err := ParseMetodePenyusutan(strings.TrimSpace(reqForm.MetodePenyusutan))
+	if err != nil {
+		return nil, err
+	}
+
+	residu := 0
+	if validateString(reqForm.NilaiResidu) {
+		residu, err = ParseNonNegativeInt(reqForm.NilaiResidu)
+		if err != nil {
+			return nil, utils.WebError{Field: "NilaiResidu", Message: "Nilai residu harus berupa angka"}
+		}
+	}
+
+	if residu > harga {
+		return nil, utils.WebError{Field: "NilaiResidu", Message: "Nilai residu tidak boleh melebihi harga satuan"}
+	}
+
+	now := time.Now()
+	perolehan, err := ParseTglPerolehan(reqForm.TglPerolehan, DateOf(now), now)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(reqForm.Name)
+
+	return &Item{
+		Id:               uuid.New(),
+		SKU:              strings.TrimSpace(reqForm.SKU),
+		Nama:             name,
+		Jumlah:           jumlah,
+		Satuan:           strings.TrimSpace(reqForm.Satuan),
+		HargaSatuan:      harga,
+		UmurEkonomis:     umur,
+		MetodePenyusutan: metode,
+		NilaiResidu:      residu,
+		Spesifikasi:      strings.TrimSpace(reqForm.Spesifikasi),
+		Slug:             utils.NewSlug(name),
+		TglDibuat:        now,
+		TglPerolehan:     perolehan,
+		IdKategori:       idKategori,
+		Atribut:          AttributeValues{},
+	}, nil
+}
+
+// ParseTglPerolehan reads an acquisition date, which depreciation starts
+// from. An empty input gives fallback.
+func ParseTglPerolehan(input string, fallback, now time.Time) (time.Time, error) {
+	if strings.TrimSpace(input) == "" {
+		return fallback, nil
+	}
+
+	tanggal, err := ParseDate(input)
+	if err != nil {
+		return time.Time{}, utils.WebError{Field: "TglPerolehan", Message: "tanggal perolehan tidak valid"}
+	}
+
+	if tanggal.After(DateOf(now)) {
+		return time.Time{}, utils.WebError{Field: "TglPerolehan", Message: "tanggal perolehan tidak boleh di masa depan"}
+	}
+
+	return tanggal, nil
 }
 
 func (i *Item) GetTotalItem() {
 	i.TotalHarga = i.HargaSatuan * i.Jumlah
 }
 
+func ParsePositiveInt(input string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, strconv.ErrRange
+	}
+	return n, nil
+}
+
+func ParseNonNegativeInt(input string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, strconv.ErrRange
+	}
+	return n, nil
+}
+
+type UnitRowForm struct {
+	NoSeri       string `form:"no_seri"`
+	Ruangan      string `form:"ruangan"`
+	TglPerolehan string `form:"tgl_perolehan"`
+}
+
+type UnitForm struct {
+	Units []UnitRowForm `form:"units"`
+}
+
+type UnitConditionForm struct {
+	Kondisi string `form:"kondisi"`
+}
+
 type ItemUnit struct {
-	Id        uuid.UUID   `db:"id"`
-	NoSeri    string      `db:"no_seri"`
-	Kondisi   KondisiUnit `db:"kondisi"`
-	TglDibuat time.Time   `db:"tgl_dibuat"`
-	TglUpdate time.Time   `db:"tgl_update"`
-	IdBarang  uuid.UUID   `db:"id_barang"`
-	Barang    Item        `db:"-"`
-	IdRuangan uuid.UUID   `db:"id_ruangan"`
-	Ruangan   Room        `db:"-"`
+	Id        uuid.UUID   `db:"id" json:"id"`
+	NoSeri    string      `db:"no_seri" json:"no_seri"`
+	Kondisi   KondisiUnit `db:"kondisi" json:"kondisi"`
+	TglDibuat time.Time   `db:"tgl_dibuat" json:"tgl_dibuat"`
+	TglUpdate time.Time   `db:"tgl_update" json:"tgl_update"`
+	// TglPerolehan defaults to the acquisition date of the item.
+	TglPerolehan time.Time `db:"tgl_perolehan" json:"tgl_perolehan"`
+	IdBarang     uuid.UUID `db:"id_barang" json:"id_barang"`
+	Barang       Item      `db:"-" json:"barang,omitzero"`
+	IdRuangan    uuid.UUID `db:"id_ruangan" json:"id_ruangan"`
+	Ruangan      Room      `db:"-" json:"ruangan,omitzero"`
+}
+
+func NewItemUnits(item Item, reqForm UnitForm) ([]ItemUnit, error) {
+	if len(reqForm.Units) == 0 {
+		return nil, utils.WebError{Field: "Units", Message: "minimal satu unit harus diisi"}
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool, len(reqForm.Units))
+	units := make([]ItemUnit, 0, len(reqForm.Units))
+
+	for i, row := range reqForm.Units {
+		noSeri := strings.TrimSpace(row.NoSeri)
+		if noSeri == "" {
+			return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("nomor seri baris %d harus diisi", i+1)}
+		}
+
+		if seen[noSeri] {
+			return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("nomor seri %s diisi lebih dari sekali", noSeri)}
+		}
+		seen[noSeri] = true
+
+		idRuangan, err := uuid.Parse(strings.TrimSpace(row.Ruangan))
+		if err != nil {
+			return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("ruangan baris %d harus ditentukan", i+1)}
+		}
+
+		perolehan, err := ParseTglPerolehan(row.TglPerolehan, DateOf(item.TglPerolehan), now)
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("%s pada baris %d", webErr.Message, i+1)}
+		}
+
+		units = append(units, ItemUnit{
+			Id:           uuid.New(),
+			NoSeri:       noSeri,
+			Kondisi:      KondisiBaik,
+			TglDibuat:    now,
+			TglUpdate:    now,
+			TglPerolehan: perolehan,
+			IdBarang:     item.Id,
+			IdRuangan:    idRuangan,
+		})
+	}
+
+	return units, nil
 }
 
 type ItemPicture struct {
-	Id         int       `db:"id"`
-	ObjectName string    `db:"nama_objek"`
-	FileName   string    `db:"nama_file"`
-	FileSize   int64     `db:"ukuran_file"`
-	TglUpload  time.Time `db:"tgl_upload"`
-	IdBarang   uuid.UUID `db:"id_barang"`
-	Barang     Item      `db:"-"`
+	Id         int       `db:"id" json:"id"`
+	ObjectName string    `db:"nama_objek" json:"nama_objek"`
+	FileName   string    `db:"nama_file" json:"nama_file"`
+	FileSize   int64     `db:"ukuran_file" json:"ukuran_file"`
+	TglUpload  time.Time `db:"tgl_upload" json:"tgl_upload"`
+	IdBarang   uuid.UUID `db:"id_barang" json:"id_barang"`
+	Barang     Item      `db:"-" json:"-"`
 }
@@ -0,0 +1,50 @@
+package entities
+
+import "testing"
+
+func TestKondisiCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to KondisiUnit
+		want     bool
+	}{
+		{KondisiBaik, KondisiRusak, true},
+		{KondisiBaik, KondisiHilang, true},
+		{KondisiBaik, KondisiDigudangkan, true},
+		{KondisiBaik, KondisiPerbaikan, false},
+		{KondisiBaik, KondisiBaik, false},
+		{KondisiRusak, KondisiPerbaikan, true},
+		{KondisiRusak, KondisiBaik, false},
+		{KondisiPerbaikan, KondisiBaik, true},
+		{KondisiPerbaikan, KondisiRusak, true},
+		{KondisiPerbaikan, KondisiDigudangkan, false},
+		{KondisiDigudangkan, KondisiBaik, true},
+		{KondisiDigudangkan, KondisiPerbaikan, false},
+		{KondisiHilang, KondisiBaik, false},
+		{KondisiHilang, KondisiRusak, false},
+		{KondisiUnit("rusak_berat"), KondisiBaik, false},
+		{KondisiBaik, KondisiUnit("rusak_berat"), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%q.CanTransitionTo(%q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestKondisiTransitionsAreValid(t *testing.T) {
+	for _, k := range KondisiUnits() {
+		if !k.IsValid() {
+			t.Errorf("%q is listed but not valid", k)
+		}
+		for _, next := range k.NextKondisi() {
+			if !next.IsValid() {
+				t.Errorf("%q leads to invalid condition %q", k, next)
+			}
+		}
+	}
+
+	if !KondisiHilang.IsTerminal() {
+		t.Errorf("hilang should be terminal")
+	}
+}
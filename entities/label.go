@@ -0,0 +1,60 @@
+package entities
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Label is the physical tag of a unit. The QR code carries the unit URL so a
+// phone camera opens the unit straight away, or the bare unit id when no
+// public URL is configured; the Code128 barcode carries the serial number for
+// handheld scanners.
+type Label struct {
+	Unit    ItemUnit
+	Payload string
+}
+
+func UnitURL(baseURL string, id uuid.UUID) string {
+	return strings.TrimRight(baseURL, "/") + "/unit/" + id.String()
+}
+
+func NewLabels(units []ItemUnit, baseURL string) []Label {
+	labels := make([]Label, len(units))
+	for i, u := range units {
+		payload := u.Id.String()
+		if baseURL != "" {
+			payload = UnitURL(baseURL, u.Id)
+		}
+		labels[i] = Label{Unit: u, Payload: payload}
+	}
+	return labels
+}
+
+// Barcode is the Code128 content, falling back to the unit id for units
+// registered without a serial number.
+func (l Label) Barcode() string {
+	if l.Unit.NoSeri != "" {
+		return l.Unit.NoSeri
+	}
+	return l.Unit.Id.String()
+}
+
+// ParseScanCode reads what a scanner or the keyboard produced: a label QR
+// payload or a bare unit id gives the id, anything else is taken as a serial
+// number.
+func ParseScanCode(kode string) (uuid.UUID, string, bool) {
+	kode = strings.TrimSpace(kode)
+
+	raw := kode
+	if _, rest, ok := strings.Cut(kode, "/unit/"); ok {
+		raw, _, _ = strings.Cut(rest, "/")
+		raw, _, _ = strings.Cut(raw, "?")
+	}
+
+	if id, err := uuid.Parse(raw); err == nil {
+		return id, "", true
+	}
+
+	return uuid.Nil, kode, false
+}
@@ -0,0 +1,61 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewLabelsPayload(t *testing.T) {
+	unit := ItemUnit{Id: uuid.MustParse("6f1c2a4e-8b7d-4c3a-9e21-0d5f6a7b8c9d"), NoSeri: "SN-01"}
+
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"", "6f1c2a4e-8b7d-4c3a-9e21-0d5f6a7b8c9d"},
+		{"https://inventaris.example.id", "https://inventaris.example.id/unit/6f1c2a4e-8b7d-4c3a-9e21-0d5f6a7b8c9d"},
+		{"https://inventaris.example.id/", "https://inventaris.example.id/unit/6f1c2a4e-8b7d-4c3a-9e21-0d5f6a7b8c9d"},
+	}
+
+	for _, tt := range tests {
+		labels := NewLabels([]ItemUnit{unit}, tt.baseURL)
+		if got := labels[0].Payload; got != tt.want {
+			t.Errorf("NewLabels(%q) payload = %q, want %q", tt.baseURL, got, tt.want)
+		}
+		if id, _, ok := ParseScanCode(labels[0].Payload); !ok || id != unit.Id {
+			t.Errorf("payload %q does not scan back to the unit", labels[0].Payload)
+		}
+	}
+}
+
+func TestParseScanCode(t *testing.T) {
+	id := uuid.MustParse("6f1c2a4e-8b7d-4c3a-9e21-0d5f6a7b8c9d")
+
+	tests := []struct {
+		name   string
+		kode   string
+		id     uuid.UUID
+		noSeri string
+		ok     bool
+	}{
+		{"bare id", "6f1c2a4e-8b7d-4c3a-9e21-0d5f6a7b8c9d", id, "", true},
+		{"bare id with spaces", "  6f1c2a4e-8b7d-4c3a-9e21-0d5f6a7b8c9d\n", id, "", true},
+		{"unit url", "https://inventaris.example.id/unit/6f1c2a4e-8b7d-4c3a-9e21-0d5f6a7b8c9d", id, "", true},
+		{"unit url with trailing path", "http://localhost:8080/unit/6f1c2a4e-8b7d-4c3a-9e21-0d5f6a7b8c9d/", id, "", true},
+		{"unit url with query", "http://localhost:8080/unit/6f1c2a4e-8b7d-4c3a-9e21-0d5f6a7b8c9d?src=qr", id, "", true},
+		{"serial number", "SN-2024-001", uuid.Nil, "SN-2024-001", false},
+		{"serial number trimmed", " SN-2024-001 ", uuid.Nil, "SN-2024-001", false},
+		{"unit url with bad id", "http://localhost:8080/unit/bukan-id", uuid.Nil, "http://localhost:8080/unit/bukan-id", false},
+		{"empty", "", uuid.Nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotId, gotNoSeri, gotOk := ParseScanCode(tt.kode)
+			if gotId != tt.id || gotNoSeri != tt.noSeri || gotOk != tt.ok {
+				t.Errorf("ParseScanCode(%q) = (%s, %q, %v), want (%s, %q, %v)", tt.kode, gotId, gotNoSeri, gotOk, tt.id, tt.noSeri, tt.ok)
+			}
+		})
+	}
+}
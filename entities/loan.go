@@ -0,0 +1,267 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/utils"
+)
+
+// StatusPeminjaman is worked out from the units of a loan: selesai once every
+// unit is back, terlambat while any is out past the due date.
+type StatusPeminjaman string
+
+const (
+	PeminjamanAktif     StatusPeminjaman = "aktif"
+	PeminjamanTerlambat StatusPeminjaman = "terlambat"
+	PeminjamanSelesai   StatusPeminjaman = "selesai"
+)
+
+func StatusPeminjamans() []StatusPeminjaman {
+	return []StatusPeminjaman{PeminjamanAktif, PeminjamanTerlambat, PeminjamanSelesai}
+}
+
+type LoanForm struct {
+	Units      []string `form:"units"`
+	Peminjam   string   `form:"peminjam"`
+	Identitas  string   `form:"identitas"`
+	Kontak     string   `form:"kontak"`
+	Keperluan  string   `form:"keperluan"`
+	TglPinjam  string   `form:"tgl_pinjam"`
+	JatuhTempo string   `form:"tgl_jatuh_tempo"`
+}
+
+// Loan lends one or more units to a borrower, who is told apart by Identitas
+// (a student or staff number). Status and the unit counts are read from the
+// units of the loan.
+type Loan struct {
+	Id            uuid.UUID        `db:"id"`
+	Peminjam      string           `db:"peminjam"`
+	Identitas     string           `db:"identitas"`
+	Kontak        string           `db:"kontak"`
+	Keperluan     string           `db:"keperluan"`
+	TglPinjam     time.Time        `db:"tgl_pinjam"`
+	TglJatuhTempo time.Time        `db:"tgl_jatuh_tempo"`
+	IdPengguna    uuid.NullUUID    `db:"id_pengguna"`
+	Petugas       string           `db:"petugas"`
+	TglDibuat     time.Time        `db:"tgl_dibuat"`
+	Status        StatusPeminjaman `db:"status"`
+	JumlahUnit    int              `db:"jumlah_unit"`
+	JumlahKembali int              `db:"jumlah_kembali"`
+	Units         []LoanUnit       `db:"-"`
+}
+
+func (l Loan) IsClosed() bool {
+	return l.Status == PeminjamanSelesai
+}
+
+type LoanRequest struct {
+	Units []uuid.UUID
+	Loan  Loan
+}
+
+func NewLoanRequest(reqForm LoanForm, now time.Time) (*LoanRequest, error) {
+	units, err := parseUnitIds(reqForm.Units)
+	if err != nil {
+		return nil, err
+	}
+
+	peminjam := strings.TrimSpace(reqForm.Peminjam)
+	if peminjam == "" {
+		return nil, utils.WebError{Field: "Peminjam", Message: "nama peminjam harus diisi"}
+	}
+	if len(peminjam) > 100 {
+		return nil, utils.WebError{Field: "Peminjam", Message: "nama peminjam maksimal 100 karakter"}
+	}
+
+	identitas := strings.ToUpper(strings.TrimSpace(reqForm.Identitas))
+	if identitas == "" {
+		return nil, utils.WebError{Field: "Identitas", Message: "NIM/NIP peminjam harus diisi"}
+	}
+	if len(identitas) > 50 {
+		return nil, utils.WebError{Field: "Identitas", Message: "NIM/NIP maksimal 50 karakter"}
+	}
+
+	kontak := strings.TrimSpace(reqForm.Kontak)
+	if len(kontak) > 100 {
+		return nil, utils.WebError{Field: "Kontak", Message: "kontak maksimal 100 karakter"}
+	}
+
+	keperluan := strings.TrimSpace(reqForm.Keperluan)
+	if keperluan == "" {
+		return nil, utils.WebError{Field: "Keperluan", Message: "keperluan peminjaman harus diisi"}
+	}
+
+	pinjam, err := ParseDate(reqForm.TglPinjam)
+	if err != nil {
+		return nil, utils.WebError{Field: "TglPinjam", Message: "tanggal pinjam tidak valid"}
+	}
+	if pinjam.After(DateOf(now)) {
+		return nil, utils.WebError{Field: "TglPinjam", Message: "tanggal pinjam tidak boleh di masa depan"}
+	}
+
+	tempo, err := ParseDate(reqForm.JatuhTempo)
+	if err != nil {
+		return nil, utils.WebError{Field: "JatuhTempo", Message: "tanggal jatuh tempo tidak valid"}
+	}
+	if tempo.Before(pinjam) {
+		return nil, utils.WebError{Field: "JatuhTempo", Message: "jatuh tempo tidak boleh sebelum tanggal pinjam"}
+	}
+
+	return &LoanRequest{
+		Units: units,
+		Loan: Loan{
+			Id:            uuid.New(),
+			Peminjam:      peminjam,
+			Identitas:     identitas,
+			Kontak:        kontak,
+			Keperluan:     keperluan,
+			TglPinjam:     pinjam,
+			TglJatuhTempo: tempo,
+			TglDibuat:     now,
+			Status:        PeminjamanAktif,
+			JumlahUnit:    len(units),
+		},
+	}, nil
+}
+
+// LoanUnit is one unit of a loan. TglKembali is nil while the unit is still
+// out; KondisiPinjam and KondisiKembali are its condition when it left and
+// when it came back. Peminjaman is filled in for lists that span loans.
+type LoanUnit struct {
+	IdPeminjaman   uuid.UUID   `db:"id_peminjaman"`
+	IdUnit         uuid.UUID   `db:"id_unit"`
+	KondisiPinjam  KondisiUnit `db:"kondisi_pinjam"`
+	TglKembali     *time.Time  `db:"tgl_kembali"`
+	KondisiKembali KondisiUnit `db:"kondisi_kembali"`
+	CatatanKembali string      `db:"catatan_kembali"`
+	Unit           ItemUnit    `db:"-"`
+	Peminjaman     Loan        `db:"-"`
+}
+
+func (u LoanUnit) IsReturned() bool {
+	return u.TglKembali != nil
+}
+
+// HariTerlambat counts the days past the due date: up to today for a unit
+// still out, up to its return otherwise.
+func (u LoanUnit) HariTerlambat(today time.Time) int {
+	until := DateOf(today)
+	if u.TglKembali != nil {
+		until = *u.TglKembali
+	}
+
+	days := int(until.Sub(u.Peminjaman.TglJatuhTempo).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// KondisiKembaliOptions are the conditions a unit may be checked in with:
+// the one it has now or any it may change into.
+func (u LoanUnit) KondisiKembaliOptions() []KondisiUnit {
+	return append([]KondisiUnit{u.Unit.Kondisi}, u.Unit.Kondisi.ManualKondisi()...)
+}
+
+type LoanReturnRow struct {
+	Unit    string `form:"unit"`
+	Kembali bool   `form:"kembali"`
+	Kondisi string `form:"kondisi"`
+	Catatan string `form:"catatan"`
+}
+
+type LoanReturnForm struct {
+	Units   []LoanReturnRow `form:"units"`
+	Tanggal string          `form:"tgl_kembali"`
+}
+
+type LoanReturn struct {
+	Kondisi KondisiUnit
+	Catatan string
+}
+
+type LoanReturnRequest struct {
+	Units   map[uuid.UUID]LoanReturn
+	Tanggal time.Time
+}
+
+// NewLoanReturnRequest reads the ticked rows of the check-in form. Whether
+// each condition fits the unit is checked later against its locked state.
+func NewLoanReturnRequest(reqForm LoanReturnForm, now time.Time) (*LoanReturnRequest, error) {
+	tanggal, err := ParseDate(reqForm.Tanggal)
+	if err != nil {
+		return nil, utils.WebError{Field: "Tanggal", Message: "tanggal kembali tidak valid"}
+	}
+	if tanggal.After(DateOf(now)) {
+		return nil, utils.WebError{Field: "Tanggal", Message: "tanggal kembali tidak boleh di masa depan"}
+	}
+
+	units := make(map[uuid.UUID]LoanReturn)
+	for i, row := range reqForm.Units {
+		if !row.Kembali {
+			continue
+		}
+
+		id, err := uuid.Parse(strings.TrimSpace(row.Unit))
+		if err != nil {
+			return nil, utils.WebError{Field: "Units", Message: "unit tidak valid"}
+		}
+
+		kondisi := KondisiUnit(strings.TrimSpace(row.Kondisi))
+		if !kondisi.IsValid() {
+			return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("kondisi baris %d tidak valid", i+1)}
+		}
+
+		units[id] = LoanReturn{Kondisi: kondisi, Catatan: strings.TrimSpace(row.Catatan)}
+	}
+
+	if len(units) == 0 {
+		return nil, utils.WebError{Field: "Units", Message: "pilih minimal satu unit yang dikembalikan"}
+	}
+
+	return &LoanReturnRequest{Units: units, Tanggal: tanggal}, nil
+}
+
+// Rows keys the submitted rows by unit so a rejected check-in shows them
+// again.
+func (f LoanReturnForm) Rows() map[string]LoanReturnRow {
+	rows := make(map[string]LoanReturnRow, len(f.Units))
+	for _, row := range f.Units {
+		rows[strings.TrimSpace(row.Unit)] = row
+	}
+	return rows
+}
+
+// BorrowerHistory is every unit a borrower has taken out, newest loan first.
+type BorrowerHistory struct {
+	Identitas    string
+	Peminjam     string
+	Units        []LoanUnit
+	Dipinjam     int
+	Terlambat    int
+	Dikembalikan int
+}
+
+func NewBorrowerHistory(identitas string, units []LoanUnit, now time.Time) BorrowerHistory {
+	history := BorrowerHistory{Identitas: identitas, Units: units}
+	if len(units) > 0 {
+		history.Peminjam = units[0].Peminjaman.Peminjam
+	}
+
+	for _, u := range units {
+		switch {
+		case u.IsReturned():
+			history.Dikembalikan++
+		case u.HariTerlambat(now) > 0:
+			history.Terlambat++
+			history.Dipinjam++
+		default:
+			history.Dipinjam++
+		}
+	}
+
+	return history
+}
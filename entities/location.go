@@ -9,19 +9,72 @@ import (
 )
 
 type LocationForm struct {
-	Code string `form:"kode_lokasi"`
-	Name string `form:"nama_lokasi"`
+	Code   string `form:"kode_lokasi"`
+	Name   string `form:"nama_lokasi"`
+	Parent string `form:"id_induk"`
+}
+
+// LocationMoveForm moves a location with everything under it; an empty parent
+// makes it a top level location.
+type LocationMoveForm struct {
+	Parent string `form:"id_induk"`
 }
 
 type Location struct {
-	Id            uuid.UUID `db:"id"`
-	Kode          string    `db:"kode"`
-	Nama          string    `db:"nama"`
-	JumlahRuangan int       `db:"jumlah_ruangan"`
-	Slug          string    `db:"slug"`
-	TglDibuat     time.Time `db:"tgl_dibuat"`
-	TglUpdate     time.Time `db:"tgl_update"`
-	Ruangan       []Room    `db:"-"`
+	Id            uuid.UUID     `db:"id" json:"id"`
+	Kode          string        `db:"kode" json:"kode"`
+	Nama          string        `db:"nama" json:"nama"`
+	JumlahRuangan int           `db:"jumlah_ruangan" json:"jumlah_ruangan"`
+	Slug          string        `db:"slug" json:"slug"`
+	TglDibuat     time.Time     `db:"tgl_dibuat" json:"tgl_dibuat"`
+	TglUpdate     time.Time     `db:"tgl_update" json:"tgl_update"`
+	IdInduk       uuid.NullUUID `db:"id_induk" json:"id_induk"`
+	Ruangan       []Room        `db:"-" json:"ruangan,omitempty"`
+
+	// Jalur lists the ancestors from the top level down, for breadcrumbs.
+	// TotalRuangan and TotalUnit count the live rooms and units of the
+	// location and every location under it.
+	Jalur        []LocationRef `db:"-" json:"jalur,omitempty"`
+	Sublokasi    []Location    `db:"-" json:"sublokasi,omitempty"`
+	TotalRuangan int           `db:"-" json:"total_ruangan"`
+	TotalUnit    int           `db:"-" json:"total_unit"`
+}
+
+// LocationRef is one step of a breadcrumb.
+type LocationRef struct {
+	Id   uuid.UUID `json:"id"`
+	Nama string    `json:"nama"`
+	Slug string    `json:"slug"`
+}
+
+// NamaLengkap is the name prefixed with its ancestors, as shown in location
+// pickers.
+func (l Location) NamaLengkap() string {
+	if len(l.Jalur) == 0 {
+		return l.Nama
+	}
+
+	names := make([]string, 0, len(l.Jalur)+1)
+	for _, ref := range l.Jalur {
+		names = append(names, ref.Nama)
+	}
+	return strings.Join(append(names, l.Nama), " / ")
+}
+
+// IsUnder reports whether id is one of the location's ancestors.
+func (l Location) IsUnder(id uuid.UUID) bool {
+	for _, ref := range l.Jalur {
+		if ref.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// LocationRollup is the room and unit count over a location's subtree.
+type LocationRollup struct {
+	Ruangan int
+	Unit    int
 }
 
 func NewLocation(code, name string) (*Location, error) {
@@ -0,0 +1,177 @@
+package entities
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/utils"
+)
+
+type StatusOpname string
+
+const (
+	OpnameBerjalan StatusOpname = "berjalan"
+	OpnameSelesai  StatusOpname = "selesai"
+)
+
+func StatusOpnames() []StatusOpname {
+	return []StatusOpname{OpnameBerjalan, OpnameSelesai}
+}
+
+// HasilOpname is how a unit ends up in the count: scanned where it is
+// registered, registered in scope but never scanned, or scanned while
+// registered somewhere else.
+type HasilOpname string
+
+const (
+	HasilDitemukan      HasilOpname = "ditemukan"
+	HasilTidakDitemukan HasilOpname = "tidak_ditemukan"
+	HasilTidakTerduga   HasilOpname = "tidak_terduga"
+)
+
+// Actions applied to a unit when the session is closed.
+const (
+	TindakanTandaiHilang = "ditandai_hilang"
+	TindakanDipindahkan  = "dipindahkan"
+)
+
+type OpnameForm struct {
+	Ruangan string `form:"ruangan"`
+	Lokasi  string `form:"lokasi"`
+	Catatan string `form:"catatan"`
+}
+
+type OpnameCloseForm struct {
+	TandaiHilang bool `form:"tandai_hilang"`
+	Pindahkan    bool `form:"pindahkan"`
+}
+
+// StockOpname is a physical count of the units in one room or, when
+// IdRuangan is null, in every room of a location. NamaCakupan keeps the name
+// of the room or location as it was when the count started.
+type StockOpname struct {
+	Id          uuid.UUID     `db:"id"`
+	IdRuangan   uuid.NullUUID `db:"id_ruangan"`
+	IdLokasi    uuid.UUID     `db:"id_lokasi"`
+	NamaCakupan string        `db:"nama_cakupan"`
+	Status      StatusOpname  `db:"status"`
+	Catatan     string        `db:"catatan"`
+	IdPengguna  uuid.NullUUID `db:"id_pengguna"`
+	Petugas     string        `db:"petugas"`
+	TglMulai    time.Time     `db:"tgl_mulai"`
+	TglSelesai  *time.Time    `db:"tgl_selesai"`
+}
+
+func (o StockOpname) IsRoom() bool {
+	return o.IdRuangan.Valid
+}
+
+func (o StockOpname) IsClosed() bool {
+	return o.Status == OpnameSelesai
+}
+
+// NewOpname validates the scope of a new session. Exactly one of room and
+// location must be chosen; the caller fills in the names and the location of
+// a room.
+func NewOpname(reqForm OpnameForm, now time.Time) (*StockOpname, error) {
+	ruangan := strings.TrimSpace(reqForm.Ruangan)
+	lokasi := strings.TrimSpace(reqForm.Lokasi)
+
+	if (ruangan == "") == (lokasi == "") {
+		return nil, utils.WebError{Field: "Cakupan", Message: "pilih salah satu ruangan atau lokasi"}
+	}
+
+	op := &StockOpname{
+		Id:       uuid.New(),
+		Status:   OpnameBerjalan,
+		Catatan:  strings.TrimSpace(reqForm.Catatan),
+		TglMulai: now,
+	}
+
+	if ruangan != "" {
+		id, err := uuid.Parse(ruangan)
+		if err != nil {
+			return nil, utils.WebError{Field: "Cakupan", Message: "ruangan tidak valid"}
+		}
+		op.IdRuangan = uuid.NullUUID{UUID: id, Valid: true}
+	} else {
+		id, err := uuid.Parse(lokasi)
+		if err != nil {
+			return nil, utils.WebError{Field: "Cakupan", Message: "lokasi tidak valid"}
+		}
+		op.IdLokasi = id
+	}
+
+	return op, nil
+}
+
+// OpnameUnit is a unit taking part in a session: either captured at the
+// start because it was registered in scope (Diharapkan), or added when it was
+// scanned while registered elsewhere. IdRuangan is the room it was registered
+// in at that moment.
+type OpnameUnit struct {
+	IdOpname   uuid.UUID     `db:"id_opname"`
+	IdUnit     uuid.UUID     `db:"id_unit"`
+	IdRuangan  uuid.NullUUID `db:"id_ruangan"`
+	Diharapkan bool          `db:"diharapkan"`
+	KodeScan   string        `db:"kode_scan"`
+	TglScan    *time.Time    `db:"tgl_scan"`
+	Catatan    string        `db:"catatan"`
+	Tindakan   string        `db:"tindakan"`
+	Unit       ItemUnit      `db:"-"`
+}
+
+func (u OpnameUnit) Hasil() HasilOpname {
+	switch {
+	case !u.Diharapkan:
+		return HasilTidakTerduga
+	case u.TglScan != nil:
+		return HasilDitemukan
+	default:
+		return HasilTidakDitemukan
+	}
+}
+
+type OpnameReport struct {
+	Opname         StockOpname
+	Ditemukan      []OpnameUnit
+	TidakDitemukan []OpnameUnit
+	TidakTerduga   []OpnameUnit
+}
+
+func NewOpnameReport(op StockOpname, units []OpnameUnit) OpnameReport {
+	report := OpnameReport{Opname: op}
+	for _, u := range units {
+		switch u.Hasil() {
+		case HasilDitemukan:
+			report.Ditemukan = append(report.Ditemukan, u)
+		case HasilTidakDitemukan:
+			report.TidakDitemukan = append(report.TidakDitemukan, u)
+		case HasilTidakTerduga:
+			report.TidakTerduga = append(report.TidakTerduga, u)
+		}
+	}
+	return report
+}
+
+func (r OpnameReport) TotalDiharapkan() int {
+	return len(r.Ditemukan) + len(r.TidakDitemukan)
+}
+
+// OpnameScan is the outcome of one scan, shown to the person holding the
+// phone.
+type OpnameScan struct {
+	Kode          string
+	Unit          ItemUnit
+	Hasil         HasilOpname
+	SudahDipindai bool
+}
+
+// OpnameClosing is what closing a session changes: units to mark hilang and
+// transfers of misplaced units into the counted room.
+type OpnameClosing struct {
+	Opname    StockOpname
+	Hilang    []ItemUnit
+	Transfers []Transfer
+}
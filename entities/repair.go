@@ -0,0 +1,264 @@
+package entities
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/utils"
+)
+
+type StatusPerbaikan string
+
+const (
+	PerbaikanBerjalan StatusPerbaikan = "berjalan"
+	PerbaikanSelesai  StatusPerbaikan = "selesai"
+)
+
+func StatusPerbaikans() []StatusPerbaikan {
+	return []StatusPerbaikan{PerbaikanBerjalan, PerbaikanSelesai}
+}
+
+type RepairForm struct {
+	Unit     string `form:"unit"`
+	Masalah  string `form:"masalah"`
+	Vendor   string `form:"vendor"`
+	Biaya    string `form:"biaya"`
+	TglMulai string `form:"tgl_mulai"`
+}
+
+// Repair is a maintenance ticket of one unit. The unit is diperbaiki while the
+// ticket is berjalan and takes KondisiHasil when it is closed. IdRuangan is
+// the room the unit was in when the ticket was opened, so spending stays with
+// that location after the unit moves.
+type Repair struct {
+	Id           uuid.UUID       `db:"id"`
+	IdUnit       uuid.UUID       `db:"id_unit"`
+	IdRuangan    uuid.NullUUID   `db:"id_ruangan"`
+	Masalah      string          `db:"masalah"`
+	Vendor       string          `db:"vendor"`
+	Biaya        int             `db:"biaya"`
+	Status       StatusPerbaikan `db:"status"`
+	KondisiAwal  KondisiUnit     `db:"kondisi_awal"`
+	KondisiHasil KondisiUnit     `db:"kondisi_hasil"`
+	Catatan      string          `db:"catatan"`
+	TglMulai     time.Time       `db:"tgl_mulai"`
+	TglSelesai   *time.Time      `db:"tgl_selesai"`
+	IdPengguna   uuid.NullUUID   `db:"id_pengguna"`
+	Petugas      string          `db:"petugas"`
+	TglDibuat    time.Time       `db:"tgl_dibuat"`
+	Unit         ItemUnit        `db:"-"`
+}
+
+func (r Repair) IsClosed() bool {
+	return r.Status == PerbaikanSelesai
+}
+
+func NewRepair(reqForm RepairForm, now time.Time) (*Repair, error) {
+	unit, err := uuid.Parse(strings.TrimSpace(reqForm.Unit))
+	if err != nil {
+		return nil, utils.WebError{Field: "Unit", Message: "unit tidak valid"}
+	}
+
+	masalah := strings.TrimSpace(reqForm.Masalah)
+	if masalah == "" {
+		return nil, utils.WebError{Field: "Masalah", Message: "deskripsi masalah harus diisi"}
+	}
+
+	vendor := strings.TrimSpace(reqForm.Vendor)
+	if len(vendor) > 100 {
+		return nil, utils.WebError{Field: "Vendor", Message: "vendor maksimal 100 karakter"}
+	}
+
+	var biaya int
+	if strings.TrimSpace(reqForm.Biaya) != "" {
+		if biaya, err = ParseNonNegativeInt(reqForm.Biaya); err != nil {
+			return nil, utils.WebError{Field: "Biaya", Message: "biaya harus berupa angka positif"}
+		}
+	}
+
+	mulai, err := ParseDate(reqForm.TglMulai)
+	if err != nil {
+		return nil, utils.WebError{Field: "TglMulai", Message: "tanggal mulai tidak valid"}
+	}
+	if mulai.After(DateOf(now)) {
+		return nil, utils.WebError{Field: "TglMulai", Message: "tanggal mulai tidak boleh di masa depan"}
+	}
+
+	return &Repair{
+		Id:        uuid.New(),
+		IdUnit:    unit,
+		Masalah:   masalah,
+		Vendor:    vendor,
+		Biaya:     biaya,
+		Status:    PerbaikanBerjalan,
+		TglMulai:  mulai,
+		TglDibuat: now,
+	}, nil
+}
+
+type RepairCloseForm struct {
+	Kondisi    string `form:"kondisi"`
+	Biaya      string `form:"biaya"`
+	TglSelesai string `form:"tgl_selesai"`
+	Catatan    string `form:"catatan"`
+}
+
+// RepairClosing is a parsed close form. Biaya is nil when it was left empty,
+// keeping the cost recorded so far.
+type RepairClosing struct {
+	Kondisi    KondisiUnit
+	Biaya      *int
+	TglSelesai time.Time
+	Catatan    string
+}
+
+// KondisiHasilPerbaikan are the conditions a unit may come out of a repair
+// with.
+func KondisiHasilPerbaikan() []KondisiUnit {
+	return KondisiPerbaikan.NextKondisi()
+}
+
+func NewRepairClosing(reqForm RepairCloseForm, now time.Time) (*RepairClosing, error) {
+	kondisi := KondisiUnit(strings.TrimSpace(reqForm.Kondisi))
+	if !KondisiPerbaikan.CanTransitionTo(kondisi) {
+		return nil, utils.WebError{Field: "Kondisi", Message: "pilih kondisi unit setelah perbaikan"}
+	}
+
+	closing := &RepairClosing{Kondisi: kondisi, Catatan: strings.TrimSpace(reqForm.Catatan)}
+
+	if strings.TrimSpace(reqForm.Biaya) != "" {
+		biaya, err := ParseNonNegativeInt(reqForm.Biaya)
+		if err != nil {
+			return nil, utils.WebError{Field: "Biaya", Message: "biaya harus berupa angka positif"}
+		}
+		closing.Biaya = &biaya
+	}
+
+	selesai, err := ParseDate(reqForm.TglSelesai)
+	if err != nil {
+		return nil, utils.WebError{Field: "TglSelesai", Message: "tanggal selesai tidak valid"}
+	}
+	if selesai.After(DateOf(now)) {
+		return nil, utils.WebError{Field: "TglSelesai", Message: "tanggal selesai tidak boleh di masa depan"}
+	}
+	closing.TglSelesai = selesai
+
+	return closing, nil
+}
+
+// RepairHistory is every repair of the units of an item, newest first.
+type RepairHistory struct {
+	Repairs    []Repair
+	Berjalan   int
+	TotalBiaya int
+}
+
+func NewRepairHistory(repairs []Repair) RepairHistory {
+	history := RepairHistory{Repairs: repairs}
+	for _, r := range repairs {
+		history.TotalBiaya += r.Biaya
+		if !r.IsClosed() {
+			history.Berjalan++
+		}
+	}
+	return history
+}
+
+// RepairSpendLine is one ticket of the spend report with the category of its
+// item and the location it was opened in.
+type RepairSpendLine struct {
+	Biaya        int
+	Status       StatusPerbaikan
+	KodeKategori string
+	NamaKategori string
+	KodeLokasi   string
+	NamaLokasi   string
+}
+
+type RepairSpendRow struct {
+	Kode     string
+	Nama     string
+	Tiket    int
+	Berjalan int
+	Biaya    int
+}
+
+type RepairSpend struct {
+	Dari       time.Time
+	Sampai     time.Time
+	ByCategory []RepairSpendRow
+	ByLocation []RepairSpendRow
+	Total      RepairSpendRow
+}
+
+// NewRepairSpend adds up the tickets started between dari and sampai, per
+// category and per location.
+func NewRepairSpend(dari, sampai time.Time, lines []RepairSpendLine) RepairSpend {
+	spend := RepairSpend{Dari: dari, Sampai: sampai, Total: RepairSpendRow{Nama: "Total"}}
+
+	categories := newRepairSpendGroups()
+	locations := newRepairSpendGroups()
+	for _, line := range lines {
+		nama := line.NamaLokasi
+		if line.KodeLokasi == "" {
+			nama = "Tidak diketahui"
+		}
+
+		for _, row := range []*RepairSpendRow{
+			categories.get(line.KodeKategori, line.NamaKategori),
+			locations.get(line.KodeLokasi, nama),
+			&spend.Total,
+		} {
+			row.Tiket++
+			row.Biaya += line.Biaya
+			if line.Status == PerbaikanBerjalan {
+				row.Berjalan++
+			}
+		}
+	}
+
+	spend.ByCategory = categories.rows()
+	spend.ByLocation = locations.rows()
+	sort.SliceStable(spend.ByLocation, func(i, j int) bool {
+		a, b := spend.ByLocation[i], spend.ByLocation[j]
+		if (a.Kode == "") != (b.Kode == "") {
+			return b.Kode == ""
+		}
+		return a.Nama < b.Nama
+	})
+	return spend
+}
+
+func (s RepairSpend) FileName(ext string) string {
+	return fmt.Sprintf("biaya-perbaikan-%s-%s.%s", s.Dari.Format(DateLayout), s.Sampai.Format(DateLayout), ext)
+}
+
+type repairSpendGroups struct {
+	order  []string
+	byKode map[string]*RepairSpendRow
+}
+
+func newRepairSpendGroups() *repairSpendGroups {
+	return &repairSpendGroups{byKode: make(map[string]*RepairSpendRow)}
+}
+
+func (g *repairSpendGroups) get(kode, nama string) *RepairSpendRow {
+	row, ok := g.byKode[kode]
+	if !ok {
+		row = &RepairSpendRow{Kode: kode, Nama: nama}
+		g.byKode[kode] = row
+		g.order = append(g.order, kode)
+	}
+	return row
+}
+
+func (g *repairSpendGroups) rows() []RepairSpendRow {
+	rows := make([]RepairSpendRow, 0, len(g.order))
+	for _, kode := range g.order {
+		rows = append(rows, *g.byKode[kode])
+	}
+	return rows
+}
@@ -0,0 +1,206 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type KIRGroup struct {
+	Barang Item
+	Units  []ItemUnit
+}
+
+func (g KIRGroup) TotalNilai() int {
+	return g.Barang.HargaSatuan * len(g.Units)
+}
+
+// KIR (Kartu Inventaris Ruangan) lists every unit placed in a room, grouped by
+// item, to be signed by the room's penanggung jawab.
+type KIR struct {
+	Ruangan    Room
+	Groups     []KIRGroup
+	TotalUnit  int
+	TotalNilai int
+	Dicetak    time.Time
+}
+
+func NewKIR(room Room, printedAt time.Time) KIR {
+	kir := KIR{Ruangan: room, Dicetak: printedAt}
+
+	index := make(map[uuid.UUID]int)
+	for _, unit := range room.Items {
+		i, ok := index[unit.Barang.Id]
+		if !ok {
+			i = len(kir.Groups)
+			index[unit.Barang.Id] = i
+			kir.Groups = append(kir.Groups, KIRGroup{Barang: unit.Barang})
+		}
+
+		kir.Groups[i].Units = append(kir.Groups[i].Units, unit)
+		kir.TotalUnit++
+		kir.TotalNilai += unit.Barang.HargaSatuan
+	}
+
+	kir.Ruangan.Items = nil
+	return kir
+}
+
+func (k KIR) Semester() string {
+	semester := 1
+	if k.Dicetak.Month() > time.June {
+		semester = 2
+	}
+	return fmt.Sprintf("Semester %d Tahun %d", semester, k.Dicetak.Year())
+}
+
+func (k KIR) FileName(ext string) string {
+	return fmt.Sprintf("KIR-%s-%s.%s", k.Ruangan.Slug, k.Dicetak.Format(DateLayout), ext)
+}
+
+var bulan = [...]string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+func FormatTanggal(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), bulan[t.Month()-1], t.Year())
+}
+
+type ValuationItem struct {
+	IdBarang     uuid.UUID
+	HargaSatuan  int
+	Jumlah       int
+	KodeKategori string
+	NamaKategori string
+}
+
+// ValuationUnit is a registered unit with the location it was in at the
+// report cutoff. Kondisi is its current condition, which is not tracked over
+// time.
+type ValuationUnit struct {
+	IdBarang   uuid.UUID
+	Kondisi    KondisiUnit
+	KodeLokasi string
+	NamaLokasi string
+}
+
+type ValuationRow struct {
+	Kode        string
+	Nama        string
+	JenisBarang int
+	Jumlah      int
+	Nilai       int
+	Kondisi     map[KondisiUnit]int
+}
+
+func (r ValuationRow) CountOf(kondisi KondisiUnit) int {
+	return r.Kondisi[kondisi]
+}
+
+type Valuation struct {
+	Cutoff     time.Time
+	ByCategory []ValuationRow
+	ByLocation []ValuationRow
+	Total      ValuationRow
+}
+
+// NewValuation values items at HargaSatuan * Jumlah. Per location only
+// registered units can be placed, the remaining quantity of each item is
+// reported as unplaced so both groupings add up to the same total.
+func NewValuation(cutoff time.Time, items []ValuationItem, units []ValuationUnit) Valuation {
+	val := Valuation{
+		Cutoff: cutoff,
+		Total:  ValuationRow{Nama: "Total", Kondisi: map[KondisiUnit]int{}},
+	}
+
+	byItem := make(map[uuid.UUID]ValuationItem, len(items))
+	categories := newValuationGroups()
+	for _, item := range items {
+		byItem[item.IdBarang] = item
+
+		row := categories.get(item.KodeKategori, item.NamaKategori)
+		row.JenisBarang++
+		row.Jumlah += item.Jumlah
+		row.Nilai += item.HargaSatuan * item.Jumlah
+
+		val.Total.JenisBarang++
+		val.Total.Jumlah += item.Jumlah
+		val.Total.Nilai += item.HargaSatuan * item.Jumlah
+	}
+
+	placed := make(map[uuid.UUID]int)
+	locations := newValuationGroups()
+	locationItems := make(map[string]map[uuid.UUID]bool)
+	for _, unit := range units {
+		item, ok := byItem[unit.IdBarang]
+		if !ok {
+			continue
+		}
+		placed[unit.IdBarang]++
+
+		categories.get(item.KodeKategori, item.NamaKategori).Kondisi[unit.Kondisi]++
+		val.Total.Kondisi[unit.Kondisi]++
+
+		nama := unit.NamaLokasi
+		if unit.KodeLokasi == "" {
+			nama = "Tidak diketahui"
+		}
+		row := locations.get(unit.KodeLokasi, nama)
+		row.Jumlah++
+		row.Nilai += item.HargaSatuan
+		row.Kondisi[unit.Kondisi]++
+
+		if locationItems[unit.KodeLokasi] == nil {
+			locationItems[unit.KodeLokasi] = make(map[uuid.UUID]bool)
+		}
+		if !locationItems[unit.KodeLokasi][unit.IdBarang] {
+			locationItems[unit.KodeLokasi][unit.IdBarang] = true
+			row.JenisBarang++
+		}
+	}
+
+	for _, item := range items {
+		rest := item.Jumlah - placed[item.IdBarang]
+		if rest <= 0 {
+			continue
+		}
+
+		row := locations.get("-", "Belum ditempatkan")
+		row.JenisBarang++
+		row.Jumlah += rest
+		row.Nilai += item.HargaSatuan * rest
+	}
+
+	val.ByCategory = categories.rows()
+	val.ByLocation = locations.rows()
+	return val
+}
+
+type valuationGroups struct {
+	order  []string
+	byKode map[string]*ValuationRow
+}
+
+func newValuationGroups() *valuationGroups {
+	return &valuationGroups{byKode: make(map[string]*ValuationRow)}
+}
+
+func (g *valuationGroups) get(kode, nama string) *ValuationRow {
+	row, ok := g.byKode[kode]
+	if !ok {
+		row = &ValuationRow{Kode: kode, Nama: nama, Kondisi: map[KondisiUnit]int{}}
+		g.byKode[kode] = row
+		g.order = append(g.order, kode)
+	}
+	return row
+}
+
+func (g *valuationGroups) rows() []ValuationRow {
+	rows := make([]ValuationRow, 0, len(g.order))
+	for _, kode := range g.order {
+		rows = append(rows, *g.byKode[kode])
+	}
+	return rows
+}
@@ -14,16 +14,16 @@ type RoomForm struct {
 }
 
 type Room struct {
-	Id              uuid.UUID  `db:"id"`
-	Nama            string     `db:"nama"`
-	PenanggungJawab string     `db:"penanggung_jawab"`
-	JumlahBarang    int        `db:"jumlah_barang"`
-	Slug            string     `db:"slug"`
-	LokasiId        uuid.UUID  `db:"id_lokasi"`
-	Lokasi          Location   `db:"-"`
-	TglDibuat       time.Time  `db:"tgl_dibuat"`
-	TglUpdate       time.Time  `db:"tgl_update"`
-	Items           []ItemUnit `db:"-"`
+	Id              uuid.UUID  `db:"id" json:"id"`
+	Nama            string     `db:"nama" json:"nama"`
+	PenanggungJawab string     `db:"penanggung_jawab" json:"penanggung_jawab"`
+	JumlahBarang    int        `db:"jumlah_barang" json:"jumlah_barang"`
+	Slug            string     `db:"slug" json:"slug"`
+	LokasiId        uuid.UUID  `db:"id_lokasi" json:"id_lokasi"`
+	Lokasi          Location   `db:"-" json:"lokasi,omitzero"`
+	TglDibuat       time.Time  `db:"tgl_dibuat" json:"tgl_dibuat"`
+	TglUpdate       time.Time  `db:"tgl_update" json:"tgl_update"`
+	Items           []ItemUnit `db:"-" json:"unit,omitempty"`
 }
 
 func NewRoom(reqForm RoomForm) (*Room, error) {
@@ -42,7 +42,7 @@ func NewRoom(reqForm RoomForm) (*Room, error) {
 	now := time.Now()
 	idLokasi, err := uuid.Parse(reqForm.Lokasi)
 	if err != nil {
-		return nil, err
+		return nil, utils.WebError{Field: "Lokasi", Message: "Lokasi tidak valid"}
 	}
 
 	return &Room{
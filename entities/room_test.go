@@ -0,0 +1,15 @@
+package entities
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/qeunasd/coniven/utils"
+)
+
+func TestNewRoomInvalidLokasi(t *testing.T) {
+	_, err := NewRoom(RoomForm{Name: "Lab", Manager: "Budi", Lokasi: "bukan-uuid"})
+	if !errors.Is(err, utils.ErrValidation) {
+		t.Errorf("NewRoom error = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,134 @@
+package entities
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/utils"
+)
+
+const DateLayout = "2006-01-02"
+
+type TransferForm struct {
+	Units   []string `form:"units"`
+	Tujuan  string   `form:"ruangan_tujuan"`
+	Alasan  string   `form:"alasan"`
+	Tanggal string   `form:"tgl_mutasi"`
+}
+
+type Transfer struct {
+	Id                uuid.UUID     `db:"id"`
+	IdUnit            uuid.UUID     `db:"id_unit"`
+	IdRuanganAsal     uuid.NullUUID `db:"id_ruangan_asal"`
+	IdRuanganTujuan   uuid.NullUUID `db:"id_ruangan_tujuan"`
+	NamaRuanganAsal   string        `db:"nama_ruangan_asal"`
+	NamaRuanganTujuan string        `db:"nama_ruangan_tujuan"`
+	Alasan            string        `db:"alasan"`
+	TglMutasi         time.Time     `db:"tgl_mutasi"`
+	TglDibuat         time.Time     `db:"tgl_dibuat"`
+}
+
+type TransferRequest struct {
+	Units   []uuid.UUID
+	Tujuan  uuid.UUID
+	Alasan  string
+	Tanggal time.Time
+}
+
+func NewTransferRequest(reqForm TransferForm, now time.Time) (*TransferRequest, error) {
+	units, err := parseUnitIds(reqForm.Units)
+	if err != nil {
+		return nil, err
+	}
+
+	tujuan, err := uuid.Parse(strings.TrimSpace(reqForm.Tujuan))
+	if err != nil {
+		return nil, utils.WebError{Field: "Tujuan", Message: "ruangan tujuan harus ditentukan"}
+	}
+
+	alasan := strings.TrimSpace(reqForm.Alasan)
+	if alasan == "" {
+		return nil, utils.WebError{Field: "Alasan", Message: "alasan mutasi harus diisi"}
+	}
+
+	tanggal, err := ParseDate(reqForm.Tanggal)
+	if err != nil {
+		return nil, utils.WebError{Field: "Tanggal", Message: "tanggal mutasi tidak valid"}
+	}
+
+	if tanggal.After(DateOf(now)) {
+		return nil, utils.WebError{Field: "Tanggal", Message: "tanggal mutasi tidak boleh di masa depan"}
+	}
+
+	return &TransferRequest{Units: units, Tujuan: tujuan, Alasan: alasan, Tanggal: tanggal}, nil
+}
+
+// parseUnitIds reads the unit checkboxes of a form, dropping duplicates.
+func parseUnitIds(raw []string) ([]uuid.UUID, error) {
+	if len(raw) == 0 {
+		return nil, utils.WebError{Field: "Units", Message: "pilih minimal satu unit"}
+	}
+
+	seen := make(map[uuid.UUID]bool, len(raw))
+	units := make([]uuid.UUID, 0, len(raw))
+	for _, r := range raw {
+		id, err := uuid.Parse(strings.TrimSpace(r))
+		if err != nil {
+			return nil, utils.WebError{Field: "Units", Message: "unit tidak valid"}
+		}
+		if !seen[id] {
+			seen[id] = true
+			units = append(units, id)
+		}
+	}
+
+	return units, nil
+}
+
+// ParseDate parses a yyyy-mm-dd form value. Dates are kept at UTC midnight,
+// which is how pgx returns DATE and TIMESTAMP columns.
+func ParseDate(input string) (time.Time, error) {
+	return time.Parse(DateLayout, strings.TrimSpace(input))
+}
+
+func DateOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+type UnitStay struct {
+	NamaRuangan string
+	Dari        time.Time
+	Sampai      time.Time
+	Alasan      string
+}
+
+func (s UnitStay) IsCurrent() bool {
+	return s.Sampai.IsZero()
+}
+
+// BuildUnitStays turns the transfer history of a unit, ordered by date, into
+// the periods the unit spent in each room, starting from its registration.
+func BuildUnitStays(unit ItemUnit, transfers []Transfer) []UnitStay {
+	if len(transfers) == 0 {
+		return []UnitStay{{NamaRuangan: unit.Ruangan.Nama, Dari: unit.TglDibuat}}
+	}
+
+	stays := []UnitStay{{NamaRuangan: transfers[0].NamaRuanganAsal, Dari: unit.TglDibuat}}
+	for _, t := range transfers {
+		stays[len(stays)-1].Sampai = t.TglMutasi
+		stays = append(stays, UnitStay{NamaRuangan: t.NamaRuanganTujuan, Dari: t.TglMutasi, Alasan: t.Alasan})
+	}
+
+	return stays
+}
+
+func UnitStayAt(stays []UnitStay, at time.Time) (UnitStay, bool) {
+	for i := len(stays) - 1; i >= 0; i-- {
+		if !at.Before(stays[i].Dari) {
+			return stays[i], true
+		}
+	}
+	return UnitStay{}, false
+}
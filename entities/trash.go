@@ -0,0 +1,82 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/qeunasd/coniven/utils"
+)
+
+type JenisSampah string
+
+const (
+	SampahKategori JenisSampah = "kategori"
+	SampahLokasi   JenisSampah = "lokasi"
+	SampahRuangan  JenisSampah = "ruangan"
+	SampahBarang   JenisSampah = "barang"
+	SampahUnit     JenisSampah = "unit"
+)
+
+func JenisSampahs() []JenisSampah {
+	return []JenisSampah{SampahKategori, SampahLokasi, SampahRuangan, SampahBarang, SampahUnit}
+}
+
+func ParseJenisSampah(input string) (JenisSampah, error) {
+	switch j := JenisSampah(input); j {
+	case SampahKategori, SampahLokasi, SampahRuangan, SampahBarang, SampahUnit:
+		return j, nil
+	default:
+		return "", utils.WebError{Field: "Jenis", Message: "jenis data tidak dikenal"}
+	}
+}
+
+// AuditEntity is the audit log entity type of the trashed record.
+func (j JenisSampah) AuditEntity() string {
+	switch j {
+	case SampahKategori:
+		return AuditKategori
+	case SampahLokasi:
+		return AuditLokasi
+	case SampahRuangan:
+		return AuditRuangan
+	case SampahBarang:
+		return AuditBarang
+	default:
+		return AuditUnit
+	}
+}
+
+// TrashRetention is how long a deleted record stays in the trash before it
+// may be purged for good.
+const (
+	TrashRetentionDays = 30
+	TrashRetention     = TrashRetentionDays * 24 * time.Hour
+)
+
+// TrashEntry is a deleted record as listed on the trash page. Records deleted
+// together with their parent are not listed on their own; Keterangan tells
+// how many of them come back with it.
+type TrashEntry struct {
+	Jenis      JenisSampah `db:"jenis" json:"jenis"`
+	Id         string      `db:"id" json:"id"`
+	Nama       string      `db:"nama" json:"nama"`
+	Keterangan string      `db:"keterangan" json:"keterangan"`
+	TglDihapus time.Time   `db:"tgl_dihapus" json:"tgl_dihapus"`
+}
+
+func (e TrashEntry) PurgeableAt() time.Time {
+	return e.TglDihapus.Add(TrashRetention)
+}
+
+func (e TrashEntry) CanPurge(now time.Time) bool {
+	return !now.Before(e.PurgeableAt())
+}
+
+// TrashState is a deleted record read under lock, right before it is restored
+// or purged. Induk names the parent when that is still in the trash. For a
+// unit, Unit and Jumlah are the live units of its item and the item's amount.
+type TrashState struct {
+	Entry  TrashEntry
+	Induk  string
+	Unit   int
+	Jumlah int
+}
@@ -0,0 +1,123 @@
+package entities
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/utils"
+)
+
+type Peran string
+
+const (
+	PeranAdmin    Peran = "admin"
+	PeranOperator Peran = "operator"
+	PeranViewer   Peran = "viewer"
+)
+
+const MinPasswordLength = 8
+
+func Perans() []Peran {
+	return []Peran{PeranAdmin, PeranOperator, PeranViewer}
+}
+
+func (p Peran) IsValid() bool {
+	switch p {
+	case PeranAdmin, PeranOperator, PeranViewer:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanWrite reports whether the role may create, change or delete data.
+func (p Peran) CanWrite() bool {
+	return p == PeranAdmin || p == PeranOperator
+}
+
+func (p Peran) IsAdmin() bool {
+	return p == PeranAdmin
+}
+
+type LoginForm struct {
+	Username string `form:"username"`
+	Password string `form:"password"`
+	Next     string `form:"next"`
+}
+
+type UserForm struct {
+	Username string `form:"username"`
+	Nama     string `form:"nama"`
+	Password string `form:"password"`
+	Peran    string `form:"peran"`
+}
+
+type User struct {
+	Id           uuid.UUID `db:"id"`
+	Username     string    `db:"username"`
+	Nama         string    `db:"nama"`
+	PasswordHash string    `db:"password_hash" json:"-"`
+	Peran        Peran     `db:"peran"`
+	TglDibuat    time.Time `db:"tgl_dibuat"`
+	TglUpdate    time.Time `db:"tgl_update"`
+}
+
+// NewUser validates the form. The password is checked but not stored; the
+// caller hashes it into PasswordHash.
+func NewUser(reqForm UserForm) (*User, error) {
+	username := strings.ToLower(strings.TrimSpace(reqForm.Username))
+	if username == "" {
+		return nil, utils.WebError{Field: "Username", Message: "Username harus diisi"}
+	}
+
+	if strings.ContainsAny(username, " \t\n") {
+		return nil, utils.WebError{Field: "Username", Message: "Username tidak boleh mengandung spasi"}
+	}
+
+	if !validateString(reqForm.Nama) {
+		return nil, utils.WebError{Field: "Nama", Message: "Nama harus diisi"}
+	}
+
+	if err := ValidatePassword(reqForm.Password); err != nil {
+		return nil, err
+	}
+
+	peran := Peran(strings.TrimSpace(reqForm.Peran))
+	if !peran.IsValid() {
+		return nil, utils.WebError{Field: "Peran", Message: "Peran tidak valid"}
+	}
+
+	now := time.Now()
+	return &User{
+		Id:        uuid.New(),
+		Username:  username,
+		Nama:      strings.TrimSpace(reqForm.Nama),
+		Peran:     peran,
+		TglDibuat: now,
+		TglUpdate: now,
+	}, nil
+}
+
+func ValidatePassword(password string) error {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return utils.WebError{Field: "Password", Message: "Password minimal 8 karakter"}
+	}
+
+	// bcrypt only uses the first 72 bytes of a password.
+	if len(password) > 72 {
+		return utils.WebError{Field: "Password", Message: "Password maksimal 72 byte"}
+	}
+
+	return nil
+}
+
+// Session is a login session. Id holds the SHA-256 of the cookie token so
+// the table never contains a usable credential.
+type Session struct {
+	Id          string    `db:"id"`
+	IdPengguna  uuid.UUID `db:"id_pengguna"`
+	Kedaluwarsa time.Time `db:"kedaluwarsa"`
+	TglDibuat   time.Time `db:"tgl_dibuat"`
+}
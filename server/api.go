@@ -0,0 +1,621 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/utils"
+)
+
+const maxAPIBody = 1 << 20
+
+type apiError struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+type apiList struct {
+	Data      any   `json:"data"`
+	Page      int   `json:"page"`
+	PerPage   int   `json:"per_page"`
+	TotalData int64 `json:"total_data"`
+	TotalPage int   `json:"total_page"`
+}
+
+type apiToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type unitSerialForm struct {
+	NoSeri string `form:"no_seri"`
+}
+
+func isAPIPath(path string) bool {
+	return strings.HasPrefix(path, "/api/")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("error encoding json: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, apiError{Error: message})
+}
+
+func writeAPIError(w http.ResponseWriter, err error) {
+	status := errorStatus(err)
+
+	var webErr utils.WebError
+	switch {
+	case errors.As(err, &webErr):
+		writeJSON(w, status, apiError{
+			Error:  webErr.Message,
+			Fields: map[string]string{webErr.Field: webErr.Message},
+		})
+	case status == http.StatusInternalServerError:
+		log.Printf("error processing api request: %s", err)
+		writeJSONError(w, status, "internal server error")
+	default:
+		writeJSONError(w, status, strings.ToLower(http.StatusText(status)))
+	}
+}
+
+// decodeAPIBody fills dst from a JSON object or a regular form body. JSON is
+// flattened into form keys so both share the form tags of the entity forms.
+func decodeAPIBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxAPIBody)
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := parseForm(r, dst); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid form body")
+			return false
+		}
+		return true
+	}
+
+	var body map[string]any
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid json body")
+		return false
+	}
+
+	values := url.Values{}
+	flattenJSON(values, "", body)
+	if err := formDecoder.Decode(dst, values); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid json body")
+		return false
+	}
+	return true
+}
+
+func flattenJSON(values url.Values, prefix string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flattenJSON(values, key, child)
+		}
+	case []any:
+		for i, child := range v {
+			switch child.(type) {
+			case map[string]any, []any:
+				flattenJSON(values, fmt.Sprintf("%s[%d]", prefix, i), child)
+			default:
+				if child != nil {
+					values.Add(prefix, fmt.Sprint(child))
+				}
+			}
+		}
+	case nil:
+	default:
+		values.Set(prefix, fmt.Sprint(v))
+	}
+}
+
+func writeAPIList(w http.ResponseWriter, r *http.Request, list func(utils.PaginationParams) (utils.PaginationResult, error)) {
+	params, err := utils.PaginationFromRequest(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := list(params)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	// an empty page is rendered as [] rather than null
+	data := result.Data
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Slice && v.IsNil() {
+		data = reflect.MakeSlice(v.Type(), 0, 0).Interface()
+	}
+
+	writeJSON(w, http.StatusOK, apiList{
+		Data:      data,
+		Page:      result.Page,
+		PerPage:   result.PerPage,
+		TotalData: result.TotalData,
+		TotalPage: result.TotalPage,
+	})
+}
+
+// Auth
+
+func (s *Server) apiLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.LoginForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	token, expires, err := s.authService.Login(r.Context(), reqForm)
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			writeJSONError(w, http.StatusUnauthorized, webErr.Message)
+			return
+		}
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiToken{Token: token, ExpiresAt: expires})
+}
+
+func (s *Server) apiLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.authService.Logout(r.Context(), sessionToken(r)); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Categories
+
+func (s *Server) apiListCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	writeAPIList(w, r, func(params utils.PaginationParams) (utils.PaginationResult, error) {
+		return s.categoryService.ListCategoriesWithFilter(r.Context(), params)
+	})
+}
+
+func (s *Server) apiCreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.CategoryForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	category, err := s.categoryService.AddNewCategory(r.Context(), reqForm.Name, reqForm.Code, reqForm.Parent)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/categories/%d", category.Id))
+	writeJSON(w, http.StatusCreated, category)
+}
+
+func (s *Server) apiGetCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category, err := s.categoryService.GetCategoryById(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+func (s *Server) apiEditCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.CategoryForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.categoryService.EditCategory(r.Context(), id, reqForm.Name, reqForm.Code); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	category, err := s.categoryService.GetCategoryById(r.Context(), id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+func (s *Server) apiMoveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.CategoryMoveForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.categoryService.MoveCategory(r.Context(), id, reqForm.Parent); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	category, err := s.categoryService.GetCategoryById(r.Context(), id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+func (s *Server) apiCategoryAttributesHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.CategoryAttributeForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.categoryService.SetCategoryAttributes(r.Context(), id, reqForm.Fields); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	category, err := s.categoryService.GetCategoryById(r.Context(), id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+func (s *Server) apiDeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.categoryService.DeleteCategory(r.Context(), r.PathValue("id")); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Locations
+
+func (s *Server) apiListLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	writeAPIList(w, r, func(params utils.PaginationParams) (utils.PaginationResult, error) {
+		return s.locationService.GetLocationsWithFilter(r.Context(), params)
+	})
+}
+
+func (s *Server) apiCreateLocationHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.LocationForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	loc, err := s.locationService.CreateLocation(r.Context(), reqForm.Name, reqForm.Code, reqForm.Parent)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/locations/"+loc.Slug)
+	writeJSON(w, http.StatusCreated, loc)
+}
+
+func (s *Server) apiGetLocationHandler(w http.ResponseWriter, r *http.Request) {
+	loc, err := s.locationService.ViewDetailLocation(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, loc)
+}
+
+func (s *Server) apiEditLocationHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.LocationForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	if err := s.locationService.EditLocation(r.Context(), r.PathValue("slug"), reqForm.Name, reqForm.Code); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) apiMoveLocationHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.LocationMoveForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	if err := s.locationService.MoveLocation(r.Context(), r.PathValue("slug"), reqForm.Parent); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) apiDeleteLocationHandler(w http.ResponseWriter, r *http.Request) {
+	loc, err := s.locationService.GetLocationBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	if err := s.locationService.DeleteLocation(r.Context(), loc.Id.String()); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Rooms
+
+func (s *Server) apiListRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	writeAPIList(w, r, func(params utils.PaginationParams) (utils.PaginationResult, error) {
+		return s.roomService.GetRoomsWithFilter(r.Context(), params)
+	})
+}
+
+func (s *Server) apiCreateRoomHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.RoomForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	room, err := s.roomService.CreateRoom(r.Context(), reqForm)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/rooms/"+room.Slug)
+	writeJSON(w, http.StatusCreated, room)
+}
+
+func (s *Server) apiGetRoomHandler(w http.ResponseWriter, r *http.Request) {
+	room, err := s.roomService.GetRoomWithUnitItems(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, room)
+}
+
+func (s *Server) apiEditRoomHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.RoomForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	if err := s.roomService.EditRoom(r.Context(), r.PathValue("slug"), reqForm); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) apiDeleteRoomHandler(w http.ResponseWriter, r *http.Request) {
+	room, err := s.roomService.GetRoomBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	if err := s.roomService.DeleteRoom(r.Context(), room.Id.String()); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Items
+
+func (s *Server) apiListItemsHandler(w http.ResponseWriter, r *http.Request) {
+	writeAPIList(w, r, func(params utils.PaginationParams) (utils.PaginationResult, error) {
+		return s.itemService.GetItemsWithFilter(r.Context(), params)
+	})
+}
+
+func (s *Server) apiCreateItemHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.ItemForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	item, err := s.itemService.CreateItem(r.Context(), reqForm)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/items/"+item.Slug)
+	writeJSON(w, http.StatusCreated, item)
+}
+
+func (s *Server) apiGetItemHandler(w http.ResponseWriter, r *http.Request) {
+	item, err := s.itemService.GetItemBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) apiEditItemHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.ItemForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	if err := s.itemService.EditItem(r.Context(), r.PathValue("slug"), reqForm); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) apiDeleteItemHandler(w http.ResponseWriter, r *http.Request) {
+	item, err := s.itemService.GetItemBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	if err := s.itemService.DeleteItem(r.Context(), item.Id.String()); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Units
+
+func (s *Server) apiListItemUnitsHandler(w http.ResponseWriter, r *http.Request) {
+	item, err := s.itemService.GetItemBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	units, err := s.unitService.GetUnitsByItem(r.Context(), item.Id)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if units == nil {
+		units = []entities.ItemUnit{}
+	}
+
+	writeJSON(w, http.StatusOK, apiList{
+		Data:      units,
+		Page:      1,
+		PerPage:   len(units),
+		TotalData: int64(len(units)),
+		TotalPage: 1,
+	})
+}
+
+func (s *Server) apiRegisterUnitsHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.UnitForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	if len(reqForm.Units) > maxUnitRows {
+		writeJSON(w, http.StatusUnprocessableEntity, apiError{
+			Error:  fmt.Sprintf("maksimal %d unit per permintaan", maxUnitRows),
+			Fields: map[string]string{"Units": fmt.Sprintf("maksimal %d unit per permintaan", maxUnitRows)},
+		})
+		return
+	}
+
+	units, err := s.unitService.RegisterUnits(r.Context(), r.PathValue("slug"), reqForm)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, units)
+}
+
+func (s *Server) apiGetUnitHandler(w http.ResponseWriter, r *http.Request) {
+	unit, err := s.unitService.GetUnitById(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, unit)
+}
+
+func (s *Server) apiEditUnitHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm unitSerialForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.unitService.EditUnitSerial(r.Context(), id, reqForm.NoSeri); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	s.apiGetUnitHandler(w, r)
+}
+
+func (s *Server) apiChangeUnitConditionHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.UnitConditionForm
+	if !decodeAPIBody(w, r, &reqForm) {
+		return
+	}
+
+	if err := s.unitService.ChangeUnitCondition(r.Context(), r.PathValue("id"), reqForm.Kondisi); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	s.apiGetUnitHandler(w, r)
+}
+
+func (s *Server) apiDeleteUnitHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.unitService.DeleteUnit(r.Context(), r.PathValue("id")); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) apiScanHandler(w http.ResponseWriter, r *http.Request) {
+	kode := r.URL.Query().Get("kode")
+	if strings.TrimSpace(kode) == "" {
+		writeJSONError(w, http.StatusBadRequest, "kode is required")
+		return
+	}
+
+	units, err := s.opnameService.LookupUnits(r.Context(), kode)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if units == nil {
+		units = []entities.ItemUnit{}
+	}
+
+	writeJSON(w, http.StatusOK, units)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/utils"
+)
+
+const sessionCookie = "coniven_session"
+
+func isPublicPath(path string) bool {
+	return path == "/login" || path == "/api/v1/login" || path == "/api/openapi.json" || strings.HasPrefix(path, "/static/")
+}
+
+// sessionToken reads the session token from the cookie set by the login page
+// or, for API clients, from an "Authorization: Bearer" header.
+func sessionToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// authorize applies the role rules: user management is admin only and
+// viewers may not send anything but reads, apart from logging out.
+func authorize(peran entities.Peran, r *http.Request) bool {
+	if r.URL.Path == "/user" || strings.HasPrefix(r.URL.Path, "/user/") {
+		return peran.IsAdmin()
+	}
+
+	if r.URL.Path == "/logout" || r.URL.Path == "/api/v1/logout" || isReadMethod(r.Method) {
+		return true
+	}
+
+	return peran.CanWrite()
+}
+
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := sessionToken(r)
+		if token == "" {
+			redirectToLogin(w, r)
+			return
+		}
+
+		user, err := s.authService.Authenticate(r.Context(), token)
+		if err != nil {
+			if !errors.Is(err, utils.ErrNotFound) {
+				log.Printf("error authenticating session: %s", err)
+			}
+			clearSessionCookie(w, r)
+			redirectToLogin(w, r)
+			return
+		}
+
+		if !authorize(user.Peran, r) {
+			if isAPIPath(r.URL.Path) {
+				writeJSONError(w, http.StatusForbidden, "akses ditolak")
+				return
+			}
+			http.Error(w, "akses ditolak", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(entities.WithActor(r.Context(), user)))
+	})
+}
+
+func redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	if isAPIPath(r.URL.Path) {
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	target := "/login"
+	if isReadMethod(r.Method) {
+		target += "?next=" + url.QueryEscape(r.URL.RequestURI())
+	}
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", target)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if !isReadMethod(r.Method) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+func currentUser(r *http.Request) entities.User {
+	user, _ := entities.ActorFrom(r.Context())
+	return user
+}
+
+// safeNext only accepts local paths so the login form cannot be used as an
+// open redirect.
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/item"
+	}
+	return next
+}
+
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+func clearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *Server) viewLoginHandler(w http.ResponseWriter, r *http.Request) {
+	s.RenderHTML(w, "layout.tmpl", map[string]any{
+		"Page":  "pages/login.tmpl",
+		"Title": "masuk",
+		"Next":  safeNext(r.URL.Query().Get("next")),
+	})
+}
+
+func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.LoginForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	token, expires, err := s.authService.Login(r.Context(), reqForm)
+	if err != nil {
+		s.handleWebError(w, r, err, "partials/login-form-partial.tmpl", map[string]any{
+			"Username": reqForm.Username,
+			"Next":     safeNext(reqForm.Next),
+		})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	w.Header().Set("HX-Redirect", safeNext(reqForm.Next))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if token := sessionToken(r); token != "" {
+		if err := s.authService.Logout(r.Context(), token); err != nil {
+			log.Printf("error removing session: %s", err)
+		}
+	}
+
+	clearSessionCookie(w, r)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// contractCheckEnv enables validation of every API response against the
+// OpenAPI document. Client suites run the server with it set so a change to an
+// entity struct shows up as a logged violation instead of a broken client.
+const contractCheckEnv = "CONIVEN_CONTRACT_CHECK"
+
+type contractRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *contractRecorder) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *contractRecorder) Write(b []byte) (int, error) {
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+func (s *Server) withContractCheck(next http.Handler) http.Handler {
+	if os.Getenv(contractCheckEnv) != "1" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAPIPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &contractRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		_, pattern := s.router.Handler(r)
+		for _, v := range s.openAPI.checkResponse(pattern, rec.status, rec.body.Bytes()) {
+			log.Printf("contract violation: %s %s -> %d: %s", r.Method, r.URL.Path, rec.status, v)
+		}
+	})
+}
+
+// checkResponse validates a response body against the schema documented for
+// the route pattern and status, returning one message per violation.
+func (o *openAPI) checkResponse(pattern string, status int, body []byte) []string {
+	statuses, ok := o.responses[pattern]
+	if !ok {
+		return []string{"route " + pattern + " is not documented"}
+	}
+
+	s, ok := statuses[status]
+	if !ok {
+		return []string{fmt.Sprintf("status %d is not documented", status)}
+	}
+
+	if s == nil {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return []string{"expected an empty body"}
+		}
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{"body is not JSON: " + err.Error()}
+	}
+
+	return o.validate(s, value, "$")
+}
+
+func (o *openAPI) validate(s schema, value any, path string) []string {
+	if ref, ok := s["$ref"].(string); ok {
+		return o.validate(o.components[strings.TrimPrefix(ref, "#/components/schemas/")], value, path)
+	}
+
+	if value == nil {
+		if s["nullable"] == true {
+			return nil
+		}
+		return []string{path + ": unexpected null"}
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		var errs []string
+		for _, sub := range all {
+			errs = append(errs, o.validate(sub.(schema), value, path)...)
+		}
+		return errs
+	}
+
+	switch s["type"] {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{path + ": expected string"}
+		}
+		if enum, ok := s["enum"].([]string); ok && !slices.Contains(enum, str) {
+			return []string{fmt.Sprintf("%s: %q is not one of %v", path, str, enum)}
+		}
+
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return []string{path + ": expected integer"}
+		}
+
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{path + ": expected number"}
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{path + ": expected boolean"}
+		}
+
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return []string{path + ": expected array"}
+		}
+		items, ok := s["items"].(schema)
+		if !ok {
+			return []string{path + ": array schema has no items"}
+		}
+		var errs []string
+		for i, v := range arr {
+			errs = append(errs, o.validate(items, v, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return errs
+
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return []string{path + ": expected object"}
+		}
+		return o.validateObject(s, obj, path)
+	}
+
+	return nil
+}
+
+func (o *openAPI) validateObject(s schema, obj map[string]any, path string) []string {
+	var errs []string
+
+	required, _ := s["required"].([]string)
+	for _, name := range required {
+		if _, ok := obj[name]; !ok {
+			errs = append(errs, path+"."+name+": missing required property")
+		}
+	}
+
+	properties, _ := s["properties"].(schema)
+	for name, v := range obj {
+		if prop, ok := properties[name].(schema); ok {
+			errs = append(errs, o.validate(prop, v, path+"."+name)...)
+			continue
+		}
+
+		switch extra := s["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				errs = append(errs, path+"."+name+": undocumented property")
+			}
+		case schema:
+			errs = append(errs, o.validate(extra, v, path+"."+name)...)
+		}
+	}
+
+	slices.Sort(errs)
+	return errs
+}
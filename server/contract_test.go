@@ -0,0 +1,326 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/services"
+	"github.com/qeunasd/coniven/utils"
+)
+
+var (
+	contractTime = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	contractCategory = entities.Category{
+		Id: 1, Kode: "ELK", Nama: "Elektronik", TglDibuat: contractTime, TglUpdate: contractTime,
+		Atribut: []entities.AttributeField{
+			{Kunci: "merk", Label: "Merk", Tipe: entities.AtributTeks, Wajib: true},
+		},
+	}
+
+	contractLocation = entities.Location{
+		Id: uuid.New(), Kode: "GDA", Nama: "Gedung A", Slug: "gedung-a",
+		TglDibuat: contractTime, TglUpdate: contractTime,
+	}
+
+	contractRoom = entities.Room{
+		Id: uuid.New(), Nama: "Lab Komputer", PenanggungJawab: "Budi", Slug: "lab-komputer",
+		LokasiId: contractLocation.Id, Lokasi: contractLocation, TglDibuat: contractTime, TglUpdate: contractTime,
+	}
+
+	contractItem = entities.Item{
+		Id: uuid.New(), SKU: "LPT-01", Nama: "Laptop", Jumlah: 1, Satuan: "unit",
+		HargaSatuan: 12_000_000, UmurEkonomis: 4, MetodePenyusutan: entities.GarisLurus,
+		Slug: "laptop", TotalHarga: 12_000_000, TglDibuat: contractTime, TglPerolehan: contractTime,
+		IdKategori: contractCategory.Id, Kategori: contractCategory,
+		Atribut: entities.AttributeValues{"merk": "Lenovo"},
+	}
+
+	contractUnit = entities.ItemUnit{
+		Id: uuid.New(), NoSeri: "SN-001", Kondisi: entities.KondisiBaik,
+		TglDibuat: contractTime, TglUpdate: contractTime, TglPerolehan: contractTime,
+		IdBarang: contractItem.Id, Barang: contractItem, IdRuangan: contractRoom.Id, Ruangan: contractRoom,
+	}
+)
+
+func contractPage(data any) utils.PaginationResult {
+	return utils.PaginationResult{Data: data, TotalData: 1, TotalPage: 1, Page: 1, PerPage: 10}
+}
+
+// The stubs below embed the service interfaces and only implement what the
+// API handlers call. Every call returns fail when it is set.
+
+type contractAuth struct {
+	services.AuthService
+	fail error
+}
+
+func (c contractAuth) Login(ctx context.Context, req entities.LoginForm) (string, time.Time, error) {
+	return "token", contractTime, c.fail
+}
+
+func (c contractAuth) Logout(ctx context.Context, token string) error {
+	return c.fail
+}
+
+type contractCategories struct {
+	services.CategoryService
+	fail error
+}
+
+func (c contractCategories) ListCategoriesWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
+	return contractPage([]entities.Category{contractCategory}), c.fail
+}
+
+func (c contractCategories) AddNewCategory(ctx context.Context, name, code, parent string) (entities.Category, error) {
+	return contractCategory, c.fail
+}
+
+func (c contractCategories) GetCategoryById(ctx context.Context, id string) (entities.Category, error) {
+	return contractCategory, c.fail
+}
+
+func (c contractCategories) EditCategory(ctx context.Context, id, name, code string) error {
+	return c.fail
+}
+
+func (c contractCategories) MoveCategory(ctx context.Context, id, parent string) error {
+	return c.fail
+}
+
+func (c contractCategories) SetCategoryAttributes(ctx context.Context, id string, rows []entities.AttributeFieldForm) error {
+	return c.fail
+}
+
+func (c contractCategories) DeleteCategory(ctx context.Context, id string) error {
+	return c.fail
+}
+
+type contractLocations struct {
+	services.LocationService
+	fail error
+}
+
+func (c contractLocations) GetLocationsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
+	return contractPage([]entities.Location{contractLocation}), c.fail
+}
+
+func (c contractLocations) CreateLocation(ctx context.Context, name, code, parent string) (entities.Location, error) {
+	return contractLocation, c.fail
+}
+
+func (c contractLocations) ViewDetailLocation(ctx context.Context, slug string) (*entities.Location, error) {
+	loc := contractLocation
+	loc.Ruangan = []entities.Room{contractRoom}
+	return &loc, c.fail
+}
+
+func (c contractLocations) EditLocation(ctx context.Context, slug, name, code string) error {
+	return c.fail
+}
+
+func (c contractLocations) MoveLocation(ctx context.Context, slug, parent string) error {
+	return c.fail
+}
+
+func (c contractLocations) GetLocationBySlug(ctx context.Context, slug string) (entities.Location, error) {
+	return contractLocation, c.fail
+}
+
+func (c contractLocations) DeleteLocation(ctx context.Context, id string) error {
+	return c.fail
+}
+
+type contractRooms struct {
+	services.RoomService
+	fail error
+}
+
+func (c contractRooms) GetRoomsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
+	return contractPage([]entities.Room{contractRoom}), c.fail
+}
+
+func (c contractRooms) CreateRoom(ctx context.Context, req entities.RoomForm) (entities.Room, error) {
+	return contractRoom, c.fail
+}
+
+func (c contractRooms) GetRoomWithUnitItems(ctx context.Context, slug string) (*entities.Room, error) {
+	room := contractRoom
+	room.Items = []entities.ItemUnit{contractUnit}
+	return &room, c.fail
+}
+
+func (c contractRooms) EditRoom(ctx context.Context, slug string, req entities.RoomForm) error {
+	return c.fail
+}
+
+func (c contractRooms) GetRoomBySlug(ctx context.Context, slug string) (entities.Room, error) {
+	return contractRoom, c.fail
+}
+
+func (c contractRooms) DeleteRoom(ctx context.Context, id string) error {
+	return c.fail
+}
+
+type contractItems struct {
+	services.ItemService
+	fail error
+}
+
+func (c contractItems) GetItemsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
+	return contractPage([]entities.Item{contractItem}), c.fail
+}
+
+func (c contractItems) CreateItem(ctx context.Context, req entities.ItemForm) (entities.Item, error) {
+	return contractItem, c.fail
+}
+
+func (c contractItems) GetItemBySlug(ctx context.Context, slug string) (entities.Item, error) {
+	return contractItem, c.fail
+}
+
+func (c contractItems) EditItem(ctx context.Context, slug string, req entities.ItemForm) error {
+	return c.fail
+}
+
+func (c contractItems) DeleteItem(ctx context.Context, id string) error {
+	return c.fail
+}
+
+type contractUnits struct {
+	services.UnitService
+	fail error
+}
+
+func (c contractUnits) RegisterUnits(ctx context.Context, itemSlug string, req entities.UnitForm) ([]entities.ItemUnit, error) {
+	return []entities.ItemUnit{contractUnit}, c.fail
+}
+
+func (c contractUnits) GetUnitsByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.ItemUnit, error) {
+	return []entities.ItemUnit{contractUnit}, c.fail
+}
+
+func (c contractUnits) GetUnitById(ctx context.Context, id string) (entities.ItemUnit, error) {
+	return contractUnit, c.fail
+}
+
+func (c contractUnits) EditUnitSerial(ctx context.Context, id, noSeri string) error {
+	return c.fail
+}
+
+func (c contractUnits) ChangeUnitCondition(ctx context.Context, id string, kondisi string) error {
+	return c.fail
+}
+
+func (c contractUnits) DeleteUnit(ctx context.Context, id string) error {
+	return c.fail
+}
+
+type contractOpnames struct {
+	services.OpnameService
+	fail error
+}
+
+func (c contractOpnames) LookupUnits(ctx context.Context, kode string) ([]entities.ItemUnit, error) {
+	return []entities.ItemUnit{contractUnit}, c.fail
+}
+
+func newContractServer(fail error) *Server {
+	s := NewServer(nil, "",
+		contractCategories{fail: fail},
+		contractLocations{fail: fail},
+		contractRooms{fail: fail},
+		contractItems{fail: fail},
+		contractUnits{fail: fail},
+		nil, nil, nil, nil,
+		contractAuth{fail: fail},
+		nil, nil,
+		contractOpnames{fail: fail},
+		nil, nil, nil, nil, nil, nil,
+	)
+	s.Routes()
+	return s
+}
+
+func contractRequest(rt apiRoute) *http.Request {
+	method, path, _ := strings.Cut(rt.pattern, " ")
+	path = strings.NewReplacer("{id}", contractUnit.Id.String(), "{slug}", "x").Replace(path)
+	for i, name := range rt.query {
+		sep := "&"
+		if i == 0 {
+			sep = "?"
+		}
+		path += sep + name + "=x"
+	}
+
+	if rt.request == nil {
+		return httptest.NewRequest(method, path, nil)
+	}
+
+	r := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func TestAPIRoutesMatchContract(t *testing.T) {
+	tests := []struct {
+		name string
+		fail error
+		// want returns the expected status, or false when the case does not
+		// apply to the route.
+		want func(rt apiRoute) (int, bool)
+	}{
+		{"sukses", nil, func(rt apiRoute) (int, bool) {
+			return rt.status, true
+		}},
+		{"validasi gagal", utils.WebError{Field: "Nama", Message: "Nama harus diisi"}, func(rt apiRoute) (int, bool) {
+			if rt.public {
+				return http.StatusUnauthorized, rt.request != nil
+			}
+			return http.StatusUnprocessableEntity, rt.request != nil
+		}},
+		{"konflik", utils.WebError{Field: "Kode", Message: "Kode sudah dipakai", Conflict: true}, func(rt apiRoute) (int, bool) {
+			return http.StatusConflict, rt.request != nil && !rt.public
+		}},
+		{"tidak ditemukan", utils.ErrNotFound, func(rt apiRoute) (int, bool) {
+			return http.StatusNotFound, strings.Contains(rt.pattern, "{")
+		}},
+		{"galat server", context.DeadlineExceeded, func(rt apiRoute) (int, bool) {
+			return http.StatusInternalServerError, true
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newContractServer(tt.fail)
+
+			for _, rt := range s.apiRoutes() {
+				want, ok := tt.want(rt)
+				if !ok {
+					continue
+				}
+
+				r := contractRequest(rt)
+				rec := httptest.NewRecorder()
+				s.router.ServeHTTP(rec, r)
+
+				_, pattern := s.router.Handler(r)
+				if pattern != rt.pattern {
+					t.Fatalf("%s resolved to %q", rt.pattern, pattern)
+				}
+				if rec.Code != want {
+					t.Fatalf("%s: got status %d, want %d: %s", rt.pattern, rec.Code, want, rec.Body)
+				}
+				if violations := s.openAPI.checkResponse(pattern, rec.Code, rec.Body.Bytes()); len(violations) > 0 {
+					t.Fatalf("%s -> %d violates the contract:\n%s", rt.pattern, rec.Code, strings.Join(violations, "\n"))
+				}
+			}
+		})
+	}
+}
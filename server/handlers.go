@@ -1,10 +1,20 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/services"
 	"github.com/qeunasd/coniven/utils"
 )
 
@@ -21,15 +31,13 @@ func (s *Server) listCategoriesHandler() http.HandlerFunc {
 
 		result, err := s.categoryService.ListCategoriesWithFilter(ctx, params)
 		if err != nil {
-			log.Printf("failed to list categories: %v\n", err)
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			s.handleError(w, r, err)
 			return
 		}
 
 		total, err := s.categoryService.GetTotalCategories(ctx)
 		if err != nil {
-			log.Printf("failed to list categories: %v\n", err)
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			s.handleError(w, r, err)
 			return
 		}
 
@@ -49,10 +57,17 @@ func (s *Server) listCategoriesHandler() http.HandlerFunc {
 
 func (s *Server) viewAddCategoryHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		parents, err := s.categoryService.GetCategoriesForUI(r.Context())
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
 		s.RenderHTML(w, "layout.tmpl", map[string]any{
-			"Page":  "pages/category_form.tmpl",
-			"Title": "form tambah kategori",
-			"Mode":  "create",
+			"Page":    "pages/category_form.tmpl",
+			"Title":   "form tambah kategori",
+			"Mode":    "create",
+			"Parents": parents,
 		})
 	}
 }
@@ -63,20 +78,31 @@ func (s *Server) addCategoryHandler() http.HandlerFunc {
 
 		if err := parseForm(r, &reqForm); err != nil {
 			log.Printf("error parsing form: %v\n", err)
-			http.Error(w, "internal error", http.StatusInternalServerError)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 			return
 		}
 
-		err := s.categoryService.AddNewCategory(r.Context(), reqForm.Name, reqForm.Code)
+		_, err := s.categoryService.AddNewCategory(r.Context(), reqForm.Name, reqForm.Code, reqForm.Parent)
 		if err != nil {
 			if r.Context().Value(htmxKey).(bool) {
+				parents, fetchErr := s.categoryService.GetCategoriesForUI(r.Context())
+				if fetchErr != nil {
+					s.handleError(w, r, fetchErr)
+					return
+				}
+
 				formData := map[string]any{
-					"FormKode": reqForm.Code,
-					"FormNama": reqForm.Name,
-					"Mode":     "create",
+					"FormKode":  reqForm.Code,
+					"FormNama":  reqForm.Name,
+					"FormInduk": reqForm.Parent,
+					"Mode":      "create",
+					"Parents":   parents,
 				}
 				s.handleWebError(w, r, err, "partials/category-form-partial.tmpl", formData)
 				return
+			} else {
+				s.handleError(w, r, err)
+				return
 			}
 		}
 
@@ -95,19 +121,121 @@ func (s *Server) viewEditCategoryHandler() http.HandlerFunc {
 
 		category, err := s.categoryService.GetCategoryById(r.Context(), id)
 		if err != nil {
-			log.Printf("getting category with id %v: %v\n", id, err)
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			s.handleError(w, r, err)
+			return
+		}
+
+		parents, err := s.categoryMoveTargets(r.Context(), category)
+		if err != nil {
+			s.handleError(w, r, err)
 			return
 		}
 
 		s.RenderHTML(w, "layout.tmpl", map[string]any{
-			"Page":     "pages/category_form.tmpl",
-			"Mode":     "edit",
-			"Title":    "form edit kategori",
+			"Page":      "pages/category_form.tmpl",
+			"Mode":      "edit",
+			"Title":     "form edit kategori",
+			"Category":  category,
+			"Id":        id,
+			"Parents":   parents,
+			"FormInduk": intPtrStr(category.IdInduk),
+			"AttrRows":  attributeEditorRows(entities.AttributeFormRows(category.Atribut)),
+			"Tipes":     entities.TipeAtributs(),
+		})
+	}
+}
+
+// categoryMoveTargets lists the categories category may be moved under: all
+// but itself and the categories below it.
+func (s *Server) categoryMoveTargets(ctx context.Context, category entities.Category) ([]entities.Category, error) {
+	all, err := s.categoryService.GetCategoriesForUI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]entities.Category, 0, len(all))
+	for _, c := range all {
+		if c.Id != category.Id && !c.IsUnder(category.Id) {
+			targets = append(targets, c)
+		}
+	}
+
+	return targets, nil
+}
+
+func intPtrStr(id *int) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.Itoa(*id)
+}
+
+// attributeEditorRows adds blank rows to the field editor for new fields.
+func attributeEditorRows(rows []entities.AttributeFieldForm) []entities.AttributeFieldForm {
+	return append(rows, make([]entities.AttributeFieldForm, 3)...)
+}
+
+func (s *Server) moveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var reqForm entities.CategoryMoveForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.categoryService.MoveCategory(r.Context(), id, reqForm.Parent); err != nil {
+		category, fetchErr := s.categoryService.GetCategoryById(r.Context(), id)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		parents, fetchErr := s.categoryMoveTargets(r.Context(), category)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		s.handleWebError(w, r, err, "partials/category-move-partial.tmpl", map[string]any{
+			"FormInduk": reqForm.Parent, "Category": category, "Id": id, "Parents": parents,
+		})
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/category/"+id+"/edit")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) categoryAttributesHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var reqForm entities.CategoryAttributeForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.categoryService.SetCategoryAttributes(r.Context(), id, reqForm.Fields); err != nil {
+		category, fetchErr := s.categoryService.GetCategoryById(r.Context(), id)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		s.handleWebError(w, r, err, "partials/category-attribute-partial.tmpl", map[string]any{
 			"Category": category,
 			"Id":       id,
+			"AttrRows": attributeEditorRows(reqForm.Fields),
+			"Tipes":    entities.TipeAtributs(),
 		})
+		return
 	}
+
+	w.Header().Set("HX-Redirect", "/category/"+id+"/edit")
+	w.WriteHeader(http.StatusOK)
 }
 
 func (s *Server) editCategoryHandler() http.HandlerFunc {
@@ -121,14 +249,14 @@ func (s *Server) editCategoryHandler() http.HandlerFunc {
 		var reqForm entities.CategoryForm
 		if err := parseForm(r, &reqForm); err != nil {
 			log.Printf("error parsing form: %v\n", err)
-			http.Error(w, "internal error", http.StatusInternalServerError)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 			return
 		}
 
 		if err := s.categoryService.EditCategory(r.Context(), id, reqForm.Name, reqForm.Code); err != nil {
 			category, fetchErr := s.categoryService.GetCategoryById(r.Context(), id)
 			if fetchErr != nil {
-				http.Error(w, "internal server error", http.StatusInternalServerError)
+				s.handleError(w, r, fetchErr)
 				return
 			}
 
@@ -165,8 +293,7 @@ func (s *Server) deleteCategoryHandler() http.HandlerFunc {
 
 		err := s.categoryService.DeleteCategory(r.Context(), id)
 		if err != nil {
-			log.Printf("error deleting category with id %v: %s", id, err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			s.handleError(w, r, err)
 			return
 		}
 
@@ -192,15 +319,13 @@ func (s *Server) getLocationsHandler() http.HandlerFunc {
 
 		result, err := s.locationService.GetLocationsWithFilter(ctx, params)
 		if err != nil {
-			log.Printf("error fetching locations: %v\n", err)
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			s.handleError(w, r, err)
 			return
 		}
 
 		total, err := s.locationService.GetTotalLocations(ctx)
 		if err != nil {
-			log.Printf("error getting total locations: %v\n", err)
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			s.handleError(w, r, err)
 			return
 		}
 
@@ -220,8 +345,14 @@ func (s *Server) getLocationsHandler() http.HandlerFunc {
 
 func (s *Server) viewAddLocationHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		parents, err := s.locationService.GetLocationsForUI(r.Context())
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
 		s.RenderHTML(w, "layout.tmpl", map[string]any{
-			"Page": "pages/location_form.tmpl", "Title": "form tambah lokasi", "Mode": "create",
+			"Page": "pages/location_form.tmpl", "Title": "form tambah lokasi", "Mode": "create", "Parents": parents,
 		})
 	}
 }
@@ -236,16 +367,27 @@ func (s *Server) addLocationHandler() http.HandlerFunc {
 			return
 		}
 
-		err := s.locationService.CreateLocation(r.Context(), reqForm.Name, reqForm.Code)
+		_, err := s.locationService.CreateLocation(r.Context(), reqForm.Name, reqForm.Code, reqForm.Parent)
 		if err != nil {
 			if r.Context().Value(htmxKey).(bool) {
+				parents, fetchErr := s.locationService.GetLocationsForUI(r.Context())
+				if fetchErr != nil {
+					s.handleError(w, r, fetchErr)
+					return
+				}
+
 				formData := map[string]any{
-					"FormKode": reqForm.Code,
-					"FormNama": reqForm.Name,
-					"Mode":     "create",
+					"FormKode":  reqForm.Code,
+					"FormNama":  reqForm.Name,
+					"FormInduk": reqForm.Parent,
+					"Mode":      "create",
+					"Parents":   parents,
 				}
 				s.handleWebError(w, r, err, "partials/location-form-partial.tmpl", formData)
 				return
+			} else {
+				s.handleError(w, r, err)
+				return
 			}
 		}
 
@@ -264,18 +406,84 @@ func (s Server) viewEditLocationHandler() http.HandlerFunc {
 
 		location, fetchErr := s.locationService.GetLocationBySlug(r.Context(), slug)
 		if fetchErr != nil {
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		parents, err := s.moveTargets(r.Context(), location)
+		if err != nil {
+			s.handleError(w, r, err)
 			return
 		}
 
 		s.RenderHTML(w, "layout.tmpl", map[string]any{
-			"Page":  "pages/location_form.tmpl",
-			"Title": "form edit lokasi",
-			"Mode":  "edit",
-			"Loc":   location,
-			"Slug":  slug,
+			"Page":      "pages/location_form.tmpl",
+			"Title":     "form edit lokasi",
+			"Mode":      "edit",
+			"Loc":       location,
+			"Slug":      slug,
+			"Parents":   parents,
+			"FormInduk": nullUUIDStr(location.IdInduk),
+		})
+	}
+}
+
+// moveTargets lists the locations loc may be moved under: all but itself and
+// the locations below it.
+func (s *Server) moveTargets(ctx context.Context, loc entities.Location) ([]entities.Location, error) {
+	all, err := s.locationService.GetLocationsForUI(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]entities.Location, 0, len(all))
+	for _, l := range all {
+		if l.Id != loc.Id && !l.IsUnder(loc.Id) {
+			targets = append(targets, l)
+		}
+	}
+
+	return targets, nil
+}
+
+func nullUUIDStr(id uuid.NullUUID) string {
+	if !id.Valid {
+		return ""
+	}
+	return id.UUID.String()
+}
+
+func (s *Server) moveLocationHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+
+	var reqForm entities.LocationMoveForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.locationService.MoveLocation(r.Context(), slug, reqForm.Parent); err != nil {
+		location, fetchErr := s.locationService.GetLocationBySlug(r.Context(), slug)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		parents, fetchErr := s.moveTargets(r.Context(), location)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		s.handleWebError(w, r, err, "partials/location-move-partial.tmpl", map[string]any{
+			"FormInduk": reqForm.Parent, "Loc": location, "Slug": slug, "Parents": parents,
 		})
+		return
 	}
+
+	w.Header().Set("HX-Redirect", "/location/"+slug)
+	w.WriteHeader(http.StatusOK)
 }
 
 func (s *Server) editLocationHandler() http.HandlerFunc {
@@ -296,8 +504,7 @@ func (s *Server) editLocationHandler() http.HandlerFunc {
 		if err := s.locationService.EditLocation(r.Context(), slug, reqForm.Name, reqForm.Code); err != nil {
 			location, fetchErr := s.locationService.GetLocationBySlug(r.Context(), slug)
 			if fetchErr != nil {
-				log.Printf("error getting location: %v", err)
-				http.Error(w, "internal server error", http.StatusInternalServerError)
+				s.handleError(w, r, fetchErr)
 				return
 			}
 
@@ -322,8 +529,7 @@ func (s Server) deleteLocationHandler() http.HandlerFunc {
 
 		err := s.locationService.DeleteLocation(r.Context(), id)
 		if err != nil {
-			log.Printf("error deleting location with id %v: %s", id, err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			s.handleError(w, r, err)
 			return
 		}
 
@@ -344,8 +550,7 @@ func (s *Server) viewLocation() http.HandlerFunc {
 
 		loc, err := s.locationService.ViewDetailLocation(r.Context(), slug)
 		if err != nil {
-			log.Printf("error getting location: %s", err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			s.handleError(w, r, err)
 			return
 		}
 
@@ -370,19 +575,24 @@ func (s *Server) getRoomsHandler(w http.ResponseWriter, r *http.Request) {
 
 	result, err := s.roomService.GetRoomsWithFilter(ctx, params)
 	if err != nil {
-		log.Printf("error fetching room: %v\n", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		s.handleError(w, r, err)
 		return
 	}
 
 	total, err := s.roomService.GetTotalRooms(ctx)
 	if err != nil {
-		log.Printf("error getting total rooms: %v\n", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		s.handleError(w, r, err)
+		return
+	}
+
+	locations, err := s.locationService.GetLocationsForUI(ctx)
+	if err != nil {
+		s.handleError(w, r, err)
 		return
 	}
 
 	data := buildTemplateData(r, result, params, total, "ruangan")
+	data["Locations"] = locations
 
 	var templateName string
 	if ctx.Value(htmxKey).(bool) {
@@ -398,8 +608,7 @@ func (s *Server) getRoomsHandler(w http.ResponseWriter, r *http.Request) {
 func (s *Server) viewAddRoomHandler(w http.ResponseWriter, r *http.Request) {
 	loc, err := s.locationService.GetLocationsForUI(r.Context())
 	if err != nil {
-		log.Printf("error fetching locations: %v\n", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		s.handleError(w, r, err)
 		return
 	}
 
@@ -418,12 +627,11 @@ func (s *Server) addRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.roomService.CreateRoom(ctx, reqForm); err != nil {
+	if _, err := s.roomService.CreateRoom(ctx, reqForm); err != nil {
 		if ctx.Value(htmxKey).(bool) {
 			loc, fetchErr := s.locationService.GetLocationsForUI(ctx)
 			if fetchErr != nil {
-				log.Printf("error fetching locations: %v\n", err)
-				http.Error(w, "internal server error", http.StatusInternalServerError)
+				s.handleError(w, r, fetchErr)
 				return
 			}
 
@@ -435,6 +643,9 @@ func (s *Server) addRoomHandler(w http.ResponseWriter, r *http.Request) {
 				"Loc":        loc,
 			})
 			return
+		} else {
+			s.handleError(w, r, err)
+			return
 		}
 	}
 
@@ -451,14 +662,13 @@ func (s *Server) viewEditRoomHandler(w http.ResponseWriter, r *http.Request) {
 
 	room, fetchErr := s.roomService.GetRoomBySlug(r.Context(), slug)
 	if fetchErr != nil {
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		s.handleError(w, r, fetchErr)
 		return
 	}
 
 	loc, err := s.locationService.GetLocationsForUI(r.Context())
 	if err != nil {
-		log.Printf("error fetching locations: %v\n", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		s.handleError(w, r, err)
 		return
 	}
 
@@ -489,8 +699,7 @@ func (s *Server) editRoomHandler(w http.ResponseWriter, r *http.Request) {
 	if err := s.roomService.EditRoom(r.Context(), slug, reqForm); err != nil {
 		room, fetchErr := s.roomService.GetRoomBySlug(r.Context(), slug)
 		if fetchErr != nil {
-			log.Printf("error getting location: %v", err)
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+			s.handleError(w, r, fetchErr)
 			return
 		}
 
@@ -518,8 +727,7 @@ func (s *Server) deleteRoomHandler(w http.ResponseWriter, r *http.Request) {
 
 	err := s.roomService.DeleteRoom(r.Context(), id)
 	if err != nil {
-		log.Printf("error deleting location with id %v: %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		s.handleError(w, r, err)
 		return
 	}
 
@@ -536,16 +744,1966 @@ func (s *Server) viewRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	data, err := s.roomDetailData(r, slug)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Page"] = "pages/room_detail.tmpl"
+	data["Title"] = "ruangan"
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+func (s *Server) roomDetailData(r *http.Request, slug string) (map[string]any, error) {
 	room, err := s.roomService.GetRoomWithUnitItems(r.Context(), slug)
 	if err != nil {
-		log.Printf("error getting location: %s", err)
+		return nil, err
+	}
+
+	rooms, err := s.roomService.GetRoomsForUI(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"Room":     room,
+		"Rooms":    rooms,
+		"Today":    time.Now().Format(entities.DateLayout),
+		"Selected": map[string]bool{},
+		"Kondisi":  entities.ManualKondisiUnits(),
+	}, nil
+}
+
+func (s *Server) roomKIRHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is required", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+
+	var write func(io.Writer, entities.KIR) error
+	var contentType string
+	switch format {
+	case "pdf":
+		write, contentType = s.reportService.WriteKIRPDF, "application/pdf"
+	case "xlsx":
+		write, contentType = s.reportService.WriteKIRXLSX, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		http.Error(w, "format must be pdf or xlsx", http.StatusBadRequest)
+		return
+	}
+
+	kir, err := s.reportService.GetRoomKIR(r.Context(), slug)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, kir); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+kir.FileName(format)+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(w)
+}
+
+func (s *Server) writeLabels(w http.ResponseWriter, labels []entities.Label, filename string) {
+	var buf bytes.Buffer
+	if err := s.reportService.WriteLabelsPDF(&buf, labels); err != nil {
+		log.Printf("error writing labels: %s", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	s.RenderHTML(w, "layout.tmpl", map[string]any{
-		"Page":  "pages/room_detail.tmpl",
-		"Title": "lokasi",
-		"Room":  room,
-	})
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(w)
+}
+
+func (s *Server) unitLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	ids := r.URL.Query()["id"]
+	if id := r.PathValue("id"); id != "" {
+		ids = []string{id}
+	}
+
+	labels, err := s.reportService.GetUnitLabels(r.Context(), ids, s.publicBaseURL)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeLabels(w, labels, "label-unit.pdf")
+}
+
+func (s *Server) roomLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is required", http.StatusBadRequest)
+		return
+	}
+
+	labels, err := s.reportService.GetRoomLabels(r.Context(), slug, s.publicBaseURL)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.writeLabels(w, labels, "label-"+slug+".pdf")
+}
+
+// viewUnitHandler is the target of the label QR code.
+func (s *Server) viewUnitHandler(w http.ResponseWriter, r *http.Request) {
+	unit, err := s.unitService.GetUnitById(r.Context(), r.PathValue("id"))
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/item/"+unit.Barang.Slug, http.StatusSeeOther)
+}
+
+func (s *Server) valuationReportHandler(w http.ResponseWriter, r *http.Request) {
+	cutoff := time.Now()
+	if raw := r.URL.Query().Get("cutoff"); raw != "" {
+		date, err := entities.ParseDate(raw)
+		if err != nil {
+			http.Error(w, "invalid cutoff date", http.StatusBadRequest)
+			return
+		}
+		cutoff = date
+	}
+
+	val, err := s.reportService.GetValuation(r.Context(), cutoff)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	var write func(io.Writer, entities.Valuation) error
+	var contentType string
+	switch format := r.URL.Query().Get("format"); format {
+	case "":
+		s.RenderHTML(w, "layout.tmpl", map[string]any{
+			"Page":    "pages/valuation_report.tmpl",
+			"Title":   "laporan nilai inventaris",
+			"Val":     val,
+			"Kondisi": entities.KondisiUnits(),
+			"Cutoff":  val.Cutoff.Format(entities.DateLayout),
+			"Sections": []map[string]any{
+				{"Title": "Kategori", "Rows": val.ByCategory},
+				{"Title": "Lokasi", "Rows": val.ByLocation},
+			},
+		})
+		return
+	case "xlsx":
+		write, contentType = s.reportService.WriteValuationXLSX, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case "csv":
+		write, contentType = s.reportService.WriteValuationCSV, "text/csv; charset=utf-8"
+	default:
+		http.Error(w, "format must be xlsx or csv", http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, val); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	filename := "nilai-inventaris-" + val.Cutoff.Format(entities.DateLayout) + "." + r.URL.Query().Get("format")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(w)
+}
+
+func (s *Server) repairSpendReportHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	dari := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	if raw := r.URL.Query().Get("dari"); raw != "" {
+		date, err := entities.ParseDate(raw)
+		if err != nil {
+			http.Error(w, "invalid dari date", http.StatusBadRequest)
+			return
+		}
+		dari = date
+	}
+
+	sampai := now
+	if raw := r.URL.Query().Get("sampai"); raw != "" {
+		date, err := entities.ParseDate(raw)
+		if err != nil {
+			http.Error(w, "invalid sampai date", http.StatusBadRequest)
+			return
+		}
+		sampai = date
+	}
+
+	spend, err := s.repairService.GetRepairSpend(r.Context(), dari, sampai)
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			http.Error(w, webErr.Message, http.StatusBadRequest)
+			return
+		}
+		s.handleError(w, r, err)
+		return
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "":
+		s.RenderHTML(w, "layout.tmpl", map[string]any{
+			"Page":   "pages/repair_report.tmpl",
+			"Title":  "laporan biaya perbaikan",
+			"Spend":  spend,
+			"Dari":   spend.Dari.Format(entities.DateLayout),
+			"Sampai": spend.Sampai.Format(entities.DateLayout),
+			"Sections": []map[string]any{
+				{"Title": "Kategori", "Rows": spend.ByCategory},
+				{"Title": "Lokasi", "Rows": spend.ByLocation},
+			},
+		})
+		return
+	case "csv":
+	default:
+		http.Error(w, "format must be csv", http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := s.repairService.WriteRepairSpendCSV(&buf, spend); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+spend.FileName("csv")+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(w)
+}
+
+func (s *Server) depreciationReportHandler(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().Year()
+	if raw := r.URL.Query().Get("year"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1900 || parsed > 9999 {
+			http.Error(w, "invalid year", http.StatusBadRequest)
+			return
+		}
+		year = parsed
+	}
+
+	summary, err := s.depreciationService.GetFiscalYearSummary(r.Context(), year)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "":
+		s.RenderHTML(w, "layout.tmpl", map[string]any{
+			"Page":    "pages/depreciation_report.tmpl",
+			"Title":   "laporan penyusutan",
+			"Summary": summary,
+		})
+	case "xlsx":
+		var buf bytes.Buffer
+		if err := s.depreciationService.WriteSummaryXLSX(&buf, summary); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", `attachment; filename="penyusutan-`+strconv.Itoa(year)+`.xlsx"`)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		buf.WriteTo(w)
+	default:
+		http.Error(w, "format must be xlsx", http.StatusBadRequest)
+	}
+}
+
+func (s *Server) transferUnitsHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is required", http.StatusBadRequest)
+		return
+	}
+
+	var reqForm entities.TransferForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.transferService.TransferUnits(r.Context(), reqForm); err != nil {
+		data, fetchErr := s.roomDetailData(r, slug)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		selected := make(map[string]bool, len(reqForm.Units))
+		for _, id := range reqForm.Units {
+			selected[id] = true
+		}
+
+		data["Selected"] = selected
+		data["Form"] = reqForm
+		s.handleWebError(w, r, err, "partials/room-unit-partial.tmpl", data)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/room/"+slug)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) unitTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	unit, stays, err := s.transferService.GetUnitTimeline(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := map[string]any{
+		"Unit":  unit,
+		"Stays": stays,
+	}
+
+	if at := r.URL.Query().Get("at"); at != "" {
+		date, err := entities.ParseDate(at)
+		if err != nil {
+			data["Errors"] = map[string]string{"At": "tanggal tidak valid"}
+		} else {
+			stay, found := entities.UnitStayAt(stays, date)
+			data["At"] = at
+			data["AtFound"] = found
+			data["AtStay"] = stay
+		}
+	}
+
+	s.RenderHTML(w, "partials/unit-timeline-partial.tmpl", data)
+}
+
+// Item Area
+
+func (s *Server) getItemsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params, err := utils.PaginationFromRequest(r)
+	if err != nil {
+		log.Printf("Invalid pagination parameters: %v", err)
+		http.Error(w, "Invalid request parameters", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.itemService.GetItemsWithFilter(ctx, params)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	total, err := s.itemService.GetTotalItems(ctx)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	categories, err := s.categoryService.GetCategoriesForUI(ctx)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := buildTemplateData(r, result, params, total, "barang")
+	data["Categories"] = categories
+
+	var templateName string
+	if ctx.Value(htmxKey).(bool) {
+		templateName = "partials/item-list-partial.tmpl"
+	} else {
+		templateName = "layout.tmpl"
+		data["Page"] = "pages/item_list.tmpl"
+	}
+
+	s.RenderHTML(w, templateName, data)
+}
+
+func (s *Server) viewAddItemHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := s.categoryService.GetCategoriesForUI(r.Context())
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.RenderHTML(w, "layout.tmpl", map[string]any{
+		"Page": "pages/item_form.tmpl", "Title": "Form Tambah Barang", "Mode": "create", "Categories": categories,
+		"AttrValues": entities.AttributeValues{},
+	})
+}
+
+// fillItemAttributes puts the attribute fields of the category picked on the
+// item form into data. An empty or unknown category has no fields.
+func (s *Server) fillItemAttributes(ctx context.Context, data map[string]any, kategori string) error {
+	id, err := strconv.Atoi(strings.TrimSpace(kategori))
+	if err != nil || id <= 0 {
+		return nil
+	}
+
+	fields, err := s.itemService.GetAttributeFields(ctx, id)
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return nil
+		}
+		return err
+	}
+
+	data["Fields"] = fields
+	return nil
+}
+
+// itemAttributesHandler renders the attribute inputs of the item form for the
+// picked category. On the edit form barang names the item, whose values fill
+// the inputs and whose category is used while none is picked.
+func (s *Server) itemAttributesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	kategori := q.Get("kategori_barang")
+	data := map[string]any{"AttrValues": entities.AttributeValues{}}
+
+	if slug := q.Get("barang"); slug != "" {
+		item, err := s.itemService.GetItemBySlug(r.Context(), slug)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		data["AttrValues"] = item.Atribut
+		if strings.TrimSpace(kategori) == "" {
+			kategori = strconv.Itoa(item.IdKategori)
+		}
+	}
+
+	if err := s.fillItemAttributes(r.Context(), data, kategori); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.RenderHTML(w, "partials/item-attribute-partial.tmpl", data)
+}
+
+func (s *Server) addItemHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var reqForm entities.ItemForm
+
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.itemService.CreateItem(ctx, reqForm); err != nil {
+		categories, fetchErr := s.categoryService.GetCategoriesForUI(ctx)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		formData := map[string]any{
+			"Form":       reqForm,
+			"Mode":       "create",
+			"Categories": categories,
+			"AttrValues": entities.AttributeFormValues(reqForm.Atribut),
+		}
+		if fetchErr := s.fillItemAttributes(ctx, formData, reqForm.Kategori); fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		s.handleWebError(w, r, err, "partials/item-form-partial.tmpl", formData)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/item")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) viewItemHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is required", http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.itemService.GetItemBySlug(r.Context(), slug)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	units, err := s.unitService.GetUnitsByItem(r.Context(), item.Id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	pictures, err := s.pictureService.GetPicturesByItem(r.Context(), item.Id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	dep, err := s.depreciationService.GetItemDepreciation(r.Context(), slug, time.Now())
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	repairs, err := s.repairService.GetItemRepairs(r.Context(), item.Id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := map[string]any{
+		"Page":     "pages/item_detail.tmpl",
+		"Title":    "barang",
+		"Item":     item,
+		"Units":    units,
+		"Pictures": pictures,
+		"Dep":      dep,
+		"Repairs":  repairs,
+	}
+	if err := s.fillItemAttributes(r.Context(), data, strconv.Itoa(item.IdKategori)); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+func (s *Server) itemDepreciationHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is required", http.StatusBadRequest)
+		return
+	}
+
+	at := time.Now()
+	data := map[string]any{}
+	if raw := r.URL.Query().Get("at"); raw != "" {
+		date, err := entities.ParseDate(raw)
+		if err != nil {
+			data["Errors"] = map[string]string{"At": "tanggal tidak valid"}
+		} else {
+			at = date
+		}
+	}
+
+	dep, err := s.depreciationService.GetItemDepreciation(r.Context(), slug, at)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Dep"] = dep
+	s.RenderHTML(w, "partials/depreciation-partial.tmpl", data)
+}
+
+func (s *Server) viewEditItemHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is empty", http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.itemService.GetItemBySlug(r.Context(), slug)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	categories, err := s.categoryService.GetCategoriesForUI(r.Context())
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := map[string]any{
+		"Page":       "pages/item_form.tmpl",
+		"Title":      "form edit barang",
+		"Mode":       "edit",
+		"Categories": categories,
+		"Item":       item,
+		"Slug":       slug,
+		"AttrValues": item.Atribut,
+	}
+	if err := s.fillItemAttributes(r.Context(), data, strconv.Itoa(item.IdKategori)); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+func (s *Server) editItemHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is required", http.StatusBadRequest)
+		return
+	}
+
+	var reqForm entities.ItemForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.itemService.EditItem(r.Context(), slug, reqForm); err != nil {
+		item, fetchErr := s.itemService.GetItemBySlug(r.Context(), slug)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		categories, fetchErr := s.categoryService.GetCategoriesForUI(r.Context())
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		formData := map[string]any{
+			"Form":       reqForm,
+			"Mode":       "edit",
+			"Categories": categories,
+			"Item":       item,
+			"Slug":       slug,
+			"AttrValues": item.Atribut,
+		}
+		if len(reqForm.Atribut) > 0 {
+			formData["AttrValues"] = entities.AttributeFormValues(reqForm.Atribut)
+		}
+
+		kategori := reqForm.Kategori
+		if strings.TrimSpace(kategori) == "" {
+			kategori = strconv.Itoa(item.IdKategori)
+		}
+		if fetchErr := s.fillItemAttributes(r.Context(), formData, kategori); fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		s.handleWebError(w, r, err, "partials/item-form-partial.tmpl", formData)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/item")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteItemHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.itemService.DeleteItem(r.Context(), id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	newReq := r.Clone(r.Context())
+	newReq.Method = "GET"
+	newReq.Header.Set("HX-Request", "true")
+	s.getItemsHandler(w, newReq)
+}
+
+// Unit Area
+
+func (s *Server) viewAddUnitsHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is required", http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.itemService.GetItemBySlug(r.Context(), slug)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	rooms, err := s.roomService.GetRoomsForUI(r.Context())
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n < 1 {
+		n = 1
+	}
+	if n > maxUnitRows {
+		n = maxUnitRows
+	}
+
+	s.RenderHTML(w, "layout.tmpl", map[string]any{
+		"Page":  "pages/unit_form.tmpl",
+		"Title": "form tambah unit barang",
+		"Item":  item,
+		"Rooms": rooms,
+		"Form":  entities.UnitForm{Units: make([]entities.UnitRowForm, n)},
+	})
+}
+
+func (s *Server) addUnitsHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is required", http.StatusBadRequest)
+		return
+	}
+
+	var reqForm entities.UnitForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.unitService.RegisterUnits(r.Context(), slug, reqForm); err != nil {
+		item, fetchErr := s.itemService.GetItemBySlug(r.Context(), slug)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		rooms, fetchErr := s.roomService.GetRoomsForUI(r.Context())
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		s.handleWebError(w, r, err, "partials/unit-form-partial.tmpl", map[string]any{
+			"Item":  item,
+			"Rooms": rooms,
+			"Form":  reqForm,
+		})
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/item/"+slug)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) viewEditUnitHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	unit, err := s.unitService.GetUnitById(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.RenderHTML(w, "layout.tmpl", map[string]any{
+		"Page":  "pages/unit_edit.tmpl",
+		"Title": "form edit unit barang",
+		"Unit":  unit,
+	})
+}
+
+func (s *Server) editUnitHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		log.Printf("parsing form: %s", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	noSeri := r.PostForm.Get("no_seri")
+	unit, err := s.unitService.GetUnitById(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.unitService.EditUnitSerial(r.Context(), id, noSeri); err != nil {
+		s.handleWebError(w, r, err, "partials/unit-edit-partial.tmpl", map[string]any{
+			"Unit":       unit,
+			"FormNoSeri": noSeri,
+		})
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/item/"+unit.Barang.Slug)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) changeUnitConditionHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	var reqForm entities.UnitConditionForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	unit, err := s.unitService.GetUnitById(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := map[string]any{}
+	if err := s.unitService.ChangeUnitCondition(r.Context(), id, reqForm.Kondisi); err != nil {
+		var val utils.WebError
+		if !errors.As(err, &val) {
+			s.handleError(w, r, err)
+			return
+		}
+		data["Errors"] = map[string]string{val.Field: val.Message}
+		data["ErrorUnit"] = unit.Id
+	}
+
+	s.renderUnitList(w, r, unit.Barang.Slug, data)
+}
+
+func (s *Server) deleteUnitHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	unit, err := s.unitService.GetUnitById(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := s.unitService.DeleteUnit(r.Context(), id); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderUnitList(w, r, unit.Barang.Slug, map[string]any{})
+}
+
+func (s *Server) renderUnitList(w http.ResponseWriter, r *http.Request, itemSlug string, data map[string]any) {
+	item, err := s.itemService.GetItemBySlug(r.Context(), itemSlug)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	units, err := s.unitService.GetUnitsByItem(r.Context(), item.Id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Item"] = item
+	data["Units"] = units
+	s.RenderHTML(w, "partials/unit-list-partial.tmpl", data)
+}
+
+// Picture Area
+
+func (s *Server) uploadPictureHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is required", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxPictureSize*maxPicturesPerUpload)
+	if err := r.ParseMultipartForm(services.MaxPictureSize); err != nil {
+		log.Printf("parsing multipart form: %s", err)
+		s.renderPictureList(w, r, slug, map[string]any{
+			"Errors": map[string]string{"Gambar": "ukuran unggahan terlalu besar"},
+		})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["gambar"]
+	if len(files) == 0 {
+		s.renderPictureList(w, r, slug, map[string]any{
+			"Errors": map[string]string{"Gambar": "pilih minimal satu gambar"},
+		})
+		return
+	}
+
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			log.Printf("opening uploaded file: %s", err)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+
+		err = s.pictureService.UploadPicture(r.Context(), slug, header.Filename, header.Size, file)
+		file.Close()
+		if err != nil {
+			var val utils.WebError
+			if !errors.As(err, &val) {
+				s.handleError(w, r, err)
+				return
+			}
+
+			s.renderPictureList(w, r, slug, map[string]any{
+				"Errors": map[string]string{val.Field: header.Filename + ": " + val.Message},
+			})
+			return
+		}
+	}
+
+	s.renderPictureList(w, r, slug, map[string]any{})
+}
+
+func (s *Server) viewPictureHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	object, info, err := s.pictureService.OpenPicture(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+	defer object.Close()
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+
+	if _, err := io.Copy(w, object); err != nil {
+		log.Printf("error streaming picture %v: %s", id, err)
+	}
+}
+
+func (s *Server) deletePictureHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	picture, err := s.pictureService.DeletePicture(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	item, err := s.itemService.GetItemById(r.Context(), picture.IdBarang)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderPictureList(w, r, item.Slug, map[string]any{})
+}
+
+func (s *Server) renderPictureList(w http.ResponseWriter, r *http.Request, itemSlug string, data map[string]any) {
+	item, err := s.itemService.GetItemBySlug(r.Context(), itemSlug)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	pictures, err := s.pictureService.GetPicturesByItem(r.Context(), item.Id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Item"] = item
+	data["Pictures"] = pictures
+	s.RenderHTML(w, "partials/picture-list-partial.tmpl", data)
+}
+
+func (s *Server) getUsersHandler(w http.ResponseWriter, r *http.Request) {
+	s.renderUserList(w, r, map[string]any{})
+}
+
+func (s *Server) renderUserList(w http.ResponseWriter, r *http.Request, data map[string]any) {
+	users, err := s.userService.GetUsers(r.Context())
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Items"] = users
+	data["Title"] = "pengguna"
+	data["Current"] = currentUser(r)
+
+	templateName := "partials/user-list-partial.tmpl"
+	if !r.Context().Value(htmxKey).(bool) {
+		templateName = "layout.tmpl"
+		data["Page"] = "pages/user_list.tmpl"
+	}
+
+	s.RenderHTML(w, templateName, data)
+}
+
+func (s *Server) viewAddUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.RenderHTML(w, "layout.tmpl", map[string]any{
+		"Page": "pages/user_form.tmpl", "Title": "Form Tambah Pengguna", "Mode": "create", "Perans": entities.Perans(),
+	})
+}
+
+func (s *Server) addUserHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.UserForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.userService.CreateUser(r.Context(), reqForm); err != nil {
+		s.handleWebError(w, r, err, "partials/user-form-partial.tmpl", map[string]any{
+			"Form":   reqForm,
+			"Mode":   "create",
+			"Perans": entities.Perans(),
+		})
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/user")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) viewEditUserHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	user, err := s.userService.GetUserById(r.Context(), id)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.RenderHTML(w, "layout.tmpl", map[string]any{
+		"Page":   "pages/user_form.tmpl",
+		"Title":  "form edit pengguna",
+		"Mode":   "edit",
+		"User":   user,
+		"Form":   entities.UserForm{Nama: user.Nama, Peran: string(user.Peran)},
+		"Perans": entities.Perans(),
+	})
+}
+
+func (s *Server) editUserHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var reqForm entities.UserForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.userService.EditUser(r.Context(), id, reqForm); err != nil {
+		user, fetchErr := s.userService.GetUserById(r.Context(), id)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		s.handleWebError(w, r, err, "partials/user-form-partial.tmpl", map[string]any{
+			"Form":   reqForm,
+			"Mode":   "edit",
+			"User":   user,
+			"Perans": entities.Perans(),
+		})
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/user")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	err := s.userService.DeleteUser(r.Context(), id, currentUser(r).Id)
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			s.renderUserList(w, r, map[string]any{"Errors": map[string]string{webErr.Field: webErr.Message}})
+			return
+		}
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.renderUserList(w, r, map[string]any{})
+}
+
+func (s *Server) getAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params, err := utils.PaginationFromRequest(r)
+	if err != nil {
+		log.Printf("Invalid pagination parameters: %v", err)
+		http.Error(w, "Invalid request parameters", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.auditService.GetAuditLogsWithFilter(ctx, params)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := buildTemplateData(r, result, params, result.TotalData, "audit")
+	q := r.URL.Query()
+	data["Ent"] = q.Get("ent")
+	data["Aksi"] = q.Get("aksi")
+	data["Dmin"] = q.Get("dmin")
+	data["Dmax"] = q.Get("dmax")
+	data["EntityTypes"] = entities.AuditEntityTypes()
+	data["Actions"] = entities.AuditActions()
+
+	var templateName string
+	if ctx.Value(htmxKey).(bool) {
+		templateName = "partials/audit-list-partial.tmpl"
+	} else {
+		templateName = "layout.tmpl"
+		data["Page"] = "pages/audit_list.tmpl"
+	}
+
+	s.RenderHTML(w, templateName, data)
+}
+
+func (s *Server) getOpnamesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params, err := utils.PaginationFromRequest(r)
+	if err != nil {
+		log.Printf("Invalid pagination parameters: %v", err)
+		http.Error(w, "Invalid request parameters", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.opnameService.GetOpnamesWithFilter(ctx, params)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := buildTemplateData(r, result, params, result.TotalData, "stok opname")
+	data["Status"] = r.URL.Query().Get("status")
+	data["Statuses"] = entities.StatusOpnames()
+
+	var templateName string
+	if ctx.Value(htmxKey).(bool) {
+		templateName = "partials/opname-list-partial.tmpl"
+	} else {
+		templateName = "layout.tmpl"
+		data["Page"] = "pages/opname_list.tmpl"
+	}
+
+	s.RenderHTML(w, templateName, data)
+}
+
+func (s *Server) opnameFormData(r *http.Request) (map[string]any, error) {
+	rooms, err := s.roomService.GetRoomsForUI(r.Context())
+	if err != nil {
+		return nil, fmt.Errorf("fetching rooms: %w", err)
+	}
+
+	loc, err := s.locationService.GetLocationsForUI(r.Context())
+	if err != nil {
+		return nil, fmt.Errorf("fetching locations: %w", err)
+	}
+
+	return map[string]any{"Rooms": rooms, "Loc": loc}, nil
+}
+
+func (s *Server) viewAddOpnameHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := s.opnameFormData(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Page"] = "pages/opname_form.tmpl"
+	data["Title"] = "Mulai Stok Opname"
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+func (s *Server) addOpnameHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.OpnameForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	op, err := s.opnameService.StartOpname(r.Context(), reqForm)
+	if err != nil {
+		data, fetchErr := s.opnameFormData(r)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		data["Form"] = reqForm
+		s.handleWebError(w, r, err, "partials/opname-form-partial.tmpl", data)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/opname/"+op.Id.String())
+	w.WriteHeader(http.StatusOK)
+}
+
+// renderOpname renders the session page, or only its scan and report part
+// for HTMX requests.
+func (s *Server) renderOpname(w http.ResponseWriter, r *http.Request, data map[string]any) {
+	report, err := s.opnameService.GetOpnameReport(r.Context(), r.PathValue("id"))
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Report"] = report
+	if r.Context().Value(htmxKey).(bool) {
+		s.RenderHTML(w, "partials/opname-scan-partial.tmpl", data)
+		return
+	}
+
+	data["Page"] = "pages/opname_detail.tmpl"
+	data["Title"] = "stok opname " + report.Opname.NamaCakupan
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+func (s *Server) viewOpnameHandler(w http.ResponseWriter, r *http.Request) {
+	s.renderOpname(w, r, map[string]any{})
+}
+
+func (s *Server) scanOpnameHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("parsing form: %s", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	data := map[string]any{}
+	scan, err := s.opnameService.ScanUnit(r.Context(), r.PathValue("id"), r.PostForm.Get("kode"))
+	if err != nil {
+		var webErr utils.WebError
+		if !errors.As(err, &webErr) {
+			s.handleError(w, r, err)
+			return
+		}
+		data["Errors"] = map[string]string{webErr.Field: webErr.Message}
+		data["Kode"] = r.PostForm.Get("kode")
+	} else {
+		data["Scan"] = scan
+	}
+
+	s.renderOpname(w, r, data)
+}
+
+func (s *Server) saveOpnameNoteHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("parsing form: %s", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	data := map[string]any{}
+	err := s.opnameService.SaveOpnameNote(r.Context(), r.PathValue("id"), r.PathValue("unit"), r.PostForm.Get("catatan"))
+	if err != nil {
+		var webErr utils.WebError
+		if !errors.As(err, &webErr) {
+			s.handleError(w, r, err)
+			return
+		}
+		data["Errors"] = map[string]string{webErr.Field: webErr.Message}
+	}
+
+	s.renderOpname(w, r, data)
+}
+
+func (s *Server) closeOpnameHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.OpnameCloseForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.opnameService.CloseOpname(r.Context(), id, reqForm); err != nil {
+		var webErr utils.WebError
+		if !errors.As(err, &webErr) {
+			s.handleError(w, r, err)
+			return
+		}
+		s.renderOpname(w, r, map[string]any{"Errors": map[string]string{webErr.Field: webErr.Message}})
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/opname/"+id)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) scanLookupHandler(w http.ResponseWriter, r *http.Request) {
+	kode := r.URL.Query().Get("kode")
+	data := map[string]any{"Kode": kode}
+
+	if kode != "" {
+		units, err := s.opnameService.LookupUnits(r.Context(), kode)
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		// a single match goes straight to its item
+		if len(units) == 1 && !r.Context().Value(htmxKey).(bool) {
+			http.Redirect(w, r, "/item/"+units[0].Barang.Slug, http.StatusSeeOther)
+			return
+		}
+		data["Units"] = units
+		data["Searched"] = true
+	}
+
+	if r.Context().Value(htmxKey).(bool) {
+		s.RenderHTML(w, "partials/scan-result-partial.tmpl", data)
+		return
+	}
+
+	data["Page"] = "pages/scan.tmpl"
+	data["Title"] = "Pindai Unit"
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+// Loan Area
+
+func (s *Server) getLoansHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params, err := utils.PaginationFromRequest(r)
+	if err != nil {
+		log.Printf("Invalid pagination parameters: %v", err)
+		http.Error(w, "Invalid request parameters", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.loanService.GetLoansWithFilter(ctx, params)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := buildTemplateData(r, result, params, result.TotalData, "peminjaman")
+	data["Status"] = r.URL.Query().Get("status")
+	data["Statuses"] = entities.StatusPeminjamans()
+
+	var templateName string
+	if ctx.Value(htmxKey).(bool) {
+		templateName = "partials/loan-list-partial.tmpl"
+	} else {
+		templateName = "layout.tmpl"
+		data["Page"] = "pages/loan_list.tmpl"
+	}
+
+	s.RenderHTML(w, templateName, data)
+}
+
+func (s *Server) loanFormData(r *http.Request, ids []string) (map[string]any, error) {
+	units, err := s.loanService.GetUnitsForLoan(r.Context(), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{"Units": units, "Today": time.Now().Format(entities.DateLayout)}, nil
+}
+
+// viewAddLoanHandler opens the loan form for the units picked on a room page,
+// passed as repeated units query parameters.
+func (s *Server) viewAddLoanHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := s.loanFormData(r, r.URL.Query()["units"])
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Page"] = "pages/loan_form.tmpl"
+	data["Title"] = "Peminjaman Baru"
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+func (s *Server) addLoanHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.LoanForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	loan, err := s.loanService.CheckoutUnits(r.Context(), reqForm)
+	if err != nil {
+		data, fetchErr := s.loanFormData(r, reqForm.Units)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		data["Form"] = reqForm
+		s.handleWebError(w, r, err, "partials/loan-form-partial.tmpl", data)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/loan/"+loan.Id.String())
+	w.WriteHeader(http.StatusOK)
+}
+
+// renderLoan renders the loan page, or only its unit and check-in part for
+// HTMX requests.
+func (s *Server) renderLoan(w http.ResponseWriter, r *http.Request, data map[string]any) {
+	loan, err := s.loanService.GetLoan(r.Context(), r.PathValue("id"))
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Loan"] = loan
+	if _, ok := data["Returns"]; !ok {
+		data["Returns"] = entities.LoanReturnForm{}.Rows()
+		data["Tanggal"] = time.Now().Format(entities.DateLayout)
+	}
+	data["Today"] = time.Now().Format(entities.DateLayout)
+	data["Now"] = time.Now()
+
+	if r.Context().Value(htmxKey).(bool) {
+		s.RenderHTML(w, "partials/loan-unit-partial.tmpl", data)
+		return
+	}
+
+	data["Page"] = "pages/loan_detail.tmpl"
+	data["Title"] = "peminjaman " + loan.Peminjam
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+func (s *Server) viewLoanHandler(w http.ResponseWriter, r *http.Request) {
+	s.renderLoan(w, r, map[string]any{})
+}
+
+func (s *Server) returnLoanHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.LoanReturnForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.loanService.ReturnUnits(r.Context(), id, reqForm); err != nil {
+		var webErr utils.WebError
+		if !errors.As(err, &webErr) {
+			s.handleError(w, r, err)
+			return
+		}
+		s.renderLoan(w, r, map[string]any{
+			"Errors":  map[string]string{webErr.Field: webErr.Message},
+			"Returns": reqForm.Rows(),
+			"Tanggal": reqForm.Tanggal,
+		})
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/loan/"+id)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) overdueLoansHandler(w http.ResponseWriter, r *http.Request) {
+	units, err := s.loanService.GetOverdueUnits(r.Context())
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.RenderHTML(w, "layout.tmpl", map[string]any{
+		"Page":  "pages/loan_overdue.tmpl",
+		"Title": "Peminjaman Terlambat",
+		"Units": units,
+		"Now":   time.Now(),
+	})
+}
+
+func (s *Server) borrowerHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	history, err := s.loanService.GetBorrowerHistory(r.Context(), r.PathValue("identitas"))
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.RenderHTML(w, "layout.tmpl", map[string]any{
+		"Page":    "pages/loan_borrower.tmpl",
+		"Title":   "riwayat peminjaman " + history.Peminjam,
+		"History": history,
+		"Now":     time.Now(),
+	})
+}
+
+// Repair Area
+
+func (s *Server) getRepairsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params, err := utils.PaginationFromRequest(r)
+	if err != nil {
+		log.Printf("Invalid pagination parameters: %v", err)
+		http.Error(w, "Invalid request parameters", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.repairService.GetRepairsWithFilter(ctx, params)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data := buildTemplateData(r, result, params, result.TotalData, "perbaikan")
+	data["Status"] = r.URL.Query().Get("status")
+	data["Statuses"] = entities.StatusPerbaikans()
+
+	var templateName string
+	if ctx.Value(htmxKey).(bool) {
+		templateName = "partials/repair-list-partial.tmpl"
+	} else {
+		templateName = "layout.tmpl"
+		data["Page"] = "pages/repair_list.tmpl"
+	}
+
+	s.RenderHTML(w, templateName, data)
+}
+
+func (s *Server) repairFormData(r *http.Request, id string) (map[string]any, error) {
+	unit, err := s.repairService.GetUnitForRepair(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{"Unit": unit, "Today": time.Now().Format(entities.DateLayout)}, nil
+}
+
+// viewAddRepairHandler opens the ticket form for the unit passed as the unit
+// query parameter.
+func (s *Server) viewAddRepairHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := s.repairFormData(r, r.URL.Query().Get("unit"))
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Page"] = "pages/repair_form.tmpl"
+	data["Title"] = "Perbaikan Baru"
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+func (s *Server) addRepairHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.RepairForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	repair, err := s.repairService.OpenRepair(r.Context(), reqForm)
+	if err != nil {
+		data, fetchErr := s.repairFormData(r, reqForm.Unit)
+		if fetchErr != nil {
+			s.handleError(w, r, fetchErr)
+			return
+		}
+
+		data["Form"] = reqForm
+		s.handleWebError(w, r, err, "partials/repair-form-partial.tmpl", data)
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/repair/"+repair.Id.String())
+	w.WriteHeader(http.StatusOK)
+}
+
+// renderRepair renders the ticket page, or only its closing form for HTMX
+// requests.
+func (s *Server) renderRepair(w http.ResponseWriter, r *http.Request, data map[string]any) {
+	repair, err := s.repairService.GetRepair(r.Context(), r.PathValue("id"))
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	data["Repair"] = repair
+	data["KondisiHasil"] = entities.KondisiHasilPerbaikan()
+	data["Today"] = time.Now().Format(entities.DateLayout)
+	if _, ok := data["Form"]; !ok {
+		data["Form"] = entities.RepairCloseForm{TglSelesai: time.Now().Format(entities.DateLayout)}
+	}
+
+	if r.Context().Value(htmxKey).(bool) {
+		s.RenderHTML(w, "partials/repair-close-partial.tmpl", data)
+		return
+	}
+
+	data["Page"] = "pages/repair_detail.tmpl"
+	data["Title"] = "perbaikan " + repair.Unit.NoSeri
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+func (s *Server) viewRepairHandler(w http.ResponseWriter, r *http.Request) {
+	s.renderRepair(w, r, map[string]any{})
+}
+
+func (s *Server) closeRepairHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.RepairCloseForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.repairService.CloseRepair(r.Context(), id, reqForm); err != nil {
+		var webErr utils.WebError
+		if !errors.As(err, &webErr) {
+			s.handleError(w, r, err)
+			return
+		}
+		s.renderRepair(w, r, map[string]any{
+			"Errors": map[string]string{webErr.Field: webErr.Message},
+			"Form":   reqForm,
+		})
+		return
+	}
+
+	w.Header().Set("HX-Redirect", "/repair/"+id)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Import Area
+
+func (s *Server) viewImportHandler(w http.ResponseWriter, r *http.Request) {
+	s.RenderHTML(w, "layout.tmpl", map[string]any{
+		"Page":    "pages/import.tmpl",
+		"Title":   "Impor Data",
+		"Jenis":   r.URL.Query().Get("jenis"),
+		"Jenises": entities.JenisImpors(),
+		"MaxRows": services.MaxImportRows,
+	})
+}
+
+func (s *Server) importTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	jenis := r.URL.Query().Get("jenis")
+
+	var buf bytes.Buffer
+	if err := s.importService.WriteImportTemplate(&buf, jenis); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="impor-`+jenis+`.csv"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(w)
+}
+
+// importHandler validates the uploaded file and, when the impor button was
+// pressed and every row is valid, saves it. The preview is rendered either
+// way so the per-row errors stay visible.
+func (s *Server) importHandler(w http.ResponseWriter, r *http.Request) {
+	data := map[string]any{}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxImportSize)
+	if err := r.ParseMultipartForm(services.MaxImportSize); err != nil {
+		log.Printf("parsing multipart form: %s", err)
+		data["Errors"] = map[string]string{"File": "ukuran file terlalu besar"}
+		s.RenderHTML(w, "partials/import-preview-partial.tmpl", data)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	jenis := r.FormValue("jenis")
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		data["Errors"] = map[string]string{"File": "pilih file yang akan diimpor"}
+		s.RenderHTML(w, "partials/import-preview-partial.tmpl", data)
+		return
+	}
+	defer file.Close()
+
+	run := s.importService.PreviewImport
+	if r.FormValue("aksi") == "impor" {
+		run = s.importService.CommitImport
+	}
+
+	preview, err := run(r.Context(), jenis, header.Filename, file)
+	data["Preview"] = preview
+	if err != nil {
+		s.handleWebError(w, r, err, "partials/import-preview-partial.tmpl", data)
+		return
+	}
+
+	s.RenderHTML(w, "partials/import-preview-partial.tmpl", data)
+}
+
+// Export Area
+
+type exportFunc func(context.Context, io.Writer, entities.FormatEkspor, utils.PaginationParams) error
+
+// exportList streams the list behind a list page with the same query string,
+// without pagination. Once the first row is written the status can no longer
+// change, so a failure halfway is only logged.
+func (s *Server) exportList(w http.ResponseWriter, r *http.Request, list string, export exportFunc) {
+	format, err := entities.ParseFormatEkspor(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params, err := utils.PaginationFromRequest(r)
+	if err != nil {
+		log.Printf("Invalid pagination parameters: %v", err)
+		http.Error(w, "Invalid request parameters", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="`+format.FileName(list, time.Now())+`"`)
+
+	if err := export(r.Context(), w, format, params); err != nil {
+		log.Printf("error exporting %s: %s", list, err)
+	}
+}
+
+func (s *Server) exportCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	s.exportList(w, r, "kategori", s.exportService.ExportCategories)
+}
+
+func (s *Server) exportLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	s.exportList(w, r, "lokasi", s.exportService.ExportLocations)
+}
+
+func (s *Server) exportRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	s.exportList(w, r, "ruangan", s.exportService.ExportRooms)
+}
+
+func (s *Server) exportItemsHandler(w http.ResponseWriter, r *http.Request) {
+	s.exportList(w, r, "barang", s.exportService.ExportItems)
+}
+
+// Bulk Area
+
+type (
+	bulkSummarizer func(ctx context.Context, ids []string) (entities.DeleteSummary, error)
+	bulkRemover    func(ctx context.Context, ids []string) error
+)
+
+// bulkDelete answers the first post of the checked rows with a summary of
+// what the delete takes along. The summary posts the same ids back with
+// konfirmasi set, and only then are they removed and the list re-rendered.
+func (s *Server) bulkDelete(w http.ResponseWriter, r *http.Request, jenis string, list http.HandlerFunc, summarize bulkSummarizer, remove bulkRemover) {
+	var reqForm entities.BulkForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	data := map[string]any{
+		"Jenis":   jenis,
+		"Action":  r.URL.RequestURI(),
+		"Ids":     reqForm.Ids,
+		"Retensi": entities.TrashRetentionDays,
+	}
+
+	if !reqForm.Konfirmasi {
+		summary, err := summarize(r.Context(), reqForm.Ids)
+		if err != nil {
+			s.handleWebError(w, r, err, "partials/bulk-panel-partial.tmpl", data)
+			return
+		}
+
+		data["Summary"] = summary
+		s.RenderHTML(w, "partials/bulk-panel-partial.tmpl", data)
+		return
+	}
+
+	if err := remove(r.Context(), reqForm.Ids); err != nil {
+		s.handleWebError(w, r, err, "partials/bulk-panel-partial.tmpl", data)
+		return
+	}
+
+	s.renderBulkList(w, r, list)
+}
+
+// renderBulkList swaps the whole list in place of the bulk panel, keeping the
+// filters that came along in the query string.
+func (s *Server) renderBulkList(w http.ResponseWriter, r *http.Request, list http.HandlerFunc) {
+	newReq := r.Clone(r.Context())
+	newReq.Method = "GET"
+	newReq.Header.Set("HX-Request", "true")
+	w.Header().Set("HX-Retarget", "#container")
+	list(w, newReq)
+}
+
+func (s *Server) bulkDeleteCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	s.bulkDelete(w, r, "kategori", s.listCategoriesHandler(), s.bulkService.SummarizeCategoryDelete, s.bulkService.DeleteCategories)
+}
+
+func (s *Server) bulkDeleteLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	s.bulkDelete(w, r, "lokasi", s.getLocationsHandler(), s.bulkService.SummarizeLocationDelete, s.bulkService.DeleteLocations)
+}
+
+func (s *Server) bulkDeleteRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	s.bulkDelete(w, r, "ruangan", s.getRoomsHandler, s.bulkService.SummarizeRoomDelete, s.bulkService.DeleteRooms)
+}
+
+func (s *Server) bulkMoveRoomsHandler(w http.ResponseWriter, r *http.Request) {
+	var reqForm entities.BulkMoveForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.bulkService.MoveRooms(r.Context(), reqForm); err != nil {
+		s.handleWebError(w, r, err, "partials/bulk-panel-partial.tmpl", map[string]any{"Jenis": "ruangan"})
+		return
+	}
+
+	s.renderBulkList(w, r, s.getRoomsHandler)
+}
+
+func (s *Server) bulkUnitConditionHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if slug == "" {
+		http.Error(w, "slug is required", http.StatusBadRequest)
+		return
+	}
+
+	var reqForm entities.BulkConditionForm
+	if err := parseForm(r, &reqForm); err != nil {
+		log.Printf("error parsing form: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	err := s.bulkService.ChangeUnitConditions(r.Context(), reqForm)
+
+	data, fetchErr := s.roomDetailData(r, slug)
+	if fetchErr != nil {
+		s.handleError(w, r, fetchErr)
+		return
+	}
+
+	if err != nil {
+		selected := make(map[string]bool, len(reqForm.Units))
+		for _, id := range reqForm.Units {
+			selected[id] = true
+		}
+
+		data["Selected"] = selected
+		data["FormKondisi"] = reqForm.Kondisi
+		s.handleWebError(w, r, err, "partials/room-unit-partial.tmpl", data)
+		return
+	}
+
+	s.RenderHTML(w, "partials/room-unit-partial.tmpl", data)
+}
+
+// Trash Area
+
+func (s *Server) trashData(r *http.Request) (map[string]any, error) {
+	jenis := r.URL.Query().Get("jenis")
+
+	entries, err := s.trashService.GetTrash(r.Context(), jenis)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"Title":   "Tempat Sampah",
+		"Entries": entries,
+		"Jenis":   jenis,
+		"Jenises": entities.JenisSampahs(),
+		"Retensi": entities.TrashRetentionDays,
+		"Now":     time.Now(),
+	}, nil
+}
+
+func (s *Server) getTrashHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := s.trashData(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if r.Context().Value(htmxKey).(bool) {
+		s.RenderHTML(w, "partials/trash-list-partial.tmpl", data)
+		return
+	}
+
+	data["Page"] = "pages/trash.tmpl"
+	s.RenderHTML(w, "layout.tmpl", data)
+}
+
+// trashAction restores or purges one record and re-renders the trash list,
+// with the reason in place when the record has to stay.
+func (s *Server) trashAction(w http.ResponseWriter, r *http.Request, act func(ctx context.Context, jenis, id string) error) {
+	actErr := act(r.Context(), r.PathValue("jenis"), r.PathValue("id"))
+
+	data, err := s.trashData(r)
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if actErr != nil {
+		s.handleWebError(w, r, actErr, "partials/trash-list-partial.tmpl", data)
+		return
+	}
+
+	s.RenderHTML(w, "partials/trash-list-partial.tmpl", data)
+}
+
+func (s *Server) restoreTrashHandler(w http.ResponseWriter, r *http.Request) {
+	s.trashAction(w, r, s.trashService.RestoreTrash)
+}
+
+func (s *Server) purgeTrashHandler(w http.ResponseWriter, r *http.Request) {
+	s.trashAction(w, r, s.trashService.PurgeTrash)
 }
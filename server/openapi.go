@@ -0,0 +1,378 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/utils"
+)
+
+type schema = map[string]any
+
+var pathParamRe = regexp.MustCompile(`\{(\w+)\}`)
+
+// enumValues lists the allowed values of the named string types exposed by
+// the API.
+var enumValues = map[reflect.Type]func() []string{
+	reflect.TypeFor[entities.KondisiUnit](): func() []string {
+		return stringsOf(entities.KondisiUnits())
+	},
+	reflect.TypeFor[entities.MetodePenyusutan](): func() []string {
+		return stringsOf(entities.MetodePenyusutans())
+	},
+	reflect.TypeFor[entities.Peran](): func() []string {
+		return stringsOf(entities.Perans())
+	},
+	reflect.TypeFor[entities.TipeAtribut](): func() []string {
+		return stringsOf(entities.TipeAtributs())
+	},
+}
+
+func stringsOf[T ~string](values []T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// openAPI holds the generated document and, for the contract check, the
+// response schema of every documented status keyed by route pattern.
+type openAPI struct {
+	document   []byte
+	components map[string]schema
+	responses  map[string]map[int]schema
+}
+
+type openAPIBuilder struct {
+	components map[string]schema
+}
+
+func refTo(name string) schema {
+	return schema{"$ref": "#/components/schemas/" + name}
+}
+
+func componentName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+// schemaOf describes how encoding/json renders a value of type t. Structs are
+// registered as components; tag selects the struct tag holding field names,
+// "json" for responses and "form" for request bodies.
+func (b *openAPIBuilder) schemaOf(t reflect.Type, tag string) schema {
+	switch t {
+	case reflect.TypeFor[time.Time]():
+		return schema{"type": "string", "format": "date-time"}
+	case reflect.TypeFor[uuid.UUID]():
+		return schema{"type": "string", "format": "uuid"}
+	case reflect.TypeFor[uuid.NullUUID]():
+		return schema{"type": "string", "format": "uuid", "nullable": true}
+	case reflect.TypeFor[[]byte]():
+		return schema{"type": "string", "format": "byte", "nullable": true}
+	}
+
+	if values, ok := enumValues[t]; ok {
+		return schema{"type": "string", "enum": values()}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return schema{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return schema{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.Pointer:
+		s := b.schemaOf(t.Elem(), tag)
+		if _, ok := s["$ref"]; ok {
+			return schema{"allOf": []any{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": b.schemaOf(t.Elem(), tag), "nullable": true}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": b.schemaOf(t.Elem(), tag)}
+	case reflect.Struct:
+		name := componentName(t)
+		if _, ok := b.components[name]; !ok {
+			b.components[name] = schema{}
+			b.components[name] = b.structSchema(t, tag)
+		}
+		return refTo(name)
+	default:
+		return schema{}
+	}
+}
+
+func (b *openAPIBuilder) structSchema(t reflect.Type, tag string) schema {
+	properties := schema{}
+	var required []string
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.schemaOf(field.Type, tag)
+		if tag == "json" && !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			required = append(required, name)
+		}
+	}
+
+	s := schema{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func listSchema(items schema) schema {
+	return schema{
+		"type": "object",
+		"properties": schema{
+			"data":       schema{"type": "array", "items": items},
+			"page":       schema{"type": "integer"},
+			"per_page":   schema{"type": "integer"},
+			"total_data": schema{"type": "integer", "format": "int64"},
+			"total_page": schema{"type": "integer"},
+		},
+		"required":             []string{"data", "page", "per_page", "total_data", "total_page"},
+		"additionalProperties": false,
+	}
+}
+
+func paginationParameters(filters map[string]utils.AllowedFilter) []any {
+	params := []any{
+		schema{"name": "q", "in": "query", "description": "Search text", "schema": schema{"type": "string"}},
+		schema{"name": "sb", "in": "query", "description": "Sort key", "schema": schema{"type": "string"}},
+		schema{"name": "ord", "in": "query", "description": "Sort direction", "schema": schema{"type": "string", "enum": []string{"asc", "desc"}, "default": "desc"}},
+		schema{"name": "page", "in": "query", "schema": schema{"type": "integer", "minimum": 1, "default": 1}},
+		schema{"name": "perpage", "in": "query", "schema": schema{"type": "integer", "minimum": 1, "maximum": utils.MaxPageSize, "default": 10}},
+	}
+
+	for _, f := range utils.FilterParams(filters) {
+		p := schema{"name": f.Name, "in": "query", "description": "Filter (" + f.Operator + ")", "schema": schema{"type": "string"}}
+		if f.Operator == "in" || f.Operator == "nin" {
+			p["schema"] = schema{"type": "array", "items": schema{"type": "string"}}
+			p["explode"] = true
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+func operationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, seg := range strings.Split(path, "/") {
+		seg = strings.Trim(seg, "{}")
+		for _, part := range strings.FieldsFunc(seg, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+			id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return id.String()
+}
+
+func errorResponse(description string) schema {
+	return schema{
+		"description": description,
+		"content":     schema{"application/json": schema{"schema": refTo("Error")}},
+	}
+}
+
+// buildOpenAPI describes every registered pattern. API routes carry their own
+// metadata; the HTML pages are listed with a text/html response.
+func buildOpenAPI(patterns []string, routes []apiRoute) (*openAPI, error) {
+	b := &openAPIBuilder{components: map[string]schema{
+		"Error": {
+			"type": "object",
+			"properties": schema{
+				"error":  schema{"type": "string"},
+				"fields": schema{"type": "object", "additionalProperties": schema{"type": "string"}, "description": "Field name to message, from utils.WebError"},
+			},
+			"required":             []string{"error"},
+			"additionalProperties": false,
+		},
+	}}
+
+	docs := make(map[string]apiRoute, len(routes))
+	for _, rt := range routes {
+		docs[rt.pattern] = rt
+	}
+
+	result := &openAPI{responses: make(map[string]map[int]schema)}
+	paths := schema{}
+
+	for _, pattern := range patterns {
+		method, path, _ := strings.Cut(pattern, " ")
+		if strings.HasSuffix(path, "/") && path != "/" {
+			path += "{file}"
+		}
+
+		var params []any
+		for _, m := range pathParamRe.FindAllStringSubmatch(path, -1) {
+			params = append(params, schema{"name": m[1], "in": "path", "required": true, "schema": schema{"type": "string"}})
+		}
+
+		op := schema{"operationId": operationID(method, path)}
+		responses := schema{}
+		statuses := make(map[int]schema)
+
+		rt, isAPI := docs[pattern]
+		switch {
+		case isAPI:
+			op["summary"] = rt.summary
+			op["tags"] = []string{"api"}
+
+			for _, name := range rt.query {
+				params = append(params, schema{"name": name, "in": "query", "required": true, "schema": schema{"type": "string"}})
+			}
+
+			// nested lists such as the units of an item return every row
+			if rt.list && !pathParamRe.MatchString(path) {
+				params = append(params, paginationParameters(rt.filters)...)
+			}
+
+			if rt.request != nil {
+				body := b.schemaOf(reflect.TypeOf(rt.request), "form")
+				op["requestBody"] = schema{
+					"required": true,
+					"content": schema{
+						"application/json":                  schema{"schema": body},
+						"application/x-www-form-urlencoded": schema{"schema": body},
+					},
+				}
+			}
+
+			success := schema{"description": http.StatusText(rt.status)}
+			if rt.response != nil {
+				body := b.schemaOf(reflect.TypeOf(rt.response), "json")
+				if rt.list {
+					body = listSchema(body)
+				}
+				success["content"] = schema{"application/json": schema{"schema": body}}
+				statuses[rt.status] = body
+			} else {
+				statuses[rt.status] = nil
+			}
+			responses[fmt.Sprint(rt.status)] = success
+
+			errs := map[int]string{http.StatusInternalServerError: "Unexpected error"}
+			if len(params) > 0 || rt.request != nil {
+				errs[http.StatusBadRequest] = "Malformed body or query parameters"
+			}
+			if !rt.public {
+				errs[http.StatusUnauthorized] = "Missing or expired token"
+				if !isReadMethod(method) {
+					errs[http.StatusForbidden] = "Role may not change data"
+				}
+			} else {
+				op["security"] = []any{}
+				errs[http.StatusUnauthorized] = "Wrong username or password"
+			}
+			if len(params) > 0 && pathParamRe.MatchString(path) {
+				errs[http.StatusNotFound] = "Resource not found"
+			}
+			if rt.request != nil {
+				errs[http.StatusConflict] = "Value already taken"
+				errs[http.StatusUnprocessableEntity] = "Validation failed"
+			}
+			for status, description := range errs {
+				responses[fmt.Sprint(status)] = errorResponse(description)
+				statuses[status] = refTo("Error")
+			}
+
+		case pattern == "GET /api/openapi.json":
+			op["summary"] = "This OpenAPI document"
+			op["tags"] = []string{"api"}
+			op["security"] = []any{}
+			responses["200"] = schema{
+				"description": "OpenAPI 3 document",
+				"content":     schema{"application/json": schema{"schema": schema{"type": "object"}}},
+			}
+
+		default:
+			op["summary"] = "HTML page " + pattern
+			op["tags"] = []string{"html"}
+			if isPublicPath(path) {
+				op["security"] = []any{}
+			}
+			responses["200"] = schema{
+				"description": "Rendered page or HTMX partial",
+				"content":     schema{"text/html": schema{"schema": schema{"type": "string"}}},
+			}
+		}
+
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		op["responses"] = responses
+
+		item, _ := paths[path].(schema)
+		if item == nil {
+			item = schema{}
+			paths[path] = item
+		}
+		item[strings.ToLower(method)] = op
+
+		if isAPI {
+			result.responses[pattern] = statuses
+		}
+	}
+
+	doc := schema{
+		"openapi": "3.0.3",
+		"info": schema{
+			"title":       "Coniven",
+			"version":     "1.0.0",
+			"description": "Inventory management. JSON endpoints live under /api/v1; the remaining paths serve the HTMX pages.",
+		},
+		"paths": paths,
+		"components": schema{
+			"schemas": b.components,
+			"securitySchemes": schema{
+				"bearerAuth": schema{"type": "http", "scheme": "bearer", "description": "Token from POST /api/v1/login"},
+				"cookieAuth": schema{"type": "apiKey", "in": "cookie", "name": sessionCookie},
+			},
+		},
+		"security": []any{schema{"bearerAuth": []any{}}, schema{"cookieAuth": []any{}}},
+	}
+
+	document, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding openapi document: %w", err)
+	}
+
+	result.document = document
+	result.components = b.components
+	return result, nil
+}
+
+func (s *Server) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.openAPI.document)
+}
@@ -1,31 +1,236 @@
 package server
 
-import "net/http"
+import (
+	"log"
+	"net/http"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/services"
+	"github.com/qeunasd/coniven/utils"
+)
+
+// apiRoute is a JSON endpoint together with the metadata used to describe it
+// in the OpenAPI document.
+type apiRoute struct {
+	pattern  string
+	handler  http.HandlerFunc
+	summary  string
+	request  any // form decoded by decodeAPIBody, nil when there is no body
+	response any // success body, nil for 204 No Content
+	status   int
+	list     bool     // response is wrapped in apiList and accepts pagination params
+	query    []string // required query parameters
+	filters  map[string]utils.AllowedFilter
+	public   bool
+}
 
 func (s *Server) Routes() {
-	s.router.Handle("GET /static/",
+	s.handle("GET /static/",
 		http.StripPrefix("/static/", http.FileServer(http.Dir("./static"))))
 
-	s.router.HandleFunc("GET /category", s.listCategoriesHandler())
-	s.router.HandleFunc("GET /category/add", s.viewAddCategoryHandler())
-	s.router.HandleFunc("POST /category/add", s.addCategoryHandler())
-	s.router.HandleFunc("GET /category/{id}/edit", s.viewEditCategoryHandler())
-	s.router.HandleFunc("PUT /category/{id}/edit", s.editCategoryHandler())
-	s.router.HandleFunc("DELETE /category/{id}/delete", s.deleteCategoryHandler())
-
-	s.router.HandleFunc("GET /location", s.getLocationsHandler())
-	s.router.HandleFunc("GET /location/{slug}", s.viewLocation())
-	s.router.HandleFunc("GET /location/add", s.viewAddLocationHandler())
-	s.router.HandleFunc("POST /location/add", s.addLocationHandler())
-	s.router.HandleFunc("GET /location/{slug}/edit", s.viewEditLocationHandler())
-	s.router.HandleFunc("PUT /location/{slug}/edit", s.editLocationHandler())
-	s.router.HandleFunc("DELETE /location/{id}/delete", s.deleteLocationHandler())
-
-	s.router.HandleFunc("GET /room", s.getRoomsHandler)
-	s.router.HandleFunc("GET /room/{slug}", s.viewRoomHandler)
-	s.router.HandleFunc("GET /room/add", s.viewAddRoomHandler)
-	s.router.HandleFunc("POST /room/add", s.addRoomHandler)
-	s.router.HandleFunc("GET /room/{slug}/edit", s.viewEditRoomHandler)
-	s.router.HandleFunc("PUT /room/{slug}/edit", s.editRoomHandler)
-	s.router.HandleFunc("DELETE /room/{id}/delete", s.deleteRoomHandler)
+	s.handleFunc("GET /login", s.viewLoginHandler)
+	s.handleFunc("POST /login", s.loginHandler)
+	s.handleFunc("POST /logout", s.logoutHandler)
+
+	s.handleFunc("GET /category", s.listCategoriesHandler())
+	s.handleFunc("GET /category/export", s.exportCategoriesHandler)
+	s.handleFunc("GET /category/add", s.viewAddCategoryHandler())
+	s.handleFunc("POST /category/add", s.addCategoryHandler())
+	s.handleFunc("GET /category/{id}/edit", s.viewEditCategoryHandler())
+	s.handleFunc("PUT /category/{id}/edit", s.editCategoryHandler())
+	s.handleFunc("POST /category/{id}/move", s.moveCategoryHandler)
+	s.handleFunc("POST /category/{id}/attributes", s.categoryAttributesHandler)
+	s.handleFunc("DELETE /category/{id}/delete", s.deleteCategoryHandler())
+	s.handleFunc("POST /category/bulk/delete", s.bulkDeleteCategoriesHandler)
+
+	s.handleFunc("GET /location", s.getLocationsHandler())
+	s.handleFunc("GET /location/{slug}", s.viewLocation())
+	s.handleFunc("GET /location/export", s.exportLocationsHandler)
+	s.handleFunc("GET /location/add", s.viewAddLocationHandler())
+	s.handleFunc("POST /location/add", s.addLocationHandler())
+	s.handleFunc("GET /location/{slug}/edit", s.viewEditLocationHandler())
+	s.handleFunc("PUT /location/{slug}/edit", s.editLocationHandler())
+	s.handleFunc("POST /location/{slug}/move", s.moveLocationHandler)
+	s.handleFunc("DELETE /location/{id}/delete", s.deleteLocationHandler())
+	s.handleFunc("POST /location/bulk/delete", s.bulkDeleteLocationsHandler)
+
+	s.handleFunc("GET /room", s.getRoomsHandler)
+	s.handleFunc("GET /room/{slug}", s.viewRoomHandler)
+	s.handleFunc("GET /room/export", s.exportRoomsHandler)
+	s.handleFunc("GET /room/add", s.viewAddRoomHandler)
+	s.handleFunc("POST /room/add", s.addRoomHandler)
+	s.handleFunc("GET /room/{slug}/edit", s.viewEditRoomHandler)
+	s.handleFunc("PUT /room/{slug}/edit", s.editRoomHandler)
+	s.handleFunc("DELETE /room/{id}/delete", s.deleteRoomHandler)
+	s.handleFunc("POST /room/bulk/delete", s.bulkDeleteRoomsHandler)
+	s.handleFunc("POST /room/bulk/move", s.bulkMoveRoomsHandler)
+	s.handleFunc("POST /room/{slug}/transfer", s.transferUnitsHandler)
+	s.handleFunc("POST /room/{slug}/condition", s.bulkUnitConditionHandler)
+	s.handleFunc("GET /room/{slug}/kir", s.roomKIRHandler)
+	s.handleFunc("GET /room/{slug}/label", s.roomLabelsHandler)
+
+	s.handleFunc("GET /item", s.getItemsHandler)
+	s.handleFunc("GET /item/{slug}", s.viewItemHandler)
+	s.handleFunc("GET /item/export", s.exportItemsHandler)
+	s.handleFunc("GET /item/attributes", s.itemAttributesHandler)
+	s.handleFunc("GET /item/add", s.viewAddItemHandler)
+	s.handleFunc("POST /item/add", s.addItemHandler)
+	s.handleFunc("GET /item/{slug}/edit", s.viewEditItemHandler)
+	s.handleFunc("PUT /item/{slug}/edit", s.editItemHandler)
+	s.handleFunc("DELETE /item/{id}/delete", s.deleteItemHandler)
+	s.handleFunc("GET /item/{slug}/unit/add", s.viewAddUnitsHandler)
+	s.handleFunc("POST /item/{slug}/unit/add", s.addUnitsHandler)
+	s.handleFunc("POST /item/{slug}/picture", s.uploadPictureHandler)
+	s.handleFunc("GET /item/{slug}/depreciation", s.itemDepreciationHandler)
+
+	s.handleFunc("GET /picture/{id}", s.viewPictureHandler)
+	s.handleFunc("DELETE /picture/{id}/delete", s.deletePictureHandler)
+
+	s.handleFunc("GET /unit/label", s.unitLabelsHandler)
+	s.handleFunc("GET /unit/{id}", s.viewUnitHandler)
+	s.handleFunc("GET /unit/{id}/label", s.unitLabelsHandler)
+	s.handleFunc("GET /unit/{id}/edit", s.viewEditUnitHandler)
+	s.handleFunc("PUT /unit/{id}/edit", s.editUnitHandler)
+	s.handleFunc("PUT /unit/{id}/condition", s.changeUnitConditionHandler)
+	s.handleFunc("DELETE /unit/{id}/delete", s.deleteUnitHandler)
+	s.handleFunc("GET /unit/{id}/timeline", s.unitTimelineHandler)
+
+	s.handleFunc("GET /report/valuation", s.valuationReportHandler)
+	s.handleFunc("GET /report/repair", s.repairSpendReportHandler)
+	s.handleFunc("GET /report/depreciation", s.depreciationReportHandler)
+
+	s.handleFunc("GET /audit", s.getAuditLogsHandler)
+
+	s.handleFunc("GET /trash", s.getTrashHandler)
+	s.handleFunc("POST /trash/{jenis}/{id}/restore", s.restoreTrashHandler)
+	s.handleFunc("DELETE /trash/{jenis}/{id}/purge", s.purgeTrashHandler)
+
+	s.handleFunc("GET /import", s.viewImportHandler)
+	s.handleFunc("GET /import/template", s.importTemplateHandler)
+	s.handleFunc("POST /import", s.importHandler)
+
+	s.handleFunc("GET /scan", s.scanLookupHandler)
+	s.handleFunc("GET /opname", s.getOpnamesHandler)
+	s.handleFunc("GET /opname/add", s.viewAddOpnameHandler)
+	s.handleFunc("POST /opname/add", s.addOpnameHandler)
+	s.handleFunc("GET /opname/{id}", s.viewOpnameHandler)
+	s.handleFunc("POST /opname/{id}/scan", s.scanOpnameHandler)
+	s.handleFunc("PUT /opname/{id}/unit/{unit}/note", s.saveOpnameNoteHandler)
+	s.handleFunc("POST /opname/{id}/close", s.closeOpnameHandler)
+
+	s.handleFunc("GET /loan", s.getLoansHandler)
+	s.handleFunc("GET /loan/add", s.viewAddLoanHandler)
+	s.handleFunc("POST /loan/add", s.addLoanHandler)
+	s.handleFunc("GET /loan/overdue", s.overdueLoansHandler)
+	s.handleFunc("GET /loan/borrower/{identitas}", s.borrowerHistoryHandler)
+	s.handleFunc("GET /loan/{id}", s.viewLoanHandler)
+	s.handleFunc("POST /loan/{id}/return", s.returnLoanHandler)
+
+	s.handleFunc("GET /repair", s.getRepairsHandler)
+	s.handleFunc("GET /repair/add", s.viewAddRepairHandler)
+	s.handleFunc("POST /repair/add", s.addRepairHandler)
+	s.handleFunc("GET /repair/{id}", s.viewRepairHandler)
+	s.handleFunc("POST /repair/{id}/close", s.closeRepairHandler)
+
+	s.handleFunc("GET /user", s.getUsersHandler)
+	s.handleFunc("GET /user/add", s.viewAddUserHandler)
+	s.handleFunc("POST /user/add", s.addUserHandler)
+	s.handleFunc("GET /user/{id}/edit", s.viewEditUserHandler)
+	s.handleFunc("PUT /user/{id}/edit", s.editUserHandler)
+	s.handleFunc("DELETE /user/{id}/delete", s.deleteUserHandler)
+
+	for _, rt := range s.apiRoutes() {
+		s.handleFunc(rt.pattern, rt.handler)
+	}
+	s.handleFunc("GET /api/openapi.json", s.openAPIHandler)
+
+	doc, err := buildOpenAPI(s.patterns, s.apiRoutes())
+	if err != nil {
+		log.Fatalf("building openapi document: %v", err)
+	}
+	s.openAPI = doc
+}
+
+func (s *Server) handle(pattern string, handler http.Handler) {
+	s.router.Handle(pattern, handler)
+	s.patterns = append(s.patterns, pattern)
+}
+
+func (s *Server) handleFunc(pattern string, handler http.HandlerFunc) {
+	s.handle(pattern, handler)
+}
+
+func (s *Server) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{pattern: "POST /api/v1/login", handler: s.apiLoginHandler, summary: "Log in and obtain a bearer token",
+			request: entities.LoginForm{}, response: apiToken{}, status: http.StatusOK, public: true},
+		{pattern: "POST /api/v1/logout", handler: s.apiLogoutHandler, summary: "Revoke the current token",
+			status: http.StatusNoContent},
+
+		{pattern: "GET /api/v1/categories", handler: s.apiListCategoriesHandler, summary: "List categories",
+			response: entities.Category{}, status: http.StatusOK, list: true, filters: services.CategoryFilters},
+		{pattern: "POST /api/v1/categories", handler: s.apiCreateCategoryHandler, summary: "Create a category",
+			request: entities.CategoryForm{}, response: entities.Category{}, status: http.StatusCreated},
+		{pattern: "GET /api/v1/categories/{id}", handler: s.apiGetCategoryHandler, summary: "Get a category",
+			response: entities.Category{}, status: http.StatusOK},
+		{pattern: "PUT /api/v1/categories/{id}", handler: s.apiEditCategoryHandler, summary: "Update a category",
+			request: entities.CategoryForm{}, response: entities.Category{}, status: http.StatusOK},
+		{pattern: "PUT /api/v1/categories/{id}/parent", handler: s.apiMoveCategoryHandler, summary: "Move a category with everything under it",
+			request: entities.CategoryMoveForm{}, response: entities.Category{}, status: http.StatusOK},
+		{pattern: "PUT /api/v1/categories/{id}/attributes", handler: s.apiCategoryAttributesHandler, summary: "Replace the attribute fields a category declares",
+			request: entities.CategoryAttributeForm{}, response: entities.Category{}, status: http.StatusOK},
+		{pattern: "DELETE /api/v1/categories/{id}", handler: s.apiDeleteCategoryHandler, summary: "Delete a category",
+			status: http.StatusNoContent},
+
+		{pattern: "GET /api/v1/locations", handler: s.apiListLocationsHandler, summary: "List locations",
+			response: entities.Location{}, status: http.StatusOK, list: true, filters: services.LocationFilters},
+		{pattern: "POST /api/v1/locations", handler: s.apiCreateLocationHandler, summary: "Create a location",
+			request: entities.LocationForm{}, response: entities.Location{}, status: http.StatusCreated},
+		{pattern: "GET /api/v1/locations/{slug}", handler: s.apiGetLocationHandler, summary: "Get a location with its rooms",
+			response: entities.Location{}, status: http.StatusOK},
+		{pattern: "PUT /api/v1/locations/{slug}", handler: s.apiEditLocationHandler, summary: "Update a location",
+			request: entities.LocationForm{}, status: http.StatusNoContent},
+		{pattern: "PUT /api/v1/locations/{slug}/parent", handler: s.apiMoveLocationHandler, summary: "Move a location with everything under it",
+			request: entities.LocationMoveForm{}, status: http.StatusNoContent},
+		{pattern: "DELETE /api/v1/locations/{slug}", handler: s.apiDeleteLocationHandler, summary: "Delete a location",
+			status: http.StatusNoContent},
+
+		{pattern: "GET /api/v1/rooms", handler: s.apiListRoomsHandler, summary: "List rooms",
+			response: entities.Room{}, status: http.StatusOK, list: true, filters: services.RoomFilters},
+		{pattern: "POST /api/v1/rooms", handler: s.apiCreateRoomHandler, summary: "Create a room",
+			request: entities.RoomForm{}, response: entities.Room{}, status: http.StatusCreated},
+		{pattern: "GET /api/v1/rooms/{slug}", handler: s.apiGetRoomHandler, summary: "Get a room with its units",
+			response: entities.Room{}, status: http.StatusOK},
+		{pattern: "PUT /api/v1/rooms/{slug}", handler: s.apiEditRoomHandler, summary: "Update a room",
+			request: entities.RoomForm{}, status: http.StatusNoContent},
+		{pattern: "DELETE /api/v1/rooms/{slug}", handler: s.apiDeleteRoomHandler, summary: "Delete a room",
+			status: http.StatusNoContent},
+
+		{pattern: "GET /api/v1/items", handler: s.apiListItemsHandler, summary: "List items",
+			response: entities.Item{}, status: http.StatusOK, list: true, filters: services.ItemFilters},
+		{pattern: "POST /api/v1/items", handler: s.apiCreateItemHandler, summary: "Create an item",
+			request: entities.ItemForm{}, response: entities.Item{}, status: http.StatusCreated},
+		{pattern: "GET /api/v1/items/{slug}", handler: s.apiGetItemHandler, summary: "Get an item",
+			response: entities.Item{}, status: http.StatusOK},
+		{pattern: "PUT /api/v1/items/{slug}", handler: s.apiEditItemHandler, summary: "Update an item",
+			request: entities.ItemForm{}, status: http.StatusNoContent},
+		{pattern: "DELETE /api/v1/items/{slug}", handler: s.apiDeleteItemHandler, summary: "Delete an item",
+			status: http.StatusNoContent},
+		{pattern: "GET /api/v1/items/{slug}/units", handler: s.apiListItemUnitsHandler, summary: "List the units of an item",
+			response: entities.ItemUnit{}, status: http.StatusOK, list: true},
+		{pattern: "POST /api/v1/items/{slug}/units", handler: s.apiRegisterUnitsHandler, summary: "Register units of an item",
+			request: entities.UnitForm{}, response: []entities.ItemUnit{}, status: http.StatusCreated},
+
+		{pattern: "GET /api/v1/units/{id}", handler: s.apiGetUnitHandler, summary: "Get a unit",
+			response: entities.ItemUnit{}, status: http.StatusOK},
+		{pattern: "PUT /api/v1/units/{id}", handler: s.apiEditUnitHandler, summary: "Change the serial number of a unit",
+			request: unitSerialForm{}, response: entities.ItemUnit{}, status: http.StatusOK},
+		{pattern: "PUT /api/v1/units/{id}/condition", handler: s.apiChangeUnitConditionHandler, summary: "Change the condition of a unit",
+			request: entities.UnitConditionForm{}, response: entities.ItemUnit{}, status: http.StatusOK},
+		{pattern: "DELETE /api/v1/units/{id}", handler: s.apiDeleteUnitHandler, summary: "Delete a unit",
+			status: http.StatusNoContent},
+
+		{pattern: "GET /api/v1/scan", handler: s.apiScanHandler, summary: "Find the units matching a scanned label or serial number",
+			response: []entities.ItemUnit{}, status: http.StatusOK, query: []string{"kode"}},
+	}
 }
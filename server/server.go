@@ -3,6 +3,7 @@ package server
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
@@ -16,33 +17,90 @@ import (
 type contextKey struct{ name string }
 
 type Server struct {
-	router          *http.ServeMux
-	template        *template.Template
-	categoryService services.CategoryService
-	locationService services.LocationService
-	roomService     services.RoomService
-	itemService     services.ItemService
+	router              *http.ServeMux
+	template            *template.Template
+	categoryService     services.CategoryService
+	locationService     services.LocationService
+	roomService         services.RoomService
+	itemService         services.ItemService
+	unitService         services.UnitService
+	pictureService      services.PictureService
+	transferService     services.TransferService
+	reportService       services.ReportService
+	depreciationService services.DepreciationService
+	authService         services.AuthService
+	userService         services.UserService
+	auditService        services.AuditService
+	opnameService       services.OpnameService
+	importService       services.ImportService
+	exportService       services.ExportService
+	bulkService         services.BulkService
+	trashService        services.TrashService
+	loanService         services.LoanService
+	repairService       services.RepairService
+	patterns            []string
+	openAPI             *openAPI
+
+	// publicBaseURL is the origin printed in label QR codes. When it is empty
+	// the labels carry only the unit id, which the scan lookup resolves just
+	// the same.
+	publicBaseURL string
 }
 
 var (
 	htmxKey     = contextKey{"htmx"}
 	formDecoder = form.NewDecoder()
+	maxUnitRows = 50
+
+	maxPicturesPerUpload int64 = 10
 )
 
 func NewServer(
 	template *template.Template,
+	publicBaseURL string,
 	categoryService services.CategoryService,
 	locationService services.LocationService,
 	roomService services.RoomService,
 	itemService services.ItemService,
+	unitService services.UnitService,
+	pictureService services.PictureService,
+	transferService services.TransferService,
+	reportService services.ReportService,
+	depreciationService services.DepreciationService,
+	authService services.AuthService,
+	userService services.UserService,
+	auditService services.AuditService,
+	opnameService services.OpnameService,
+	importService services.ImportService,
+	exportService services.ExportService,
+	bulkService services.BulkService,
+	trashService services.TrashService,
+	loanService services.LoanService,
+	repairService services.RepairService,
 ) *Server {
 	return &Server{
-		router:          http.NewServeMux(),
-		template:        template,
-		categoryService: categoryService,
-		locationService: locationService,
-		roomService:     roomService,
-		itemService:     itemService,
+		router:              http.NewServeMux(),
+		template:            template,
+		publicBaseURL:       publicBaseURL,
+		categoryService:     categoryService,
+		locationService:     locationService,
+		roomService:         roomService,
+		itemService:         itemService,
+		unitService:         unitService,
+		pictureService:      pictureService,
+		transferService:     transferService,
+		reportService:       reportService,
+		depreciationService: depreciationService,
+		authService:         authService,
+		userService:         userService,
+		auditService:        auditService,
+		opnameService:       opnameService,
+		importService:       importService,
+		exportService:       exportService,
+		bulkService:         bulkService,
+		trashService:        trashService,
+		loanService:         loanService,
+		repairService:       repairService,
 	}
 }
 
@@ -50,13 +108,17 @@ func (s *Server) Run() error {
 	s.Routes()
 
 	server := http.Server{
-		Addr: ":8080", Handler: withHTMX(s.router),
+		Addr: ":8080", Handler: withHTMX(s.withContractCheck(s.withAuth(s.router))),
 	}
 
 	return server.ListenAndServe()
 }
 
 func (s *Server) RenderHTML(w http.ResponseWriter, tmpl string, data any) {
+	s.renderHTMLStatus(w, http.StatusOK, tmpl, data)
+}
+
+func (s *Server) renderHTMLStatus(w http.ResponseWriter, status int, tmpl string, data any) {
 	buf := new(bytes.Buffer)
 
 	if err := s.template.ExecuteTemplate(buf, tmpl, data); err != nil {
@@ -66,26 +128,79 @@ func (s *Server) RenderHTML(w http.ResponseWriter, tmpl string, data any) {
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
 	buf.WriteTo(w)
 }
 
+// handleWebError renders a WebError next to the field it belongs to, inside
+// the partial of the form that was sent. Any other error goes to handleError.
 func (s *Server) handleWebError(w http.ResponseWriter, r *http.Request, err error, partial string, formData map[string]any) {
-	webError := make(map[string]string)
-
-	if val, ok := err.(utils.WebError); ok {
-		webError[val.Field] = val.Message
-		formData["Errors"] = webError
-	} else {
-		log.Printf("error processing form: %s", err)
-		http.Error(w, "An unexpected error occurred", http.StatusInternalServerError)
+	var val utils.WebError
+	if !errors.As(err, &val) || !r.Context().Value(htmxKey).(bool) {
+		s.handleError(w, r, err)
 		return
 	}
 
+	formData["Errors"] = map[string]string{val.Field: val.Message}
+	s.renderHTMLStatus(w, errorStatus(err), partial, formData)
+}
+
+// errorStatus maps an error from the services to the status it is answered
+// with.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, utils.ErrNotFound), errors.Is(err, utils.ErrInvalidId):
+		return http.StatusNotFound
+	case errors.Is(err, utils.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, utils.ErrValidation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func errorMessage(err error, status int) string {
+	var webErr utils.WebError
+	if errors.As(err, &webErr) {
+		return webErr.Message
+	}
+
+	switch status {
+	case http.StatusNotFound:
+		return "data tidak ditemukan"
+	case http.StatusConflict:
+		return "data sudah berubah, muat ulang halaman lalu coba lagi"
+	default:
+		return "terjadi kesalahan pada server, coba lagi nanti"
+	}
+}
+
+// handleError answers a failed request with the status its error maps to. A
+// full page gets the error page; an HTMX request gets the message swapped
+// into the #flash area of the layout, whatever its own target was. Errors
+// that map to 500 are logged.
+func (s *Server) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Printf("error handling %s %s: %s", r.Method, r.URL.Path, err)
+	}
+
+	data := map[string]any{
+		"Title":   http.StatusText(status),
+		"Status":  status,
+		"Message": errorMessage(err, status),
+	}
+
 	if r.Context().Value(htmxKey).(bool) {
-		s.RenderHTML(w, partial, formData)
-	} else {
-		http.Error(w, "Validation failed", http.StatusBadRequest)
+		w.Header().Set("HX-Retarget", "#flash")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		s.renderHTMLStatus(w, status, "partials/error-partial.tmpl", data)
+		return
 	}
+
+	data["Page"] = "pages/error.tmpl"
+	s.renderHTMLStatus(w, status, "layout.tmpl", data)
 }
 
 func buildTemplateData(r *http.Request, res utils.PaginationResult, params utils.PaginationParams, data ...any) map[string]any {
@@ -104,8 +219,8 @@ func buildTemplateData(r *http.Request, res utils.PaginationResult, params utils
 			"Query":       params.Query,
 			"SortBy":      params.SortBy,
 			"SortDir":     params.SortDir,
-			"Filters":     utils.FiltersToMap(params.Filters),
-			"QueryString": queryParams.Encode(),
+			"Filters":     utils.FiltersToMap(params.FilterValues),
+			"QueryString": template.URL(queryParams.Encode()),
 		},
 	}
 }
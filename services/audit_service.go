@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+var auditTableConfig = utils.TableConfig{
+	QueryCols: []string{"aktor", "id_entitas"},
+	SortCols: []utils.AllowedSort{
+		{Name: "dt", Column: "tgl_dibuat"},
+		{Name: "aktor", Column: "aktor"},
+		{Name: "ent", Column: "jenis_entitas"},
+		{Name: "aksi", Column: "aksi"},
+	},
+	DefaultSort: "dt",
+	Filters: map[string]utils.AllowedFilter{
+		"dmin": {Column: "audit_log.tgl_dibuat", Operator: "gte"},
+		"dmax": {Column: "audit_log.tgl_dibuat", Operator: "lte"},
+		"ent":  {Column: "audit_log.jenis_entitas", Operator: "eq"},
+		"aksi": {Column: "audit_log.aksi", Operator: "in"},
+		"eid":  {Column: "audit_log.id_entitas", Operator: "eq"},
+	},
+}
+
+// recordAudit appends an audit entry for a mutation through the recorder its
+// storage call hands out, inside the same transaction. before and after are
+// snapshots of the entity; pass nil for the side that does not exist.
+func recordAudit(ctx context.Context, rec storage.AuditRecorder, jenis string, id any, aksi entities.AuditAction, before, after any) error {
+	entry, err := entities.NewAuditLog(ctx, jenis, id, aksi, before, after)
+	if err != nil {
+		return err
+	}
+
+	if err := rec.CreateAuditLog(ctx, entry); err != nil {
+		return fmt.Errorf("recording audit of %s %v: %w", jenis, id, err)
+	}
+
+	return nil
+}
+
+type AuditService interface {
+	GetAuditLogsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error)
+}
+
+type auditService struct {
+	storage storage.AuditRepository
+}
+
+func NewAuditService(storage storage.AuditRepository) AuditService {
+	return &auditService{storage: storage}
+}
+
+func (s *auditService) GetAuditLogsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
+	params.SetColumnSearch(auditTableConfig.QueryCols...)
+	params.SetFilters(auditTableConfig.Filters)
+	where, args := utils.BuildWhereClauses(params)
+
+	total, err := s.storage.CountAuditLogs(ctx, where, args)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("counting audit logs: %w", err)
+	}
+
+	totalPage := (total + params.PerPage - 1) / params.PerPage
+	if params.Page > totalPage && totalPage > 0 {
+		params.Page = totalPage
+	}
+
+	sort := utils.BuildSortClause(params, auditTableConfig)
+	limit := utils.BuildLimitClause(params)
+
+	entries, err := s.storage.GetAuditLogs(ctx, limit, sort, where, args)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("getting audit logs: %w", err)
+	}
+
+	return utils.PaginationResult{
+		Data:      entries,
+		TotalData: int64(total),
+		Page:      params.Page,
+		PerPage:   params.PerPage,
+		TotalPage: totalPage,
+	}, nil
+}
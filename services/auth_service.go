@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const SessionDuration = 12 * time.Hour
+
+// dummyHash is compared against when the username does not exist so a failed
+// login takes the same time whether or not the account is there.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte(rand.Text()), bcrypt.DefaultCost)
+	return hash
+})
+
+type AuthService interface {
+	Login(ctx context.Context, req entities.LoginForm) (string, time.Time, error)
+	Logout(ctx context.Context, token string) error
+	Authenticate(ctx context.Context, token string) (entities.User, error)
+}
+
+type authService struct {
+	storage storage.UserRepository
+}
+
+func NewAuthService(storage storage.UserRepository) AuthService {
+	return &authService{storage: storage}
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hashing password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *authService) Login(ctx context.Context, req entities.LoginForm) (string, time.Time, error) {
+	invalid := utils.WebError{Field: "Login", Message: "username atau password salah"}
+
+	username := strings.ToLower(strings.TrimSpace(req.Username))
+	if username == "" || req.Password == "" {
+		return "", time.Time{}, invalid
+	}
+
+	user, err := s.storage.GetUserByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			bcrypt.CompareHashAndPassword(dummyHash(), []byte(req.Password))
+			return "", time.Time{}, invalid
+		}
+		return "", time.Time{}, fmt.Errorf("getting user by username: %w", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return "", time.Time{}, invalid
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, fmt.Errorf("generating session token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now()
+	session := entities.Session{
+		Id:          hashToken(token),
+		IdPengguna:  user.Id,
+		Kedaluwarsa: now.Add(SessionDuration),
+		TglDibuat:   now,
+	}
+
+	if err := s.storage.CreateSession(ctx, session); err != nil {
+		return "", time.Time{}, fmt.Errorf("saving session: %w", err)
+	}
+
+	if removed, err := s.storage.DeleteExpiredSessions(ctx, now); err != nil {
+		log.Printf("removing expired sessions: %v", err)
+	} else if removed > 0 {
+		log.Printf("removed %d expired sessions", removed)
+	}
+
+	return token, session.Kedaluwarsa, nil
+}
+
+func (s *authService) Logout(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return s.storage.DeleteSession(ctx, hashToken(token))
+}
+
+func (s *authService) Authenticate(ctx context.Context, token string) (entities.User, error) {
+	if token == "" {
+		return entities.User{}, utils.ErrNotFound
+	}
+	return s.storage.GetSessionUser(ctx, hashToken(token), time.Now())
+}
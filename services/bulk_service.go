@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+// BulkService acts on the rows checked in a list. Each action runs in one
+// transaction, so either every selected record changes or none does.
+type BulkService interface {
+	SummarizeCategoryDelete(ctx context.Context, ids []string) (entities.DeleteSummary, error)
+	SummarizeLocationDelete(ctx context.Context, ids []string) (entities.DeleteSummary, error)
+	SummarizeRoomDelete(ctx context.Context, ids []string) (entities.DeleteSummary, error)
+	DeleteCategories(ctx context.Context, ids []string) error
+	DeleteLocations(ctx context.Context, ids []string) error
+	DeleteRooms(ctx context.Context, ids []string) error
+	MoveRooms(ctx context.Context, req entities.BulkMoveForm) error
+	ChangeUnitConditions(ctx context.Context, req entities.BulkConditionForm) error
+}
+
+type bulkService struct {
+	storage storage.BulkRepository
+}
+
+func NewBulkService(storage storage.BulkRepository) BulkService {
+	return &bulkService{storage: storage}
+}
+
+var errBulkStale = utils.WebError{Field: "Ids", Message: "sebagian data sudah tidak ada, muat ulang halaman", Conflict: true}
+
+func (s *bulkService) SummarizeCategoryDelete(ctx context.Context, ids []string) (entities.DeleteSummary, error) {
+	resIds, err := entities.ParseBulkInts("Ids", ids)
+	if err != nil {
+		return entities.DeleteSummary{}, err
+	}
+
+	summary, err := s.storage.SummarizeCategoryDelete(ctx, resIds)
+	if err != nil {
+		return summary, fmt.Errorf("summarizing category delete: %w", err)
+	}
+	if len(summary.Nama) != len(resIds) {
+		return summary, errBulkStale
+	}
+
+	return summary, nil
+}
+
+func (s *bulkService) SummarizeLocationDelete(ctx context.Context, ids []string) (entities.DeleteSummary, error) {
+	resIds, err := entities.ParseBulkUUIDs("Ids", ids)
+	if err != nil {
+		return entities.DeleteSummary{}, err
+	}
+
+	summary, err := s.storage.SummarizeLocationDelete(ctx, resIds)
+	if err != nil {
+		return summary, fmt.Errorf("summarizing location delete: %w", err)
+	}
+	if len(summary.Nama) != len(resIds) {
+		return summary, errBulkStale
+	}
+
+	return summary, nil
+}
+
+func (s *bulkService) SummarizeRoomDelete(ctx context.Context, ids []string) (entities.DeleteSummary, error) {
+	resIds, err := entities.ParseBulkUUIDs("Ids", ids)
+	if err != nil {
+		return entities.DeleteSummary{}, err
+	}
+
+	summary, err := s.storage.SummarizeRoomDelete(ctx, resIds)
+	if err != nil {
+		return summary, fmt.Errorf("summarizing room delete: %w", err)
+	}
+	if len(summary.Nama) != len(resIds) {
+		return summary, errBulkStale
+	}
+
+	return summary, nil
+}
+
+func (s *bulkService) DeleteCategories(ctx context.Context, ids []string) error {
+	resIds, err := entities.ParseBulkInts("Ids", ids)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.DeleteCategories(ctx, resIds, func(rec storage.AuditRecorder, deleted []entities.Category) error {
+		for _, c := range deleted {
+			if err := recordAudit(ctx, rec, entities.AuditKategori, c.Id, entities.AuditDelete, c, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			return errBulkStale
+		}
+		return fmt.Errorf("deleting categories: %w", err)
+	}
+
+	return nil
+}
+
+func (s *bulkService) DeleteLocations(ctx context.Context, ids []string) error {
+	resIds, err := entities.ParseBulkUUIDs("Ids", ids)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.DeleteLocations(ctx, resIds, func(rec storage.AuditRecorder, deleted []entities.Location) error {
+		for _, l := range deleted {
+			if err := recordAudit(ctx, rec, entities.AuditLokasi, l.Id, entities.AuditDelete, l, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			return errBulkStale
+		}
+		return fmt.Errorf("deleting locations: %w", err)
+	}
+
+	return nil
+}
+
+func (s *bulkService) DeleteRooms(ctx context.Context, ids []string) error {
+	resIds, err := entities.ParseBulkUUIDs("Ids", ids)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.DeleteRooms(ctx, resIds, func(rec storage.AuditRecorder, deleted []entities.Room) error {
+		for _, r := range deleted {
+			if err := recordAudit(ctx, rec, entities.AuditRuangan, r.Id, entities.AuditDelete, r, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			return errBulkStale
+		}
+		return fmt.Errorf("deleting rooms: %w", err)
+	}
+
+	return nil
+}
+
+func (s *bulkService) MoveRooms(ctx context.Context, req entities.BulkMoveForm) error {
+	resIds, err := entities.ParseBulkUUIDs("Ids", req.Ids)
+	if err != nil {
+		return err
+	}
+
+	idLokasi, err := uuid.Parse(req.Lokasi)
+	if err != nil {
+		return utils.WebError{Field: "Lokasi", Message: "pilih lokasi tujuan"}
+	}
+
+	var before []entities.Room
+	var moved entities.Location
+	err = s.storage.MoveRooms(ctx, resIds, idLokasi, func(rooms []entities.Room, dest entities.Location) error {
+		if len(rooms) != len(resIds) {
+			return errBulkStale
+		}
+
+		for _, r := range rooms {
+			if r.LokasiId == dest.Id {
+				return utils.WebError{Field: "Lokasi", Message: fmt.Sprintf("ruangan %s sudah berada di %s", r.Nama, dest.Nama)}
+			}
+		}
+
+		before, moved = rooms, dest
+		return nil
+	}, func(rec storage.AuditRecorder) error {
+		for _, r := range before {
+			after := r
+			after.LokasiId = moved.Id
+			after.Lokasi = moved
+			if err := recordAudit(ctx, rec, entities.AuditRuangan, r.Id, entities.AuditUpdate, r, after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		if errors.Is(err, utils.ErrNotFound) {
+			return utils.WebError{Field: "Lokasi", Message: "lokasi tujuan tidak ditemukan"}
+		}
+		return fmt.Errorf("moving rooms: %w", err)
+	}
+
+	return nil
+}
+
+func (s *bulkService) ChangeUnitConditions(ctx context.Context, req entities.BulkConditionForm) error {
+	resIds, err := entities.ParseBulkUUIDs("Units", req.Units)
+	if err != nil {
+		return err
+	}
+
+	next := entities.KondisiUnit(req.Kondisi)
+	if !next.IsValid() {
+		return utils.WebError{Field: "Kondisi", Message: "pilih kondisi tujuan"}
+	}
+	if next == entities.KondisiPerbaikan {
+		return utils.WebError{Field: "Kondisi", Message: "kondisi diperbaiki diatur melalui tiket perbaikan"}
+	}
+
+	var before []entities.ItemUnit
+	err = s.storage.ChangeUnitConditions(ctx, resIds, next, func(units []entities.ItemUnit, to entities.KondisiUnit) error {
+		if len(units) != len(resIds) {
+			return utils.WebError{Field: "Units", Message: "sebagian unit tidak ditemukan, muat ulang halaman", Conflict: true}
+		}
+
+		for _, u := range units {
+			if u.Kondisi == entities.KondisiPerbaikan {
+				return utils.WebError{Field: "Kondisi", Message: fmt.Sprintf("unit %s sedang diperbaiki, tutup tiket perbaikannya terlebih dahulu", u.NoSeri)}
+			}
+			if !u.Kondisi.CanSetManually(to) {
+				return utils.WebError{Field: "Kondisi", Message: fmt.Sprintf("kondisi unit %s (%s) tidak dapat diubah menjadi %s", u.NoSeri, u.Kondisi, to)}
+			}
+		}
+
+		before = units
+		return nil
+	}, func(rec storage.AuditRecorder) error {
+		for _, u := range before {
+			after := u
+			after.Kondisi = next
+			if err := recordAudit(ctx, rec, entities.AuditUnit, u.Id, entities.AuditUpdate, u, after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		return fmt.Errorf("changing unit conditions: %w", err)
+	}
+
+	return nil
+}
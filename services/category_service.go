@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,8 +15,11 @@ import (
 )
 
 type CategoryService interface {
-	AddNewCategory(ctx context.Context, name, code string) error
+	GetCategoriesForUI(ctx context.Context) ([]entities.Category, error)
+	AddNewCategory(ctx context.Context, name, code, parent string) (entities.Category, error)
 	EditCategory(ctx context.Context, id, name, code string) error
+	MoveCategory(ctx context.Context, id, parent string) error
+	SetCategoryAttributes(ctx context.Context, id string, rows []entities.AttributeFieldForm) error
 	ListCategoriesWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error)
 	GetTotalCategories(ctx context.Context) (int, error)
 	DeleteCategory(ctx context.Context, id string) error
@@ -26,6 +30,12 @@ type categoryService struct {
 	storage storage.CategoryRepository
 }
 
+// CategoryFilters are the filter query parameters the category list accepts.
+var CategoryFilters = map[string]utils.AllowedFilter{
+	"dmin": {Column: "kategori.tgl_dibuat", Operator: "gte"},
+	"dmax": {Column: "kategori.tgl_dibuat", Operator: "lte"},
+}
+
 var categoryTableConfig = utils.TableConfig{
 	QueryCols: []string{"nama", "kode"},
 	SortCols: []utils.AllowedSort{
@@ -34,14 +44,104 @@ var categoryTableConfig = utils.TableConfig{
 		{Name: "dt", Column: "tgl_dibuat"},
 	},
 	DefaultSort: "dt",
+	DeletedCol:  "tgl_dihapus",
+	Filters:     CategoryFilters,
 }
 
+var errCategoryParentNotFound = utils.WebError{Field: "Induk", Message: "kategori induk tidak ditemukan"}
+
 func NewCategoryService(storage storage.CategoryRepository) CategoryService {
 	return &categoryService{storage: storage}
 }
 
+// GetCategoriesForUI lists every live category with its ancestors, ordered by
+// the full name so each category follows its parent in a picker.
+func (c *categoryService) GetCategoriesForUI(ctx context.Context) ([]entities.Category, error) {
+	where, args := liveWhere(categoryTableConfig)
+	categories, err := c.storage.GetCategoriesWithFilter(ctx, "", "", where, args)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[int]entities.Category, len(categories))
+	for _, cat := range categories {
+		byId[cat.Id] = cat
+	}
+	for i := range categories {
+		categories[i].Jalur = entities.CategoryPath(byId, categories[i].Id)
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].NamaLengkap() < categories[j].NamaLengkap()
+	})
+
+	return categories, nil
+}
+
+func (c *categoryService) fillPaths(ctx context.Context, categories []entities.Category) error {
+	if len(categories) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(categories))
+	for i, cat := range categories {
+		ids[i] = cat.Id
+	}
+
+	paths, err := c.storage.GetCategoryPaths(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range categories {
+		categories[i].Jalur = paths[categories[i].Id]
+	}
+
+	return nil
+}
+
+// parseCategoryParent resolves the parent picked on a category form; an
+// empty value is the top level.
+func parseCategoryParent(parent string) (*int, error) {
+	parent = strings.TrimSpace(parent)
+	if parent == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.Atoi(parent)
+	if err != nil || id <= 0 {
+		return nil, errCategoryParentNotFound
+	}
+
+	return &id, nil
+}
+
+// attributeClash rejects a field of lower that reuses the key of a field in
+// upper. Items of a category fill in the fields of all its ancestors, so a
+// key may be declared only once along any branch.
+func attributeClash(upper, lower []entities.Category) error {
+	owner := make(map[string]string)
+	for _, cat := range upper {
+		for _, f := range cat.Atribut {
+			owner[f.Kunci] = cat.Nama
+		}
+	}
+
+	for _, cat := range lower {
+		for _, f := range cat.Atribut {
+			if nama, ok := owner[f.Kunci]; ok {
+				return utils.WebError{Field: "Atribut", Message: fmt.Sprintf("kunci %s milik %s sudah dipakai oleh %s", f.Kunci, cat.Nama, nama), Conflict: true}
+			}
+		}
+	}
+
+	return nil
+}
+
 func (c *categoryService) ListCategoriesWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
 	params.SetColumnSearch(categoryTableConfig.QueryCols...)
+	params.SetFilters(categoryTableConfig.Filters)
+	params.ExcludeDeleted(categoryTableConfig.DeletedCol)
 	where, args := utils.BuildWhereClauses(params)
 
 	total, err := c.storage.CountCategories(ctx, where, args)
@@ -62,6 +162,10 @@ func (c *categoryService) ListCategoriesWithFilter(ctx context.Context, params u
 		return utils.PaginationResult{}, fmt.Errorf("getting categories: %w", err)
 	}
 
+	if err := c.fillPaths(ctx, categories); err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("getting category paths: %w", err)
+	}
+
 	return utils.PaginationResult{
 		Data:      categories,
 		TotalData: int64(total),
@@ -72,62 +176,89 @@ func (c *categoryService) ListCategoriesWithFilter(ctx context.Context, params u
 }
 
 func (c *categoryService) GetTotalCategories(ctx context.Context) (int, error) {
-	return c.storage.CountCategories(ctx, "", nil)
+	where, args := liveWhere(categoryTableConfig)
+	return c.storage.CountCategories(ctx, where, args)
 }
 
-func (c *categoryService) AddNewCategory(ctx context.Context, name, code string) error {
+func (c *categoryService) AddNewCategory(ctx context.Context, name, code, parent string) (entities.Category, error) {
 	category := entities.NewCategory(code, name)
 
 	if err := category.Validate(); err != nil {
-		return err
+		return entities.Category{}, err
+	}
+
+	idInduk, err := parseCategoryParent(parent)
+	if err != nil {
+		return entities.Category{}, err
+	}
+
+	if idInduk != nil {
+		if _, err := c.storage.GetCategoryById(ctx, *idInduk); err != nil {
+			if errors.Is(err, utils.ErrNotFound) {
+				return entities.Category{}, errCategoryParentNotFound
+			}
+			return entities.Category{}, fmt.Errorf("getting parent category: %w", err)
+		}
+		category.IdInduk = idInduk
 	}
 
 	existKode, err := c.storage.FindCategoryByCode(ctx, category.Kode)
 	if err != nil {
-		return fmt.Errorf("(msg): finding category by code (err): %w", err)
+		return entities.Category{}, fmt.Errorf("(msg): finding category by code (err): %w", err)
 	}
 
 	if existKode {
-		return utils.WebError{Field: "Kode", Message: "kode sudah terpakai"}
+		return entities.Category{}, utils.WebError{Field: "Kode", Message: "kode sudah terpakai", Conflict: true}
 	}
 
 	existNama, err := c.storage.FindCategoryByName(ctx, name)
 	if err != nil {
-		return fmt.Errorf("(msg): finding category by name (err): %w", err)
+		return entities.Category{}, fmt.Errorf("(msg): finding category by name (err): %w", err)
 	}
 
 	if existNama {
-		return utils.WebError{Field: "Nama", Message: "nama sudah terpakai"}
+		return entities.Category{}, utils.WebError{Field: "Nama", Message: "nama sudah terpakai", Conflict: true}
 	}
 
-	if err := c.storage.SaveCategory(ctx, *category); err != nil {
-		return fmt.Errorf("(msg): saving category (err): %w", err)
+	category.Id, err = c.storage.SaveCategory(ctx, *category, func(rec storage.AuditRecorder, id int) error {
+		saved := *category
+		saved.Id = id
+		return recordAudit(ctx, rec, entities.AuditKategori, id, entities.AuditCreate, nil, saved)
+	})
+	if errors.Is(err, storage.ErrDuplicate) {
+		return entities.Category{}, utils.WebError{Field: "Kode", Message: "kode sudah terpakai", Conflict: true}
+	}
+	if err != nil {
+		return entities.Category{}, fmt.Errorf("(msg): saving category (err): %w", err)
 	}
 
-	return nil
+	return *category, nil
 }
 
 func (c *categoryService) GetCategoryById(ctx context.Context, id string) (entities.Category, error) {
 	Id, err := strconv.Atoi(id)
 	if err != nil {
-		return entities.Category{}, errors.New("invalid id")
+		return entities.Category{}, utils.ErrInvalidId
 	}
 
 	category, err := c.storage.GetCategoryById(ctx, Id)
 	if err != nil {
-		if err.Error() == "not found" {
-			return entities.Category{}, err
-		}
 		return entities.Category{}, fmt.Errorf("getting category by id: %w", err)
 	}
 
+	paths, err := c.storage.GetCategoryPaths(ctx, []int{category.Id})
+	if err != nil {
+		return entities.Category{}, fmt.Errorf("getting category path: %w", err)
+	}
+	category.Jalur = paths[category.Id]
+
 	return category, nil
 }
 
 func (c *categoryService) EditCategory(ctx context.Context, id, name, code string) error {
 	Id, err := strconv.Atoi(id)
 	if err != nil || Id <= 0 {
-		return errors.New("invalid id")
+		return utils.ErrInvalidId
 	}
 
 	name = strings.TrimSpace(name)
@@ -139,11 +270,9 @@ func (c *categoryService) EditCategory(ctx context.Context, id, name, code strin
 
 	category, err := c.storage.GetCategoryById(ctx, Id)
 	if err != nil {
-		if err.Error() == "not found" {
-			return err
-		}
 		return fmt.Errorf("(msg): getting category by id (err): %w", err)
 	}
+	before := category
 
 	if code != "" && code != category.Kode {
 		exist, err := c.storage.FindCategoryByCode(ctx, code)
@@ -152,7 +281,7 @@ func (c *categoryService) EditCategory(ctx context.Context, id, name, code strin
 		}
 
 		if exist {
-			return utils.WebError{Field: "Kode", Message: "kode sudah terpakai"}
+			return utils.WebError{Field: "Kode", Message: "kode sudah terpakai", Conflict: true}
 		}
 
 		category.Kode = code
@@ -165,14 +294,20 @@ func (c *categoryService) EditCategory(ctx context.Context, id, name, code strin
 		}
 
 		if exist {
-			return utils.WebError{Field: "Nama", Message: "nama sudah terpakai"}
+			return utils.WebError{Field: "Nama", Message: "nama sudah terpakai", Conflict: true}
 		}
 
 		category.Nama = name
 	}
 	category.TglUpdate = time.Now()
 
-	if err := c.storage.UpdateCategory(ctx, category); err != nil {
+	err = c.storage.UpdateCategory(ctx, category, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditKategori, category.Id, entities.AuditUpdate, before, category)
+	})
+	if errors.Is(err, storage.ErrDuplicate) {
+		return utils.WebError{Field: "Kode", Message: "kode sudah terpakai", Conflict: true}
+	}
+	if err != nil {
 		return fmt.Errorf("(msg): updating category with id %d (err): %w", Id, err)
 	}
 
@@ -182,21 +317,100 @@ func (c *categoryService) EditCategory(ctx context.Context, id, name, code strin
 func (c *categoryService) DeleteCategory(ctx context.Context, id string) error {
 	Id, err := strconv.Atoi(id)
 	if err != nil || Id <= 0 {
-		return errors.New("invalid id")
+		return utils.ErrInvalidId
 	}
 
 	category, err := c.storage.GetCategoryById(ctx, Id)
 	if err != nil {
-		if err.Error() == "not found" {
-			return err
-		}
 		return fmt.Errorf("(msg): getting category by id (err): %w", err)
 	}
 
-	err = c.storage.DeleteCategory(ctx, category.Id)
+	err = c.storage.DeleteCategory(ctx, category.Id, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditKategori, category.Id, entities.AuditDelete, category, nil)
+	})
 	if err != nil {
 		return fmt.Errorf("(msg): deleting category by id (err): %w", err)
 	}
 
 	return nil
 }
+
+func (c *categoryService) MoveCategory(ctx context.Context, id, parent string) error {
+	Id, err := strconv.Atoi(id)
+	if err != nil || Id <= 0 {
+		return utils.ErrInvalidId
+	}
+
+	dest, err := parseCategoryParent(parent)
+	if err != nil {
+		return err
+	}
+
+	var before entities.Category
+	err = c.storage.MoveCategory(ctx, Id, dest, func(locked entities.Category, ancestors, descendants []entities.Category) error {
+		before = locked
+		if dest == nil {
+			return nil
+		}
+		if ancestors == nil {
+			return errCategoryParentNotFound
+		}
+		for _, anc := range ancestors {
+			if anc.Id == locked.Id {
+				return utils.WebError{Field: "Induk", Message: fmt.Sprintf("%s tidak dapat dipindahkan ke dalam dirinya sendiri atau subkategorinya", locked.Nama), Conflict: true}
+			}
+		}
+		return attributeClash(ancestors, append(descendants, locked))
+	}, func(rec storage.AuditRecorder) error {
+		after := before
+		after.IdInduk = dest
+		return recordAudit(ctx, rec, entities.AuditKategori, before.Id, entities.AuditUpdate, before, after)
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		return fmt.Errorf("moving category with id %d: %w", Id, err)
+	}
+
+	return nil
+}
+
+// SetCategoryAttributes replaces the fields the category declares. Values
+// items already hold are kept; they are checked against the new fields the
+// next time the item is saved.
+func (c *categoryService) SetCategoryAttributes(ctx context.Context, id string, rows []entities.AttributeFieldForm) error {
+	Id, err := strconv.Atoi(id)
+	if err != nil || Id <= 0 {
+		return utils.ErrInvalidId
+	}
+
+	fields, err := entities.NewAttributeFields(rows)
+	if err != nil {
+		return err
+	}
+
+	var before entities.Category
+	err = c.storage.UpdateCategoryAttributes(ctx, Id, fields, func(locked entities.Category, ancestors, descendants []entities.Category) error {
+		before = locked
+		locked.Atribut = fields
+		if err := attributeClash(ancestors, []entities.Category{locked}); err != nil {
+			return err
+		}
+		return attributeClash([]entities.Category{locked}, descendants)
+	}, func(rec storage.AuditRecorder) error {
+		after := before
+		after.Atribut = fields
+		return recordAudit(ctx, rec, entities.AuditKategori, before.Id, entities.AuditUpdate, before, after)
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		return fmt.Errorf("updating attributes of category with id %d: %w", Id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/xuri/excelize/v2"
+)
+
+func writeDepreciationSummaryXLSX(w io.Writer, summary entities.DepreciationSummary) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	border := []excelize.Border{
+		{Type: "left", Color: "000000", Style: 1},
+		{Type: "top", Color: "000000", Style: 1},
+		{Type: "right", Color: "000000", Style: 1},
+		{Type: "bottom", Color: "000000", Style: 1},
+	}
+	rupiahFmt := `"Rp "#,##0`
+
+	titleStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		return err
+	}
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Border:    border,
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"DDDDDD"}},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	})
+	if err != nil {
+		return err
+	}
+	bodyStyle, err := f.NewStyle(&excelize.Style{Border: border, CustomNumFmt: &rupiahFmt})
+	if err != nil {
+		return err
+	}
+	totalStyle, err := f.NewStyle(&excelize.Style{Border: border, Font: &excelize.Font{Bold: true}, CustomNumFmt: &rupiahFmt})
+	if err != nil {
+		return err
+	}
+
+	cell := func(col, row int) string {
+		name, _ := excelize.CoordinatesToCellName(col, row)
+		return name
+	}
+
+	sheets := []struct {
+		name   string
+		header []string
+		lines  []entities.DepreciationLine
+		values func(entities.DepreciationLine) []any
+	}{
+		{
+			name:   "Per Kategori",
+			header: []string{"Kode", "Kategori"},
+			lines:  summary.ByCategory,
+			values: func(l entities.DepreciationLine) []any { return []any{l.Kode, l.Nama} },
+		},
+		{
+			name:   "Rincian Barang",
+			header: []string{"SKU", "Nama Barang", "Kategori"},
+			lines:  summary.Items,
+			values: func(l entities.DepreciationLine) []any { return []any{l.Kode, l.Nama, l.Kategori} },
+		},
+	}
+	amountHeader := []string{"Harga Perolehan", "Akumulasi Awal Tahun", "Penyusutan Tahun Ini", "Akumulasi Akhir Tahun", "Nilai Buku"}
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := f.SetSheetName("Sheet1", sheet.name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(sheet.name); err != nil {
+			return err
+		}
+
+		f.SetCellValue(sheet.name, "A1", fmt.Sprintf("Ringkasan Penyusutan Tahun Anggaran %d", summary.Tahun))
+		f.SetCellStyle(sheet.name, "A1", "A1", titleStyle)
+
+		header := append(append([]string{}, sheet.header...), amountHeader...)
+		for col, title := range header {
+			f.SetCellValue(sheet.name, cell(col+1, 3), title)
+		}
+		f.SetCellStyle(sheet.name, cell(1, 3), cell(len(header), 3), headerStyle)
+		lastCol, _ := excelize.ColumnNumberToName(len(header))
+		f.SetColWidth(sheet.name, "A", lastCol, 22)
+
+		lines := append(append([]entities.DepreciationLine{}, sheet.lines...), summary.Total)
+		for r, line := range lines {
+			row := 4 + r
+			values := sheet.values(line)
+			if r == len(lines)-1 {
+				values = make([]any, len(sheet.header))
+				values[len(values)-1] = line.Nama
+			}
+			values = append(values, line.HargaPerolehan, line.AkumulasiAwal, line.Penyusutan, line.AkumulasiAkhir, line.NilaiBuku)
+			for col, v := range values {
+				f.SetCellValue(sheet.name, cell(col+1, row), v)
+			}
+
+			style := bodyStyle
+			if r == len(lines)-1 {
+				style = totalStyle
+			}
+			f.SetCellStyle(sheet.name, cell(1, row), cell(len(header), row), style)
+		}
+	}
+
+	return f.Write(w)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+)
+
+type DepreciationService interface {
+	GetItemDepreciation(ctx context.Context, slug string, at time.Time) (entities.ItemDepreciation, error)
+	GetFiscalYearSummary(ctx context.Context, year int) (entities.DepreciationSummary, error)
+	WriteSummaryXLSX(w io.Writer, summary entities.DepreciationSummary) error
+}
+
+type depreciationService struct {
+	storage storage.DepreciationRepository
+}
+
+func NewDepreciationService(storage storage.DepreciationRepository) DepreciationService {
+	return &depreciationService{storage: storage}
+}
+
+func (s *depreciationService) GetItemDepreciation(ctx context.Context, slug string, at time.Time) (entities.ItemDepreciation, error) {
+	item, err := s.storage.GetItemBySlug(ctx, slug)
+	if err != nil {
+		return entities.ItemDepreciation{}, fmt.Errorf("getting item by slug: %w", err)
+	}
+
+	units, err := s.storage.GetUnitsByItem(ctx, item.Id)
+	if err != nil {
+		return entities.ItemDepreciation{}, fmt.Errorf("getting units by item: %w", err)
+	}
+
+	return entities.NewItemDepreciation(item, units, entities.DateOf(at)), nil
+}
+
+func (s *depreciationService) GetFiscalYearSummary(ctx context.Context, year int) (entities.DepreciationSummary, error) {
+	_, end := entities.FiscalYearBounds(year)
+
+	items, err := s.storage.GetItemsAcquiredBefore(ctx, end)
+	if err != nil {
+		return entities.DepreciationSummary{}, fmt.Errorf("getting items acquired before %v: %w", end, err)
+	}
+
+	return entities.NewDepreciationSummary(year, items), nil
+}
+
+func (s *depreciationService) WriteSummaryXLSX(w io.Writer, summary entities.DepreciationSummary) error {
+	return writeDepreciationSummaryXLSX(w, summary)
+}
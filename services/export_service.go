@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+	"github.com/xuri/excelize/v2"
+)
+
+// ExportService writes the whole filtered result of a list page, ignoring
+// pagination. The params are the same as for the list page so an export
+// matches what the user was looking at.
+type ExportService interface {
+	ExportCategories(ctx context.Context, w io.Writer, format entities.FormatEkspor, params utils.PaginationParams) error
+	ExportLocations(ctx context.Context, w io.Writer, format entities.FormatEkspor, params utils.PaginationParams) error
+	ExportRooms(ctx context.Context, w io.Writer, format entities.FormatEkspor, params utils.PaginationParams) error
+	ExportItems(ctx context.Context, w io.Writer, format entities.FormatEkspor, params utils.PaginationParams) error
+}
+
+type exportService struct {
+	storage storage.ExportRepository
+}
+
+func NewExportService(storage storage.ExportRepository) ExportService {
+	return &exportService{storage: storage}
+}
+
+func exportQuery(params utils.PaginationParams, config utils.TableConfig) (string, string, []interface{}) {
+	params.SetColumnSearch(config.QueryCols...)
+	params.SetFilters(config.Filters)
+	params.ExcludeDeleted(config.DeletedCol)
+	where, args := utils.BuildWhereClauses(params)
+	return utils.BuildSortClause(params, config), where, args
+}
+
+func (s *exportService) ExportCategories(ctx context.Context, w io.Writer, format entities.FormatEkspor, params utils.PaginationParams) error {
+	sort, where, args := exportQuery(params, categoryTableConfig)
+
+	ew, err := newExportWriter(w, format, "Kategori", []string{"kode", "nama", "tgl_dibuat", "tgl_update"})
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.EachCategory(ctx, sort, where, args, func(c entities.Category) error {
+		return ew.Write(c, []any{c.Kode, c.Nama, c.TglDibuat, c.TglUpdate})
+	})
+	if err != nil {
+		return fmt.Errorf("exporting categories: %w", err)
+	}
+
+	return ew.Close()
+}
+
+func (s *exportService) ExportLocations(ctx context.Context, w io.Writer, format entities.FormatEkspor, params utils.PaginationParams) error {
+	sort, where, args := exportQuery(params, locationTableConfig)
+
+	ew, err := newExportWriter(w, format, "Lokasi", []string{"kode", "nama", "jumlah_ruangan", "tgl_dibuat", "tgl_update"})
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.EachLocation(ctx, sort, where, args, func(l entities.Location) error {
+		return ew.Write(l, []any{l.Kode, l.Nama, l.JumlahRuangan, l.TglDibuat, l.TglUpdate})
+	})
+	if err != nil {
+		return fmt.Errorf("exporting locations: %w", err)
+	}
+
+	return ew.Close()
+}
+
+func (s *exportService) ExportRooms(ctx context.Context, w io.Writer, format entities.FormatEkspor, params utils.PaginationParams) error {
+	sort, where, args := exportQuery(params, roomTableConfig)
+
+	ew, err := newExportWriter(w, format, "Ruangan", []string{"nama", "penanggung_jawab", "kode_lokasi", "lokasi", "jumlah_barang", "tgl_dibuat", "tgl_update"})
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.EachRoom(ctx, sort, where, args, func(r entities.Room) error {
+		return ew.Write(r, []any{r.Nama, r.PenanggungJawab, r.Lokasi.Kode, r.Lokasi.Nama, r.JumlahBarang, r.TglDibuat, r.TglUpdate})
+	})
+	if err != nil {
+		return fmt.Errorf("exporting rooms: %w", err)
+	}
+
+	return ew.Close()
+}
+
+func (s *exportService) ExportItems(ctx context.Context, w io.Writer, format entities.FormatEkspor, params utils.PaginationParams) error {
+	sort, where, args := exportQuery(params, itemTableConfig)
+
+	ew, err := newExportWriter(w, format, "Barang", []string{
+		"sku", "nama", "kode_kategori", "kategori", "jumlah", "satuan", "harga_satuan", "total_harga",
+		"umur_ekonomis", "metode_penyusutan", "nilai_residu", "spesifikasi", "atribut", "tgl_perolehan", "tgl_dibuat",
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.EachItem(ctx, sort, where, args, func(i entities.Item) error {
+		atribut, err := json.Marshal(i.Atribut)
+		if err != nil {
+			return err
+		}
+
+		return ew.Write(i, []any{
+			i.SKU, i.Nama, i.Kategori.Kode, i.Kategori.Nama, i.Jumlah, i.Satuan, i.HargaSatuan, i.TotalHarga,
+			i.UmurEkonomis, string(i.MetodePenyusutan), i.NilaiResidu, i.Spesifikasi, string(atribut), i.TglPerolehan.Format(entities.DateLayout), i.TglDibuat,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("exporting items: %w", err)
+	}
+
+	return ew.Close()
+}
+
+// exportWriter receives one row at a time. CSV and JSON rows go out to w as
+// they are written; XLSX rows go through the excelize stream writer, which
+// spills to a temporary file, and the workbook is written to w on Close.
+type exportWriter interface {
+	Write(entity any, values []any) error
+	Close() error
+}
+
+func newExportWriter(w io.Writer, format entities.FormatEkspor, sheet string, header []string) (exportWriter, error) {
+	switch format {
+	case entities.EksporXLSX:
+		return newXLSXExport(w, sheet, header)
+	case entities.EksporJSON:
+		return newJSONExport(w)
+	default:
+		return newCSVExport(w, header)
+	}
+}
+
+type csvExport struct {
+	writer *csv.Writer
+	row    []string
+}
+
+func newCSVExport(w io.Writer, header []string) (*csvExport, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	return &csvExport{writer: writer, row: make([]string, len(header))}, nil
+}
+
+func (e *csvExport) Write(_ any, values []any) error {
+	for i, v := range values {
+		e.row[i] = exportString(v)
+	}
+	return e.writer.Write(e.row)
+}
+
+func (e *csvExport) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// jsonExport writes an array of the entities with their API field names.
+type jsonExport struct {
+	w     *bufio.Writer
+	count int
+}
+
+func newJSONExport(w io.Writer) (*jsonExport, error) {
+	buf := bufio.NewWriter(w)
+	if _, err := buf.WriteString("["); err != nil {
+		return nil, err
+	}
+	return &jsonExport{w: buf}, nil
+}
+
+func (e *jsonExport) Write(entity any, _ []any) error {
+	b, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+
+	if e.count > 0 {
+		e.w.WriteString(",")
+	}
+	e.w.WriteString("\n")
+	e.count++
+
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *jsonExport) Close() error {
+	if _, err := e.w.WriteString("\n]\n"); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+type xlsxExport struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXExport(w io.Writer, sheet string, header []string) (*xlsxExport, error) {
+	f := excelize.NewFile()
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+
+	stream, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make([]any, len(header))
+	for i, h := range header {
+		cells[i] = h
+	}
+	if err := stream.SetRow("A1", cells); err != nil {
+		return nil, err
+	}
+
+	return &xlsxExport{w: w, file: f, stream: stream, row: 1}, nil
+}
+
+func (e *xlsxExport) Write(_ any, values []any) error {
+	e.row++
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(cell, values)
+}
+
+func (e *xlsxExport) Close() error {
+	defer e.file.Close()
+
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
+
+func exportString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case interface{ Format(string) string }:
+		return v.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/qeunasd/coniven/utils"
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxImportSize bounds the uploaded file, MaxImportRows the data rows in it.
+var (
+	MaxImportSize int64 = 10 << 20
+	MaxImportRows       = 5000
+)
+
+// readImportRecords reads every row of a CSV or of the first sheet of an XLSX
+// file, header included.
+func readImportRecords(filename string, file io.Reader) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return readCSVRecords(file)
+	case ".xlsx":
+		return readXLSXRecords(file)
+	default:
+		return nil, utils.WebError{Field: "File", Message: "format file harus .csv atau .xlsx"}
+	}
+}
+
+// readCSVRecords accepts both comma and semicolon separated files, the latter
+// being what spreadsheets save in an Indonesian locale.
+func readCSVRecords(file io.Reader) ([][]string, error) {
+	buf := bufio.NewReader(file)
+	firstLine, err := buf.Peek(buf.Size())
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("reading csv: %w", err)
+	}
+	if i := bytes.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	reader := csv.NewReader(buf)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, utils.WebError{Field: "File", Message: "file csv tidak dapat dibaca: " + err.Error()}
+	}
+
+	return records, nil
+}
+
+func readXLSXRecords(file io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(file)
+	if err != nil {
+		return nil, utils.WebError{Field: "File", Message: "file xlsx tidak dapat dibaca"}
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, utils.WebError{Field: "File", Message: "file xlsx tidak memiliki sheet"}
+	}
+
+	records, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("reading sheet %s: %w", sheets[0], err)
+	}
+
+	return records, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+type ImportService interface {
+	PreviewImport(ctx context.Context, jenis, filename string, file io.Reader) (entities.ImportPreview, error)
+	CommitImport(ctx context.Context, jenis, filename string, file io.Reader) (entities.ImportPreview, error)
+	WriteImportTemplate(w io.Writer, jenis string) error
+}
+
+type importService struct {
+	storage storage.ImportRepository
+}
+
+func NewImportService(storage storage.ImportRepository) ImportService {
+	return &importService{storage: storage}
+}
+
+func (s *importService) WriteImportTemplate(w io.Writer, jenis string) error {
+	j, err := entities.ParseJenisImpor(jenis)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(j.Columns()); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// PreviewImport validates every row of the file without saving anything.
+func (s *importService) PreviewImport(ctx context.Context, jenis, filename string, file io.Reader) (entities.ImportPreview, error) {
+	preview, _, err := s.prepareImport(ctx, jenis, filename, file)
+	return preview, err
+}
+
+// CommitImport validates the file again and, only when every row is valid,
+// saves all of them in one transaction. A file with errors comes back as a
+// preview with Disimpan false.
+func (s *importService) CommitImport(ctx context.Context, jenis, filename string, file io.Reader) (entities.ImportPreview, error) {
+	preview, batch, err := s.prepareImport(ctx, jenis, filename, file)
+	if err != nil || preview.HasErrors() {
+		return preview, err
+	}
+
+	err = s.storage.ImportRecords(ctx, &batch, func(rec storage.AuditRecorder) error {
+		for _, c := range batch.Categories {
+			if err := recordAudit(ctx, rec, entities.AuditKategori, c.Id, entities.AuditCreate, nil, c); err != nil {
+				return err
+			}
+		}
+		for _, l := range batch.Locations {
+			if err := recordAudit(ctx, rec, entities.AuditLokasi, l.Id, entities.AuditCreate, nil, l); err != nil {
+				return err
+			}
+		}
+		for _, r := range batch.Rooms {
+			if err := recordAudit(ctx, rec, entities.AuditRuangan, r.Id, entities.AuditCreate, nil, r); err != nil {
+				return err
+			}
+		}
+		for _, i := range batch.Items {
+			if err := recordAudit(ctx, rec, entities.AuditBarang, i.Id, entities.AuditCreate, nil, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrDuplicate):
+			return preview, utils.WebError{Field: "File", Message: "sebagian data sudah ditambahkan di tempat lain, ulangi pratinjau", Conflict: true}
+		case errors.Is(err, utils.ErrNotFound):
+			return preview, utils.WebError{Field: "File", Message: "lokasi atau kategori yang dirujuk sudah dihapus, ulangi pratinjau", Conflict: true}
+		}
+		return preview, fmt.Errorf("importing %s: %w", preview.Jenis, err)
+	}
+
+	preview.Disimpan = true
+	return preview, nil
+}
+
+func (s *importService) prepareImport(ctx context.Context, jenis, filename string, file io.Reader) (entities.ImportPreview, entities.ImportBatch, error) {
+	j, err := entities.ParseJenisImpor(jenis)
+	if err != nil {
+		return entities.ImportPreview{}, entities.ImportBatch{}, err
+	}
+
+	preview := entities.ImportPreview{Jenis: j, NamaFile: filename}
+
+	records, err := readImportRecords(filename, file)
+	if err != nil {
+		return preview, entities.ImportBatch{}, err
+	}
+	if len(records) == 0 {
+		return preview, entities.ImportBatch{}, utils.WebError{Field: "File", Message: "file kosong"}
+	}
+
+	index, err := entities.ImportHeader(j, records[0])
+	if err != nil {
+		return preview, entities.ImportBatch{}, err
+	}
+
+	for i, record := range records[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+		preview.Rows = append(preview.Rows, entities.NewImportRow(j, i+2, index, record))
+	}
+
+	if len(preview.Rows) == 0 {
+		return preview, entities.ImportBatch{}, utils.WebError{Field: "File", Message: "file tidak berisi data"}
+	}
+	if len(preview.Rows) > MaxImportRows {
+		preview.Rows = nil
+		return preview, entities.ImportBatch{}, utils.WebError{Field: "File", Message: fmt.Sprintf("maksimal %d baris per impor", MaxImportRows)}
+	}
+
+	var batch entities.ImportBatch
+	switch j {
+	case entities.ImporKategori:
+		batch.Categories, err = s.buildCategories(ctx, preview.Rows)
+	case entities.ImporLokasi:
+		batch.Locations, err = s.buildLocations(ctx, preview.Rows)
+	case entities.ImporRuangan:
+		batch.Rooms, err = s.buildRooms(ctx, preview.Rows)
+	case entities.ImporBarang:
+		batch.Items, err = s.buildItems(ctx, preview.Rows)
+	}
+	if err != nil {
+		return preview, entities.ImportBatch{}, err
+	}
+
+	return preview, batch, nil
+}
+
+// The builders validate each row with the same constructors as the forms and
+// record problems on the row itself, so the preview can list every invalid
+// row at once. Uniqueness is checked against the database and against the
+// rows above in the same file. Records in the trash still hold their kode and
+// SKU, so they count as taken; rooms and items only refer to live records.
+
+func (s *importService) buildCategories(ctx context.Context, rows []entities.ImportRow) ([]entities.Category, error) {
+	existing, err := s.storage.GetCategoriesWithFilter(ctx, "", "", "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting categories: %w", err)
+	}
+
+	kodes := make(map[string]bool, len(existing)+len(rows))
+	names := make(map[string]bool, len(existing)+len(rows))
+	for _, c := range existing {
+		kodes[c.Kode] = true
+		names[c.Nama] = true
+	}
+
+	categories := make([]entities.Category, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		category := entities.NewCategory(row.Get("kode"), row.Get("nama"))
+		if err := category.Validate(); err != nil {
+			row.AddError(err)
+			continue
+		}
+
+		if kodes[category.Kode] {
+			row.AddError(fmt.Errorf("kode %s sudah terpakai", category.Kode))
+		}
+		if names[category.Nama] {
+			row.AddError(fmt.Errorf("nama %s sudah terpakai", category.Nama))
+		}
+		kodes[category.Kode] = true
+		names[category.Nama] = true
+
+		categories = append(categories, *category)
+	}
+
+	return categories, nil
+}
+
+func (s *importService) buildLocations(ctx context.Context, rows []entities.ImportRow) ([]entities.Location, error) {
+	existing, err := s.storage.GetLocations(ctx, "", "", "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("getting locations: %w", err)
+	}
+
+	kodes := make(map[string]bool, len(existing)+len(rows))
+	for _, l := range existing {
+		kodes[l.Kode] = true
+	}
+
+	locations := make([]entities.Location, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		loc, err := entities.NewLocation(row.Get("kode"), row.Get("nama"))
+		if err != nil {
+			row.AddError(err)
+			continue
+		}
+
+		if kodes[loc.Kode] {
+			row.AddError(fmt.Errorf("kode %s sudah terpakai", loc.Kode))
+		}
+		kodes[loc.Kode] = true
+
+		locations = append(locations, *loc)
+	}
+
+	return locations, nil
+}
+
+func (s *importService) buildRooms(ctx context.Context, rows []entities.ImportRow) ([]entities.Room, error) {
+	where, args := liveWhere(locationTableConfig)
+	existing, err := s.storage.GetLocations(ctx, "", "", where, args)
+	if err != nil {
+		return nil, fmt.Errorf("getting locations: %w", err)
+	}
+
+	byKode := make(map[string]entities.Location, len(existing))
+	for _, l := range existing {
+		byKode[l.Kode] = l
+	}
+
+	rooms := make([]entities.Room, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+
+		lokasi := ""
+		if kode := row.Get("kode_lokasi"); kode != "" {
+			loc, ok := byKode[kode]
+			if !ok {
+				row.AddError(fmt.Errorf("lokasi dengan kode %s tidak ditemukan", kode))
+				continue
+			}
+			lokasi = loc.Id.String()
+		}
+
+		room, err := entities.NewRoom(entities.RoomForm{
+			Name:    row.Get("nama"),
+			Manager: row.Get("penanggung_jawab"),
+			Lokasi:  lokasi,
+		})
+		if err != nil {
+			row.AddError(err)
+			continue
+		}
+
+		rooms = append(rooms, *room)
+	}
+
+	return rooms, nil
+}
+
+func (s *importService) buildItems(ctx context.Context, rows []entities.ImportRow) ([]entities.Item, error) {
+	where, args := liveWhere(categoryTableConfig)
+	categories, err := s.storage.GetCategoriesWithFilter(ctx, "", "", where, args)
+	if err != nil {
+		return nil, fmt.Errorf("getting categories: %w", err)
+	}
+
+	byKode := make(map[string]entities.Category, len(categories))
+	byId := make(map[int]entities.Category, len(categories))
+	for _, c := range categories {
+		byKode[c.Kode] = c
+		byId[c.Id] = c
+	}
+
+	skus := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if sku := row.Get("sku"); sku != "" {
+			skus = append(skus, sku)
+		}
+	}
+
+	taken, err := s.storage.FindItemSKUs(ctx, skus)
+	if err != nil {
+		return nil, fmt.Errorf("finding item skus: %w", err)
+	}
+
+	seen := make(map[string]bool, len(taken)+len(rows))
+	for _, sku := range taken {
+		seen[sku] = true
+	}
+
+	items := make([]entities.Item, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+
+		kategori := ""
+		var fields []entities.AttributeField
+		if kode := row.Get("kode_kategori"); kode != "" {
+			c, ok := byKode[kode]
+			if !ok {
+				row.AddError(fmt.Errorf("kategori dengan kode %s tidak ditemukan", kode))
+				continue
+			}
+			kategori = strconv.Itoa(c.Id)
+			c.Jalur = entities.CategoryPath(byId, c.Id)
+			fields = c.SemuaAtribut()
+		}
+
+		atribut, err := entities.ParseAttributeJSON(row.Get("atribut"))
+		if err != nil {
+			row.AddError(err)
+			continue
+		}
+
+		item, err := entities.NewItem(entities.ItemForm{
+			SKU:              row.Get("sku"),
+			Name:             row.Get("nama"),
+			Kategori:         kategori,
+			Jumlah:           row.Get("jumlah"),
+			Satuan:           row.Get("satuan"),
+			HargaSatuan:      row.Get("harga_satuan"),
+			UmurEkonomis:     row.Get("umur_ekonomis"),
+			MetodePenyusutan: strings.ToLower(row.Get("metode_penyusutan")),
+			NilaiResidu:      row.Get("nilai_residu"),
+			Spesifikasi:      row.Get("spesifikasi"),
+			TglPerolehan:     row.Get("tgl_perolehan"),
+		})
+		if err != nil {
+			row.AddError(err)
+			continue
+		}
+
+		item.Atribut, err = entities.ParseAttributeValues(fields, atribut)
+		if err != nil {
+			row.AddError(err)
+			continue
+		}
+
+		if seen[item.SKU] {
+			row.AddError(fmt.Errorf("SKU %s sudah terpakai", item.SKU))
+		}
+		seen[item.SKU] = true
+
+		items = append(items, *item)
+	}
+
+	return items, nil
+}
@@ -1,8 +1,51 @@
 package services
 
-import "github.com/qeunasd/coniven/storage"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+// ItemFilters are the filter query parameters the item list accepts.
+var ItemFilters = map[string]utils.AllowedFilter{
+	"dmin": {Column: "b.tgl_dibuat", Operator: "gte"},
+	"dmax": {Column: "b.tgl_dibuat", Operator: "lte"},
+	"kat":  {Column: "b.id_kategori", Operator: "eq"},
+	"atr":  {Column: "b.atribut", Operator: "attr"},
+}
+
+var itemTableConfig = utils.TableConfig{
+	QueryCols: []string{"b.nama", "b.sku", "k.nama", "(SELECT string_agg(value, ' ') FROM jsonb_each_text(b.atribut))"},
+	SortCols: []utils.AllowedSort{
+		{Name: "nama", Column: "b.nama"},
+		{Name: "sku", Column: "b.sku"},
+		{Name: "kt", Column: "k.nama"},
+		{Name: "jml", Column: "b.jumlah"},
+		{Name: "hs", Column: "b.harga_satuan"},
+		{Name: "dt", Column: "b.tgl_dibuat"},
+	},
+	DefaultSort: "dt",
+	DeletedCol:  "b.tgl_dihapus",
+	Filters:     ItemFilters,
+}
 
 type ItemService interface {
+	CreateItem(ctx context.Context, req entities.ItemForm) (entities.Item, error)
+	GetItemsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error)
+	GetTotalItems(ctx context.Context) (int, error)
+	GetItemBySlug(ctx context.Context, slug string) (entities.Item, error)
+	GetItemById(ctx context.Context, id uuid.UUID) (entities.Item, error)
+	EditItem(ctx context.Context, slug string, req entities.ItemForm) error
+	DeleteItem(ctx context.Context, id string) error
+	GetAttributeFields(ctx context.Context, idKategori int) ([]entities.AttributeField, error)
 }
 
 type itemService struct {
@@ -12,3 +55,267 @@ type itemService struct {
 func NewItemService(storage storage.ItemRepository) ItemService {
 	return &itemService{storage: storage}
 }
+
+func (s *itemService) CreateItem(ctx context.Context, req entities.ItemForm) (entities.Item, error) {
+	item, err := entities.NewItem(req)
+	if err != nil {
+		return entities.Item{}, err
+	}
+
+	fields, err := s.categoryFields(ctx, item.IdKategori)
+	if err != nil {
+		return entities.Item{}, err
+	}
+
+	item.Atribut, err = entities.ParseAttributeValues(fields, req.Atribut)
+	if err != nil {
+		return entities.Item{}, err
+	}
+
+	exist, err := s.storage.FindItemBySKU(ctx, item.SKU)
+	if err != nil {
+		return entities.Item{}, fmt.Errorf("finding item by sku: %w", err)
+	}
+
+	if exist {
+		return entities.Item{}, utils.WebError{Field: "SKU", Message: "SKU sudah terpakai", Conflict: true}
+	}
+
+	err = s.storage.CreateItem(ctx, *item, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditBarang, item.Id, entities.AuditCreate, nil, item)
+	})
+	if errors.Is(err, storage.ErrDuplicate) {
+		return entities.Item{}, utils.WebError{Field: "SKU", Message: "SKU sudah terpakai", Conflict: true}
+	}
+	if err != nil {
+		return entities.Item{}, fmt.Errorf("saving item: %w", err)
+	}
+
+	return *item, nil
+}
+
+func (s *itemService) GetItemsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
+	params.SetColumnSearch(itemTableConfig.QueryCols...)
+	params.SetFilters(itemTableConfig.Filters)
+	params.ExcludeDeleted(itemTableConfig.DeletedCol)
+	where, args := utils.BuildWhereClauses(params)
+
+	total, err := s.storage.CountItems(ctx, where, args)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("counting items: %w", err)
+	}
+
+	totalPage := (total + params.PerPage - 1) / params.PerPage
+	if params.Page > totalPage && totalPage > 0 {
+		params.Page = totalPage
+	}
+
+	sort := utils.BuildSortClause(params, itemTableConfig)
+	limit := utils.BuildLimitClause(params)
+
+	items, err := s.storage.GetItems(ctx, limit, sort, where, args)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("getting items: %w", err)
+	}
+
+	return utils.PaginationResult{
+		Data:      items,
+		TotalData: int64(total),
+		TotalPage: totalPage,
+		Page:      params.Page,
+		PerPage:   params.PerPage,
+	}, nil
+}
+
+func (s *itemService) GetTotalItems(ctx context.Context) (int, error) {
+	where, args := liveWhere(itemTableConfig)
+	return s.storage.CountItems(ctx, where, args)
+}
+
+func (s *itemService) GetItemBySlug(ctx context.Context, slug string) (entities.Item, error) {
+	return s.storage.GetItemBySlug(ctx, slug)
+}
+
+func (s *itemService) GetItemById(ctx context.Context, id uuid.UUID) (entities.Item, error) {
+	return s.storage.GetItemById(ctx, id)
+}
+
+func (s *itemService) EditItem(ctx context.Context, slug string, req entities.ItemForm) error {
+	item, err := s.storage.GetItemBySlug(ctx, slug)
+	if err != nil {
+		return fmt.Errorf("getting item by slug: %w", err)
+	}
+	before := item
+
+	sku := strings.TrimSpace(req.SKU)
+	name := strings.TrimSpace(req.Name)
+	satuan := strings.TrimSpace(req.Satuan)
+	spesifikasi := strings.TrimSpace(req.Spesifikasi)
+
+	if sku != "" && sku != item.SKU {
+		exist, err := s.storage.FindItemBySKU(ctx, sku)
+		if err != nil {
+			return fmt.Errorf("finding item by sku: %w", err)
+		}
+
+		if exist {
+			return utils.WebError{Field: "SKU", Message: "SKU sudah terpakai", Conflict: true}
+		}
+
+		item.SKU = sku
+	}
+
+	if name != "" && name != item.Nama {
+		item.Nama = name
+		item.Slug = utils.NewSlug(name)
+	}
+
+	if kategori := strings.TrimSpace(req.Kategori); kategori != "" {
+		idKategori, err := strconv.Atoi(kategori)
+		if err != nil || idKategori <= 0 {
+			return utils.WebError{Field: "Kategori", Message: "Kategori tidak valid"}
+		}
+
+		if idKategori != item.IdKategori {
+			if _, err := s.storage.GetCategoryById(ctx, idKategori); err != nil {
+				if errors.Is(err, utils.ErrNotFound) {
+					return utils.WebError{Field: "Kategori", Message: "kategori tidak ditemukan"}
+				}
+				return fmt.Errorf("getting category by id: %w", err)
+			}
+			item.IdKategori = idKategori
+		}
+	}
+
+	// Values are checked again when they are sent or the category changed;
+	// otherwise the stored ones stay as they are.
+	if len(req.Atribut) > 0 || item.IdKategori != before.IdKategori {
+		fields, err := s.categoryFields(ctx, item.IdKategori)
+		if err != nil {
+			return err
+		}
+
+		rows := req.Atribut
+		if len(rows) == 0 {
+			rows = item.Atribut.Form()
+		}
+
+		item.Atribut, err = entities.ParseAttributeValues(fields, rows)
+		if err != nil {
+			return err
+		}
+	}
+
+	if strings.TrimSpace(req.Jumlah) != "" {
+		jumlah, err := entities.ParsePositiveInt(req.Jumlah)
+		if err != nil {
+			return utils.WebError{Field: "Jumlah", Message: "Jumlah harus berupa angka lebih dari 0"}
+		}
+		item.Jumlah = jumlah
+	}
+
+	if satuan != "" {
+		item.Satuan = satuan
+	}
+
+	if strings.TrimSpace(req.HargaSatuan) != "" {
+		harga, err := entities.ParseNonNegativeInt(req.HargaSatuan)
+		if err != nil {
+			return utils.WebError{Field: "HargaSatuan", Message: "Harga satuan harus berupa angka"}
+		}
+		item.HargaSatuan = harga
+	}
+
+	if strings.TrimSpace(req.UmurEkonomis) != "" {
+		umur, err := entities.ParsePositiveInt(req.UmurEkonomis)
+		if err != nil {
+			return utils.WebError{Field: "UmurEkonomis", Message: "Umur ekonomis harus berupa angka lebih dari 0"}
+		}
+		item.UmurEkonomis = umur
+	}
+
+	if metode := strings.TrimSpace(req.MetodePenyusutan); metode != "" {
+		item.MetodePenyusutan, err = entities.ParseMetodePenyusutan(metode)
+		if err != nil {
+			return err
+		}
+	}
+
+	if strings.TrimSpace(req.NilaiResidu) != "" {
+		residu, err := entities.ParseNonNegativeInt(req.NilaiResidu)
+		if err != nil {
+			return utils.WebError{Field: "NilaiResidu", Message: "Nilai residu harus berupa angka"}
+		}
+		item.NilaiResidu = residu
+	}
+
+	if item.NilaiResidu > item.HargaSatuan {
+		return utils.WebError{Field: "NilaiResidu", Message: "Nilai residu tidak boleh melebihi harga satuan"}
+	}
+
+	if spesifikasi != "" {
+		item.Spesifikasi = spesifikasi
+	}
+
+	item.TglPerolehan, err = entities.ParseTglPerolehan(req.TglPerolehan, item.TglPerolehan, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.UpdateItem(ctx, item, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditBarang, item.Id, entities.AuditUpdate, before, item)
+	})
+	if errors.Is(err, storage.ErrDuplicate) {
+		return utils.WebError{Field: "SKU", Message: "SKU sudah terpakai", Conflict: true}
+	}
+	if err != nil {
+		return fmt.Errorf("updating item with id %v: %w", item.Id, err)
+	}
+
+	return nil
+}
+
+func (s *itemService) DeleteItem(ctx context.Context, id string) error {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	item, err := s.storage.GetItemById(ctx, resId)
+	if err != nil {
+		return fmt.Errorf("getting item by id: %w", err)
+	}
+
+	err = s.storage.DeleteItem(ctx, item.Id, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditBarang, item.Id, entities.AuditDelete, item, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("deleting item with id %v: %w", item.Id, err)
+	}
+
+	return nil
+}
+
+// categoryFields returns the attribute fields items of the category fill in,
+// the inherited ones included.
+func (s *itemService) categoryFields(ctx context.Context, idKategori int) ([]entities.AttributeField, error) {
+	category, err := s.storage.GetCategoryById(ctx, idKategori)
+	if err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			return nil, utils.WebError{Field: "Kategori", Message: "kategori tidak ditemukan"}
+		}
+		return nil, fmt.Errorf("getting category by id: %w", err)
+	}
+
+	paths, err := s.storage.GetCategoryPaths(ctx, []int{category.Id})
+	if err != nil {
+		return nil, fmt.Errorf("getting category path: %w", err)
+	}
+	category.Jalur = paths[category.Id]
+
+	return category.SemuaAtribut(), nil
+}
+
+func (s *itemService) GetAttributeFields(ctx context.Context, idKategori int) ([]entities.AttributeField, error) {
+	return s.categoryFields(ctx, idKategori)
+}
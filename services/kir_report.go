@@ -0,0 +1,299 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/utils"
+	"github.com/xuri/excelize/v2"
+)
+
+var kirColumns = []struct {
+	title string
+	width float64
+}{
+	{"No", 10},
+	{"Nama Barang", 60},
+	{"SKU", 35},
+	{"Kategori", 35},
+	{"Nomor Seri", 40},
+	{"Kondisi", 27},
+	{"Tgl Perolehan", 30},
+	{"Harga Satuan", 40},
+}
+
+const kirDateLayout = "02-01-2006"
+
+// kirRows flattens the grouped units into table rows. Item columns are only
+// filled on the first row of each group.
+func kirRows(kir entities.KIR) [][]string {
+	var rows [][]string
+	for i, g := range kir.Groups {
+		for j, u := range g.Units {
+			row := []string{"", "", "", "", u.NoSeri, string(u.Kondisi), u.TglPerolehan.Format(kirDateLayout), utils.FormatRupiah(g.Barang.HargaSatuan)}
+			if j == 0 {
+				row[0] = strconv.Itoa(i + 1)
+				row[1] = g.Barang.Nama
+				row[2] = g.Barang.SKU
+				row[3] = g.Barang.Kategori.Nama
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func writeKIRPDF(w io.Writer, kir entities.KIR) error {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	const (
+		margin = 10.0
+		rowH   = 7.0
+	)
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(false, margin)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-margin)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 5, tr(fmt.Sprintf("KIR %s - halaman %d/{nb}", kir.Ruangan.Nama, pdf.PageNo())), "", 0, "R", false, 0, "")
+	})
+
+	_, pageH := pdf.GetPageSize()
+	bottom := pageH - margin - 5
+
+	fit := func(text string, width float64) string {
+		text = tr(text)
+		for len(text) > 0 && pdf.GetStringWidth(text) > width-2 {
+			text = text[:len(text)-1]
+		}
+		return text
+	}
+
+	tableHeader := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(220, 220, 220)
+		for _, c := range kirColumns {
+			pdf.CellFormat(c.width, rowH, tr(c.title), "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+	}
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 8, "KARTU INVENTARIS RUANGAN (KIR)", "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, tr(kir.Semester()), "", 1, "C", false, 0, "")
+	pdf.Ln(2)
+
+	info := [][2]string{
+		{"Lokasi", fmt.Sprintf("%s (%s)", kir.Ruangan.Lokasi.Nama, kir.Ruangan.Lokasi.Kode)},
+		{"Ruangan", kir.Ruangan.Nama},
+		{"Penanggung Jawab", kir.Ruangan.PenanggungJawab},
+	}
+	for _, line := range info {
+		pdf.CellFormat(40, 6, tr(line[0]), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, tr(": "+line[1]), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(2)
+
+	tableHeader()
+	rows := kirRows(kir)
+	if len(rows) == 0 {
+		pdf.CellFormat(0, rowH, "tidak memiliki barang", "1", 1, "C", false, 0, "")
+	}
+
+	for _, row := range rows {
+		if pdf.GetY()+rowH > bottom {
+			pdf.AddPage()
+			tableHeader()
+		}
+
+		for i, c := range kirColumns {
+			align := "L"
+			if i == 0 {
+				align = "C"
+			} else if i == len(kirColumns)-1 {
+				align = "R"
+			}
+			pdf.CellFormat(c.width, rowH, fit(row[i], c.width), "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.SetFont("Helvetica", "B", 9)
+	labelW := 0.0
+	for _, c := range kirColumns[:len(kirColumns)-1] {
+		labelW += c.width
+	}
+	pdf.CellFormat(labelW, rowH, tr(fmt.Sprintf("Jumlah %d unit dari %d jenis barang", kir.TotalUnit, len(kir.Groups))), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(kirColumns[len(kirColumns)-1].width, rowH, utils.FormatRupiah(kir.TotalNilai), "1", 1, "R", false, 0, "")
+
+	const signH = 40.0
+	if pdf.GetY()+signH > bottom {
+		pdf.AddPage()
+	}
+
+	pdf.Ln(8)
+	pdf.SetFont("Helvetica", "", 10)
+	signX := 297 - margin - 80
+	for _, line := range []string{
+		fmt.Sprintf("%s, %s", kir.Ruangan.Lokasi.Nama, entities.FormatTanggal(kir.Dicetak)),
+		"Penanggung Jawab Ruangan",
+	} {
+		pdf.SetX(signX)
+		pdf.CellFormat(80, 6, tr(line), "", 1, "C", false, 0, "")
+	}
+	pdf.Ln(18)
+	pdf.SetX(signX)
+	pdf.SetFont("Helvetica", "BU", 10)
+	pdf.CellFormat(80, 6, tr(kir.Ruangan.PenanggungJawab), "", 1, "C", false, 0, "")
+
+	return pdf.Output(w)
+}
+
+func writeKIRXLSX(w io.Writer, kir entities.KIR) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "KIR"
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	lastCol, _ := excelize.ColumnNumberToName(len(kirColumns))
+	cell := func(col, row int) string {
+		name, _ := excelize.CoordinatesToCellName(col, row)
+		return name
+	}
+
+	border := []excelize.Border{
+		{Type: "left", Color: "000000", Style: 1},
+		{Type: "top", Color: "000000", Style: 1},
+		{Type: "right", Color: "000000", Style: 1},
+		{Type: "bottom", Color: "000000", Style: 1},
+	}
+	dateFmt := "dd-mm-yyyy"
+	rupiahFmt := `"Rp "#,##0`
+
+	titleStyle, err := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 14},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	})
+	if err != nil {
+		return err
+	}
+	centerStyle, err := f.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{Horizontal: "center"}})
+	if err != nil {
+		return err
+	}
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Border:    border,
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"DDDDDD"}},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	})
+	if err != nil {
+		return err
+	}
+	bodyStyle, err := f.NewStyle(&excelize.Style{Border: border})
+	if err != nil {
+		return err
+	}
+	dateStyle, err := f.NewStyle(&excelize.Style{Border: border, CustomNumFmt: &dateFmt})
+	if err != nil {
+		return err
+	}
+	moneyStyle, err := f.NewStyle(&excelize.Style{Border: border, CustomNumFmt: &rupiahFmt})
+	if err != nil {
+		return err
+	}
+	totalStyle, err := f.NewStyle(&excelize.Style{Border: border, Font: &excelize.Font{Bold: true}, CustomNumFmt: &rupiahFmt})
+	if err != nil {
+		return err
+	}
+	signStyle, err := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Underline: "single"},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	})
+	if err != nil {
+		return err
+	}
+
+	f.SetCellValue(sheet, "A1", "KARTU INVENTARIS RUANGAN (KIR)")
+	f.MergeCell(sheet, "A1", lastCol+"1")
+	f.SetCellStyle(sheet, "A1", "A1", titleStyle)
+	f.SetCellValue(sheet, "A2", kir.Semester())
+	f.MergeCell(sheet, "A2", lastCol+"2")
+	f.SetCellStyle(sheet, "A2", "A2", centerStyle)
+
+	info := [][2]string{
+		{"Lokasi", fmt.Sprintf("%s (%s)", kir.Ruangan.Lokasi.Nama, kir.Ruangan.Lokasi.Kode)},
+		{"Ruangan", kir.Ruangan.Nama},
+		{"Penanggung Jawab", kir.Ruangan.PenanggungJawab},
+	}
+	for i, line := range info {
+		f.SetCellValue(sheet, cell(1, 4+i), line[0])
+		f.MergeCell(sheet, cell(1, 4+i), cell(2, 4+i))
+		f.SetCellValue(sheet, cell(3, 4+i), ": "+line[1])
+	}
+
+	row := 8
+	for i, c := range kirColumns {
+		f.SetCellValue(sheet, cell(i+1, row), c.title)
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		f.SetColWidth(sheet, col, col, c.width/2)
+	}
+	f.SetCellStyle(sheet, cell(1, row), cell(len(kirColumns), row), headerStyle)
+
+	for i, g := range kir.Groups {
+		for j, u := range g.Units {
+			row++
+			if j == 0 {
+				f.SetCellValue(sheet, cell(1, row), i+1)
+				f.SetCellValue(sheet, cell(2, row), g.Barang.Nama)
+				f.SetCellValue(sheet, cell(3, row), g.Barang.SKU)
+				f.SetCellValue(sheet, cell(4, row), g.Barang.Kategori.Nama)
+			}
+			f.SetCellValue(sheet, cell(5, row), u.NoSeri)
+			f.SetCellValue(sheet, cell(6, row), string(u.Kondisi))
+			f.SetCellValue(sheet, cell(7, row), u.TglPerolehan)
+			f.SetCellValue(sheet, cell(8, row), g.Barang.HargaSatuan)
+
+			f.SetCellStyle(sheet, cell(1, row), cell(6, row), bodyStyle)
+			f.SetCellStyle(sheet, cell(7, row), cell(7, row), dateStyle)
+			f.SetCellStyle(sheet, cell(8, row), cell(8, row), moneyStyle)
+		}
+	}
+
+	row++
+	f.SetCellValue(sheet, cell(1, row), fmt.Sprintf("Jumlah %d unit dari %d jenis barang", kir.TotalUnit, len(kir.Groups)))
+	f.MergeCell(sheet, cell(1, row), cell(len(kirColumns)-1, row))
+	f.SetCellValue(sheet, cell(len(kirColumns), row), kir.TotalNilai)
+	f.SetCellStyle(sheet, cell(1, row), cell(len(kirColumns), row), totalStyle)
+
+	signCol := len(kirColumns) - 1
+	row += 2
+	f.SetCellValue(sheet, cell(signCol, row), fmt.Sprintf("%s, %s", kir.Ruangan.Lokasi.Nama, entities.FormatTanggal(kir.Dicetak)))
+	f.MergeCell(sheet, cell(signCol, row), cell(signCol+1, row))
+	f.SetCellStyle(sheet, cell(signCol, row), cell(signCol, row), centerStyle)
+	row++
+	f.SetCellValue(sheet, cell(signCol, row), "Penanggung Jawab Ruangan")
+	f.MergeCell(sheet, cell(signCol, row), cell(signCol+1, row))
+	f.SetCellStyle(sheet, cell(signCol, row), cell(signCol, row), centerStyle)
+	row += 4
+	f.SetCellValue(sheet, cell(signCol, row), kir.Ruangan.PenanggungJawab)
+	f.MergeCell(sheet, cell(signCol, row), cell(signCol+1, row))
+	f.SetCellStyle(sheet, cell(signCol, row), cell(signCol, row), signStyle)
+
+	orientation := "landscape"
+	size := 9
+	f.SetPageLayout(sheet, &excelize.PageLayoutOptions{Size: &size, Orientation: &orientation})
+
+	return f.Write(w)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/qeunasd/coniven/entities"
+)
+
+// A4 sheet of 3 x 8 labels of 70 x 37 mm, the common 24-up layout.
+const (
+	labelCols    = 3
+	labelRows    = 8
+	labelW       = 70.0
+	labelH       = 37.0
+	labelPadding = 3.0
+	labelQRSize  = 24.0
+)
+
+func registerPNG(pdf *gofpdf.Fpdf, name string, code barcode.Barcode, w, h int) error {
+	scaled, err := barcode.Scale(code, w, h)
+	if err != nil {
+		return err
+	}
+
+	// gofpdf only reads 8-bit PNGs while barcode images are 16-bit
+	gray := image.NewGray(scaled.Bounds())
+	draw.Draw(gray, gray.Bounds(), scaled, scaled.Bounds().Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gray); err != nil {
+		return err
+	}
+
+	pdf.RegisterImageOptionsReader(name, gofpdf.ImageOptions{ImageType: "PNG"}, &buf)
+	return pdf.Error()
+}
+
+func writeLabelsPDF(w io.Writer, labels []entities.Label) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pageW, pageH := pdf.GetPageSize()
+	marginX := (pageW - labelCols*labelW) / 2
+	marginY := (pageH - labelRows*labelH) / 2
+	pdf.SetMargins(marginX, marginY, marginX)
+	pdf.SetAutoPageBreak(false, 0)
+
+	textW := labelW - labelQRSize - 3*labelPadding
+	fit := func(text string) string {
+		text = tr(text)
+		for len(text) > 0 && pdf.GetStringWidth(text) > textW {
+			text = text[:len(text)-1]
+		}
+		return text
+	}
+
+	pdf.AddPage()
+	if len(labels) == 0 {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 8, "tidak ada unit", "", 1, "C", false, 0, "")
+		return pdf.Output(w)
+	}
+
+	perPage := labelCols * labelRows
+	for i, label := range labels {
+		if i > 0 && i%perPage == 0 {
+			pdf.AddPage()
+		}
+
+		pos := i % perPage
+		x := marginX + float64(pos%labelCols)*labelW
+		y := marginY + float64(pos/labelCols)*labelH
+		id := label.Unit.Id.String()
+
+		pdf.SetDrawColor(200, 200, 200)
+		pdf.Rect(x, y, labelW, labelH, "D")
+
+		qrCode, err := qr.Encode(label.Payload, qr.M, qr.Auto)
+		if err != nil {
+			return err
+		}
+		if err := registerPNG(pdf, "qr-"+id, qrCode, 256, 256); err != nil {
+			return err
+		}
+		pdf.ImageOptions("qr-"+id, x+labelPadding, y+labelPadding, labelQRSize, labelQRSize, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+		pdf.SetFont("Helvetica", "", 5)
+		pdf.SetXY(x+labelPadding, y+labelPadding+labelQRSize+0.5)
+		pdf.CellFormat(labelQRSize, 3, id[:8], "", 0, "C", false, 0, "")
+
+		textX := x + labelQRSize + 2*labelPadding
+		pdf.SetXY(textX, y+labelPadding)
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.CellFormat(textW, 4, fit(label.Unit.Barang.Nama), "", 2, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 7)
+		for _, line := range []string{
+			"SKU: " + label.Unit.Barang.SKU,
+			"No. Seri: " + label.Unit.NoSeri,
+			"Ruangan: " + label.Unit.Ruangan.Nama,
+		} {
+			pdf.CellFormat(textW, 3.5, fit(line), "", 2, "L", false, 0, "")
+		}
+
+		// serials outside the Code128 character set only get the QR code
+		barCode, err := code128.Encode(label.Barcode())
+		if err != nil {
+			continue
+		}
+		if err := registerPNG(pdf, "bc-"+id, barCode, barCode.Bounds().Dx()*4, 80); err != nil {
+			return err
+		}
+		pdf.ImageOptions("bc-"+id, textX, y+labelH-labelPadding-9, textW, 9, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	}
+
+	return pdf.Output(w)
+}
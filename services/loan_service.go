@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+var loanTableConfig = utils.TableConfig{
+	QueryCols: []string{"peminjam", "identitas", "keperluan"},
+	SortCols: []utils.AllowedSort{
+		{Name: "dt", Column: "tgl_pinjam"},
+		{Name: "tempo", Column: "tgl_jatuh_tempo"},
+		{Name: "nama", Column: "peminjam"},
+		{Name: "status", Column: "status"},
+	},
+	DefaultSort: "dt",
+	Filters: map[string]utils.AllowedFilter{
+		"dmin":   {Column: "peminjaman.tgl_dibuat", Operator: "gte"},
+		"dmax":   {Column: "peminjaman.tgl_dibuat", Operator: "lte"},
+		"status": {Column: "peminjaman.status", Operator: "in"},
+	},
+}
+
+type LoanService interface {
+	GetLoansWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error)
+	GetUnitsForLoan(ctx context.Context, ids []string) ([]entities.ItemUnit, error)
+	CheckoutUnits(ctx context.Context, req entities.LoanForm) (entities.Loan, error)
+	GetLoan(ctx context.Context, id string) (entities.Loan, error)
+	ReturnUnits(ctx context.Context, id string, req entities.LoanReturnForm) error
+	GetOverdueUnits(ctx context.Context) ([]entities.LoanUnit, error)
+	GetBorrowerHistory(ctx context.Context, identitas string) (entities.BorrowerHistory, error)
+}
+
+type loanService struct {
+	storage storage.LoanRepository
+}
+
+func NewLoanService(storage storage.LoanRepository) LoanService {
+	return &loanService{storage: storage}
+}
+
+func (s *loanService) GetLoansWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
+	params.SetColumnSearch(loanTableConfig.QueryCols...)
+	params.SetFilters(loanTableConfig.Filters)
+	where, args := utils.BuildWhereClauses(params)
+
+	total, err := s.storage.CountLoans(ctx, where, args)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("counting peminjaman: %w", err)
+	}
+
+	totalPage := (total + params.PerPage - 1) / params.PerPage
+	if params.Page > totalPage && totalPage > 0 {
+		params.Page = totalPage
+	}
+
+	sort := utils.BuildSortClause(params, loanTableConfig)
+	limit := utils.BuildLimitClause(params)
+
+	loans, err := s.storage.GetLoans(ctx, limit, sort, where, args)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("getting peminjaman: %w", err)
+	}
+
+	return utils.PaginationResult{
+		Data:      loans,
+		TotalData: int64(total),
+		Page:      params.Page,
+		PerPage:   params.PerPage,
+		TotalPage: totalPage,
+	}, nil
+}
+
+// GetUnitsForLoan reads the units picked on a room page for the loan form.
+// Ids that do not parse are left out.
+func (s *loanService) GetUnitsForLoan(ctx context.Context, ids []string) ([]entities.ItemUnit, error) {
+	var parsed []uuid.UUID
+	for _, raw := range ids {
+		if id, err := uuid.Parse(strings.TrimSpace(raw)); err == nil {
+			parsed = append(parsed, id)
+		}
+	}
+
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+
+	units, err := s.storage.GetUnitsByIds(ctx, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("getting units by ids: %w", err)
+	}
+
+	return units, nil
+}
+
+func (s *loanService) CheckoutUnits(ctx context.Context, req entities.LoanForm) (entities.Loan, error) {
+	now := time.Now()
+
+	loanReq, err := entities.NewLoanRequest(req, now)
+	if err != nil {
+		return entities.Loan{}, err
+	}
+
+	loanReq.Loan.Petugas = "system"
+	if user, ok := entities.ActorFrom(ctx); ok {
+		loanReq.Loan.IdPengguna = uuid.NullUUID{UUID: user.Id, Valid: true}
+		loanReq.Loan.Petugas = user.Username
+	}
+
+	loan := loanReq.Loan
+	err = s.storage.CheckoutUnits(ctx, *loanReq, func(units []entities.ItemUnit, onLoan map[uuid.UUID]entities.Loan) ([]entities.LoanUnit, error) {
+		if len(units) != len(loanReq.Units) {
+			return nil, utils.WebError{Field: "Units", Message: "sebagian unit tidak ditemukan, muat ulang halaman"}
+		}
+
+		loanUnits := make([]entities.LoanUnit, 0, len(units))
+		for _, u := range units {
+			if other, ok := onLoan[u.Id]; ok {
+				return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("unit %s sedang dipinjam oleh %s", u.NoSeri, other.Peminjam)}
+			}
+
+			if u.Kondisi != entities.KondisiBaik {
+				return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("unit %s berkondisi %s, hanya unit baik yang dapat dipinjam", u.NoSeri, u.Kondisi)}
+			}
+
+			if loan.TglPinjam.Before(entities.DateOf(u.TglPerolehan)) {
+				return nil, utils.WebError{Field: "TglPinjam", Message: fmt.Sprintf("tanggal pinjam sebelum unit %s diperoleh", u.NoSeri)}
+			}
+
+			loanUnits = append(loanUnits, entities.LoanUnit{
+				IdPeminjaman:  loan.Id,
+				IdUnit:        u.Id,
+				KondisiPinjam: u.Kondisi,
+				Unit:          u,
+			})
+		}
+
+		loan.Units = loanUnits
+		return loanUnits, nil
+	}, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditPinjam, loan.Id, entities.AuditCreate, nil, loan)
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return entities.Loan{}, err
+		}
+		if errors.Is(err, storage.ErrUnitOnLoan) {
+			return entities.Loan{}, utils.WebError{Field: "Units", Message: "sebagian unit baru saja dipinjam, muat ulang halaman", Conflict: true}
+		}
+		return entities.Loan{}, fmt.Errorf("checking out units: %w", err)
+	}
+
+	return loan, nil
+}
+
+func (s *loanService) GetLoan(ctx context.Context, id string) (entities.Loan, error) {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return entities.Loan{}, utils.ErrInvalidId
+	}
+
+	loan, err := s.storage.GetLoanById(ctx, resId)
+	if err != nil {
+		return entities.Loan{}, fmt.Errorf("getting peminjaman by id: %w", err)
+	}
+
+	loan.Units, err = s.storage.GetLoanUnits(ctx, loan.Id)
+	if err != nil {
+		return entities.Loan{}, fmt.Errorf("getting units of peminjaman %v: %w", loan.Id, err)
+	}
+
+	return loan, nil
+}
+
+func (s *loanService) ReturnUnits(ctx context.Context, id string, req entities.LoanReturnForm) error {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	returnReq, err := entities.NewLoanReturnRequest(req, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var before entities.Loan
+	var returned []entities.LoanUnit
+
+	err = s.storage.ReturnUnits(ctx, resId, func(loan entities.Loan, units []entities.LoanUnit) ([]entities.LoanUnit, error) {
+		if returnReq.Tanggal.Before(loan.TglPinjam) {
+			return nil, utils.WebError{Field: "Tanggal", Message: "tanggal kembali tidak boleh sebelum tanggal pinjam"}
+		}
+
+		picked := make([]entities.LoanUnit, 0, len(returnReq.Units))
+		for _, u := range units {
+			ret, ok := returnReq.Units[u.IdUnit]
+			if !ok {
+				continue
+			}
+
+			if u.IsReturned() {
+				return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("unit %s sudah dikembalikan, muat ulang halaman", u.Unit.NoSeri), Conflict: true}
+			}
+
+			if ret.Kondisi != u.Unit.Kondisi && !u.Unit.Kondisi.CanSetManually(ret.Kondisi) {
+				return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("unit %s tidak dapat kembali dari %s menjadi %s", u.Unit.NoSeri, u.Unit.Kondisi, ret.Kondisi)}
+			}
+
+			tanggal := returnReq.Tanggal
+			u.TglKembali = &tanggal
+			u.KondisiKembali = ret.Kondisi
+			u.CatatanKembali = ret.Catatan
+			picked = append(picked, u)
+		}
+
+		if len(picked) != len(returnReq.Units) {
+			return nil, utils.WebError{Field: "Units", Message: "sebagian unit bukan bagian dari peminjaman ini"}
+		}
+
+		loan.Units = units
+		before = loan
+		returned = picked
+		return picked, nil
+	}, func(rec storage.AuditRecorder) error {
+		after := before
+		after.Units = make([]entities.LoanUnit, len(before.Units))
+		copy(after.Units, before.Units)
+		for _, r := range returned {
+			for i := range after.Units {
+				if after.Units[i].IdUnit == r.IdUnit {
+					after.Units[i] = r
+				}
+			}
+		}
+		after.JumlahKembali += len(returned)
+		if after.JumlahKembali == after.JumlahUnit {
+			after.Status = entities.PeminjamanSelesai
+		}
+
+		if err := recordAudit(ctx, rec, entities.AuditPinjam, after.Id, entities.AuditUpdate, before, after); err != nil {
+			return err
+		}
+
+		for _, r := range returned {
+			if r.KondisiKembali == r.Unit.Kondisi {
+				continue
+			}
+
+			changed := r.Unit
+			changed.Kondisi = r.KondisiKembali
+			if err := recordAudit(ctx, rec, entities.AuditUnit, r.IdUnit, entities.AuditUpdate, r.Unit, changed); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		return fmt.Errorf("returning units of peminjaman %v: %w", resId, err)
+	}
+
+	return nil
+}
+
+func (s *loanService) GetOverdueUnits(ctx context.Context) ([]entities.LoanUnit, error) {
+	units, err := s.storage.GetOverdueLoanUnits(ctx, entities.DateOf(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("getting overdue units: %w", err)
+	}
+
+	return units, nil
+}
+
+func (s *loanService) GetBorrowerHistory(ctx context.Context, identitas string) (entities.BorrowerHistory, error) {
+	identitas = strings.ToUpper(strings.TrimSpace(identitas))
+	if identitas == "" {
+		return entities.BorrowerHistory{}, utils.ErrInvalidId
+	}
+
+	units, err := s.storage.GetLoanUnitsByBorrower(ctx, identitas)
+	if err != nil {
+		return entities.BorrowerHistory{}, fmt.Errorf("getting units of borrower %s: %w", identitas, err)
+	}
+
+	if len(units) == 0 {
+		return entities.BorrowerHistory{}, utils.ErrNotFound
+	}
+
+	return entities.NewBorrowerHistory(identitas, units, time.Now()), nil
+}
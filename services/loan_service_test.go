@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+type fakeLoanStorage struct {
+	storage.LoanRepository
+	unit entities.ItemUnit
+}
+
+func (f *fakeLoanStorage) CheckoutUnits(ctx context.Context, req entities.LoanRequest, build storage.LoanBuilder, audit storage.Audit) error {
+	_, err := build([]entities.ItemUnit{f.unit}, map[uuid.UUID]entities.Loan{})
+	return err
+}
+
+func TestLoanDateFromAcquisition(t *testing.T) {
+	tests := []struct {
+		name    string
+		pinjam  string
+		wantErr bool
+	}{
+		{"sebelum perolehan", "2024-01-09", true},
+		{"hari perolehan", "2024-01-10", false},
+		{"setelah perolehan sebelum didata", "2024-03-01", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit := backdatedUnit()
+			svc := NewLoanService(&fakeLoanStorage{unit: unit})
+
+			_, err := svc.CheckoutUnits(context.Background(), entities.LoanForm{
+				Units:      []string{unit.Id.String()},
+				Peminjam:   "Budi",
+				Identitas:  "1234",
+				Keperluan:  "praktikum",
+				TglPinjam:  tt.pinjam,
+				JatuhTempo: "2024-12-31",
+			})
+
+			if tt.wantErr {
+				var webErr utils.WebError
+				if !errors.As(err, &webErr) || webErr.Field != "TglPinjam" {
+					t.Errorf("CheckoutUnits error = %v, want a TglPinjam error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("CheckoutUnits error = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/google/uuid"
@@ -18,8 +19,9 @@ type LocationService interface {
 	// Operation Server
 	GetLocationsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error)
 	GetTotalLocations(ctx context.Context) (int, error)
-	CreateLocation(ctx context.Context, name, code string) error
+	CreateLocation(ctx context.Context, name, code, parent string) (entities.Location, error)
 	EditLocation(ctx context.Context, slug, name, code string) error
+	MoveLocation(ctx context.Context, slug, parent string) error
 	DeleteLocation(ctx context.Context, id string) error
 	GetLocationBySlug(ctx context.Context, slug string) (entities.Location, error)
 	ViewDetailLocation(ctx context.Context, slug string) (*entities.Location, error)
@@ -29,6 +31,13 @@ type locationService struct {
 	storage storage.LocationRepository
 }
 
+// LocationFilters are the filter query parameters the location list accepts.
+var LocationFilters = map[string]utils.AllowedFilter{
+	"dmin": {Column: "lokasi.tgl_dibuat", Operator: "gte"},
+	"dmax": {Column: "lokasi.tgl_dibuat", Operator: "lte"},
+	"jr":   {Column: "lokasi.jumlah_ruangan", Operator: "eq"},
+}
+
 var locationTableConfig = utils.TableConfig{
 	QueryCols: []string{"nama", "kode"},
 	SortCols: []utils.AllowedSort{
@@ -38,18 +47,91 @@ var locationTableConfig = utils.TableConfig{
 		{Name: "jr", Column: "jumlah_ruangan"},
 	},
 	DefaultSort: "dt",
+	DeletedCol:  "tgl_dihapus",
+	Filters:     LocationFilters,
 }
 
+var errParentNotFound = utils.WebError{Field: "Induk", Message: "lokasi induk tidak ditemukan"}
+
 func NewLocationService(storage storage.LocationRepository) LocationService {
 	return &locationService{storage: storage}
 }
 
+// GetLocationsForUI lists every live location with its breadcrumb, ordered by
+// the full name so each location follows its parent in a picker.
 func (l *locationService) GetLocationsForUI(ctx context.Context) ([]entities.Location, error) {
-	return l.storage.GetLocations(ctx, "", "", "", nil)
+	where, args := liveWhere(locationTableConfig)
+	locations, err := l.storage.GetLocations(ctx, "", "", where, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.fillHierarchy(ctx, locations, false); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].NamaLengkap() < locations[j].NamaLengkap()
+	})
+
+	return locations, nil
+}
+
+// fillHierarchy sets the breadcrumb of each location and, when rollup is set,
+// its room and unit count over the subtree.
+func (l *locationService) fillHierarchy(ctx context.Context, locations []entities.Location, rollup bool) error {
+	if len(locations) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(locations))
+	for i, loc := range locations {
+		ids[i] = loc.Id
+	}
+
+	paths, err := l.storage.GetLocationPaths(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	var rollups map[uuid.UUID]entities.LocationRollup
+	if rollup {
+		if rollups, err = l.storage.GetLocationRollups(ctx, ids); err != nil {
+			return err
+		}
+	}
+
+	for i := range locations {
+		locations[i].Jalur = paths[locations[i].Id]
+		if rollup {
+			locations[i].TotalRuangan = rollups[locations[i].Id].Ruangan
+			locations[i].TotalUnit = rollups[locations[i].Id].Unit
+		}
+	}
+
+	return nil
+}
+
+// parseParent resolves the parent picked on a location form; an empty value
+// is the top level.
+func parseParent(parent string) (uuid.NullUUID, error) {
+	parent = strings.TrimSpace(parent)
+	if parent == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	id, err := uuid.Parse(parent)
+	if err != nil {
+		return uuid.NullUUID{}, errParentNotFound
+	}
+
+	return uuid.NullUUID{UUID: id, Valid: true}, nil
 }
 
 func (l *locationService) GetLocationsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
 	params.SetColumnSearch(locationTableConfig.QueryCols...)
+	params.SetFilters(locationTableConfig.Filters)
+	params.ExcludeDeleted(locationTableConfig.DeletedCol)
 	where, args := utils.BuildWhereClauses(params)
 
 	total, err := l.storage.CountTotalLocations(ctx, where, args)
@@ -70,6 +152,10 @@ func (l *locationService) GetLocationsWithFilter(ctx context.Context, params uti
 		return utils.PaginationResult{}, err
 	}
 
+	if err := l.fillHierarchy(ctx, locations, true); err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("getting location hierarchy: %w", err)
+	}
+
 	return utils.PaginationResult{
 		Data:      locations,
 		TotalData: int64(total),
@@ -80,29 +166,50 @@ func (l *locationService) GetLocationsWithFilter(ctx context.Context, params uti
 }
 
 func (l *locationService) GetTotalLocations(ctx context.Context) (int, error) {
-	return l.storage.CountTotalLocations(ctx, "", nil)
+	where, args := liveWhere(locationTableConfig)
+	return l.storage.CountTotalLocations(ctx, where, args)
 }
 
-func (l *locationService) CreateLocation(ctx context.Context, name string, code string) error {
+func (l *locationService) CreateLocation(ctx context.Context, name, code, parent string) (entities.Location, error) {
 	loc, err := entities.NewLocation(code, name)
 	if err != nil {
-		return err
+		return entities.Location{}, err
+	}
+
+	loc.IdInduk, err = parseParent(parent)
+	if err != nil {
+		return entities.Location{}, err
+	}
+
+	if loc.IdInduk.Valid {
+		if _, err := l.storage.GetLocationById(ctx, loc.IdInduk.UUID); err != nil {
+			if errors.Is(err, utils.ErrNotFound) {
+				return entities.Location{}, errParentNotFound
+			}
+			return entities.Location{}, fmt.Errorf("getting parent location: %w", err)
+		}
 	}
 
 	exist, err := l.storage.FindLocationByCode(ctx, loc.Kode)
 	if err != nil {
-		return fmt.Errorf("finding location: %w", err)
+		return entities.Location{}, fmt.Errorf("finding location: %w", err)
 	}
 
 	if exist {
-		return utils.WebError{Field: "Kode", Message: "kode sudah terpakai"}
+		return entities.Location{}, utils.WebError{Field: "Kode", Message: "kode sudah terpakai", Conflict: true}
 	}
 
-	if err := l.storage.SaveLocation(ctx, *loc); err != nil {
-		return err
+	err = l.storage.SaveLocation(ctx, *loc, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditLokasi, loc.Id, entities.AuditCreate, nil, loc)
+	})
+	if errors.Is(err, storage.ErrDuplicate) {
+		return entities.Location{}, utils.WebError{Field: "Kode", Message: "kode sudah terpakai", Conflict: true}
+	}
+	if err != nil {
+		return entities.Location{}, err
 	}
 
-	return nil
+	return *loc, nil
 }
 
 func (l *locationService) EditLocation(ctx context.Context, slug, name, code string) error {
@@ -115,11 +222,9 @@ func (l *locationService) EditLocation(ctx context.Context, slug, name, code str
 
 	loc, err := l.storage.GetLocationBySlug(ctx, slug)
 	if err != nil {
-		if err.Error() == "not found" {
-			return err
-		}
 		return fmt.Errorf("getting location by slug: %w", err)
 	}
+	before := loc
 
 	if code != "" && code != loc.Kode {
 		exist, err := l.storage.FindLocationByCode(ctx, code)
@@ -128,7 +233,7 @@ func (l *locationService) EditLocation(ctx context.Context, slug, name, code str
 		}
 
 		if exist {
-			return utils.WebError{Field: "Kode", Message: "kode sudah terpakai"}
+			return utils.WebError{Field: "Kode", Message: "kode sudah terpakai", Conflict: true}
 		}
 
 		loc.Kode = code
@@ -139,13 +244,65 @@ func (l *locationService) EditLocation(ctx context.Context, slug, name, code str
 		loc.Slug = utils.NewSlug(name)
 	}
 
-	if err := l.storage.UpdateLocation(ctx, loc); err != nil {
+	err = l.storage.UpdateLocation(ctx, loc, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditLokasi, loc.Id, entities.AuditUpdate, before, loc)
+	})
+	if errors.Is(err, storage.ErrDuplicate) {
+		return utils.WebError{Field: "Kode", Message: "kode sudah terpakai", Conflict: true}
+	}
+	if err != nil {
 		return fmt.Errorf("updating location with id %v: %w", loc.Id, err)
 	}
 
 	return nil
 }
 
+func (l *locationService) MoveLocation(ctx context.Context, slug, parent string) error {
+	loc, err := l.storage.GetLocationBySlug(ctx, slug)
+	if err != nil {
+		return fmt.Errorf("getting location by slug: %w", err)
+	}
+
+	dest, err := parseParent(parent)
+	if err != nil {
+		return err
+	}
+
+	if dest == loc.IdInduk {
+		return nil
+	}
+
+	var before entities.Location
+	err = l.storage.MoveLocation(ctx, loc.Id, dest, func(locked entities.Location, path []entities.LocationRef) error {
+		before = locked
+		if !dest.Valid {
+			return nil
+		}
+		if path == nil {
+			return errParentNotFound
+		}
+		for _, ref := range path {
+			if ref.Id == locked.Id {
+				return utils.WebError{Field: "Induk", Message: fmt.Sprintf("%s tidak dapat dipindahkan ke dalam dirinya sendiri atau sublokasinya", locked.Nama), Conflict: true}
+			}
+		}
+		return nil
+	}, func(rec storage.AuditRecorder) error {
+		after := before
+		after.IdInduk = dest
+		return recordAudit(ctx, rec, entities.AuditLokasi, before.Id, entities.AuditUpdate, before, after)
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		return fmt.Errorf("moving location with id %v: %w", loc.Id, err)
+	}
+
+	return nil
+}
+
 func (l *locationService) GetLocationBySlug(ctx context.Context, slug string) (entities.Location, error) {
 	return l.storage.GetLocationBySlug(ctx, slug)
 }
@@ -153,18 +310,17 @@ func (l *locationService) GetLocationBySlug(ctx context.Context, slug string) (e
 func (l *locationService) DeleteLocation(ctx context.Context, id string) error {
 	resId, err := uuid.Parse(id)
 	if err != nil {
-		return errors.New("invalid id")
+		return utils.ErrInvalidId
 	}
 
 	loc, err := l.storage.GetLocationById(ctx, resId)
 	if err != nil {
-		if err.Error() == "not found" {
-			return err
-		}
 		return fmt.Errorf("getting location by slug: %w", err)
 	}
 
-	err = l.storage.DeleteLocation(ctx, loc.Id)
+	err = l.storage.DeleteLocation(ctx, loc.Id, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditLokasi, loc.Id, entities.AuditDelete, loc, nil)
+	})
 	if err != nil {
 		return fmt.Errorf("deleting category with id %v: %w", loc.Id, err)
 	}
@@ -175,9 +331,6 @@ func (l *locationService) DeleteLocation(ctx context.Context, id string) error {
 func (l *locationService) ViewDetailLocation(ctx context.Context, slug string) (*entities.Location, error) {
 	loc, err := l.storage.GetLocationBySlug(ctx, slug)
 	if err != nil {
-		if err.Error() == "not found" {
-			return nil, err
-		}
 		return nil, fmt.Errorf("getting location by slug: %w", err)
 	}
 
@@ -186,5 +339,19 @@ func (l *locationService) ViewDetailLocation(ctx context.Context, slug string) (
 		return nil, err
 	}
 
-	return locWithRoom, nil
+	subs, err := l.storage.GetSubLocations(ctx, loc.Id)
+	if err != nil {
+		return nil, fmt.Errorf("getting sub locations: %w", err)
+	}
+
+	// The location itself goes through the same lookup as its children so one
+	// query serves the whole page.
+	all := append([]entities.Location{*locWithRoom}, subs...)
+	if err := l.fillHierarchy(ctx, all, true); err != nil {
+		return nil, fmt.Errorf("getting location hierarchy: %w", err)
+	}
+
+	all[0].Ruangan = locWithRoom.Ruangan
+	all[0].Sublokasi = all[1:]
+	return &all[0], nil
 }
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+var opnameTableConfig = utils.TableConfig{
+	QueryCols: []string{"nama_cakupan", "petugas"},
+	SortCols: []utils.AllowedSort{
+		{Name: "dt", Column: "tgl_mulai"},
+		{Name: "nama", Column: "nama_cakupan"},
+		{Name: "status", Column: "status"},
+	},
+	DefaultSort: "dt",
+	Filters: map[string]utils.AllowedFilter{
+		"status": {Column: "stok_opname.status", Operator: "in"},
+	},
+}
+
+type OpnameService interface {
+	GetOpnamesWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error)
+	StartOpname(ctx context.Context, req entities.OpnameForm) (entities.StockOpname, error)
+	GetOpnameReport(ctx context.Context, id string) (entities.OpnameReport, error)
+	ScanUnit(ctx context.Context, id, kode string) (entities.OpnameScan, error)
+	SaveOpnameNote(ctx context.Context, id, idUnit, catatan string) error
+	CloseOpname(ctx context.Context, id string, req entities.OpnameCloseForm) error
+	LookupUnits(ctx context.Context, kode string) ([]entities.ItemUnit, error)
+}
+
+type opnameService struct {
+	storage storage.OpnameRepository
+}
+
+func NewOpnameService(storage storage.OpnameRepository) OpnameService {
+	return &opnameService{storage: storage}
+}
+
+func (s *opnameService) GetOpnamesWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
+	params.SetColumnSearch(opnameTableConfig.QueryCols...)
+	params.SetFilters(opnameTableConfig.Filters)
+	where, args := utils.BuildWhereClauses(params)
+
+	total, err := s.storage.CountOpnames(ctx, where, args)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("counting stok opname: %w", err)
+	}
+
+	totalPage := (total + params.PerPage - 1) / params.PerPage
+	if params.Page > totalPage && totalPage > 0 {
+		params.Page = totalPage
+	}
+
+	sort := utils.BuildSortClause(params, opnameTableConfig)
+	limit := utils.BuildLimitClause(params)
+
+	opnames, err := s.storage.GetOpnames(ctx, limit, sort, where, args)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("getting stok opname: %w", err)
+	}
+
+	return utils.PaginationResult{
+		Data:      opnames,
+		TotalData: int64(total),
+		Page:      params.Page,
+		PerPage:   params.PerPage,
+		TotalPage: totalPage,
+	}, nil
+}
+
+func (s *opnameService) StartOpname(ctx context.Context, req entities.OpnameForm) (entities.StockOpname, error) {
+	op, err := entities.NewOpname(req, time.Now())
+	if err != nil {
+		return entities.StockOpname{}, err
+	}
+
+	if op.IsRoom() {
+		room, err := s.storage.GetRoomById(ctx, op.IdRuangan.UUID)
+		if err != nil {
+			if errors.Is(err, utils.ErrNotFound) {
+				return entities.StockOpname{}, utils.WebError{Field: "Cakupan", Message: "ruangan tidak ditemukan"}
+			}
+			return entities.StockOpname{}, fmt.Errorf("getting room by id: %w", err)
+		}
+		op.IdLokasi = room.LokasiId
+		op.NamaCakupan = room.Nama
+	} else {
+		loc, err := s.storage.GetLocationById(ctx, op.IdLokasi)
+		if err != nil {
+			if errors.Is(err, utils.ErrNotFound) {
+				return entities.StockOpname{}, utils.WebError{Field: "Cakupan", Message: "lokasi tidak ditemukan"}
+			}
+			return entities.StockOpname{}, fmt.Errorf("getting location by id: %w", err)
+		}
+		op.NamaCakupan = loc.Nama
+	}
+
+	op.Petugas = "system"
+	if user, ok := entities.ActorFrom(ctx); ok {
+		op.IdPengguna = uuid.NullUUID{UUID: user.Id, Valid: true}
+		op.Petugas = user.Username
+	}
+
+	err = s.storage.CreateOpname(ctx, *op, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditOpname, op.Id, entities.AuditCreate, nil, op)
+	})
+	if err != nil {
+		return entities.StockOpname{}, fmt.Errorf("creating stok opname: %w", err)
+	}
+
+	return *op, nil
+}
+
+func (s *opnameService) GetOpnameReport(ctx context.Context, id string) (entities.OpnameReport, error) {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return entities.OpnameReport{}, utils.ErrInvalidId
+	}
+
+	op, err := s.storage.GetOpnameById(ctx, resId)
+	if err != nil {
+		return entities.OpnameReport{}, fmt.Errorf("getting stok opname by id: %w", err)
+	}
+
+	units, err := s.storage.GetOpnameUnits(ctx, op.Id)
+	if err != nil {
+		return entities.OpnameReport{}, fmt.Errorf("getting units of stok opname %v: %w", op.Id, err)
+	}
+
+	return entities.NewOpnameReport(op, units), nil
+}
+
+// LookupUnits resolves a scanned code to units. A serial number is only
+// unique per item, so it may match several.
+func (s *opnameService) LookupUnits(ctx context.Context, kode string) ([]entities.ItemUnit, error) {
+	if strings.TrimSpace(kode) == "" {
+		return nil, utils.WebError{Field: "Kode", Message: "kode harus diisi"}
+	}
+
+	id, serial, ok := entities.ParseScanCode(kode)
+	if ok {
+		unit, err := s.storage.GetUnitById(ctx, id)
+		if err != nil {
+			if errors.Is(err, utils.ErrNotFound) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("getting unit by id: %w", err)
+		}
+		return []entities.ItemUnit{unit}, nil
+	}
+
+	units, err := s.storage.GetUnitsBySerial(ctx, serial)
+	if err != nil {
+		return nil, fmt.Errorf("getting units by serial: %w", err)
+	}
+
+	return units, nil
+}
+
+func (s *opnameService) ScanUnit(ctx context.Context, id, kode string) (entities.OpnameScan, error) {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return entities.OpnameScan{}, utils.ErrInvalidId
+	}
+
+	op, err := s.storage.GetOpnameById(ctx, resId)
+	if err != nil {
+		return entities.OpnameScan{}, fmt.Errorf("getting stok opname by id: %w", err)
+	}
+
+	if op.IsClosed() {
+		return entities.OpnameScan{}, utils.WebError{Field: "Kode", Message: "stok opname sudah ditutup"}
+	}
+
+	units, err := s.LookupUnits(ctx, kode)
+	if err != nil {
+		return entities.OpnameScan{}, err
+	}
+
+	// a serial shared by several items is settled by the units in scope
+	if len(units) > 1 {
+		captured, err := s.storage.GetOpnameUnits(ctx, op.Id)
+		if err != nil {
+			return entities.OpnameScan{}, fmt.Errorf("getting units of stok opname %v: %w", op.Id, err)
+		}
+
+		expected := make(map[uuid.UUID]bool)
+		for _, u := range captured {
+			expected[u.IdUnit] = u.Diharapkan
+		}
+
+		var inScope []entities.ItemUnit
+		for _, u := range units {
+			if expected[u.Id] {
+				inScope = append(inScope, u)
+			}
+		}
+		if len(inScope) == 1 {
+			units = inScope
+		}
+	}
+
+	switch len(units) {
+	case 0:
+		return entities.OpnameScan{}, utils.WebError{Field: "Kode", Message: fmt.Sprintf("kode %q tidak dikenal", strings.TrimSpace(kode))}
+	case 1:
+	default:
+		return entities.OpnameScan{}, utils.WebError{Field: "Kode", Message: "nomor seri dimiliki beberapa unit, pindai label QR"}
+	}
+
+	unit := units[0]
+	recorded, scanned, err := s.storage.RecordOpnameScan(ctx, op.Id, unit, strings.TrimSpace(kode), time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrOpnameClosed) {
+			return entities.OpnameScan{}, utils.WebError{Field: "Kode", Message: "stok opname sudah ditutup"}
+		}
+		return entities.OpnameScan{}, fmt.Errorf("recording scan of unit %v: %w", unit.Id, err)
+	}
+
+	return entities.OpnameScan{
+		Kode:          kode,
+		Unit:          unit,
+		Hasil:         recorded.Hasil(),
+		SudahDipindai: scanned,
+	}, nil
+}
+
+func (s *opnameService) SaveOpnameNote(ctx context.Context, id, idUnit, catatan string) error {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	unitId, err := uuid.Parse(idUnit)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	op, err := s.storage.GetOpnameById(ctx, resId)
+	if err != nil {
+		return fmt.Errorf("getting stok opname by id: %w", err)
+	}
+
+	if op.IsClosed() {
+		return utils.WebError{Field: "Catatan", Message: "stok opname sudah ditutup"}
+	}
+
+	if err := s.storage.UpdateOpnameNote(ctx, op.Id, unitId, strings.TrimSpace(catatan)); err != nil {
+		return fmt.Errorf("updating note of unit %v: %w", unitId, err)
+	}
+
+	return nil
+}
+
+func (s *opnameService) CloseOpname(ctx context.Context, id string, req entities.OpnameCloseForm) error {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	now := time.Now()
+	var closing entities.OpnameClosing
+
+	err = s.storage.CloseOpname(ctx, resId, func(op entities.StockOpname, units []entities.OpnameUnit, lastMoved map[uuid.UUID]time.Time, onLoan map[uuid.UUID]entities.Loan, dest entities.Room) (entities.OpnameClosing, error) {
+		if req.Pindahkan && !op.IsRoom() {
+			return entities.OpnameClosing{}, utils.WebError{Field: "Tutup", Message: "pemindahan unit hanya untuk stok opname ruangan"}
+		}
+
+		result := entities.OpnameClosing{Opname: op}
+		tanggal := entities.DateOf(now)
+
+		for _, u := range units {
+			// deleted since it joined the session
+			if u.Unit.Id == uuid.Nil {
+				continue
+			}
+
+			// out on loan, so neither missing nor to be moved
+			if _, ok := onLoan[u.Unit.Id]; ok {
+				continue
+			}
+
+			switch u.Hasil() {
+			case entities.HasilTidakDitemukan:
+				// a unit under repair is away at the vendor
+				if req.TandaiHilang && u.Unit.Kondisi != entities.KondisiPerbaikan && u.Unit.Kondisi.CanTransitionTo(entities.KondisiHilang) {
+					result.Hilang = append(result.Hilang, u.Unit)
+				}
+
+			case entities.HasilTidakTerduga:
+				if !req.Pindahkan || u.Unit.IdRuangan == dest.Id || u.Unit.Kondisi == entities.KondisiHilang {
+					continue
+				}
+				if last, ok := lastMoved[u.Unit.Id]; ok && tanggal.Before(last) {
+					continue
+				}
+
+				result.Transfers = append(result.Transfers, entities.Transfer{
+					Id:                uuid.New(),
+					IdUnit:            u.Unit.Id,
+					IdRuanganAsal:     uuid.NullUUID{UUID: u.Unit.IdRuangan, Valid: true},
+					IdRuanganTujuan:   uuid.NullUUID{UUID: dest.Id, Valid: true},
+					NamaRuanganAsal:   u.Unit.Ruangan.Nama,
+					NamaRuanganTujuan: dest.Nama,
+					Alasan:            fmt.Sprintf("stok opname %s: unit ditemukan di %s", tanggal.Format(entities.DateLayout), dest.Nama),
+					TglMutasi:         tanggal,
+					TglDibuat:         now,
+				})
+			}
+		}
+
+		closing = result
+		return result, nil
+	}, func(rec storage.AuditRecorder) error {
+		after := closing.Opname
+		after.Status = entities.OpnameSelesai
+		after.TglSelesai = &now
+		if err := recordAudit(ctx, rec, entities.AuditOpname, after.Id, entities.AuditUpdate, closing.Opname, after); err != nil {
+			return err
+		}
+
+		for _, u := range closing.Hilang {
+			lost := u
+			lost.Kondisi = entities.KondisiHilang
+			if err := recordAudit(ctx, rec, entities.AuditUnit, u.Id, entities.AuditUpdate, u, lost); err != nil {
+				return err
+			}
+		}
+
+		for _, t := range closing.Transfers {
+			if err := recordAudit(ctx, rec, entities.AuditMutasi, t.Id, entities.AuditCreate, nil, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		if errors.Is(err, storage.ErrOpnameClosed) {
+			return utils.WebError{Field: "Tutup", Message: "stok opname sudah ditutup"}
+		}
+		return fmt.Errorf("closing stok opname %v: %w", resId, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+var MaxPictureSize int64 = 5 << 20
+
+var allowedPictureTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+type PictureService interface {
+	UploadPicture(ctx context.Context, itemSlug, fileName string, size int64, r io.Reader) error
+	GetPicturesByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.ItemPicture, error)
+	GetPictureById(ctx context.Context, id string) (entities.ItemPicture, error)
+	OpenPicture(ctx context.Context, id string) (io.ReadCloser, storage.ObjectInfo, error)
+	DeletePicture(ctx context.Context, id string) (entities.ItemPicture, error)
+}
+
+type pictureService struct {
+	storage storage.PictureRepository
+}
+
+func NewPictureService(storage storage.PictureRepository) PictureService {
+	return &pictureService{storage: storage}
+}
+
+func (s *pictureService) UploadPicture(ctx context.Context, itemSlug, fileName string, size int64, r io.Reader) error {
+	if size <= 0 {
+		return utils.WebError{Field: "Gambar", Message: "file gambar kosong"}
+	}
+
+	if size > MaxPictureSize {
+		return utils.WebError{Field: "Gambar", Message: fmt.Sprintf("ukuran gambar maksimal %d MB", MaxPictureSize>>20)}
+	}
+
+	item, err := s.storage.GetItemBySlug(ctx, itemSlug)
+	if err != nil {
+		return fmt.Errorf("getting item by slug: %w", err)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("reading picture: %w", err)
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	ext, ok := allowedPictureTypes[contentType]
+	if !ok {
+		return utils.WebError{Field: "Gambar", Message: "format gambar harus jpg, png, webp atau gif"}
+	}
+
+	picture := entities.ItemPicture{
+		ObjectName: fmt.Sprintf("barang/%s/%s%s", item.Id, uuid.NewString(), ext),
+		FileName:   filepath.Base(strings.TrimSpace(fileName)),
+		FileSize:   size,
+		TglUpload:  time.Now(),
+		IdBarang:   item.Id,
+	}
+
+	body := io.MultiReader(bytes.NewReader(head), r)
+	err = s.storage.SavePicture(ctx, picture, body, contentType, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditGambar, picture.ObjectName, entities.AuditCreate, nil, picture)
+	})
+	if err != nil {
+		return fmt.Errorf("saving picture: %w", err)
+	}
+
+	return nil
+}
+
+func (s *pictureService) GetPicturesByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.ItemPicture, error) {
+	return s.storage.GetPicturesByItem(ctx, idBarang)
+}
+
+func (s *pictureService) GetPictureById(ctx context.Context, id string) (entities.ItemPicture, error) {
+	resId, err := strconv.Atoi(id)
+	if err != nil || resId <= 0 {
+		return entities.ItemPicture{}, utils.ErrInvalidId
+	}
+
+	return s.storage.GetPictureById(ctx, resId)
+}
+
+func (s *pictureService) OpenPicture(ctx context.Context, id string) (io.ReadCloser, storage.ObjectInfo, error) {
+	picture, err := s.GetPictureById(ctx, id)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+
+	return s.storage.OpenPicture(ctx, picture)
+}
+
+func (s *pictureService) DeletePicture(ctx context.Context, id string) (entities.ItemPicture, error) {
+	picture, err := s.GetPictureById(ctx, id)
+	if err != nil {
+		return entities.ItemPicture{}, err
+	}
+
+	err = s.storage.DeletePicture(ctx, picture, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditGambar, picture.ObjectName, entities.AuditDelete, picture, nil)
+	})
+	if err != nil {
+		return entities.ItemPicture{}, fmt.Errorf("deleting picture with id %d: %w", picture.Id, err)
+	}
+
+	return picture, nil
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+var repairTableConfig = utils.TableConfig{
+	QueryCols: []string{"no_seri", "nama_barang", "vendor", "masalah"},
+	SortCols: []utils.AllowedSort{
+		{Name: "dt", Column: "tgl_mulai"},
+		{Name: "nama", Column: "nama_barang"},
+		{Name: "biaya", Column: "biaya"},
+		{Name: "status", Column: "status"},
+	},
+	DefaultSort: "dt",
+	Filters: map[string]utils.AllowedFilter{
+		"dmin":   {Column: "perbaikan.tgl_dibuat", Operator: "gte"},
+		"dmax":   {Column: "perbaikan.tgl_dibuat", Operator: "lte"},
+		"status": {Column: "perbaikan.status", Operator: "in"},
+	},
+}
+
+type RepairService interface {
+	GetRepairsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error)
+	GetUnitForRepair(ctx context.Context, id string) (entities.ItemUnit, error)
+	OpenRepair(ctx context.Context, req entities.RepairForm) (entities.Repair, error)
+	GetRepair(ctx context.Context, id string) (entities.Repair, error)
+	CloseRepair(ctx context.Context, id string, req entities.RepairCloseForm) error
+	GetItemRepairs(ctx context.Context, idBarang uuid.UUID) (entities.RepairHistory, error)
+	GetRepairSpend(ctx context.Context, dari, sampai time.Time) (entities.RepairSpend, error)
+	WriteRepairSpendCSV(w io.Writer, spend entities.RepairSpend) error
+}
+
+type repairService struct {
+	storage storage.RepairRepository
+}
+
+func NewRepairService(storage storage.RepairRepository) RepairService {
+	return &repairService{storage: storage}
+}
+
+func (s *repairService) GetRepairsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
+	params.SetColumnSearch(repairTableConfig.QueryCols...)
+	params.SetFilters(repairTableConfig.Filters)
+	where, args := utils.BuildWhereClauses(params)
+
+	total, err := s.storage.CountRepairs(ctx, where, args)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("counting perbaikan: %w", err)
+	}
+
+	totalPage := (total + params.PerPage - 1) / params.PerPage
+	if params.Page > totalPage && totalPage > 0 {
+		params.Page = totalPage
+	}
+
+	sort := utils.BuildSortClause(params, repairTableConfig)
+	limit := utils.BuildLimitClause(params)
+
+	repairs, err := s.storage.GetRepairs(ctx, limit, sort, where, args)
+	if err != nil {
+		return utils.PaginationResult{}, fmt.Errorf("getting perbaikan: %w", err)
+	}
+
+	return utils.PaginationResult{
+		Data:      repairs,
+		TotalData: int64(total),
+		Page:      params.Page,
+		PerPage:   params.PerPage,
+		TotalPage: totalPage,
+	}, nil
+}
+
+func (s *repairService) GetUnitForRepair(ctx context.Context, id string) (entities.ItemUnit, error) {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return entities.ItemUnit{}, utils.ErrInvalidId
+	}
+
+	unit, err := s.storage.GetUnitById(ctx, resId)
+	if err != nil {
+		return entities.ItemUnit{}, fmt.Errorf("getting unit by id: %w", err)
+	}
+
+	return unit, nil
+}
+
+// OpenRepair records a ticket for a damaged unit and puts it under repair. A
+// unit already diperbaiki without an open ticket, as set before tickets
+// existed, may be given one.
+func (s *repairService) OpenRepair(ctx context.Context, req entities.RepairForm) (entities.Repair, error) {
+	repair, err := entities.NewRepair(req, time.Now())
+	if err != nil {
+		return entities.Repair{}, err
+	}
+
+	repair.Petugas = "system"
+	if user, ok := entities.ActorFrom(ctx); ok {
+		repair.IdPengguna = uuid.NullUUID{UUID: user.Id, Valid: true}
+		repair.Petugas = user.Username
+	}
+
+	var before entities.ItemUnit
+	err = s.storage.OpenRepair(ctx, repair.IdUnit, func(unit entities.ItemUnit, onLoan map[uuid.UUID]entities.Loan, open bool) (entities.Repair, error) {
+		if open {
+			return entities.Repair{}, utils.WebError{Field: "Unit", Message: fmt.Sprintf("unit %s masih memiliki tiket perbaikan yang berjalan", unit.NoSeri), Conflict: true}
+		}
+
+		if loan, ok := onLoan[unit.Id]; ok {
+			return entities.Repair{}, utils.WebError{Field: "Unit", Message: fmt.Sprintf("unit %s sedang dipinjam oleh %s", unit.NoSeri, loan.Peminjam)}
+		}
+
+		if unit.Kondisi != entities.KondisiPerbaikan && !unit.Kondisi.CanTransitionTo(entities.KondisiPerbaikan) {
+			return entities.Repair{}, utils.WebError{Field: "Unit", Message: fmt.Sprintf("unit %s berkondisi %s, hanya unit rusak yang dapat diperbaiki", unit.NoSeri, unit.Kondisi)}
+		}
+
+		if repair.TglMulai.Before(entities.DateOf(unit.TglPerolehan)) {
+			return entities.Repair{}, utils.WebError{Field: "TglMulai", Message: fmt.Sprintf("tanggal mulai sebelum unit %s diperoleh", unit.NoSeri)}
+		}
+
+		repair.IdRuangan = uuid.NullUUID{UUID: unit.IdRuangan, Valid: true}
+		repair.KondisiAwal = unit.Kondisi
+		repair.Unit = unit
+		before = unit
+		return *repair, nil
+	}, func(rec storage.AuditRecorder) error {
+		if err := recordAudit(ctx, rec, entities.AuditPerbaikan, repair.Id, entities.AuditCreate, nil, repair); err != nil {
+			return err
+		}
+
+		if before.Kondisi != entities.KondisiPerbaikan {
+			after := before
+			after.Kondisi = entities.KondisiPerbaikan
+			if err := recordAudit(ctx, rec, entities.AuditUnit, before.Id, entities.AuditUpdate, before, after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return entities.Repair{}, err
+		}
+		if errors.Is(err, storage.ErrRepairOpen) {
+			return entities.Repair{}, utils.WebError{Field: "Unit", Message: "unit baru saja masuk perbaikan, muat ulang halaman", Conflict: true}
+		}
+		return entities.Repair{}, fmt.Errorf("opening perbaikan: %w", err)
+	}
+
+	return *repair, nil
+}
+
+func (s *repairService) GetRepair(ctx context.Context, id string) (entities.Repair, error) {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return entities.Repair{}, utils.ErrInvalidId
+	}
+
+	repair, err := s.storage.GetRepairById(ctx, resId)
+	if err != nil {
+		return entities.Repair{}, fmt.Errorf("getting perbaikan by id: %w", err)
+	}
+
+	return repair, nil
+}
+
+// CloseRepair finishes a ticket and sets its unit to the outcome condition.
+// The ticket of a unit deleted since is closed without touching the unit.
+func (s *repairService) CloseRepair(ctx context.Context, id string, req entities.RepairCloseForm) error {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	closing, err := entities.NewRepairClosing(req, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var before, after entities.Repair
+	var unit entities.ItemUnit
+
+	err = s.storage.CloseRepair(ctx, resId, func(repair entities.Repair, locked entities.ItemUnit) (entities.Repair, error) {
+		if repair.IsClosed() {
+			return entities.Repair{}, utils.WebError{Field: "Kondisi", Message: "perbaikan sudah selesai, muat ulang halaman", Conflict: true}
+		}
+
+		if locked.Id != uuid.Nil && locked.Kondisi != entities.KondisiPerbaikan {
+			return entities.Repair{}, utils.WebError{Field: "Kondisi", Message: fmt.Sprintf("unit %s kini berkondisi %s, muat ulang halaman", locked.NoSeri, locked.Kondisi), Conflict: true}
+		}
+
+		if closing.TglSelesai.Before(repair.TglMulai) {
+			return entities.Repair{}, utils.WebError{Field: "TglSelesai", Message: "tanggal selesai tidak boleh sebelum tanggal mulai"}
+		}
+
+		closed := repair
+		closed.Status = entities.PerbaikanSelesai
+		closed.KondisiHasil = closing.Kondisi
+		closed.Catatan = closing.Catatan
+		tanggal := closing.TglSelesai
+		closed.TglSelesai = &tanggal
+		if closing.Biaya != nil {
+			closed.Biaya = *closing.Biaya
+		}
+
+		before, after, unit = repair, closed, locked
+		return closed, nil
+	}, func(rec storage.AuditRecorder) error {
+		if err := recordAudit(ctx, rec, entities.AuditPerbaikan, after.Id, entities.AuditUpdate, before, after); err != nil {
+			return err
+		}
+
+		if unit.Id != uuid.Nil && unit.Kondisi != after.KondisiHasil {
+			changed := unit
+			changed.Kondisi = after.KondisiHasil
+			if err := recordAudit(ctx, rec, entities.AuditUnit, unit.Id, entities.AuditUpdate, unit, changed); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		return fmt.Errorf("closing perbaikan %v: %w", resId, err)
+	}
+
+	return nil
+}
+
+func (s *repairService) GetItemRepairs(ctx context.Context, idBarang uuid.UUID) (entities.RepairHistory, error) {
+	repairs, err := s.storage.GetRepairsByItem(ctx, idBarang)
+	if err != nil {
+		return entities.RepairHistory{}, fmt.Errorf("getting perbaikan of item %v: %w", idBarang, err)
+	}
+
+	return entities.NewRepairHistory(repairs), nil
+}
+
+func (s *repairService) GetRepairSpend(ctx context.Context, dari, sampai time.Time) (entities.RepairSpend, error) {
+	dari, sampai = entities.DateOf(dari), entities.DateOf(sampai)
+	if sampai.Before(dari) {
+		return entities.RepairSpend{}, utils.WebError{Field: "Sampai", Message: "tanggal akhir tidak boleh sebelum tanggal awal"}
+	}
+
+	lines, err := s.storage.GetRepairSpendLines(ctx, dari, sampai)
+	if err != nil {
+		return entities.RepairSpend{}, fmt.Errorf("getting repair spend lines: %w", err)
+	}
+
+	return entities.NewRepairSpend(dari, sampai, lines), nil
+}
+
+func (s *repairService) WriteRepairSpendCSV(w io.Writer, spend entities.RepairSpend) error {
+	cw := csv.NewWriter(w)
+
+	title := fmt.Sprintf("Laporan Biaya Perbaikan %s s.d. %s", spend.Dari.Format(entities.DateLayout), spend.Sampai.Format(entities.DateLayout))
+	if err := cw.Write([]string{title}); err != nil {
+		return err
+	}
+
+	record := func(kelompok string, row entities.RepairSpendRow) []string {
+		return []string{kelompok, row.Kode, row.Nama, strconv.Itoa(row.Tiket), strconv.Itoa(row.Berjalan), strconv.Itoa(row.Biaya)}
+	}
+
+	sections := []struct {
+		title string
+		rows  []entities.RepairSpendRow
+	}{
+		{"Kategori", spend.ByCategory},
+		{"Lokasi", spend.ByLocation},
+	}
+	for _, section := range sections {
+		if err := cw.Write([]string{"Kelompok", "Kode", "Nama", "Tiket", "Berjalan", "Biaya"}); err != nil {
+			return err
+		}
+		for _, row := range section.rows {
+			if err := cw.Write(record(section.title, row)); err != nil {
+				return err
+			}
+		}
+		if err := cw.Write(record(section.title, spend.Total)); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+const maxLabelUnits = 500
+
+type ReportService interface {
+	GetRoomKIR(ctx context.Context, slug string) (entities.KIR, error)
+	WriteKIRPDF(w io.Writer, kir entities.KIR) error
+	WriteKIRXLSX(w io.Writer, kir entities.KIR) error
+	GetUnitLabels(ctx context.Context, ids []string, baseURL string) ([]entities.Label, error)
+	GetRoomLabels(ctx context.Context, slug, baseURL string) ([]entities.Label, error)
+	WriteLabelsPDF(w io.Writer, labels []entities.Label) error
+	GetValuation(ctx context.Context, cutoff time.Time) (entities.Valuation, error)
+	WriteValuationXLSX(w io.Writer, val entities.Valuation) error
+	WriteValuationCSV(w io.Writer, val entities.Valuation) error
+}
+
+type reportService struct {
+	storage storage.ReportRepository
+}
+
+func NewReportService(storage storage.ReportRepository) ReportService {
+	return &reportService{storage: storage}
+}
+
+func (s *reportService) GetRoomKIR(ctx context.Context, slug string) (entities.KIR, error) {
+	room, err := s.storage.GetRoomBySlug(ctx, slug)
+	if err != nil {
+		return entities.KIR{}, fmt.Errorf("getting room by slug: %w", err)
+	}
+
+	roomWithItems, err := s.storage.GetRoomWithItems(ctx, room.Id)
+	if err != nil {
+		return entities.KIR{}, fmt.Errorf("getting room with unit items: %w", err)
+	}
+
+	return entities.NewKIR(*roomWithItems, time.Now()), nil
+}
+
+func (s *reportService) WriteKIRPDF(w io.Writer, kir entities.KIR) error {
+	return writeKIRPDF(w, kir)
+}
+
+func (s *reportService) WriteKIRXLSX(w io.Writer, kir entities.KIR) error {
+	return writeKIRXLSX(w, kir)
+}
+
+func (s *reportService) GetUnitLabels(ctx context.Context, ids []string, baseURL string) ([]entities.Label, error) {
+	if len(ids) == 0 {
+		return nil, utils.WebError{Field: "Units", Message: "Pilih minimal satu unit"}
+	}
+
+	if len(ids) > maxLabelUnits {
+		return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("Maksimal %d unit sekali cetak", maxLabelUnits)}
+	}
+
+	unitIds := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		unitId, err := uuid.Parse(id)
+		if err != nil {
+			return nil, utils.WebError{Field: "Units", Message: "Unit tidak valid"}
+		}
+		unitIds = append(unitIds, unitId)
+	}
+
+	units, err := s.storage.GetUnitsByIds(ctx, unitIds)
+	if err != nil {
+		return nil, fmt.Errorf("getting units by ids: %w", err)
+	}
+
+	if len(units) == 0 {
+		return nil, utils.ErrNotFound
+	}
+
+	return entities.NewLabels(units, baseURL), nil
+}
+
+func (s *reportService) GetRoomLabels(ctx context.Context, slug, baseURL string) ([]entities.Label, error) {
+	room, err := s.storage.GetRoomBySlug(ctx, slug)
+	if err != nil {
+		return nil, fmt.Errorf("getting room by slug: %w", err)
+	}
+
+	roomWithItems, err := s.storage.GetRoomWithItems(ctx, room.Id)
+	if err != nil {
+		return nil, fmt.Errorf("getting room with unit items: %w", err)
+	}
+
+	for i := range roomWithItems.Items {
+		roomWithItems.Items[i].Ruangan = room
+	}
+
+	return entities.NewLabels(roomWithItems.Items, baseURL), nil
+}
+
+func (s *reportService) WriteLabelsPDF(w io.Writer, labels []entities.Label) error {
+	return writeLabelsPDF(w, labels)
+}
+
+func (s *reportService) GetValuation(ctx context.Context, cutoff time.Time) (entities.Valuation, error) {
+	cutoff = entities.DateOf(cutoff)
+
+	items, units, err := s.storage.GetValuationLines(ctx, cutoff)
+	if err != nil {
+		return entities.Valuation{}, fmt.Errorf("getting valuation lines: %w", err)
+	}
+
+	return entities.NewValuation(cutoff, items, units), nil
+}
+
+func (s *reportService) WriteValuationXLSX(w io.Writer, val entities.Valuation) error {
+	return writeValuationXLSX(w, val)
+}
+
+func (s *reportService) WriteValuationCSV(w io.Writer, val entities.Valuation) error {
+	return writeValuationCSV(w, val)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/google/uuid"
@@ -13,20 +12,30 @@ import (
 	"github.com/qeunasd/coniven/utils"
 )
 
+// RoomFilters are the filter query parameters the room list accepts.
+var RoomFilters = map[string]utils.AllowedFilter{
+	"dmin": {Column: "r.tgl_dibuat", Operator: "gte"},
+	"dmax": {Column: "r.tgl_dibuat", Operator: "lte"},
+	"jb":   {Column: "r.jumlah_barang", Operator: "eq"},
+}
+
 var roomTableConfig = utils.TableConfig{
 	QueryCols: []string{"r.nama", "r.penanggung_jawab", "l.nama"},
 	SortCols: []utils.AllowedSort{
 		{Name: "nama", Column: "r.nama"},
-		{Name: "dt", Column: "tgl_dibuat"},
+		{Name: "dt", Column: "r.tgl_dibuat"},
 		{Name: "pj", Column: "penanggung_jawab"},
 		{Name: "jb", Column: "jumlah_barang"},
 		{Name: "lk", Column: "l.nama"},
 	},
 	DefaultSort: "dt",
+	DeletedCol:  "r.tgl_dihapus",
+	Filters:     RoomFilters,
 }
 
 type RoomService interface {
-	CreateRoom(ctx context.Context, req entities.RoomForm) error
+	GetRoomsForUI(ctx context.Context) ([]entities.Room, error)
+	CreateRoom(ctx context.Context, req entities.RoomForm) (entities.Room, error)
 	GetRoomsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error)
 	EditRoom(ctx context.Context, slug string, req entities.RoomForm) error
 	GetRoomBySlug(ctx context.Context, slug string) (entities.Room, error)
@@ -39,21 +48,50 @@ type roomService struct {
 	storage storage.RoomRepository
 }
 
-func (s *roomService) CreateRoom(ctx context.Context, req entities.RoomForm) error {
+var errLocationNotFound = utils.WebError{Field: "Lokasi", Message: "lokasi tidak ditemukan"}
+
+// checkLocation makes sure a room is placed in a live location, so a missing
+// id answers 422 instead of tripping the foreign key and a trashed one does
+// not gain a room.
+func (s *roomService) checkLocation(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.storage.GetLocationById(ctx, id); err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			return errLocationNotFound
+		}
+		return fmt.Errorf("getting location: %w", err)
+	}
+	return nil
+}
+
+func (s *roomService) CreateRoom(ctx context.Context, req entities.RoomForm) (entities.Room, error) {
 	room, err := entities.NewRoom(req)
 	if err != nil {
-		return err
+		return entities.Room{}, err
 	}
 
-	if err := s.storage.CreateRoom(ctx, *room); err != nil {
-		return fmt.Errorf("saving room: %w", err)
+	if err := s.checkLocation(ctx, room.LokasiId); err != nil {
+		return entities.Room{}, err
 	}
 
-	return nil
+	err = s.storage.CreateRoom(ctx, *room, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditRuangan, room.Id, entities.AuditCreate, nil, room)
+	})
+	if err != nil {
+		return entities.Room{}, fmt.Errorf("saving room: %w", err)
+	}
+
+	return *room, nil
+}
+
+func (s *roomService) GetRoomsForUI(ctx context.Context) ([]entities.Room, error) {
+	where, args := liveWhere(roomTableConfig)
+	return s.storage.GetRooms(ctx, "", " ORDER BY l.nama, r.nama", where, args)
 }
 
 func (s *roomService) GetRoomsWithFilter(ctx context.Context, params utils.PaginationParams) (utils.PaginationResult, error) {
 	params.SetColumnSearch(roomTableConfig.QueryCols...)
+	params.SetFilters(roomTableConfig.Filters)
+	params.ExcludeDeleted(roomTableConfig.DeletedCol)
 	where, args := utils.BuildWhereClauses(params)
 
 	total, err := s.storage.CountRoomWithFilter(ctx, where, args)
@@ -98,7 +136,7 @@ func (s *roomService) EditRoom(ctx context.Context, slug string, req entities.Ro
 	if idLokasi != "" {
 		lokasi, err = uuid.Parse(idLokasi)
 		if err != nil {
-			return errors.New("invali id")
+			return utils.WebError{Field: "Lokasi", Message: "Lokasi tidak valid"}
 		}
 	}
 
@@ -106,6 +144,7 @@ func (s *roomService) EditRoom(ctx context.Context, slug string, req entities.Ro
 	if err != nil {
 		return fmt.Errorf("getting room by slug: %w", err)
 	}
+	before := room
 
 	if name != "" && name != room.Nama {
 		room.Nama = name
@@ -117,10 +156,16 @@ func (s *roomService) EditRoom(ctx context.Context, slug string, req entities.Ro
 	}
 
 	if lokasi != uuid.Nil && lokasi != room.LokasiId {
+		if err := s.checkLocation(ctx, lokasi); err != nil {
+			return err
+		}
 		room.LokasiId = lokasi
 	}
 
-	if err := s.storage.UpdateRoom(ctx, room); err != nil {
+	err = s.storage.UpdateRoom(ctx, room, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditRuangan, room.Id, entities.AuditUpdate, before, room)
+	})
+	if err != nil {
 		return fmt.Errorf("updating room with id %v: %w", room.Id, err)
 	}
 
@@ -132,38 +177,34 @@ func (s *roomService) GetRoomBySlug(ctx context.Context, slug string) (entities.
 }
 
 func (s *roomService) GetTotalRooms(ctx context.Context) (int, error) {
-	return s.storage.CountRoomWithFilter(ctx, "", nil)
+	where, args := liveWhere(roomTableConfig)
+	return s.storage.CountRoomWithFilter(ctx, where, args)
 }
 
 func (s *roomService) DeleteRoom(ctx context.Context, id string) error {
 	resId, err := uuid.Parse(id)
 	if err != nil {
-		return errors.New("invalid id")
+		return utils.ErrInvalidId
 	}
 
 	room, err := s.storage.GetRoomById(ctx, resId)
 	if err != nil {
-		if err.Error() == "not found" {
-			return err
-		}
 		return fmt.Errorf("getting room by id: %w", err)
 	}
 
-	err = s.storage.DeleteRoom(ctx, room.Id)
+	err = s.storage.DeleteRoom(ctx, room.Id, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditRuangan, room.Id, entities.AuditDelete, room, nil)
+	})
 	if err != nil {
 		return fmt.Errorf("deleting room with id %v: %w", room.Id, err)
 	}
 
-	log.Printf("berhasil hapus ruangan dengan id %v\n", room.Id)
 	return nil
 }
 
 func (s *roomService) GetRoomWithUnitItems(ctx context.Context, slug string) (*entities.Room, error) {
 	room, err := s.storage.GetRoomBySlug(ctx, slug)
 	if err != nil {
-		if err.Error() == "not found" {
-			return nil, err
-		}
 		return nil, fmt.Errorf("getting room by slug: %w", err)
 	}
 
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+// fakeRoomStorage keeps locations by id, true when live and false when in
+// the trash, and fails the test on a save that should not have happened.
+type fakeRoomStorage struct {
+	storage.RoomRepository
+	t         *testing.T
+	locations map[uuid.UUID]bool
+	room      entities.Room
+}
+
+func (f *fakeRoomStorage) GetLocationById(ctx context.Context, id uuid.UUID) (entities.Location, error) {
+	if !f.locations[id] {
+		return entities.Location{}, utils.ErrNotFound
+	}
+	return entities.Location{Id: id}, nil
+}
+
+func (f *fakeRoomStorage) GetRoomBySlug(ctx context.Context, slug string) (entities.Room, error) {
+	return f.room, nil
+}
+
+func (f *fakeRoomStorage) CreateRoom(ctx context.Context, room entities.Room, audit storage.Audit) error {
+	f.t.Fatalf("room saved into location %v", room.LokasiId)
+	return nil
+}
+
+func (f *fakeRoomStorage) UpdateRoom(ctx context.Context, room entities.Room, audit storage.Audit) error {
+	f.t.Fatalf("room moved into location %v", room.LokasiId)
+	return nil
+}
+
+func TestRoomNeedsLiveLocation(t *testing.T) {
+	live, trashed, missing := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name   string
+		lokasi uuid.UUID
+	}{
+		{"lokasi tidak ada", missing},
+		{"lokasi di tempat sampah", trashed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRoomStorage{
+				t:         t,
+				locations: map[uuid.UUID]bool{live: true, trashed: false},
+				room:      entities.Room{Id: uuid.New(), Nama: "Lab", Slug: "lab", LokasiId: live},
+			}
+			svc := NewRoomService(repo)
+			form := entities.RoomForm{Name: "Lab", Manager: "Budi", Lokasi: tt.lokasi.String()}
+
+			_, err := svc.CreateRoom(context.Background(), form)
+			if !errors.Is(err, errLocationNotFound) {
+				t.Errorf("CreateRoom error = %v, want %v", err, errLocationNotFound)
+			}
+
+			err = svc.EditRoom(context.Background(), "lab", form)
+			if !errors.Is(err, errLocationNotFound) {
+				t.Errorf("EditRoom error = %v, want %v", err, errLocationNotFound)
+			}
+		})
+	}
+}
+
+func TestEditRoomInvalidLokasi(t *testing.T) {
+	svc := NewRoomService(&fakeRoomStorage{t: t})
+
+	err := svc.EditRoom(context.Background(), "lab", entities.RoomForm{Lokasi: "bukan-uuid"})
+	if !errors.Is(err, utils.ErrValidation) {
+		t.Errorf("EditRoom error = %v, want a validation error", err)
+	}
+}
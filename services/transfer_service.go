@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+type TransferService interface {
+	TransferUnits(ctx context.Context, req entities.TransferForm) error
+	GetUnitTimeline(ctx context.Context, id string) (entities.ItemUnit, []entities.UnitStay, error)
+}
+
+type transferService struct {
+	storage storage.TransferRepository
+}
+
+func NewTransferService(storage storage.TransferRepository) TransferService {
+	return &transferService{storage: storage}
+}
+
+func (s *transferService) TransferUnits(ctx context.Context, req entities.TransferForm) error {
+	now := time.Now()
+
+	transfer, err := entities.NewTransferRequest(req, now)
+	if err != nil {
+		return err
+	}
+
+	var recorded []entities.Transfer
+	err = s.storage.TransferUnits(ctx, *transfer, func(units []entities.ItemUnit, lastMoved map[uuid.UUID]time.Time, onLoan map[uuid.UUID]entities.Loan, dest entities.Room) ([]entities.Transfer, error) {
+		if len(units) != len(transfer.Units) {
+			return nil, utils.WebError{Field: "Units", Message: "sebagian unit tidak ditemukan, muat ulang halaman"}
+		}
+
+		transfers := make([]entities.Transfer, 0, len(units))
+		for _, u := range units {
+			if u.IdRuangan == dest.Id {
+				return nil, utils.WebError{Field: "Tujuan", Message: fmt.Sprintf("unit %s sudah berada di %s", u.NoSeri, dest.Nama)}
+			}
+
+			if u.Kondisi == entities.KondisiHilang {
+				return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("unit %s berstatus hilang", u.NoSeri)}
+			}
+
+			if loan, ok := onLoan[u.Id]; ok {
+				return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("unit %s sedang dipinjam oleh %s", u.NoSeri, loan.Peminjam)}
+			}
+
+			if transfer.Tanggal.Before(entities.DateOf(u.TglPerolehan)) {
+				return nil, utils.WebError{Field: "Tanggal", Message: fmt.Sprintf("tanggal mutasi sebelum unit %s diperoleh", u.NoSeri)}
+			}
+
+			if last, ok := lastMoved[u.Id]; ok && transfer.Tanggal.Before(last) {
+				return nil, utils.WebError{
+					Field:   "Tanggal",
+					Message: fmt.Sprintf("unit %s terakhir dimutasi pada %s", u.NoSeri, last.Format(entities.DateLayout)),
+				}
+			}
+
+			transfers = append(transfers, entities.Transfer{
+				Id:                uuid.New(),
+				IdUnit:            u.Id,
+				IdRuanganAsal:     uuid.NullUUID{UUID: u.IdRuangan, Valid: true},
+				IdRuanganTujuan:   uuid.NullUUID{UUID: dest.Id, Valid: true},
+				NamaRuanganAsal:   u.Ruangan.Nama,
+				NamaRuanganTujuan: dest.Nama,
+				Alasan:            transfer.Alasan,
+				TglMutasi:         transfer.Tanggal,
+				TglDibuat:         now,
+			})
+		}
+
+		recorded = transfers
+		return transfers, nil
+	}, func(rec storage.AuditRecorder) error {
+		for _, t := range recorded {
+			if err := recordAudit(ctx, rec, entities.AuditMutasi, t.Id, entities.AuditCreate, nil, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		if errors.Is(err, utils.ErrNotFound) {
+			return utils.WebError{Field: "Tujuan", Message: "ruangan tujuan tidak ditemukan"}
+		}
+		return fmt.Errorf("transferring units: %w", err)
+	}
+
+	return nil
+}
+
+func (s *transferService) GetUnitTimeline(ctx context.Context, id string) (entities.ItemUnit, []entities.UnitStay, error) {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return entities.ItemUnit{}, nil, utils.ErrInvalidId
+	}
+
+	unit, err := s.storage.GetUnitById(ctx, resId)
+	if err != nil {
+		return entities.ItemUnit{}, nil, fmt.Errorf("getting unit by id: %w", err)
+	}
+
+	transfers, err := s.storage.GetTransfersByUnit(ctx, unit.Id)
+	if err != nil {
+		return entities.ItemUnit{}, nil, fmt.Errorf("getting transfers of unit %v: %w", unit.Id, err)
+	}
+
+	return unit, entities.BuildUnitStays(unit, transfers), nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+// backdatedUnit was acquired well before it was entered into the system.
+func backdatedUnit() entities.ItemUnit {
+	return entities.ItemUnit{
+		Id:           uuid.New(),
+		NoSeri:       "SN-001",
+		Kondisi:      entities.KondisiBaik,
+		TglDibuat:    time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC),
+		TglPerolehan: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+		IdRuangan:    uuid.New(),
+	}
+}
+
+type fakeTransferStorage struct {
+	storage.TransferRepository
+	unit entities.ItemUnit
+}
+
+func (f *fakeTransferStorage) TransferUnits(ctx context.Context, req entities.TransferRequest, build storage.TransferBuilder, audit storage.Audit) error {
+	dest := entities.Room{Id: req.Tujuan, Nama: "Gudang"}
+	_, err := build([]entities.ItemUnit{f.unit}, nil, nil, dest)
+	return err
+}
+
+func TestTransferDateFromAcquisition(t *testing.T) {
+	tests := []struct {
+		name    string
+		tanggal string
+		wantErr bool
+	}{
+		{"sebelum perolehan", "2024-01-09", true},
+		{"hari perolehan", "2024-01-10", false},
+		{"setelah perolehan sebelum didata", "2024-03-01", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unit := backdatedUnit()
+			svc := NewTransferService(&fakeTransferStorage{unit: unit})
+
+			err := svc.TransferUnits(context.Background(), entities.TransferForm{
+				Units:   []string{unit.Id.String()},
+				Tujuan:  uuid.NewString(),
+				Alasan:  "pindah",
+				Tanggal: tt.tanggal,
+			})
+
+			if tt.wantErr {
+				var webErr utils.WebError
+				if !errors.As(err, &webErr) || webErr.Field != "Tanggal" {
+					t.Errorf("TransferUnits error = %v, want a Tanggal error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("TransferUnits error = %v, want nil", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+// liveWhere is the where clause of an unfiltered list, leaving out soft
+// deleted rows.
+func liveWhere(config utils.TableConfig) (string, []interface{}) {
+	var params utils.PaginationParams
+	params.ExcludeDeleted(config.DeletedCol)
+	return utils.BuildWhereClauses(params)
+}
+
+// TrashService lists deleted records and restores or purges them. A record
+// can be purged once it has been in the trash for entities.TrashRetention.
+type TrashService interface {
+	GetTrash(ctx context.Context, jenis string) ([]entities.TrashEntry, error)
+	RestoreTrash(ctx context.Context, jenis, id string) error
+	PurgeTrash(ctx context.Context, jenis, id string) error
+	PurgeExpiredTrash(ctx context.Context, now time.Time) (int64, error)
+}
+
+type trashService struct {
+	storage storage.TrashRepository
+}
+
+func NewTrashService(storage storage.TrashRepository) TrashService {
+	return &trashService{storage: storage}
+}
+
+var errTrashStale = utils.WebError{Field: "Trash", Message: "data sudah tidak ada di tempat sampah, muat ulang halaman", Conflict: true}
+
+func parseTrashId(jenis, id string) (entities.JenisSampah, any, error) {
+	j, err := entities.ParseJenisSampah(jenis)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if j == entities.SampahKategori {
+		resId, err := strconv.Atoi(id)
+		if err != nil || resId <= 0 {
+			return "", nil, errTrashStale
+		}
+		return j, resId, nil
+	}
+
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return "", nil, errTrashStale
+	}
+	return j, resId, nil
+}
+
+func (s *trashService) GetTrash(ctx context.Context, jenis string) ([]entities.TrashEntry, error) {
+	var j entities.JenisSampah
+	if jenis != "" {
+		var err error
+		if j, err = entities.ParseJenisSampah(jenis); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := s.storage.GetTrash(ctx, j)
+	if err != nil {
+		return nil, fmt.Errorf("getting trash: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (s *trashService) RestoreTrash(ctx context.Context, jenis, id string) error {
+	j, resId, err := parseTrashId(jenis, id)
+	if err != nil {
+		return err
+	}
+
+	var entry entities.TrashEntry
+	err = s.storage.RestoreTrash(ctx, j, resId, func(state entities.TrashState) error {
+		if state.Induk != "" {
+			return utils.WebError{Field: "Trash", Message: fmt.Sprintf("pulihkan %s terlebih dahulu", state.Induk)}
+		}
+		if j == entities.SampahUnit && state.Unit >= state.Jumlah {
+			return utils.WebError{Field: "Trash", Message: fmt.Sprintf("unit %s melebihi jumlah barang", state.Entry.Nama)}
+		}
+		entry = state.Entry
+		return nil
+	}, func(rec storage.AuditRecorder) error {
+		restored := entry
+		restored.TglDihapus = time.Time{}
+		return recordAudit(ctx, rec, j.AuditEntity(), entry.Id, entities.AuditUpdate, entry, restored)
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		if errors.Is(err, utils.ErrNotFound) {
+			return errTrashStale
+		}
+		return fmt.Errorf("restoring %s: %w", j, err)
+	}
+
+	return nil
+}
+
+func (s *trashService) PurgeTrash(ctx context.Context, jenis, id string) error {
+	j, resId, err := parseTrashId(jenis, id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var entry entities.TrashEntry
+	err = s.storage.PurgeTrash(ctx, j, resId, func(state entities.TrashState) error {
+		if !state.Entry.CanPurge(now) {
+			return utils.WebError{Field: "Trash", Message: fmt.Sprintf("%s baru dapat dihapus permanen mulai %s", state.Entry.Nama, state.Entry.PurgeableAt().Format("02/01/2006 15:04"))}
+		}
+		entry = state.Entry
+		return nil
+	}, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, j.AuditEntity(), entry.Id, entities.AuditDelete, entry, nil)
+	})
+	if err != nil {
+		var webErr utils.WebError
+		if errors.As(err, &webErr) {
+			return err
+		}
+		if errors.Is(err, utils.ErrNotFound) {
+			return errTrashStale
+		}
+		return fmt.Errorf("purging %s: %w", j, err)
+	}
+
+	return nil
+}
+
+func (s *trashService) PurgeExpiredTrash(ctx context.Context, now time.Time) (int64, error) {
+	purged, err := s.storage.PurgeExpiredTrash(ctx, now.Add(-entities.TrashRetention))
+	if err != nil {
+		return 0, fmt.Errorf("purging expired trash: %w", err)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+type UnitService interface {
+	RegisterUnits(ctx context.Context, itemSlug string, req entities.UnitForm) ([]entities.ItemUnit, error)
+	GetUnitsByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.ItemUnit, error)
+	GetUnitById(ctx context.Context, id string) (entities.ItemUnit, error)
+	EditUnitSerial(ctx context.Context, id, noSeri string) error
+	ChangeUnitCondition(ctx context.Context, id string, kondisi string) error
+	DeleteUnit(ctx context.Context, id string) error
+}
+
+type unitService struct {
+	storage storage.UnitRepository
+}
+
+func NewUnitService(storage storage.UnitRepository) UnitService {
+	return &unitService{storage: storage}
+}
+
+func (s *unitService) RegisterUnits(ctx context.Context, itemSlug string, req entities.UnitForm) ([]entities.ItemUnit, error) {
+	item, err := s.storage.GetItemBySlug(ctx, itemSlug)
+	if err != nil {
+		return nil, fmt.Errorf("getting item by slug: %w", err)
+	}
+
+	units, err := entities.NewItemUnits(item, req)
+	if err != nil {
+		return nil, err
+	}
+
+	registered, err := s.storage.CountUnitsByItem(ctx, item.Id)
+	if err != nil {
+		return nil, fmt.Errorf("counting units: %w", err)
+	}
+
+	if registered+len(units) > item.Jumlah {
+		return nil, utils.WebError{
+			Field:   "Units",
+			Message: fmt.Sprintf("jumlah unit melebihi jumlah barang (%d terdaftar dari %d)", registered, item.Jumlah),
+		}
+	}
+
+	serials := make([]string, 0, len(units))
+	checkedRooms := make(map[uuid.UUID]bool)
+	for _, u := range units {
+		serials = append(serials, u.NoSeri)
+
+		if checkedRooms[u.IdRuangan] {
+			continue
+		}
+
+		if _, err := s.storage.GetRoomById(ctx, u.IdRuangan); err != nil {
+			if errors.Is(err, utils.ErrNotFound) {
+				return nil, utils.WebError{Field: "Units", Message: "ruangan tidak ditemukan"}
+			}
+			return nil, fmt.Errorf("getting room by id: %w", err)
+		}
+		checkedRooms[u.IdRuangan] = true
+	}
+
+	taken, err := s.storage.FindUnitSerials(ctx, item.Id, serials)
+	if err != nil {
+		return nil, fmt.Errorf("finding unit serials: %w", err)
+	}
+
+	if len(taken) > 0 {
+		return nil, utils.WebError{Field: "Units", Message: fmt.Sprintf("nomor seri sudah terpakai: %s", strings.Join(taken, ", ")), Conflict: true}
+	}
+
+	err = s.storage.CreateUnits(ctx, units, func(rec storage.AuditRecorder) error {
+		for _, u := range units {
+			if err := recordAudit(ctx, rec, entities.AuditUnit, u.Id, entities.AuditCreate, nil, u); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("saving units: %w", err)
+	}
+
+	return units, nil
+}
+
+func (s *unitService) GetUnitsByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.ItemUnit, error) {
+	return s.storage.GetUnitsByItem(ctx, idBarang)
+}
+
+func (s *unitService) GetUnitById(ctx context.Context, id string) (entities.ItemUnit, error) {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return entities.ItemUnit{}, utils.ErrInvalidId
+	}
+
+	return s.storage.GetUnitById(ctx, resId)
+}
+
+func (s *unitService) EditUnitSerial(ctx context.Context, id, noSeri string) error {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	noSeri = strings.TrimSpace(noSeri)
+	if noSeri == "" {
+		return nil
+	}
+
+	unit, err := s.storage.GetUnitById(ctx, resId)
+	if err != nil {
+		return fmt.Errorf("getting unit by id: %w", err)
+	}
+
+	if noSeri == unit.NoSeri {
+		return nil
+	}
+
+	taken, err := s.storage.FindUnitSerials(ctx, unit.IdBarang, []string{noSeri})
+	if err != nil {
+		return fmt.Errorf("finding unit serials: %w", err)
+	}
+
+	if len(taken) > 0 {
+		return utils.WebError{Field: "NoSeri", Message: "nomor seri sudah terpakai", Conflict: true}
+	}
+
+	before := unit
+	unit.NoSeri = noSeri
+	unit.TglUpdate = time.Now()
+
+	err = s.storage.UpdateUnitSerial(ctx, unit, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditUnit, unit.Id, entities.AuditUpdate, before, unit)
+	})
+	if err != nil {
+		return fmt.Errorf("updating unit with id %v: %w", unit.Id, err)
+	}
+
+	return nil
+}
+
+func (s *unitService) ChangeUnitCondition(ctx context.Context, id string, kondisi string) error {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	next := entities.KondisiUnit(strings.TrimSpace(kondisi))
+	if !next.IsValid() {
+		return utils.WebError{Field: "Kondisi", Message: "kondisi tidak valid"}
+	}
+
+	unit, err := s.storage.GetUnitById(ctx, resId)
+	if err != nil {
+		return fmt.Errorf("getting unit by id: %w", err)
+	}
+
+	if unit.Kondisi.IsTerminal() {
+		return utils.WebError{Field: "Kondisi", Message: fmt.Sprintf("unit berstatus %s tidak dapat diubah", unit.Kondisi)}
+	}
+
+	if unit.Kondisi == entities.KondisiPerbaikan || next == entities.KondisiPerbaikan {
+		return utils.WebError{Field: "Kondisi", Message: "kondisi diperbaiki diatur melalui tiket perbaikan"}
+	}
+
+	if !unit.Kondisi.CanSetManually(next) {
+		return utils.WebError{Field: "Kondisi", Message: fmt.Sprintf("kondisi %s tidak dapat diubah menjadi %s", unit.Kondisi, next)}
+	}
+
+	after := unit
+	after.Kondisi = next
+	err = s.storage.UpdateUnitCondition(ctx, unit.Id, unit.Kondisi, next, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditUnit, unit.Id, entities.AuditUpdate, unit, after)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrConditionChanged) {
+			return utils.WebError{Field: "Kondisi", Message: "kondisi unit telah diubah oleh pengguna lain, muat ulang halaman"}
+		}
+		return fmt.Errorf("updating unit condition with id %v: %w", unit.Id, err)
+	}
+
+	return nil
+}
+
+func (s *unitService) DeleteUnit(ctx context.Context, id string) error {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	unit, err := s.storage.GetUnitById(ctx, resId)
+	if err != nil {
+		return fmt.Errorf("getting unit by id: %w", err)
+	}
+
+	err = s.storage.DeleteUnit(ctx, unit.Id, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditUnit, unit.Id, entities.AuditDelete, unit, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("deleting unit with id %v: %w", unit.Id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/storage"
+	"github.com/qeunasd/coniven/utils"
+)
+
+type UserService interface {
+	GetUsers(ctx context.Context) ([]entities.User, error)
+	GetUserById(ctx context.Context, id string) (entities.User, error)
+	CreateUser(ctx context.Context, req entities.UserForm) error
+	EditUser(ctx context.Context, id string, req entities.UserForm) error
+	DeleteUser(ctx context.Context, id string, actor uuid.UUID) error
+}
+
+type userService struct {
+	storage storage.UserRepository
+}
+
+func NewUserService(storage storage.UserRepository) UserService {
+	return &userService{storage: storage}
+}
+
+func (s *userService) GetUsers(ctx context.Context) ([]entities.User, error) {
+	return s.storage.GetUsers(ctx)
+}
+
+func (s *userService) GetUserById(ctx context.Context, id string) (entities.User, error) {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return entities.User{}, utils.ErrInvalidId
+	}
+
+	return s.storage.GetUserById(ctx, resId)
+}
+
+func (s *userService) CreateUser(ctx context.Context, req entities.UserForm) error {
+	user, err := entities.NewUser(req)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.storage.GetUserByUsername(ctx, user.Username); err == nil {
+		return utils.WebError{Field: "Username", Message: "Username sudah terpakai", Conflict: true}
+	} else if !errors.Is(err, utils.ErrNotFound) {
+		return fmt.Errorf("getting user by username: %w", err)
+	}
+
+	user.PasswordHash, err = HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.CreateUser(ctx, *user, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditPengguna, user.Id, entities.AuditCreate, nil, user)
+	})
+	if err != nil {
+		return fmt.Errorf("saving user: %w", err)
+	}
+
+	return nil
+}
+
+func (s *userService) EditUser(ctx context.Context, id string, req entities.UserForm) error {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	user, err := s.storage.GetUserById(ctx, resId)
+	if err != nil {
+		return fmt.Errorf("getting user by id: %w", err)
+	}
+	before := user
+
+	if nama := strings.TrimSpace(req.Nama); nama != "" {
+		user.Nama = nama
+	}
+
+	if peran := strings.TrimSpace(req.Peran); peran != "" {
+		user.Peran = entities.Peran(peran)
+		if !user.Peran.IsValid() {
+			return utils.WebError{Field: "Peran", Message: "Peran tidak valid"}
+		}
+	}
+
+	passwordChanged := req.Password != ""
+	if passwordChanged {
+		if err := entities.ValidatePassword(req.Password); err != nil {
+			return err
+		}
+
+		user.PasswordHash, err = HashPassword(req.Password)
+		if err != nil {
+			return err
+		}
+	}
+
+	user.TglUpdate = time.Now()
+	err = s.storage.UpdateUser(ctx, user, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditPengguna, user.Id, entities.AuditUpdate, before, user)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrLastAdmin) {
+			return utils.WebError{Field: "Peran", Message: "harus ada minimal satu admin"}
+		}
+		return fmt.Errorf("updating user with id %v: %w", user.Id, err)
+	}
+
+	if passwordChanged {
+		if err := s.storage.DeleteUserSessions(ctx, user.Id); err != nil {
+			return fmt.Errorf("removing sessions of user %v: %w", user.Id, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *userService) DeleteUser(ctx context.Context, id string, actor uuid.UUID) error {
+	resId, err := uuid.Parse(id)
+	if err != nil {
+		return utils.ErrInvalidId
+	}
+
+	if resId == actor {
+		return utils.WebError{Field: "User", Message: "tidak dapat menghapus akun sendiri"}
+	}
+
+	user, err := s.storage.GetUserById(ctx, resId)
+	if err != nil {
+		return fmt.Errorf("getting user by id: %w", err)
+	}
+
+	err = s.storage.DeleteUser(ctx, user.Id, func(rec storage.AuditRecorder) error {
+		return recordAudit(ctx, rec, entities.AuditPengguna, user.Id, entities.AuditDelete, user, nil)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrLastAdmin) {
+			return utils.WebError{Field: "User", Message: "harus ada minimal satu admin"}
+		}
+		return fmt.Errorf("deleting user with id %v: %w", user.Id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/qeunasd/coniven/entities"
+	"github.com/xuri/excelize/v2"
+)
+
+// valuationNote explains the condition columns, which count units by their
+// condition today rather than on the cutoff.
+const valuationNote = "Kolom kondisi memakai kondisi unit saat ini, bukan kondisi pada tanggal laporan."
+
+func valuationHeader() []string {
+	header := []string{"Kode", "Nama", "Jenis Barang", "Jumlah", "Nilai"}
+	for _, k := range entities.KondisiUnits() {
+		header = append(header, "Unit "+string(k)+" (saat ini)")
+	}
+	return header
+}
+
+func valuationRecord(row entities.ValuationRow) []string {
+	record := []string{
+		row.Kode,
+		row.Nama,
+		strconv.Itoa(row.JenisBarang),
+		strconv.Itoa(row.Jumlah),
+		strconv.Itoa(row.Nilai),
+	}
+	for _, k := range entities.KondisiUnits() {
+		record = append(record, strconv.Itoa(row.CountOf(k)))
+	}
+	return record
+}
+
+func writeValuationCSV(w io.Writer, val entities.Valuation) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"Laporan Nilai Inventaris per " + val.Cutoff.Format(entities.DateLayout)}); err != nil {
+		return err
+	}
+	if err := cw.Write([]string{valuationNote}); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		title string
+		rows  []entities.ValuationRow
+	}{
+		{"Kategori", val.ByCategory},
+		{"Lokasi", val.ByLocation},
+	}
+	for _, section := range sections {
+		if err := cw.Write(append([]string{"Kelompok"}, valuationHeader()...)); err != nil {
+			return err
+		}
+		for _, row := range section.rows {
+			if err := cw.Write(append([]string{section.title}, valuationRecord(row)...)); err != nil {
+				return err
+			}
+		}
+		if err := cw.Write(append([]string{section.title}, valuationRecord(val.Total)...)); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeValuationXLSX(w io.Writer, val entities.Valuation) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	border := []excelize.Border{
+		{Type: "left", Color: "000000", Style: 1},
+		{Type: "top", Color: "000000", Style: 1},
+		{Type: "right", Color: "000000", Style: 1},
+		{Type: "bottom", Color: "000000", Style: 1},
+	}
+	rupiahFmt := `"Rp "#,##0`
+
+	titleStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		return err
+	}
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Border:    border,
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"DDDDDD"}},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+	})
+	if err != nil {
+		return err
+	}
+	bodyStyle, err := f.NewStyle(&excelize.Style{Border: border})
+	if err != nil {
+		return err
+	}
+	moneyStyle, err := f.NewStyle(&excelize.Style{Border: border, CustomNumFmt: &rupiahFmt})
+	if err != nil {
+		return err
+	}
+	totalStyle, err := f.NewStyle(&excelize.Style{Border: border, Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	totalMoneyStyle, err := f.NewStyle(&excelize.Style{Border: border, Font: &excelize.Font{Bold: true}, CustomNumFmt: &rupiahFmt})
+	if err != nil {
+		return err
+	}
+
+	cell := func(col, row int) string {
+		name, _ := excelize.CoordinatesToCellName(col, row)
+		return name
+	}
+
+	header := valuationHeader()
+	sheets := []struct {
+		name string
+		rows []entities.ValuationRow
+	}{
+		{"Per Kategori", val.ByCategory},
+		{"Per Lokasi", val.ByLocation},
+	}
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := f.SetSheetName("Sheet1", sheet.name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(sheet.name); err != nil {
+			return err
+		}
+
+		f.SetCellValue(sheet.name, "A1", "Laporan Nilai Inventaris "+sheet.name)
+		f.SetCellStyle(sheet.name, "A1", "A1", titleStyle)
+		f.SetCellValue(sheet.name, "A2", "Per tanggal "+entities.FormatTanggal(val.Cutoff))
+		f.SetCellValue(sheet.name, "A3", valuationNote)
+
+		for col, title := range header {
+			f.SetCellValue(sheet.name, cell(col+1, 4), title)
+		}
+		f.SetCellStyle(sheet.name, cell(1, 4), cell(len(header), 4), headerStyle)
+		f.SetColWidth(sheet.name, "A", "A", 14)
+		f.SetColWidth(sheet.name, "B", "B", 32)
+		f.SetColWidth(sheet.name, "E", "E", 20)
+
+		rows := append(append([]entities.ValuationRow{}, sheet.rows...), val.Total)
+		for r, row := range rows {
+			line := 5 + r
+			values := []any{row.Kode, row.Nama, row.JenisBarang, row.Jumlah, row.Nilai}
+			for _, k := range entities.KondisiUnits() {
+				values = append(values, row.CountOf(k))
+			}
+			for col, v := range values {
+				f.SetCellValue(sheet.name, cell(col+1, line), v)
+			}
+
+			style, money := bodyStyle, moneyStyle
+			if r == len(rows)-1 {
+				style, money = totalStyle, totalMoneyStyle
+			}
+			f.SetCellStyle(sheet.name, cell(1, line), cell(len(header), line), style)
+			f.SetCellStyle(sheet.name, cell(5, line), cell(5, line), money)
+		}
+	}
+
+	return f.Write(w)
+}
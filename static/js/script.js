@@ -23,9 +23,40 @@ function toggleRowHighlight(checkbox) {
 }
 
 function toggleAll(source) {
-  const checkboxes = document.querySelectorAll('input[name="ids"]');
+  const checkboxes = document.querySelectorAll('input[type="checkbox"][name="ids"]');
   checkboxes.forEach(cb => {
     cb.checked = source.checked;
     toggleRowHighlight(cb);
   });
-}
\ No newline at end of file
+}
+function printSelectedLabels(button) {
+  const params = new URLSearchParams();
+  button.closest("form").querySelectorAll('input[name="units"]:checked').forEach(cb => {
+    params.append("id", cb.value);
+  });
+
+  if (!params.has("id")) {
+    alert("pilih unit terlebih dahulu");
+    return;
+  }
+  window.open("/unit/label?" + params.toString(), "_blank");
+}
+
+function loanSelectedUnits(button) {
+  const params = new URLSearchParams();
+  button.closest("form").querySelectorAll('input[name="units"]:checked').forEach(cb => {
+    params.append("units", cb.value);
+  });
+
+  if (!params.has("units")) {
+    alert("pilih unit terlebih dahulu");
+    return;
+  }
+  window.location.href = "/loan/add?" + params.toString();
+}
+
+// Error responses from the server's error mapper carry a partial to show: a
+// 404, 409 or 422 is swapped like a success, other errors are left alone.
+if (window.htmx) {
+  htmx.config.responseHandling.unshift({ code: "^(404|409|422)$", swap: true, error: false });
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/qeunasd/coniven/utils"
+)
+
+// States the services handle on their own. Each wraps utils.ErrConflict, so
+// one that reaches the server still answers with 409.
+var (
+	ErrDuplicate        = fmt.Errorf("duplicate: %w", utils.ErrConflict)
+	ErrLastAdmin        = fmt.Errorf("last admin: %w", utils.ErrConflict)
+	ErrOpnameClosed     = fmt.Errorf("opname closed: %w", utils.ErrConflict)
+	ErrConditionChanged = fmt.Errorf("condition changed: %w", utils.ErrConflict)
+	ErrUnitOnLoan       = fmt.Errorf("unit on loan: %w", utils.ErrConflict)
+	ErrRepairOpen       = fmt.Errorf("repair open: %w", utils.ErrConflict)
+)
+
+// isUniqueViolation reports whether err is Postgres rejecting a write on a
+// unique constraint, which a check-then-insert race in the services ends in.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type LocalStore struct {
+	root string
+}
+
+func NewLocal(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("creating storage directory %s: %w", root, err)
+	}
+
+	return &LocalStore{root: filepath.Clean(root)}, nil
+}
+
+func (l *LocalStore) path(name string) (string, error) {
+	clean := filepath.Clean("/" + name)
+	if strings.Contains(name, "..") || clean == "/" {
+		return "", fmt.Errorf("invalid object name %q", name)
+	}
+
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
+
+func (l *LocalStore) PutObject(ctx context.Context, name string, r io.Reader, size int64, contentType string) error {
+	path, err := l.path(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating object directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing object %s: %w", name, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing object %s: %w", name, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("moving object %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func (l *LocalStore) GetObject(ctx context.Context, name string) (io.ReadCloser, ObjectInfo, error) {
+	path, err := l.path(name)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ObjectInfo{}, ErrObjectNotFound
+		}
+		return nil, ObjectInfo{}, fmt.Errorf("opening object %s: %w", name, err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, ObjectInfo{}, fmt.Errorf("stat object %s: %w", name, err)
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return f, ObjectInfo{Size: stat.Size(), ContentType: contentType}, nil
+}
+
+func (l *LocalStore) RemoveObject(ctx context.Context, name string) error {
+	path, err := l.path(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing object %s: %w", name, err)
+	}
+
+	return nil
+}
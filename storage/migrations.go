@@ -0,0 +1,689 @@
+package storage
+
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_kategori",
+		Up: `
+		CREATE TABLE IF NOT EXISTS kategori (
+			id SERIAL PRIMARY KEY,
+			kode VARCHAR(255) NOT NULL UNIQUE,
+			nama VARCHAR(255) NOT NULL,
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			tgl_update TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		`,
+		Down: `DROP TABLE IF EXISTS kategori;`,
+	},
+	{
+		Version: 2,
+		Name:    "create_lokasi",
+		Up: `
+		CREATE TABLE IF NOT EXISTS lokasi (
+			id UUID PRIMARY KEY,
+			kode VARCHAR(255) NOT NULL UNIQUE,
+			nama VARCHAR(255) NOT NULL,
+			jumlah_ruangan INTEGER DEFAULT 0,
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			tgl_update TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		`,
+		Down: `DROP TABLE IF EXISTS lokasi;`,
+	},
+	{
+		Version: 3,
+		Name:    "create_ruangan",
+		Up: `
+		CREATE TABLE IF NOT EXISTS ruangan (
+			id UUID PRIMARY KEY,
+			id_lokasi UUID NOT NULL,
+			nama VARCHAR(255) NOT NULL,
+			penanggung_jawab VARCHAR(255) NOT NULL,
+			jumlah_barang INTEGER NOT NULL DEFAULT 0,
+			slug VARCHAR(255) NOT NULL UNIQUE,
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			tgl_update TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(id_lokasi)
+				REFERENCES lokasi(id)
+				ON DELETE CASCADE
+		);
+		`,
+		Down: `DROP TABLE IF EXISTS ruangan;`,
+	},
+	{
+		Version: 4,
+		Name:    "create_barang",
+		Up: `
+		CREATE TABLE IF NOT EXISTS barang (
+			id UUID PRIMARY KEY,
+			id_kategori INTEGER NOT NULL,
+			sku VARCHAR(255) NOT NULL UNIQUE,
+			nama VARCHAR(255) NOT NULL,
+			jumlah INTEGER NOT NULL DEFAULT 1,
+			satuan VARCHAR(100) NOT NULL,
+			harga_satuan INTEGER NOT NULL,
+			umur_ekonomis INTEGER NOT NULL,
+			spesifikasi TEXT,
+			slug VARCHAR(255) NOT NULL UNIQUE,
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(id_kategori)
+				REFERENCES kategori(id)
+				ON DELETE CASCADE
+		);
+		`,
+		Down: `DROP TABLE IF EXISTS barang;`,
+	},
+	{
+		Version: 5,
+		Name:    "create_gambar_barang",
+		Up: `
+		CREATE TABLE IF NOT EXISTS gambar_barang (
+			id SERIAL PRIMARY KEY,
+			id_barang UUID NOT NULL,
+			nama_objek VARCHAR NOT NULL UNIQUE,
+			nama_file VARCHAR NOT NULL,
+			ukuran_file BIGINT NOT NULL,
+			tgl_upload TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(id_barang)
+				REFERENCES barang(id)
+				ON DELETE CASCADE
+		);
+		`,
+		Down: `DROP TABLE IF EXISTS gambar_barang;`,
+	},
+	{
+		Version: 6,
+		Name:    "create_unit_barang",
+		// The labels are fixed so the checksum never changes; new conditions
+		// are added with ALTER TYPE ... ADD VALUE in a later migration.
+		Up: `
+		DO $$ BEGIN
+			CREATE TYPE kon_unit_barang AS ENUM ('baik', 'rusak', 'diperbaiki', 'hilang', 'digudangkan');
+		EXCEPTION
+			WHEN duplicate_object THEN NULL;
+		END $$;
+	
+		CREATE TABLE IF NOT EXISTS unit_barang (
+			id UUID PRIMARY KEY,
+			id_barang UUID NOT NULL,
+			id_ruangan UUID NOT NULL,
+			no_seri VARCHAR(255) NOT NULL,
+			kondisi kon_unit_barang NOT NULL DEFAULT 'baik',
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			tgl_update TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(id_barang, no_seri),
+			FOREIGN KEY(id_barang)
+				REFERENCES barang(id)
+				ON DELETE CASCADE,
+			FOREIGN KEY(id_ruangan)
+				REFERENCES ruangan(id)
+				ON DELETE CASCADE
+		);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS unit_barang;
+		DROP TYPE IF EXISTS kon_unit_barang;
+		`,
+	},
+	{
+		Version: 7,
+		Name:    "add_lokasi_slug",
+		Up: `
+		ALTER TABLE lokasi ADD COLUMN IF NOT EXISTS slug VARCHAR(255);
+		UPDATE lokasi
+			SET slug = trim(both '-' from lower(regexp_replace(nama, '[^a-zA-Z0-9+]', '-', 'g')))
+				|| '-' || substr(md5(id::text), 1, 7)
+			WHERE slug IS NULL;
+		ALTER TABLE lokasi ALTER COLUMN slug SET NOT NULL;
+		CREATE UNIQUE INDEX IF NOT EXISTS lokasi_slug_key ON lokasi(slug);
+		`,
+		Down: `
+		DROP INDEX IF EXISTS lokasi_slug_key;
+		ALTER TABLE lokasi DROP COLUMN IF EXISTS slug;
+		`,
+	},
+	{
+		Version: 8,
+		Name:    "maintain_counters",
+		Up: `
+		UPDATE lokasi SET jumlah_ruangan = 0 WHERE jumlah_ruangan IS NULL;
+		ALTER TABLE lokasi ALTER COLUMN jumlah_ruangan SET NOT NULL;
+
+		CREATE OR REPLACE FUNCTION sync_jumlah_ruangan() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE' AND OLD.id_lokasi = NEW.id_lokasi THEN
+				RETURN NULL;
+			END IF;
+			IF TG_OP IN ('INSERT', 'UPDATE') THEN
+				UPDATE lokasi SET jumlah_ruangan = jumlah_ruangan + 1 WHERE id = NEW.id_lokasi;
+			END IF;
+			IF TG_OP IN ('DELETE', 'UPDATE') THEN
+				UPDATE lokasi SET jumlah_ruangan = jumlah_ruangan - 1 WHERE id = OLD.id_lokasi;
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		CREATE OR REPLACE FUNCTION sync_jumlah_barang() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE' AND OLD.id_ruangan = NEW.id_ruangan THEN
+				RETURN NULL;
+			END IF;
+			IF TG_OP IN ('INSERT', 'UPDATE') THEN
+				UPDATE ruangan SET jumlah_barang = jumlah_barang + 1 WHERE id = NEW.id_ruangan;
+			END IF;
+			IF TG_OP IN ('DELETE', 'UPDATE') THEN
+				UPDATE ruangan SET jumlah_barang = jumlah_barang - 1 WHERE id = OLD.id_ruangan;
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		CREATE TRIGGER ruangan_sync_jumlah_ruangan
+			AFTER INSERT OR DELETE OR UPDATE OF id_lokasi ON ruangan
+			FOR EACH ROW EXECUTE FUNCTION sync_jumlah_ruangan();
+
+		CREATE TRIGGER unit_barang_sync_jumlah_barang
+			AFTER INSERT OR DELETE OR UPDATE OF id_ruangan ON unit_barang
+			FOR EACH ROW EXECUTE FUNCTION sync_jumlah_barang();
+
+		UPDATE lokasi l SET jumlah_ruangan = (SELECT COUNT(*) FROM ruangan r WHERE r.id_lokasi = l.id);
+		UPDATE ruangan r SET jumlah_barang = (SELECT COUNT(*) FROM unit_barang u WHERE u.id_ruangan = r.id);
+		`,
+		Down: `
+		DROP TRIGGER IF EXISTS unit_barang_sync_jumlah_barang ON unit_barang;
+		DROP TRIGGER IF EXISTS ruangan_sync_jumlah_ruangan ON ruangan;
+		DROP FUNCTION IF EXISTS sync_jumlah_barang();
+		DROP FUNCTION IF EXISTS sync_jumlah_ruangan();
+		ALTER TABLE lokasi ALTER COLUMN jumlah_ruangan DROP NOT NULL;
+		`,
+	},
+	{
+		Version: 9,
+		Name:    "create_mutasi",
+		Up: `
+		CREATE TABLE IF NOT EXISTS mutasi (
+			id UUID PRIMARY KEY,
+			id_unit UUID NOT NULL,
+			id_ruangan_asal UUID,
+			id_ruangan_tujuan UUID,
+			nama_ruangan_asal VARCHAR(255) NOT NULL,
+			nama_ruangan_tujuan VARCHAR(255) NOT NULL,
+			alasan TEXT NOT NULL,
+			tgl_mutasi DATE NOT NULL,
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(id_unit)
+				REFERENCES unit_barang(id)
+				ON DELETE CASCADE,
+			FOREIGN KEY(id_ruangan_asal)
+				REFERENCES ruangan(id)
+				ON DELETE SET NULL,
+			FOREIGN KEY(id_ruangan_tujuan)
+				REFERENCES ruangan(id)
+				ON DELETE SET NULL
+		);
+		CREATE INDEX IF NOT EXISTS mutasi_id_unit_idx ON mutasi(id_unit, tgl_mutasi);
+		`,
+		Down: `DROP TABLE IF EXISTS mutasi;`,
+	},
+	{
+		Version: 10,
+		Name:    "add_barang_penyusutan",
+		Up: `
+		ALTER TABLE barang
+			ADD COLUMN IF NOT EXISTS metode_penyusutan VARCHAR(20) NOT NULL DEFAULT 'garis_lurus'
+				CHECK (metode_penyusutan IN ('garis_lurus', 'saldo_menurun')),
+			ADD COLUMN IF NOT EXISTS nilai_residu INTEGER NOT NULL DEFAULT 0
+				CHECK (nilai_residu >= 0);
+		`,
+		Down: `
+		ALTER TABLE barang
+			DROP COLUMN IF EXISTS metode_penyusutan,
+			DROP COLUMN IF EXISTS nilai_residu;
+		`,
+	},
+	{
+		Version: 11,
+		Name:    "create_pengguna",
+		Up: `
+		CREATE TABLE IF NOT EXISTS pengguna (
+			id UUID PRIMARY KEY,
+			username VARCHAR(100) NOT NULL UNIQUE,
+			nama VARCHAR(255) NOT NULL,
+			password_hash VARCHAR(255) NOT NULL,
+			peran VARCHAR(20) NOT NULL
+				CHECK (peran IN ('admin', 'operator', 'viewer')),
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			tgl_update TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS sesi (
+			id CHAR(64) PRIMARY KEY,
+			id_pengguna UUID NOT NULL,
+			kedaluwarsa TIMESTAMP NOT NULL,
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(id_pengguna)
+				REFERENCES pengguna(id)
+				ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS sesi_id_pengguna_idx ON sesi(id_pengguna);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS sesi;
+		DROP TABLE IF EXISTS pengguna;
+		`,
+	},
+	{
+		Version: 12,
+		Name:    "create_audit_log",
+		Up: `
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id BIGSERIAL PRIMARY KEY,
+			id_pengguna UUID,
+			aktor VARCHAR(100) NOT NULL,
+			jenis_entitas VARCHAR(50) NOT NULL,
+			id_entitas VARCHAR(100) NOT NULL,
+			aksi VARCHAR(10) NOT NULL
+				CHECK (aksi IN ('create', 'update', 'delete')),
+			sebelum JSONB,
+			sesudah JSONB,
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS audit_log_entitas_idx ON audit_log(jenis_entitas, id_entitas);
+		CREATE INDEX IF NOT EXISTS audit_log_tgl_dibuat_idx ON audit_log(tgl_dibuat);
+
+		CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_log is append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		CREATE TRIGGER audit_log_no_change
+			BEFORE UPDATE OR DELETE ON audit_log
+			FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+		CREATE TRIGGER audit_log_no_truncate
+			BEFORE TRUNCATE ON audit_log
+			FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+		`,
+		Down: `
+		DROP TABLE IF EXISTS audit_log;
+		DROP FUNCTION IF EXISTS audit_log_append_only();
+		`,
+	},
+	{
+		Version: 13,
+		Name:    "create_stok_opname",
+		Up: `
+		CREATE TABLE IF NOT EXISTS stok_opname (
+			id UUID PRIMARY KEY,
+			id_ruangan UUID,
+			id_lokasi UUID NOT NULL,
+			nama_cakupan VARCHAR(255) NOT NULL,
+			status VARCHAR(10) NOT NULL DEFAULT 'berjalan'
+				CHECK (status IN ('berjalan', 'selesai')),
+			catatan TEXT NOT NULL DEFAULT '',
+			id_pengguna UUID,
+			petugas VARCHAR(100) NOT NULL,
+			tgl_mulai TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			tgl_selesai TIMESTAMP,
+			FOREIGN KEY(id_ruangan)
+				REFERENCES ruangan(id)
+				ON DELETE CASCADE,
+			FOREIGN KEY(id_lokasi)
+				REFERENCES lokasi(id)
+				ON DELETE CASCADE,
+			FOREIGN KEY(id_pengguna)
+				REFERENCES pengguna(id)
+				ON DELETE SET NULL
+		);
+		CREATE INDEX IF NOT EXISTS stok_opname_tgl_mulai_idx ON stok_opname(tgl_mulai);
+
+		CREATE TABLE IF NOT EXISTS stok_opname_unit (
+			id_opname UUID NOT NULL,
+			id_unit UUID NOT NULL,
+			id_ruangan UUID,
+			diharapkan BOOLEAN NOT NULL,
+			kode_scan VARCHAR(255),
+			tgl_scan TIMESTAMP,
+			catatan TEXT NOT NULL DEFAULT '',
+			tindakan VARCHAR(20) NOT NULL DEFAULT ''
+				CHECK (tindakan IN ('', 'ditandai_hilang', 'dipindahkan')),
+			PRIMARY KEY(id_opname, id_unit),
+			FOREIGN KEY(id_opname)
+				REFERENCES stok_opname(id)
+				ON DELETE CASCADE,
+			FOREIGN KEY(id_unit)
+				REFERENCES unit_barang(id)
+				ON DELETE CASCADE,
+			FOREIGN KEY(id_ruangan)
+				REFERENCES ruangan(id)
+				ON DELETE SET NULL
+		);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS stok_opname_unit;
+		DROP TABLE IF EXISTS stok_opname;
+		`,
+	},
+	{
+		Version: 14,
+		Name:    "add_soft_delete",
+		Up: `
+		ALTER TABLE kategori ADD COLUMN IF NOT EXISTS tgl_dihapus TIMESTAMP;
+		ALTER TABLE lokasi ADD COLUMN IF NOT EXISTS tgl_dihapus TIMESTAMP;
+		ALTER TABLE ruangan ADD COLUMN IF NOT EXISTS tgl_dihapus TIMESTAMP;
+		ALTER TABLE barang ADD COLUMN IF NOT EXISTS tgl_dihapus TIMESTAMP;
+		ALTER TABLE unit_barang ADD COLUMN IF NOT EXISTS tgl_dihapus TIMESTAMP;
+
+		CREATE INDEX IF NOT EXISTS kategori_tgl_dihapus_idx ON kategori(tgl_dihapus) WHERE tgl_dihapus IS NOT NULL;
+		CREATE INDEX IF NOT EXISTS lokasi_tgl_dihapus_idx ON lokasi(tgl_dihapus) WHERE tgl_dihapus IS NOT NULL;
+		CREATE INDEX IF NOT EXISTS ruangan_tgl_dihapus_idx ON ruangan(tgl_dihapus) WHERE tgl_dihapus IS NOT NULL;
+		CREATE INDEX IF NOT EXISTS barang_tgl_dihapus_idx ON barang(tgl_dihapus) WHERE tgl_dihapus IS NOT NULL;
+		CREATE INDEX IF NOT EXISTS unit_barang_tgl_dihapus_idx ON unit_barang(tgl_dihapus) WHERE tgl_dihapus IS NOT NULL;
+
+		CREATE OR REPLACE FUNCTION sync_jumlah_ruangan() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE' AND OLD.id_lokasi = NEW.id_lokasi
+				AND (OLD.tgl_dihapus IS NULL) = (NEW.tgl_dihapus IS NULL) THEN
+				RETURN NULL;
+			END IF;
+			IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.tgl_dihapus IS NULL THEN
+				UPDATE lokasi SET jumlah_ruangan = jumlah_ruangan + 1 WHERE id = NEW.id_lokasi;
+			END IF;
+			IF TG_OP IN ('DELETE', 'UPDATE') AND OLD.tgl_dihapus IS NULL THEN
+				UPDATE lokasi SET jumlah_ruangan = jumlah_ruangan - 1 WHERE id = OLD.id_lokasi;
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		CREATE OR REPLACE FUNCTION sync_jumlah_barang() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE' AND OLD.id_ruangan = NEW.id_ruangan
+				AND (OLD.tgl_dihapus IS NULL) = (NEW.tgl_dihapus IS NULL) THEN
+				RETURN NULL;
+			END IF;
+			IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.tgl_dihapus IS NULL THEN
+				UPDATE ruangan SET jumlah_barang = jumlah_barang + 1 WHERE id = NEW.id_ruangan;
+			END IF;
+			IF TG_OP IN ('DELETE', 'UPDATE') AND OLD.tgl_dihapus IS NULL THEN
+				UPDATE ruangan SET jumlah_barang = jumlah_barang - 1 WHERE id = OLD.id_ruangan;
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS ruangan_sync_jumlah_ruangan ON ruangan;
+		CREATE TRIGGER ruangan_sync_jumlah_ruangan
+			AFTER INSERT OR DELETE OR UPDATE OF id_lokasi, tgl_dihapus ON ruangan
+			FOR EACH ROW EXECUTE FUNCTION sync_jumlah_ruangan();
+
+		DROP TRIGGER IF EXISTS unit_barang_sync_jumlah_barang ON unit_barang;
+		CREATE TRIGGER unit_barang_sync_jumlah_barang
+			AFTER INSERT OR DELETE OR UPDATE OF id_ruangan, tgl_dihapus ON unit_barang
+			FOR EACH ROW EXECUTE FUNCTION sync_jumlah_barang();
+		`,
+		Down: `
+		DROP TRIGGER IF EXISTS unit_barang_sync_jumlah_barang ON unit_barang;
+		DROP TRIGGER IF EXISTS ruangan_sync_jumlah_ruangan ON ruangan;
+
+		CREATE OR REPLACE FUNCTION sync_jumlah_ruangan() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE' AND OLD.id_lokasi = NEW.id_lokasi THEN
+				RETURN NULL;
+			END IF;
+			IF TG_OP IN ('INSERT', 'UPDATE') THEN
+				UPDATE lokasi SET jumlah_ruangan = jumlah_ruangan + 1 WHERE id = NEW.id_lokasi;
+			END IF;
+			IF TG_OP IN ('DELETE', 'UPDATE') THEN
+				UPDATE lokasi SET jumlah_ruangan = jumlah_ruangan - 1 WHERE id = OLD.id_lokasi;
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		CREATE OR REPLACE FUNCTION sync_jumlah_barang() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE' AND OLD.id_ruangan = NEW.id_ruangan THEN
+				RETURN NULL;
+			END IF;
+			IF TG_OP IN ('INSERT', 'UPDATE') THEN
+				UPDATE ruangan SET jumlah_barang = jumlah_barang + 1 WHERE id = NEW.id_ruangan;
+			END IF;
+			IF TG_OP IN ('DELETE', 'UPDATE') THEN
+				UPDATE ruangan SET jumlah_barang = jumlah_barang - 1 WHERE id = OLD.id_ruangan;
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		CREATE TRIGGER ruangan_sync_jumlah_ruangan
+			AFTER INSERT OR DELETE OR UPDATE OF id_lokasi ON ruangan
+			FOR EACH ROW EXECUTE FUNCTION sync_jumlah_ruangan();
+
+		CREATE TRIGGER unit_barang_sync_jumlah_barang
+			AFTER INSERT OR DELETE OR UPDATE OF id_ruangan ON unit_barang
+			FOR EACH ROW EXECUTE FUNCTION sync_jumlah_barang();
+
+		DELETE FROM unit_barang WHERE tgl_dihapus IS NOT NULL;
+		DELETE FROM barang WHERE tgl_dihapus IS NOT NULL;
+		DELETE FROM ruangan WHERE tgl_dihapus IS NOT NULL;
+		DELETE FROM lokasi WHERE tgl_dihapus IS NOT NULL;
+		DELETE FROM kategori WHERE tgl_dihapus IS NOT NULL;
+
+		ALTER TABLE unit_barang DROP COLUMN IF EXISTS tgl_dihapus;
+		ALTER TABLE barang DROP COLUMN IF EXISTS tgl_dihapus;
+		ALTER TABLE ruangan DROP COLUMN IF EXISTS tgl_dihapus;
+		ALTER TABLE lokasi DROP COLUMN IF EXISTS tgl_dihapus;
+		ALTER TABLE kategori DROP COLUMN IF EXISTS tgl_dihapus;
+
+		UPDATE lokasi l SET jumlah_ruangan = (SELECT COUNT(*) FROM ruangan r WHERE r.id_lokasi = l.id);
+		UPDATE ruangan r SET jumlah_barang = (SELECT COUNT(*) FROM unit_barang u WHERE u.id_ruangan = r.id);
+		`,
+	},
+	{
+		Version: 15,
+		Name:    "add_location_hierarchy",
+		Up: `
+		ALTER TABLE lokasi ADD COLUMN IF NOT EXISTS id_induk UUID
+			REFERENCES lokasi(id) ON DELETE CASCADE;
+		CREATE INDEX IF NOT EXISTS lokasi_id_induk_idx ON lokasi(id_induk);
+
+		CREATE OR REPLACE FUNCTION lokasi_cegah_siklus() RETURNS trigger AS $$
+		BEGIN
+			IF NEW.id_induk IS NULL THEN
+				RETURN NEW;
+			END IF;
+			IF NEW.id_induk = NEW.id OR EXISTS (
+				WITH RECURSIVE naik AS (
+					SELECT id, id_induk FROM lokasi WHERE id = NEW.id_induk
+					UNION
+					SELECT l.id, l.id_induk FROM lokasi l JOIN naik ON l.id = naik.id_induk
+				)
+				SELECT 1 FROM naik WHERE id = NEW.id
+			) THEN
+				RAISE EXCEPTION 'lokasi % tidak boleh berada di bawah dirinya sendiri', NEW.id;
+			END IF;
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS lokasi_cegah_siklus ON lokasi;
+		CREATE TRIGGER lokasi_cegah_siklus
+			BEFORE INSERT OR UPDATE OF id_induk ON lokasi
+			FOR EACH ROW EXECUTE FUNCTION lokasi_cegah_siklus();
+		`,
+		Down: `
+		DROP TRIGGER IF EXISTS lokasi_cegah_siklus ON lokasi;
+		DROP FUNCTION IF EXISTS lokasi_cegah_siklus();
+		DROP INDEX IF EXISTS lokasi_id_induk_idx;
+		ALTER TABLE lokasi DROP COLUMN IF EXISTS id_induk;
+		`,
+	},
+	{
+		Version: 16,
+		Name:    "add_category_tree_and_attributes",
+		Up: `
+		ALTER TABLE kategori ADD COLUMN IF NOT EXISTS id_induk INTEGER
+			REFERENCES kategori(id) ON DELETE CASCADE;
+		ALTER TABLE kategori ADD COLUMN IF NOT EXISTS atribut JSONB NOT NULL DEFAULT '[]'
+			CHECK (jsonb_typeof(atribut) = 'array');
+		CREATE INDEX IF NOT EXISTS kategori_id_induk_idx ON kategori(id_induk);
+
+		ALTER TABLE barang ADD COLUMN IF NOT EXISTS atribut JSONB NOT NULL DEFAULT '{}'
+			CHECK (jsonb_typeof(atribut) = 'object');
+		CREATE INDEX IF NOT EXISTS barang_atribut_idx ON barang USING GIN (atribut);
+
+		CREATE OR REPLACE FUNCTION kategori_cegah_siklus() RETURNS trigger AS $$
+		BEGIN
+			IF NEW.id_induk IS NULL THEN
+				RETURN NEW;
+			END IF;
+			IF NEW.id_induk = NEW.id OR EXISTS (
+				WITH RECURSIVE naik AS (
+					SELECT id, id_induk FROM kategori WHERE id = NEW.id_induk
+					UNION
+					SELECT k.id, k.id_induk FROM kategori k JOIN naik ON k.id = naik.id_induk
+				)
+				SELECT 1 FROM naik WHERE id = NEW.id
+			) THEN
+				RAISE EXCEPTION 'kategori % tidak boleh berada di bawah dirinya sendiri', NEW.id;
+			END IF;
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS kategori_cegah_siklus ON kategori;
+		CREATE TRIGGER kategori_cegah_siklus
+			BEFORE INSERT OR UPDATE OF id_induk ON kategori
+			FOR EACH ROW EXECUTE FUNCTION kategori_cegah_siklus();
+		`,
+		Down: `
+		DROP TRIGGER IF EXISTS kategori_cegah_siklus ON kategori;
+		DROP FUNCTION IF EXISTS kategori_cegah_siklus();
+		DROP INDEX IF EXISTS barang_atribut_idx;
+		ALTER TABLE barang DROP COLUMN IF EXISTS atribut;
+		DROP INDEX IF EXISTS kategori_id_induk_idx;
+		ALTER TABLE kategori DROP COLUMN IF EXISTS atribut;
+		ALTER TABLE kategori DROP COLUMN IF EXISTS id_induk;
+		`,
+	},
+	{
+		Version: 17,
+		Name:    "create_peminjaman",
+		Up: `
+		CREATE TABLE IF NOT EXISTS peminjaman (
+			id UUID PRIMARY KEY,
+			peminjam VARCHAR(100) NOT NULL,
+			identitas VARCHAR(50) NOT NULL,
+			kontak VARCHAR(100) NOT NULL DEFAULT '',
+			keperluan TEXT NOT NULL,
+			tgl_pinjam DATE NOT NULL,
+			tgl_jatuh_tempo DATE NOT NULL,
+			id_pengguna UUID,
+			petugas VARCHAR(100) NOT NULL,
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK (tgl_jatuh_tempo >= tgl_pinjam),
+			FOREIGN KEY(id_pengguna)
+				REFERENCES pengguna(id)
+				ON DELETE SET NULL
+		);
+		CREATE INDEX IF NOT EXISTS peminjaman_identitas_idx ON peminjaman(identitas);
+		CREATE INDEX IF NOT EXISTS peminjaman_tgl_pinjam_idx ON peminjaman(tgl_pinjam);
+
+		CREATE TABLE IF NOT EXISTS peminjaman_unit (
+			id_peminjaman UUID NOT NULL,
+			id_unit UUID NOT NULL,
+			kondisi_pinjam VARCHAR(20) NOT NULL,
+			tgl_kembali DATE,
+			kondisi_kembali VARCHAR(20) NOT NULL DEFAULT '',
+			catatan_kembali TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY(id_peminjaman, id_unit),
+			FOREIGN KEY(id_peminjaman)
+				REFERENCES peminjaman(id)
+				ON DELETE CASCADE,
+			FOREIGN KEY(id_unit)
+				REFERENCES unit_barang(id)
+				ON DELETE CASCADE
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS peminjaman_unit_aktif_idx
+			ON peminjaman_unit(id_unit) WHERE tgl_kembali IS NULL;
+		`,
+		Down: `
+		DROP TABLE IF EXISTS peminjaman_unit;
+		DROP TABLE IF EXISTS peminjaman;
+		`,
+	},
+	{
+		Version: 18,
+		Name:    "create_perbaikan",
+		Up: `
+		CREATE TABLE IF NOT EXISTS perbaikan (
+			id UUID PRIMARY KEY,
+			id_unit UUID NOT NULL,
+			id_ruangan UUID,
+			masalah TEXT NOT NULL,
+			vendor VARCHAR(100) NOT NULL DEFAULT '',
+			biaya INTEGER NOT NULL DEFAULT 0 CHECK (biaya >= 0),
+			status VARCHAR(20) NOT NULL DEFAULT 'berjalan',
+			kondisi_awal VARCHAR(20) NOT NULL,
+			kondisi_hasil VARCHAR(20) NOT NULL DEFAULT '',
+			catatan TEXT NOT NULL DEFAULT '',
+			tgl_mulai DATE NOT NULL,
+			tgl_selesai DATE,
+			id_pengguna UUID,
+			petugas VARCHAR(100) NOT NULL,
+			tgl_dibuat TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CHECK (tgl_selesai IS NULL OR tgl_selesai >= tgl_mulai),
+			FOREIGN KEY(id_unit)
+				REFERENCES unit_barang(id)
+				ON DELETE CASCADE,
+			FOREIGN KEY(id_ruangan)
+				REFERENCES ruangan(id)
+				ON DELETE SET NULL,
+			FOREIGN KEY(id_pengguna)
+				REFERENCES pengguna(id)
+				ON DELETE SET NULL
+		);
+		CREATE INDEX IF NOT EXISTS perbaikan_id_unit_idx ON perbaikan(id_unit);
+		CREATE INDEX IF NOT EXISTS perbaikan_tgl_mulai_idx ON perbaikan(tgl_mulai);
+		CREATE UNIQUE INDEX IF NOT EXISTS perbaikan_berjalan_idx
+			ON perbaikan(id_unit) WHERE status = 'berjalan';
+		`,
+		Down: `
+		DROP TABLE IF EXISTS perbaikan;
+		`,
+	},
+	{
+		Version: 19,
+		Name:    "complete_kon_unit_barang",
+		// An enum created before migration 6 may lack the later conditions.
+		Up: `
+		ALTER TYPE kon_unit_barang ADD VALUE IF NOT EXISTS 'diperbaiki';
+		ALTER TYPE kon_unit_barang ADD VALUE IF NOT EXISTS 'digudangkan';
+		`,
+		// Postgres cannot drop enum labels, they go with the type when
+		// migration 6 is reverted.
+		Down: `SELECT 1;`,
+	},
+	{
+		Version: 20,
+		Name:    "add_tgl_perolehan",
+		Up: `
+		ALTER TABLE barang ADD COLUMN IF NOT EXISTS tgl_perolehan DATE;
+		UPDATE barang SET tgl_perolehan = tgl_dibuat::date WHERE tgl_perolehan IS NULL;
+		ALTER TABLE barang
+			ALTER COLUMN tgl_perolehan SET DEFAULT CURRENT_DATE,
+			ALTER COLUMN tgl_perolehan SET NOT NULL;
+		ALTER TABLE unit_barang ADD COLUMN IF NOT EXISTS tgl_perolehan DATE;
+		UPDATE unit_barang SET tgl_perolehan = tgl_dibuat::date WHERE tgl_perolehan IS NULL;
+		ALTER TABLE unit_barang
+			ALTER COLUMN tgl_perolehan SET DEFAULT CURRENT_DATE,
+			ALTER COLUMN tgl_perolehan SET NOT NULL;
+		`,
+		Down: `
+		ALTER TABLE unit_barang DROP COLUMN IF EXISTS tgl_perolehan;
+		ALTER TABLE barang DROP COLUMN IF EXISTS tgl_perolehan;
+		`,
+	},
+}
@@ -0,0 +1,49 @@
+package storage
+
+import "testing"
+
+// Applied migrations are refused when their checksum changes, so the SQL of
+// released migrations must stay byte for byte the same.
+func TestMigrationChecksumsArePinned(t *testing.T) {
+	pinned := map[int]string{
+		1:  "2df242e85d6768fd1cb448e3543812280a6ecac6f97e2bf3bb60592604a21292",
+		2:  "56738831218cd3cacf2db56912f20df438b074f059845ace5f349e1092d2501c",
+		3:  "1edcbcc37a973bf0ff8e8708d35ac7726b30bbebc089e40a32eeb24a75c64241",
+		4:  "b6710b4bd844ec8c6786690d1a31493a200423debd67b513d4ac40baa5375543",
+		5:  "29eea3a6d339e5efbf36175ba9e47f1f64445b362ff3c2c1a61d577f1ffedc0f",
+		6:  "d4d938800be2d00a46359ff55e88b4e1b2ceb583c780f668dcad396414f72ecf",
+		7:  "b5c4f805f53952c809062ae77caf46069f3fd42572780188fb0fb8c33c9f418a",
+		8:  "ddb47e878e55128a439cbc01548851e9be99a51d7a034d29c6c6557975440a5e",
+		9:  "7923c6aa7ceffd2efcceff2f431a1b59d1d07a4680ae9e6141517666cb275abb",
+		10: "d28d138f45a1a1dc245738356a2c25b5584f8cac273ff3f4e133be68409e5492",
+		11: "c6221d231d1aad4382d733a6bac7de8ecb061f4334ab890e71715c942d461c60",
+		12: "f685c3cdcf01aae2ee7d1a5e90222683430fe41db2bafb5b883b4a76c8984979",
+		13: "4f634fae7896b3ff3dea5f91bb949f28ee7e9da3b585e59d13a680b9c0b364b8",
+		14: "adcdeac04cd0b165935143299c1ce96fd7d194d15ee292b4ee5d146609988599",
+		15: "3d67979157f9bda0e7bce2dd8dbc8a1768777f3eec252900120301be120c01b4",
+		16: "b9da13506f10a35eacdf42e2fb29d57823baec471f8a4bd6dcce84ef662582b6",
+		17: "e067d528d81b7fe676155db6a46e3b57da51a81afa209108046589a37e35e110",
+		18: "9476723f89359fd0109c0444a0405404e88da11b7f4fa3a523154af0b048e2ee",
+		19: "00141448ccb9a1f200c2c098d4cb9fa07cd43efecba1b3ece6cf97b771114e6c",
+		20: "f051c604bfae6b6cc62a9d9c09347ada462fd55806ef70891439aafc32447e8d",
+	}
+
+	for _, m := range migrations {
+		want, ok := pinned[m.Version]
+		if !ok {
+			t.Errorf("migration %d_%s has no pinned checksum", m.Version, m.Name)
+			continue
+		}
+		if m.Checksum() != want {
+			t.Errorf("migration %d_%s checksum = %s, want %s", m.Version, m.Name, m.Checksum(), want)
+		}
+	}
+}
+
+func TestMigrationVersionsAreSequential(t *testing.T) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migration %d_%s at position %d, want version %d", m.Version, m.Name, i, i+1)
+		}
+	}
+}
@@ -1 +1,73 @@
 package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type MinioStore struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewMinio(ctx context.Context, endpoint, accessKey, secretKey, bucket string, useSSL bool) (*MinioStore, error) {
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating minio client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("checking bucket %s: %w", bucket, err)
+	}
+
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
+			return nil, fmt.Errorf("creating bucket %s: %w", bucket, err)
+		}
+	}
+
+	return &MinioStore{client: client, bucket: bucket}, nil
+}
+
+func (m *MinioStore) PutObject(ctx context.Context, name string, r io.Reader, size int64, contentType string) error {
+	_, err := m.client.PutObject(ctx, m.bucket, name, r, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("putting object %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func (m *MinioStore) GetObject(ctx context.Context, name string) (io.ReadCloser, ObjectInfo, error) {
+	obj, err := m.client.GetObject(ctx, m.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, ObjectInfo{}, fmt.Errorf("getting object %s: %w", name, err)
+	}
+
+	stat, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ObjectInfo{}, ErrObjectNotFound
+		}
+		return nil, ObjectInfo{}, fmt.Errorf("stat object %s: %w", name, err)
+	}
+
+	return obj, ObjectInfo{Size: stat.Size, ContentType: stat.ContentType}, nil
+}
+
+func (m *MinioStore) RemoveObject(ctx context.Context, name string) error {
+	if err := m.client.RemoveObject(ctx, m.bucket, name, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("removing object %s: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/qeunasd/coniven/utils"
+)
+
+var ErrObjectNotFound = fmt.Errorf("object %w", utils.ErrNotFound)
+
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+type ObjectStore interface {
+	PutObject(ctx context.Context, name string, r io.Reader, size int64, contentType string) error
+	GetObject(ctx context.Context, name string) (io.ReadCloser, ObjectInfo, error)
+	RemoveObject(ctx context.Context, name string) error
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/qeunasd/coniven/entities"
+)
+
+// schemaEntities are checked column by column; derived lists db tags that are
+// computed by the queries rather than stored.
+var schemaEntities = []struct {
+	table   string
+	entity  any
+	derived []string
+}{
+	{"kategori", entities.Category{}, nil},
+	{"lokasi", entities.Location{}, nil},
+	{"ruangan", entities.Room{}, nil},
+	{"barang", entities.Item{}, nil},
+	{"gambar_barang", entities.ItemPicture{}, nil},
+	{"unit_barang", entities.ItemUnit{}, nil},
+	{"mutasi", entities.Transfer{}, nil},
+	{"pengguna", entities.User{}, nil},
+	{"sesi", entities.Session{}, nil},
+	{"audit_log", entities.AuditLog{}, nil},
+	{"stok_opname", entities.StockOpname{}, nil},
+	{"stok_opname_unit", entities.OpnameUnit{}, nil},
+	{"peminjaman", entities.Loan{}, []string{"status", "jumlah_unit", "jumlah_kembali"}},
+	{"peminjaman_unit", entities.LoanUnit{}, nil},
+	{"perbaikan", entities.Repair{}, nil},
+}
+
+func entityColumns(entity any, derived ...string) []string {
+	t := reflect.TypeOf(entity)
+
+	var cols []string
+	for i := range t.NumField() {
+		tag := t.Field(i).Tag.Get("db")
+		if tag == "" || tag == "-" || slices.Contains(derived, tag) {
+			continue
+		}
+		cols = append(cols, tag)
+	}
+
+	return cols
+}
+
+// CheckSchema compares the db tags of the entities against information_schema
+// and the kon_unit_barang labels against entities.KondisiUnits, returning an
+// error describing every difference found.
+func (s *Storage) CheckSchema(ctx context.Context) error {
+	var drift []string
+
+	for _, se := range schemaEntities {
+		rows, err := s.db.Query(ctx, `
+			SELECT column_name FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = $1
+		`, se.table)
+		if err != nil {
+			return fmt.Errorf("querying columns of %s: %w", se.table, err)
+		}
+
+		existing := make(map[string]bool)
+		for rows.Next() {
+			var col string
+			if err := rows.Scan(&col); err != nil {
+				rows.Close()
+				return fmt.Errorf("scanning columns of %s: %w", se.table, err)
+			}
+			existing[col] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("reading columns of %s: %w", se.table, err)
+		}
+
+		if len(existing) == 0 {
+			drift = append(drift, fmt.Sprintf("table %s does not exist", se.table))
+			continue
+		}
+
+		for _, col := range entityColumns(se.entity, se.derived...) {
+			if !existing[col] {
+				drift = append(drift, fmt.Sprintf("column %s.%s does not exist", se.table, col))
+			}
+		}
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT e.enumlabel FROM pg_enum e
+		JOIN pg_type t ON t.oid = e.enumtypid
+		WHERE t.typname = 'kon_unit_barang'
+	`)
+	if err != nil {
+		return fmt.Errorf("querying kon_unit_barang labels: %w", err)
+	}
+
+	labels := make(map[string]bool)
+	for rows.Next() {
+		var label string
+		if err := rows.Scan(&label); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning kon_unit_barang labels: %w", err)
+		}
+		labels[label] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading kon_unit_barang labels: %w", err)
+	}
+
+	for _, k := range entities.KondisiUnits() {
+		if !labels[string(k)] {
+			drift = append(drift, fmt.Sprintf("enum kon_unit_barang is missing %q", k))
+		}
+	}
+
+	if len(drift) > 0 {
+		sort.Strings(drift)
+		return fmt.Errorf("schema drift detected:\n  %s", strings.Join(drift, "\n  "))
+	}
+
+	return nil
+}
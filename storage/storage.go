@@ -4,102 +4,273 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/minio/minio-go/v7"
 	"github.com/qeunasd/coniven/entities"
+	"github.com/qeunasd/coniven/utils"
 )
 
 type CategoryRepository interface {
-	SaveCategory(ctx context.Context, category entities.Category) error
+	SaveCategory(ctx context.Context, category entities.Category, audit func(rec AuditRecorder, id int) error) (int, error)
 	FindCategoryByCode(ctx context.Context, code string) (bool, error)
 	GetCategoryById(ctx context.Context, id int) (entities.Category, error)
-	UpdateCategory(ctx context.Context, category entities.Category) error
+	UpdateCategory(ctx context.Context, category entities.Category, audit Audit) error
 	CountCategories(ctx context.Context, where string, args []interface{}) (int, error)
 	GetCategoriesWithFilter(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Category, error)
 	FindCategoryByName(ctx context.Context, name string) (bool, error)
-	DeleteCategory(ctx context.Context, id int) error
+	DeleteCategory(ctx context.Context, id int, audit Audit) error
+	GetCategoryPaths(ctx context.Context, ids []int) (map[int][]entities.Category, error)
+	MoveCategory(ctx context.Context, id int, parent *int, check CategoryTreeChecker, audit Audit) error
+	UpdateCategoryAttributes(ctx context.Context, id int, fields []entities.AttributeField, check CategoryTreeChecker, audit Audit) error
 }
 
 type LocationRepository interface {
-	SaveLocation(ctx context.Context, location entities.Location) error
+	SaveLocation(ctx context.Context, location entities.Location, audit Audit) error
 	CountTotalLocations(ctx context.Context, where string, args []interface{}) (int, error)
 	GetLocations(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Location, error)
 	GetLocationBySlug(ctx context.Context, slug string) (entities.Location, error)
 	GetLocationById(ctx context.Context, id uuid.UUID) (entities.Location, error)
 	FindLocationByCode(ctx context.Context, code string) (bool, error)
 	GetLocationWithRooms(ctx context.Context, id uuid.UUID) (*entities.Location, error)
-	UpdateLocation(ctx context.Context, loc entities.Location) error
-	DeleteLocation(ctx context.Context, id uuid.UUID) error
+	UpdateLocation(ctx context.Context, loc entities.Location, audit Audit) error
+	DeleteLocation(ctx context.Context, id uuid.UUID, audit Audit) error
+	GetSubLocations(ctx context.Context, id uuid.UUID) ([]entities.Location, error)
+	GetLocationPaths(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]entities.LocationRef, error)
+	GetLocationRollups(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]entities.LocationRollup, error)
+	MoveLocation(ctx context.Context, id uuid.UUID, parent uuid.NullUUID, check LocationMoveChecker, audit Audit) error
 }
 
 type RoomRepository interface {
-	CreateRoom(ctx context.Context, room entities.Room) error
+	CreateRoom(ctx context.Context, room entities.Room, audit Audit) error
 	CountRoomWithFilter(ctx context.Context, where string, args []interface{}) (int, error)
 	GetRooms(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Room, error)
 	GetRoomBySlug(ctx context.Context, slug string) (entities.Room, error)
-	UpdateRoom(ctx context.Context, room entities.Room) error
+	UpdateRoom(ctx context.Context, room entities.Room, audit Audit) error
 	GetRoomById(ctx context.Context, id uuid.UUID) (entities.Room, error)
-	DeleteRoom(ctx context.Context, id uuid.UUID) error
+	DeleteRoom(ctx context.Context, id uuid.UUID, audit Audit) error
 	GetRoomWithItems(ctx context.Context, id uuid.UUID) (*entities.Room, error)
+	GetLocationById(ctx context.Context, id uuid.UUID) (entities.Location, error)
 }
 
 type ItemRepository interface {
-	CreateItem(ctx context.Context, name, code string) error
+	CreateItem(ctx context.Context, item entities.Item, audit Audit) error
+	FindItemBySKU(ctx context.Context, sku string) (bool, error)
+	CountItems(ctx context.Context, where string, args []interface{}) (int, error)
+	GetItems(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Item, error)
+	GetItemBySlug(ctx context.Context, slug string) (entities.Item, error)
+	GetItemById(ctx context.Context, id uuid.UUID) (entities.Item, error)
+	UpdateItem(ctx context.Context, item entities.Item, audit Audit) error
+	DeleteItem(ctx context.Context, id uuid.UUID, audit Audit) error
+	GetCategoryById(ctx context.Context, id int) (entities.Category, error)
+	GetCategoryPaths(ctx context.Context, ids []int) (map[int][]entities.Category, error)
 }
 
-type Storage struct {
-	db *pgxpool.Pool
-	mn *minio.Client
+type UnitRepository interface {
+	CreateUnits(ctx context.Context, units []entities.ItemUnit, audit Audit) error
+	CountUnitsByItem(ctx context.Context, idBarang uuid.UUID) (int, error)
+	FindUnitSerials(ctx context.Context, idBarang uuid.UUID, serials []string) ([]string, error)
+	GetUnitsByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.ItemUnit, error)
+	GetUnitById(ctx context.Context, id uuid.UUID) (entities.ItemUnit, error)
+	UpdateUnitSerial(ctx context.Context, unit entities.ItemUnit, audit Audit) error
+	UpdateUnitCondition(ctx context.Context, id uuid.UUID, from, to entities.KondisiUnit, audit Audit) error
+	DeleteUnit(ctx context.Context, id uuid.UUID, audit Audit) error
+	GetItemBySlug(ctx context.Context, slug string) (entities.Item, error)
+	GetItemById(ctx context.Context, id uuid.UUID) (entities.Item, error)
+	GetRoomById(ctx context.Context, id uuid.UUID) (entities.Room, error)
 }
 
-func NewRepository(db *pgxpool.Pool, mn *minio.Client) *Storage {
-	return &Storage{db: db, mn: mn}
+type PictureRepository interface {
+	SavePicture(ctx context.Context, picture entities.ItemPicture, r io.Reader, contentType string, audit Audit) error
+	GetPicturesByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.ItemPicture, error)
+	GetPictureById(ctx context.Context, id int) (entities.ItemPicture, error)
+	OpenPicture(ctx context.Context, picture entities.ItemPicture) (io.ReadCloser, ObjectInfo, error)
+	DeletePicture(ctx context.Context, picture entities.ItemPicture, audit Audit) error
+	GetItemBySlug(ctx context.Context, slug string) (entities.Item, error)
 }
 
-func (s *Storage) RunMigration(ctx context.Context) error {
-	tx, err := s.db.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("(msg): begin transaction (err): %w", err)
-	}
-	defer func() {
-		if err := recover(); err != nil {
-			tx.Rollback(ctx)
-			panic(err)
-		}
-	}()
+type TransferRepository interface {
+	TransferUnits(ctx context.Context, req entities.TransferRequest, build TransferBuilder, audit Audit) error
+	GetTransfersByUnit(ctx context.Context, idUnit uuid.UUID) ([]entities.Transfer, error)
+	GetUnitById(ctx context.Context, id uuid.UUID) (entities.ItemUnit, error)
+	GetRoomBySlug(ctx context.Context, slug string) (entities.Room, error)
+}
 
-	if err := createCategoryTable(tx, ctx); err != nil {
-		return err
-	}
+type ReportRepository interface {
+	GetRoomBySlug(ctx context.Context, slug string) (entities.Room, error)
+	GetRoomWithItems(ctx context.Context, id uuid.UUID) (*entities.Room, error)
+	GetUnitsByIds(ctx context.Context, ids []uuid.UUID) ([]entities.ItemUnit, error)
+	GetValuationLines(ctx context.Context, cutoff time.Time) ([]entities.ValuationItem, []entities.ValuationUnit, error)
+}
 
-	if err := createLocationTable(tx, ctx); err != nil {
-		return err
-	}
+type DepreciationRepository interface {
+	GetItemBySlug(ctx context.Context, slug string) (entities.Item, error)
+	GetUnitsByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.ItemUnit, error)
+	GetItemsAcquiredBefore(ctx context.Context, before time.Time) ([]entities.Item, error)
+}
 
-	if err := createRoomTable(tx, ctx); err != nil {
-		return err
-	}
+type UserRepository interface {
+	CreateUser(ctx context.Context, user entities.User, audit Audit) error
+	GetUsers(ctx context.Context) ([]entities.User, error)
+	GetUserById(ctx context.Context, id uuid.UUID) (entities.User, error)
+	GetUserByUsername(ctx context.Context, username string) (entities.User, error)
+	UpdateUser(ctx context.Context, user entities.User, audit Audit) error
+	DeleteUser(ctx context.Context, id uuid.UUID, audit Audit) error
+	CreateSession(ctx context.Context, session entities.Session) error
+	GetSessionUser(ctx context.Context, id string, now time.Time) (entities.User, error)
+	DeleteSession(ctx context.Context, id string) error
+	DeleteUserSessions(ctx context.Context, idPengguna uuid.UUID) error
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+}
 
-	if err := createItemTable(tx, ctx); err != nil {
-		return err
-	}
+type AuditRepository interface {
+	CountAuditLogs(ctx context.Context, where string, args []interface{}) (int, error)
+	GetAuditLogs(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.AuditLog, error)
+}
 
-	if err := createItemPictureTable(tx, ctx); err != nil {
-		return err
-	}
+type OpnameRepository interface {
+	CreateOpname(ctx context.Context, op entities.StockOpname, audit Audit) error
+	CountOpnames(ctx context.Context, where string, args []interface{}) (int, error)
+	GetOpnames(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.StockOpname, error)
+	GetOpnameById(ctx context.Context, id uuid.UUID) (entities.StockOpname, error)
+	GetOpnameUnits(ctx context.Context, idOpname uuid.UUID) ([]entities.OpnameUnit, error)
+	RecordOpnameScan(ctx context.Context, idOpname uuid.UUID, unit entities.ItemUnit, kode string, at time.Time) (entities.OpnameUnit, bool, error)
+	UpdateOpnameNote(ctx context.Context, idOpname, idUnit uuid.UUID, catatan string) error
+	CloseOpname(ctx context.Context, id uuid.UUID, build OpnameCloser, audit Audit) error
+	GetUnitById(ctx context.Context, id uuid.UUID) (entities.ItemUnit, error)
+	GetUnitsBySerial(ctx context.Context, serial string) ([]entities.ItemUnit, error)
+	GetRoomById(ctx context.Context, id uuid.UUID) (entities.Room, error)
+	GetLocationById(ctx context.Context, id uuid.UUID) (entities.Location, error)
+}
 
-	if err := createItemUnitTable(tx, ctx); err != nil {
-		return err
-	}
+type LoanRepository interface {
+	CheckoutUnits(ctx context.Context, req entities.LoanRequest, build LoanBuilder, audit Audit) error
+	CountLoans(ctx context.Context, where string, args []interface{}) (int, error)
+	GetLoans(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Loan, error)
+	GetLoanById(ctx context.Context, id uuid.UUID) (entities.Loan, error)
+	GetLoanUnits(ctx context.Context, idPeminjaman uuid.UUID) ([]entities.LoanUnit, error)
+	ReturnUnits(ctx context.Context, id uuid.UUID, build LoanReturner, audit Audit) error
+	GetOverdueLoanUnits(ctx context.Context, today time.Time) ([]entities.LoanUnit, error)
+	GetLoanUnitsByBorrower(ctx context.Context, identitas string) ([]entities.LoanUnit, error)
+	GetUnitsByIds(ctx context.Context, ids []uuid.UUID) ([]entities.ItemUnit, error)
+}
 
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("(msg): commit transaction (err): %w", err)
-	}
+type RepairRepository interface {
+	OpenRepair(ctx context.Context, idUnit uuid.UUID, open RepairOpener, audit Audit) error
+	CloseRepair(ctx context.Context, id uuid.UUID, finish RepairCloser, audit Audit) error
+	CountRepairs(ctx context.Context, where string, args []interface{}) (int, error)
+	GetRepairs(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Repair, error)
+	GetRepairById(ctx context.Context, id uuid.UUID) (entities.Repair, error)
+	GetRepairsByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.Repair, error)
+	GetRepairSpendLines(ctx context.Context, dari, sampai time.Time) ([]entities.RepairSpendLine, error)
+	GetUnitById(ctx context.Context, id uuid.UUID) (entities.ItemUnit, error)
+}
 
-	return nil
+type ImportRepository interface {
+	GetCategoriesWithFilter(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Category, error)
+	GetLocations(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Location, error)
+	FindItemSKUs(ctx context.Context, skus []string) ([]string, error)
+	ImportRecords(ctx context.Context, batch *entities.ImportBatch, audit Audit) error
+}
+
+type ExportRepository interface {
+	EachCategory(ctx context.Context, sort, where string, args []interface{}, fn func(entities.Category) error) error
+	EachLocation(ctx context.Context, sort, where string, args []interface{}, fn func(entities.Location) error) error
+	EachRoom(ctx context.Context, sort, where string, args []interface{}, fn func(entities.Room) error) error
+	EachItem(ctx context.Context, sort, where string, args []interface{}, fn func(entities.Item) error) error
+}
+
+type BulkRepository interface {
+	SummarizeCategoryDelete(ctx context.Context, ids []int) (entities.DeleteSummary, error)
+	SummarizeLocationDelete(ctx context.Context, ids []uuid.UUID) (entities.DeleteSummary, error)
+	SummarizeRoomDelete(ctx context.Context, ids []uuid.UUID) (entities.DeleteSummary, error)
+	DeleteCategories(ctx context.Context, ids []int, audit func(rec AuditRecorder, deleted []entities.Category) error) error
+	DeleteLocations(ctx context.Context, ids []uuid.UUID, audit func(rec AuditRecorder, deleted []entities.Location) error) error
+	DeleteRooms(ctx context.Context, ids []uuid.UUID, audit func(rec AuditRecorder, deleted []entities.Room) error) error
+	MoveRooms(ctx context.Context, ids []uuid.UUID, idLokasi uuid.UUID, check RoomMoveChecker, audit Audit) error
+	ChangeUnitConditions(ctx context.Context, ids []uuid.UUID, to entities.KondisiUnit, check UnitConditionChecker, audit Audit) error
+}
+
+type TrashRepository interface {
+	GetTrash(ctx context.Context, jenis entities.JenisSampah) ([]entities.TrashEntry, error)
+	RestoreTrash(ctx context.Context, jenis entities.JenisSampah, id any, check TrashChecker, audit Audit) error
+	PurgeTrash(ctx context.Context, jenis entities.JenisSampah, id any, check TrashChecker, audit Audit) error
+	PurgeExpiredTrash(ctx context.Context, before time.Time) (int64, error)
+}
+
+// TransferBuilder validates the locked units against the destination room and
+// returns the history rows to record. It runs inside the transfer transaction.
+// onLoan holds the loan each unit is out on, if any.
+type TransferBuilder func(units []entities.ItemUnit, lastMoved map[uuid.UUID]time.Time, onLoan map[uuid.UUID]entities.Loan, dest entities.Room) ([]entities.Transfer, error)
+
+// OpnameCloser decides, inside the closing transaction, what happens to the
+// units of a session. Unit holds the locked current state of each unit and is
+// zero when the unit has been deleted since; dest is the counted room, zero
+// for a location session.
+type OpnameCloser func(op entities.StockOpname, units []entities.OpnameUnit, lastMoved map[uuid.UUID]time.Time, onLoan map[uuid.UUID]entities.Loan, dest entities.Room) (entities.OpnameClosing, error)
+
+// LoanBuilder validates the locked units of a new loan, with the loan each is
+// already out on, and returns the rows to record.
+type LoanBuilder func(units []entities.ItemUnit, onLoan map[uuid.UUID]entities.Loan) ([]entities.LoanUnit, error)
+
+// LoanReturner picks, from the locked units of a loan, the ones to check in.
+// Each returned row carries its return date and condition; Unit.Kondisi is
+// what the unit had when it was locked.
+type LoanReturner func(loan entities.Loan, units []entities.LoanUnit) ([]entities.LoanUnit, error)
+
+// RepairOpener checks the locked unit, the loan it is out on and whether it
+// already has an open ticket, and returns the ticket to record.
+type RepairOpener func(unit entities.ItemUnit, onLoan map[uuid.UUID]entities.Loan, open bool) (entities.Repair, error)
+
+// RepairCloser returns the closed ticket from the locked one. unit is the
+// locked unit, zero when it has been deleted since the ticket was opened.
+type RepairCloser func(repair entities.Repair, unit entities.ItemUnit) (entities.Repair, error)
+
+// RoomMoveChecker and UnitConditionChecker validate the locked rows of a bulk
+// update inside its transaction; an error rolls the whole update back.
+type RoomMoveChecker func(rooms []entities.Room, dest entities.Location) error
+
+type UnitConditionChecker func(units []entities.ItemUnit, to entities.KondisiUnit) error
+
+// LocationMoveChecker validates a location move under the tree lock. path runs
+// from the top level down to the new parent and is nil when there is none.
+type LocationMoveChecker func(loc entities.Location, path []entities.LocationRef) error
+
+// CategoryTreeChecker validates a change to a category under the tree lock.
+// ancestors run from the top level down; descendants are the live categories
+// under it.
+type CategoryTreeChecker func(category entities.Category, ancestors, descendants []entities.Category) error
+
+// TrashChecker decides whether the locked deleted record may be restored or
+// purged; an error leaves it in the trash.
+type TrashChecker func(state entities.TrashState) error
+
+// AuditRecorder writes audit entries. A mutation hands its Audit callback one
+// bound to its own transaction, so the entries commit or roll back together
+// with the rows they describe.
+type AuditRecorder interface {
+	CreateAuditLog(ctx context.Context, entry entities.AuditLog) error
+}
+
+// Audit records the audit entries of a mutation once its rows are written.
+type Audit func(rec AuditRecorder) error
+
+type txAudit struct {
+	tx pgx.Tx
+}
+
+type Storage struct {
+	db      *pgxpool.Pool
+	objects ObjectStore
+}
+
+func NewRepository(db *pgxpool.Pool, objects ObjectStore) *Storage {
+	return &Storage{db: db, objects: objects}
 }
 
 // Category Area
@@ -128,38 +299,49 @@ func (s *Storage) FindCategoryByName(ctx context.Context, name string) (bool, er
 	return count > 0, nil
 }
 
-func (s *Storage) SaveCategory(ctx context.Context, category entities.Category) error {
+func (s *Storage) SaveCategory(ctx context.Context, category entities.Category, audit func(rec AuditRecorder, id int) error) (int, error) {
 	sql := `
-		INSERT INTO kategori (kode, nama, tgl_dibuat, tgl_update)
-		VALUES ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING
+		INSERT INTO kategori (kode, nama, tgl_dibuat, tgl_update, id_induk, atribut)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO NOTHING
+		RETURNING id
 	`
 
-	commandTag, err := s.db.Exec(ctx, sql, category.Kode, category.Nama, category.TglDibuat, category.TglUpdate)
-	if err != nil {
-		return fmt.Errorf("(msg): querying save category (err): %w", err)
-	}
+	var id int
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, sql, category.Kode, category.Nama, category.TglDibuat, category.TglUpdate, category.IdInduk, category.Atribut).Scan(&id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return errors.New("failed to save category")
+			}
+			if isUniqueViolation(err) {
+				return ErrDuplicate
+			}
+			return fmt.Errorf("(msg): querying save category (err): %w", err)
+		}
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("failed to save category")
+		return audit(txAudit{tx}, id)
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	return id, nil
 }
 
 func (s *Storage) GetCategoryById(ctx context.Context, id int) (entities.Category, error) {
 	sql := `
-		SELECT id, kode, nama, tgl_dibuat, tgl_update 
-		FROM kategori WHERE id = $1
+		SELECT id, kode, nama, tgl_dibuat, tgl_update, id_induk, atribut
+		FROM kategori WHERE id = $1 AND tgl_dihapus IS NULL
 	`
 	var category entities.Category
 
 	err := s.db.QueryRow(ctx, sql, id).Scan(
 		&category.Id, &category.Kode, &category.Nama,
-		&category.TglDibuat, &category.TglUpdate,
+		&category.TglDibuat, &category.TglUpdate, &category.IdInduk, &category.Atribut,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return entities.Category{}, errors.New("not found")
+			return entities.Category{}, utils.ErrNotFound
 		}
 		return entities.Category{}, fmt.Errorf("(msg): querying get category by id (err): %w", err)
 	}
@@ -167,34 +349,39 @@ func (s *Storage) GetCategoryById(ctx context.Context, id int) (entities.Categor
 	return category, nil
 }
 
-func (s *Storage) UpdateCategory(ctx context.Context, category entities.Category) error {
+func (s *Storage) UpdateCategory(ctx context.Context, category entities.Category, audit Audit) error {
 	sql := `UPDATE kategori SET kode = $1, nama = $2, tgl_update = $3 WHERE id = $4`
 
-	commandTag, err := s.db.Exec(ctx, sql, category.Kode, category.Nama, category.TglUpdate, category.Id)
-	if err != nil {
-		return fmt.Errorf("(msg): querying update category (err): %w", err)
-	}
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, sql, category.Kode, category.Nama, category.TglUpdate, category.Id)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicate
+			}
+			return fmt.Errorf("(msg): querying update category (err): %w", err)
+		}
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("failed to update category")
-	}
+		if commandTag.RowsAffected() == 0 {
+			return fmt.Errorf("failed to update category: %w", utils.ErrNotFound)
+		}
 
-	return nil
+		return audit(txAudit{tx})
+	})
 }
 
-func (s *Storage) DeleteCategory(ctx context.Context, id int) error {
-	sql := `DELETE FROM kategori where id = $1`
-
-	commandTag, err := s.db.Exec(ctx, sql, id)
-	if err != nil {
-		return fmt.Errorf("(msg): querying delete category (err): %w", err)
-	}
+func (s *Storage) DeleteCategory(ctx context.Context, id int, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		trashed, err := trashRows(ctx, tx, []int{id}, time.Now(), trashCategorySQL)
+		if err != nil {
+			return fmt.Errorf("(msg): querying delete category (err): %w", err)
+		}
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("failed to delete category")
-	}
+		if trashed == 0 {
+			return fmt.Errorf("failed to delete category: %w", utils.ErrNotFound)
+		}
 
-	return nil
+		return audit(txAudit{tx})
+	})
 }
 
 func (s *Storage) CountCategories(ctx context.Context, where string, args []interface{}) (int, error) {
@@ -210,7 +397,7 @@ func (s *Storage) CountCategories(ctx context.Context, where string, args []inte
 }
 
 func (s *Storage) GetCategoriesWithFilter(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Category, error) {
-	sql := `SELECT id, kode, nama, tgl_dibuat, tgl_update FROM kategori`
+	sql := `SELECT id, kode, nama, tgl_dibuat, tgl_update, id_induk, atribut FROM kategori`
 
 	rows, err := s.db.Query(ctx, sql+where+sort+limit, args...)
 	if err != nil {
@@ -226,23 +413,180 @@ func (s *Storage) GetCategoriesWithFilter(ctx context.Context, limit, sort, wher
 	return categories, nil
 }
 
-// Location Area
+// categoryPathSQL walks up from each of the categories in $1 and returns its
+// ancestors, the top level first. The cycle trigger on kategori keeps the
+// walk finite.
+const categoryPathSQL = `
+	WITH RECURSIVE naik AS (
+		SELECT k.id AS asal, k.id_induk AS id, 1 AS tingkat FROM kategori k WHERE k.id = ANY($1)
+		UNION ALL
+		SELECT naik.asal, k.id_induk, naik.tingkat + 1 FROM naik JOIN kategori k ON k.id = naik.id
+	)
+	SELECT naik.asal, k.id, k.kode, k.nama, k.tgl_dibuat, k.tgl_update, k.id_induk, k.atribut
+	FROM naik JOIN kategori k ON k.id = naik.id
+	ORDER BY naik.asal, naik.tingkat DESC
+`
 
-func (s *Storage) SaveLocation(ctx context.Context, location entities.Location) error {
-	sql := `
-		INSERT INTO lokasi (id, kode, nama, slug, tgl_dibuat, tgl_update) VALUES ($1, $2, $3, $4, $5, $6)
-	`
+func queryCategoryPaths(ctx context.Context, q querier, ids []int) (map[int][]entities.Category, error) {
+	rows, err := q.Query(ctx, categoryPathSQL, ids)
+	if err != nil {
+		return nil, fmt.Errorf("querying category paths: %w", err)
+	}
+	defer rows.Close()
+
+	paths := make(map[int][]entities.Category)
+	for rows.Next() {
+		var asal int
+		var c entities.Category
+		if err := rows.Scan(&asal, &c.Id, &c.Kode, &c.Nama, &c.TglDibuat, &c.TglUpdate, &c.IdInduk, &c.Atribut); err != nil {
+			return nil, fmt.Errorf("scanning category path: %w", err)
+		}
+		paths[asal] = append(paths[asal], c)
+	}
 
-	commandTag, err := s.db.Exec(ctx, sql, location.Id, location.Kode, location.Nama, location.Slug, location.TglDibuat, location.TglUpdate)
+	return paths, rows.Err()
+}
+
+// GetCategoryPaths returns the ancestors of each category, with the fields
+// they declare; top level categories have no entry.
+func (s *Storage) GetCategoryPaths(ctx context.Context, ids []int) (map[int][]entities.Category, error) {
+	return queryCategoryPaths(ctx, s.db, ids)
+}
+
+// lockCategoryTree takes the lock that moves and field changes share, so two
+// of them cannot close a cycle or clash on a key between them, then reads the
+// category and the live categories under it.
+func lockCategoryTree(ctx context.Context, tx pgx.Tx, id int) (entities.Category, []entities.Category, error) {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('kategori.id_induk'))`); err != nil {
+		return entities.Category{}, nil, fmt.Errorf("locking category tree: %w", err)
+	}
+
+	var category entities.Category
+	err := tx.QueryRow(ctx, `
+		SELECT id, kode, nama, tgl_dibuat, tgl_update, id_induk, atribut
+		FROM kategori WHERE id = $1 AND tgl_dihapus IS NULL FOR UPDATE
+	`, id).Scan(&category.Id, &category.Kode, &category.Nama, &category.TglDibuat, &category.TglUpdate, &category.IdInduk, &category.Atribut)
 	if err != nil {
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entities.Category{}, nil, utils.ErrNotFound
+		}
+		return entities.Category{}, nil, fmt.Errorf("querying lock category: %w", err)
 	}
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("error saving category")
+	rows, err := tx.Query(ctx, `
+		WITH RECURSIVE turun AS (
+			SELECT id FROM kategori WHERE id_induk = $1 AND tgl_dihapus IS NULL
+			UNION ALL
+			SELECT k.id FROM kategori k JOIN turun ON k.id_induk = turun.id WHERE k.tgl_dihapus IS NULL
+		)
+		SELECT id, kode, nama, tgl_dibuat, tgl_update, id_induk, atribut
+		FROM kategori WHERE id IN (SELECT id FROM turun)
+	`, id)
+	if err != nil {
+		return entities.Category{}, nil, fmt.Errorf("querying sub categories: %w", err)
 	}
 
-	return nil
+	descendants, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.Category])
+	if err != nil {
+		return entities.Category{}, nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return category, descendants, nil
+}
+
+// MoveCategory puts the category, with everything under it, below parent or
+// at the top level when parent is nil. check gets the locked category and the
+// new ancestors down to the parent, which are nil when the parent is not a
+// live category.
+func (s *Storage) MoveCategory(ctx context.Context, id int, parent *int, check CategoryTreeChecker, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		category, descendants, err := lockCategoryTree(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		var ancestors []entities.Category
+		if parent != nil {
+			var dest entities.Category
+			err := tx.QueryRow(ctx, `
+				SELECT id, kode, nama, tgl_dibuat, tgl_update, id_induk, atribut
+				FROM kategori WHERE id = $1 AND tgl_dihapus IS NULL FOR SHARE
+			`, *parent).Scan(&dest.Id, &dest.Kode, &dest.Nama, &dest.TglDibuat, &dest.TglUpdate, &dest.IdInduk, &dest.Atribut)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("querying lock parent category: %w", err)
+			}
+
+			if err == nil {
+				paths, err := queryCategoryPaths(ctx, tx, []int{dest.Id})
+				if err != nil {
+					return err
+				}
+				ancestors = append(paths[dest.Id], dest)
+			}
+		}
+
+		if err := check(category, ancestors, descendants); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE kategori SET id_induk = $1, tgl_update = $2 WHERE id = $3`, parent, time.Now(), category.Id)
+		if err != nil {
+			return fmt.Errorf("querying move category: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// UpdateCategoryAttributes replaces the fields the category declares. check
+// gets the category as it was and its current ancestors.
+func (s *Storage) UpdateCategoryAttributes(ctx context.Context, id int, fields []entities.AttributeField, check CategoryTreeChecker, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		category, descendants, err := lockCategoryTree(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		paths, err := queryCategoryPaths(ctx, tx, []int{category.Id})
+		if err != nil {
+			return err
+		}
+
+		if err := check(category, paths[category.Id], descendants); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE kategori SET atribut = $1, tgl_update = $2 WHERE id = $3`, fields, time.Now(), category.Id)
+		if err != nil {
+			return fmt.Errorf("querying update category attributes: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// Location Area
+
+func (s *Storage) SaveLocation(ctx context.Context, location entities.Location, audit Audit) error {
+	sql := `
+		INSERT INTO lokasi (id, kode, nama, slug, tgl_dibuat, tgl_update, id_induk) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, sql, location.Id, location.Kode, location.Nama, location.Slug, location.TglDibuat, location.TglUpdate, location.IdInduk)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicate
+			}
+			return err
+		}
+
+		if commandTag.RowsAffected() == 0 {
+			return errors.New("error saving category")
+		}
+
+		return audit(txAudit{tx})
+	})
 }
 
 func (s *Storage) CountTotalLocations(ctx context.Context, where string, args []interface{}) (int, error) {
@@ -257,7 +601,7 @@ func (s *Storage) CountTotalLocations(ctx context.Context, where string, args []
 }
 
 func (s *Storage) GetLocations(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Location, error) {
-	sql := `SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update FROM lokasi`
+	sql := `SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update, id_induk FROM lokasi`
 
 	rows, err := s.db.Query(ctx, sql+where+sort+limit, args...)
 	if err != nil {
@@ -286,19 +630,19 @@ func (s *Storage) FindLocationByCode(ctx context.Context, code string) (bool, er
 
 func (s *Storage) GetLocationWithRooms(ctx context.Context, id uuid.UUID) (*entities.Location, error) {
 	sqlLoc := `
-		SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update FROM lokasi WHERE id = $1
+		SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update, id_induk FROM lokasi WHERE id = $1 AND tgl_dihapus IS NULL
 	`
 	var loc entities.Location
 
 	err := s.db.QueryRow(ctx, sqlLoc, id).Scan(
-		&loc.Id, &loc.Kode, &loc.Nama, &loc.JumlahRuangan, &loc.Slug, &loc.TglDibuat, &loc.TglUpdate,
+		&loc.Id, &loc.Kode, &loc.Nama, &loc.JumlahRuangan, &loc.Slug, &loc.TglDibuat, &loc.TglUpdate, &loc.IdInduk,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching location: %w", err)
 	}
 
 	sqlRoom := `
-		SELECT id, id_lokasi, nama, penanggung_jawab, slug, tgl_dibuat, tgl_update FROM ruangan WHERE id_lokasi = $1
+		SELECT id, id_lokasi, nama, penanggung_jawab, jumlah_barang, slug, tgl_dibuat, tgl_update FROM ruangan WHERE id_lokasi = $1 AND tgl_dihapus IS NULL
 	`
 
 	rows, err := s.db.Query(ctx, sqlRoom, loc.Id)
@@ -310,7 +654,7 @@ func (s *Storage) GetLocationWithRooms(ctx context.Context, id uuid.UUID) (*enti
 	var rooms []entities.Room
 	for rows.Next() {
 		var r entities.Room
-		err := rows.Scan(&r.Id, &r.LokasiId, &r.Nama, &r.PenanggungJawab, &r.Slug, &r.TglDibuat, &r.TglUpdate)
+		err := rows.Scan(&r.Id, &r.LokasiId, &r.Nama, &r.PenanggungJawab, &r.JumlahBarang, &r.Slug, &r.TglDibuat, &r.TglUpdate)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning rows: %w", err)
 		}
@@ -323,14 +667,14 @@ func (s *Storage) GetLocationWithRooms(ctx context.Context, id uuid.UUID) (*enti
 
 func (s *Storage) GetLocationBySlug(ctx context.Context, slug string) (entities.Location, error) {
 	sql := `
-		SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update FROM lokasi WHERE slug = $1
+		SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update, id_induk FROM lokasi WHERE slug = $1 AND tgl_dihapus IS NULL
 	`
 	var loc entities.Location
 
-	err := s.db.QueryRow(ctx, sql, slug).Scan(&loc.Id, &loc.Kode, &loc.Nama, &loc.JumlahRuangan, &loc.Slug, &loc.TglDibuat, &loc.TglUpdate)
+	err := s.db.QueryRow(ctx, sql, slug).Scan(&loc.Id, &loc.Kode, &loc.Nama, &loc.JumlahRuangan, &loc.Slug, &loc.TglDibuat, &loc.TglUpdate, &loc.IdInduk)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return entities.Location{}, errors.New("not found")
+			return entities.Location{}, utils.ErrNotFound
 		}
 		return entities.Location{}, err
 	}
@@ -340,14 +684,14 @@ func (s *Storage) GetLocationBySlug(ctx context.Context, slug string) (entities.
 
 func (s *Storage) GetLocationById(ctx context.Context, id uuid.UUID) (entities.Location, error) {
 	sql := `
-		SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update FROM lokasi WHERE id = $1
+		SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update, id_induk FROM lokasi WHERE id = $1 AND tgl_dihapus IS NULL
 	`
 	var loc entities.Location
 
-	err := s.db.QueryRow(ctx, sql, id).Scan(&loc.Id, &loc.Kode, &loc.Nama, &loc.JumlahRuangan, &loc.Slug, &loc.TglDibuat, &loc.TglUpdate)
+	err := s.db.QueryRow(ctx, sql, id).Scan(&loc.Id, &loc.Kode, &loc.Nama, &loc.JumlahRuangan, &loc.Slug, &loc.TglDibuat, &loc.TglUpdate, &loc.IdInduk)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return entities.Location{}, errors.New("not found")
+			return entities.Location{}, utils.ErrNotFound
 		}
 		return entities.Location{}, err
 	}
@@ -355,54 +699,212 @@ func (s *Storage) GetLocationById(ctx context.Context, id uuid.UUID) (entities.L
 	return loc, nil
 }
 
-func (s *Storage) DeleteLocation(ctx context.Context, id uuid.UUID) error {
-	sql := `DELETE FROM lokasi where id = $1`
+func (s *Storage) DeleteLocation(ctx context.Context, id uuid.UUID, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		trashed, err := trashRows(ctx, tx, []uuid.UUID{id}, time.Now(), trashLocationSQL)
+		if err != nil {
+			return err
+		}
+
+		if trashed == 0 {
+			return fmt.Errorf("error deleting location: %w", utils.ErrNotFound)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+func (s *Storage) UpdateLocation(ctx context.Context, loc entities.Location, audit Audit) error {
+	sql := `UPDATE lokasi SET kode = $1, nama = $2, slug = $3 WHERE id = $4`
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, sql, loc.Kode, loc.Nama, loc.Slug, loc.Id)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicate
+			}
+			return err
+		}
+
+		if commandTag.RowsAffected() == 0 {
+			return fmt.Errorf("error updating location: %w", utils.ErrNotFound)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+func (s *Storage) GetSubLocations(ctx context.Context, id uuid.UUID) ([]entities.Location, error) {
+	sql := `
+		SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update, id_induk
+		FROM lokasi WHERE id_induk = $1 AND tgl_dihapus IS NULL ORDER BY nama
+	`
 
-	commandTag, err := s.db.Exec(ctx, sql, id)
+	rows, err := s.db.Query(ctx, sql, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("error deleting location")
+	locations, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.Location])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
 	}
 
-	return nil
+	return locations, nil
 }
 
-func (s *Storage) UpdateLocation(ctx context.Context, loc entities.Location) error {
-	sql := `UPDATE lokasi SET kode = $1, nama = $2, slug = $3 WHERE id = $4`
+// locationPathSQL walks up from each of the locations in $1 and returns its
+// ancestors, the top level first. The cycle trigger on lokasi keeps the walk
+// finite.
+const locationPathSQL = `
+	WITH RECURSIVE naik AS (
+		SELECT l.id AS asal, l.id_induk AS id, 1 AS tingkat FROM lokasi l WHERE l.id = ANY($1)
+		UNION ALL
+		SELECT naik.asal, l.id_induk, naik.tingkat + 1 FROM naik JOIN lokasi l ON l.id = naik.id
+	)
+	SELECT naik.asal, l.id, l.nama, l.slug
+	FROM naik JOIN lokasi l ON l.id = naik.id
+	ORDER BY naik.asal, naik.tingkat DESC
+`
+
+// querier is satisfied by both the pool and a transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
 
-	commandTag, err := s.db.Exec(ctx, sql, loc.Kode, loc.Nama, loc.Slug, loc.Id)
+func queryLocationPaths(ctx context.Context, q querier, ids []uuid.UUID) (map[uuid.UUID][]entities.LocationRef, error) {
+	rows, err := q.Query(ctx, locationPathSQL, ids)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("querying location paths: %w", err)
 	}
+	defer rows.Close()
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("error updating location")
+	paths := make(map[uuid.UUID][]entities.LocationRef)
+	for rows.Next() {
+		var asal uuid.UUID
+		var ref entities.LocationRef
+		if err := rows.Scan(&asal, &ref.Id, &ref.Nama, &ref.Slug); err != nil {
+			return nil, fmt.Errorf("scanning location path: %w", err)
+		}
+		paths[asal] = append(paths[asal], ref)
 	}
 
-	return nil
+	return paths, rows.Err()
 }
 
-// Room Area
+// GetLocationPaths returns the breadcrumb of each location; top level
+// locations have no entry.
+func (s *Storage) GetLocationPaths(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]entities.LocationRef, error) {
+	return queryLocationPaths(ctx, s.db, ids)
+}
 
-func (s *Storage) CreateRoom(ctx context.Context, room entities.Room) error {
+// GetLocationRollups counts the live rooms and units of each location
+// together with every live location under it.
+func (s *Storage) GetLocationRollups(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]entities.LocationRollup, error) {
 	sql := `
-		INSERT INTO ruangan (id, id_lokasi, nama, penanggung_jawab, slug) 
-		VALUES ($1, $2, $3, $4, $5)
+		WITH RECURSIVE turun AS (
+			SELECT id AS asal, id FROM lokasi WHERE id = ANY($1)
+			UNION ALL
+			SELECT turun.asal, l.id FROM turun JOIN lokasi l ON l.id_induk = turun.id WHERE l.tgl_dihapus IS NULL
+		)
+		SELECT turun.asal, COUNT(r.id), COALESCE(SUM(r.jumlah_barang), 0)
+		FROM turun LEFT JOIN ruangan r ON r.id_lokasi = turun.id AND r.tgl_dihapus IS NULL
+		GROUP BY turun.asal
 	`
 
-	commandTag, err := s.db.Exec(ctx, sql, room.Id, room.LokasiId, room.Nama, room.PenanggungJawab, room.Slug)
+	rows, err := s.db.Query(ctx, sql, ids)
 	if err != nil {
-		return fmt.Errorf("querying create room: %w", err)
+		return nil, fmt.Errorf("querying location rollups: %w", err)
 	}
+	defer rows.Close()
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("failed to create room")
+	rollups := make(map[uuid.UUID]entities.LocationRollup, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var rollup entities.LocationRollup
+		if err := rows.Scan(&id, &rollup.Ruangan, &rollup.Unit); err != nil {
+			return nil, fmt.Errorf("scanning location rollup: %w", err)
+		}
+		rollups[id] = rollup
 	}
 
-	return nil
+	return rollups, rows.Err()
+}
+
+// MoveLocation puts the location, with everything under it, below parent or
+// at the top level when parent is not valid. Moves take one advisory lock so
+// two of them cannot close a cycle between them; check gets the locked
+// location and the path from the top level down to the new parent, which is
+// nil when the parent is not a live location.
+func (s *Storage) MoveLocation(ctx context.Context, id uuid.UUID, parent uuid.NullUUID, check LocationMoveChecker, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('lokasi.id_induk'))`); err != nil {
+			return fmt.Errorf("locking location tree: %w", err)
+		}
+
+		var loc entities.Location
+		err := tx.QueryRow(ctx, `
+			SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update, id_induk
+			FROM lokasi WHERE id = $1 AND tgl_dihapus IS NULL FOR UPDATE
+		`, id).Scan(&loc.Id, &loc.Kode, &loc.Nama, &loc.JumlahRuangan, &loc.Slug, &loc.TglDibuat, &loc.TglUpdate, &loc.IdInduk)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return utils.ErrNotFound
+			}
+			return fmt.Errorf("querying lock location: %w", err)
+		}
+
+		var path []entities.LocationRef
+		if parent.Valid {
+			var dest entities.LocationRef
+			err := tx.QueryRow(ctx, `SELECT id, nama, slug FROM lokasi WHERE id = $1 AND tgl_dihapus IS NULL FOR SHARE`, parent.UUID).
+				Scan(&dest.Id, &dest.Nama, &dest.Slug)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("querying lock parent location: %w", err)
+			}
+
+			if err == nil {
+				paths, err := queryLocationPaths(ctx, tx, []uuid.UUID{dest.Id})
+				if err != nil {
+					return err
+				}
+				path = append(paths[dest.Id], dest)
+			}
+		}
+
+		if err := check(loc, path); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE lokasi SET id_induk = $1, tgl_update = $2 WHERE id = $3`, parent, time.Now(), loc.Id)
+		if err != nil {
+			return fmt.Errorf("querying move location: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// Room Area
+
+func (s *Storage) CreateRoom(ctx context.Context, room entities.Room, audit Audit) error {
+	sql := `
+		INSERT INTO ruangan (id, id_lokasi, nama, penanggung_jawab, slug) 
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, sql, room.Id, room.LokasiId, room.Nama, room.PenanggungJawab, room.Slug)
+		if err != nil {
+			return fmt.Errorf("querying create room: %w", err)
+		}
+
+		if commandTag.RowsAffected() == 0 {
+			return errors.New("failed to create room")
+		}
+
+		return audit(txAudit{tx})
+	})
 }
 
 func (s *Storage) CountRoomWithFilter(ctx context.Context, where string, args []interface{}) (int, error) {
@@ -416,17 +918,35 @@ func (s *Storage) CountRoomWithFilter(ctx context.Context, where string, args []
 	return total, nil
 }
 
-func (s *Storage) GetRooms(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Room, error) {
-	sql := `
-		SELECT 
-			r.id, r.nama, r.penanggung_jawab, r.jumlah_barang, 
-			r.slug, r.tgl_dibuat, r.tgl_update,
-			l.id, l.kode AS kode_lokasi, l.nama, l.slug 
-		FROM ruangan r
-		LEFT JOIN lokasi l ON r.id_lokasi = l.id
-	`
+const selectRoomsSQL = `
+	SELECT 
+		r.id, r.nama, r.penanggung_jawab, r.jumlah_barang, 
+		r.slug, r.tgl_dibuat, r.tgl_update,
+		l.id, l.kode AS kode_lokasi, l.nama, l.slug 
+	FROM ruangan r
+	LEFT JOIN lokasi l ON r.id_lokasi = l.id
+`
+
+func scanRoom(row pgx.Row) (entities.Room, error) {
+	var r entities.Room
+	var l entities.Location
+
+	err := row.Scan(
+		&r.Id, &r.Nama, &r.PenanggungJawab, &r.JumlahBarang,
+		&r.Slug, &r.TglDibuat, &r.TglUpdate,
+		&l.Id, &l.Kode, &l.Nama, &l.Slug,
+	)
+	if err != nil {
+		return entities.Room{}, err
+	}
 
-	rows, err := s.db.Query(ctx, sql+where+sort+limit, args...)
+	r.Lokasi = l
+	r.LokasiId = l.Id
+	return r, nil
+}
+
+func (s *Storage) GetRooms(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Room, error) {
+	rows, err := s.db.Query(ctx, selectRoomsSQL+where+sort+limit, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -434,20 +954,10 @@ func (s *Storage) GetRooms(ctx context.Context, limit, sort, where string, args
 
 	var rooms []entities.Room
 	for rows.Next() {
-		var r entities.Room
-		var l entities.Location
-
-		err := rows.Scan(
-			&r.Id, &r.Nama, &r.PenanggungJawab, &r.JumlahBarang,
-			&r.Slug, &r.TglDibuat, &r.TglUpdate,
-			&l.Id, &l.Kode, &l.Nama, &l.Slug,
-		)
+		r, err := scanRoom(rows)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning rows room: %w", err)
 		}
-
-		r.Lokasi = l
-		r.LokasiId = l.Id
 		rooms = append(rooms, r)
 	}
 
@@ -462,7 +972,7 @@ func (s *Storage) GetRoomBySlug(ctx context.Context, slug string) (entities.Room
 			l.id, l.kode, l.nama, l.slug 
 		FROM ruangan r
 		LEFT JOIN lokasi l ON r.id_lokasi = l.id 
-		WHERE r.slug = $1
+		WHERE r.slug = $1 AND r.tgl_dihapus IS NULL
 	`
 	var room entities.Room
 	var loc entities.Location
@@ -474,7 +984,7 @@ func (s *Storage) GetRoomBySlug(ctx context.Context, slug string) (entities.Room
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return entities.Room{}, errors.New("not found")
+			return entities.Room{}, utils.ErrNotFound
 		}
 		return entities.Room{}, err
 	}
@@ -485,35 +995,37 @@ func (s *Storage) GetRoomBySlug(ctx context.Context, slug string) (entities.Room
 	return room, nil
 }
 
-func (s *Storage) UpdateRoom(ctx context.Context, room entities.Room) error {
+func (s *Storage) UpdateRoom(ctx context.Context, room entities.Room, audit Audit) error {
 	sql := `UPDATE ruangan SET nama = $1, penanggung_jawab = $2, id_lokasi = $3, slug = $4 WHERE id = $5`
 
-	commandTag, err := s.db.Exec(ctx, sql, room.Nama, room.PenanggungJawab, room.LokasiId, room.Slug, room.Id)
-	if err != nil {
-		return err
-	}
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, sql, room.Nama, room.PenanggungJawab, room.LokasiId, room.Slug, room.Id)
+		if err != nil {
+			return err
+		}
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("error updating room")
-	}
+		if commandTag.RowsAffected() == 0 {
+			return fmt.Errorf("error updating room: %w", utils.ErrNotFound)
+		}
 
-	return nil
+		return audit(txAudit{tx})
+	})
 }
 
 func (s *Storage) GetRoomById(ctx context.Context, id uuid.UUID) (entities.Room, error) {
 	sql := `
-		SELECT id, nama, penanggung_jawab, jumlah_barang, slug, tgl_dibuat
-		FROM ruangan WHERE id = $1
+		SELECT id, nama, penanggung_jawab, jumlah_barang, slug, id_lokasi, tgl_dibuat
+		FROM ruangan WHERE id = $1 AND tgl_dihapus IS NULL
 	`
 	var room entities.Room
 
 	err := s.db.QueryRow(ctx, sql, id).Scan(
 		&room.Id, &room.Nama, &room.PenanggungJawab,
-		&room.JumlahBarang, &room.Slug, &room.TglDibuat,
+		&room.JumlahBarang, &room.Slug, &room.LokasiId, &room.TglDibuat,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return entities.Room{}, errors.New("not found")
+			return entities.Room{}, utils.ErrNotFound
 		}
 		return entities.Room{}, err
 	}
@@ -521,19 +1033,19 @@ func (s *Storage) GetRoomById(ctx context.Context, id uuid.UUID) (entities.Room,
 	return room, nil
 }
 
-func (s *Storage) DeleteRoom(ctx context.Context, id uuid.UUID) error {
-	sql := `DELETE FROM ruangan where id = $1`
-
-	commandTag, err := s.db.Exec(ctx, sql, id)
-	if err != nil {
-		return fmt.Errorf("querying delete room: %w", err)
-	}
+func (s *Storage) DeleteRoom(ctx context.Context, id uuid.UUID, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		trashed, err := trashRows(ctx, tx, []uuid.UUID{id}, time.Now(), trashRoomSQL)
+		if err != nil {
+			return fmt.Errorf("querying delete room: %w", err)
+		}
 
-	if commandTag.RowsAffected() == 0 {
-		return errors.New("error deleting room")
-	}
+		if trashed == 0 {
+			return fmt.Errorf("error deleting room: %w", utils.ErrNotFound)
+		}
 
-	return nil
+		return audit(txAudit{tx})
+	})
 }
 
 func (s *Storage) GetRoomWithItems(ctx context.Context, id uuid.UUID) (*entities.Room, error) {
@@ -544,7 +1056,7 @@ func (s *Storage) GetRoomWithItems(ctx context.Context, id uuid.UUID) (*entities
 			l.id, l.kode, l.nama
 		FROM ruangan r 
 		LEFT JOIN lokasi l ON r.id_lokasi = l.id
-		WHERE r.id = $1
+		WHERE r.id = $1 AND r.tgl_dihapus IS NULL
 	`
 	var room entities.Room
 	var loc entities.Location
@@ -563,12 +1075,14 @@ func (s *Storage) GetRoomWithItems(ctx context.Context, id uuid.UUID) (*entities
 
 	sqlItems := `
 		SELECT
-			b.sku, b.nama, ub.id, ub.no_seri, 
-			ub.kondisi, ub.tgl_dibuat, ub.tgl_update
+			b.id, b.sku, b.nama, b.slug, b.satuan, b.harga_satuan,
+			COALESCE(k.nama, ''), ub.id, ub.no_seri, 
+			ub.kondisi, ub.tgl_dibuat, ub.tgl_update, ub.tgl_perolehan
 		FROM unit_barang ub
 		LEFT JOIN barang b ON ub.id_barang = b.id
 		LEFT JOIN kategori k ON b.id_kategori = k.id
-		WHERE ub.id_ruangan = $1
+		WHERE ub.id_ruangan = $1 AND ub.tgl_dihapus IS NULL
+		ORDER BY b.nama, ub.no_seri
 	`
 
 	rows, err := s.db.Query(ctx, sqlItems, room.Id)
@@ -582,12 +1096,17 @@ func (s *Storage) GetRoomWithItems(ctx context.Context, id uuid.UUID) (*entities
 		var i entities.ItemUnit
 		var b entities.Item
 
-		err := rows.Scan(&b.SKU, &b.Nama, &i.Id, &i.NoSeri, &i.Kondisi, &i.TglDibuat, &i.TglUpdate)
+		err := rows.Scan(
+			&b.Id, &b.SKU, &b.Nama, &b.Slug, &b.Satuan, &b.HargaSatuan,
+			&b.Kategori.Nama, &i.Id, &i.NoSeri, &i.Kondisi, &i.TglDibuat, &i.TglUpdate, &i.TglPerolehan,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning rows: %w", err)
 		}
 
+		i.IdBarang = b.Id
 		i.Barang = b
+		i.IdRuangan = room.Id
 		items = append(items, i)
 	}
 
@@ -598,6 +1117,2747 @@ func (s *Storage) GetRoomWithItems(ctx context.Context, id uuid.UUID) (*entities
 
 // Item Area
 
-func (s *Storage) CreateItem(ctx context.Context, name, code string) error {
+func (s *Storage) CreateItem(ctx context.Context, item entities.Item, audit Audit) error {
+	sql := `
+		INSERT INTO barang (
+			id, id_kategori, sku, nama, jumlah, satuan, harga_satuan, umur_ekonomis,
+			metode_penyusutan, nilai_residu, spesifikasi, slug, tgl_dibuat, atribut, tgl_perolehan
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, sql,
+			item.Id, item.IdKategori, item.SKU, item.Nama, item.Jumlah, item.Satuan, item.HargaSatuan, item.UmurEkonomis,
+			item.MetodePenyusutan, item.NilaiResidu, item.Spesifikasi, item.Slug, item.TglDibuat, item.Atribut, item.TglPerolehan,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicate
+			}
+			return fmt.Errorf("querying create item: %w", err)
+		}
+
+		if commandTag.RowsAffected() == 0 {
+			return errors.New("failed to create item")
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+func (s *Storage) FindItemBySKU(ctx context.Context, sku string) (bool, error) {
+	sql := `SELECT COUNT(*) FROM barang WHERE sku = $1`
+	count := -1
+
+	if err := s.db.QueryRow(ctx, sql, sku).Scan(&count); err != nil {
+		return false, fmt.Errorf("querying find item by sku: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (s *Storage) CountItems(ctx context.Context, where string, args []interface{}) (int, error) {
+	sql := `SELECT COUNT(*) FROM barang b LEFT JOIN kategori k ON b.id_kategori = k.id`
+	total := -1
+
+	if err := s.db.QueryRow(ctx, sql+where, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("querying count items: %w", err)
+	}
+
+	return total, nil
+}
+
+const selectItemSQL = `
+	SELECT
+		b.id, b.sku, b.nama, b.jumlah, b.satuan, b.harga_satuan,
+		b.umur_ekonomis, b.metode_penyusutan, b.nilai_residu,
+		COALESCE(b.spesifikasi, ''), b.slug, b.tgl_dibuat, b.tgl_perolehan, b.atribut,
+		k.id, k.kode, k.nama
+	FROM barang b
+	LEFT JOIN kategori k ON b.id_kategori = k.id
+`
+
+func scanItem(row pgx.Row) (entities.Item, error) {
+	var i entities.Item
+	var k entities.Category
+
+	err := row.Scan(
+		&i.Id, &i.SKU, &i.Nama, &i.Jumlah, &i.Satuan, &i.HargaSatuan,
+		&i.UmurEkonomis, &i.MetodePenyusutan, &i.NilaiResidu,
+		&i.Spesifikasi, &i.Slug, &i.TglDibuat, &i.TglPerolehan, &i.Atribut,
+		&k.Id, &k.Kode, &k.Nama,
+	)
+	if err != nil {
+		return entities.Item{}, err
+	}
+
+	i.IdKategori = k.Id
+	i.Kategori = k
+	i.GetTotalItem()
+
+	return i, nil
+}
+
+func (s *Storage) GetItems(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Item, error) {
+	rows, err := s.db.Query(ctx, selectItemSQL+where+sort+limit, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []entities.Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning rows item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (s *Storage) GetItemBySlug(ctx context.Context, slug string) (entities.Item, error) {
+	item, err := scanItem(s.db.QueryRow(ctx, selectItemSQL+` WHERE b.slug = $1 AND b.tgl_dihapus IS NULL`, slug))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entities.Item{}, utils.ErrNotFound
+		}
+		return entities.Item{}, fmt.Errorf("querying get item by slug: %w", err)
+	}
+
+	return item, nil
+}
+
+func (s *Storage) GetItemById(ctx context.Context, id uuid.UUID) (entities.Item, error) {
+	item, err := scanItem(s.db.QueryRow(ctx, selectItemSQL+` WHERE b.id = $1 AND b.tgl_dihapus IS NULL`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entities.Item{}, utils.ErrNotFound
+		}
+		return entities.Item{}, fmt.Errorf("querying get item by id: %w", err)
+	}
+
+	return item, nil
+}
+
+func (s *Storage) GetItemsAcquiredBefore(ctx context.Context, before time.Time) ([]entities.Item, error) {
+	rows, err := s.db.Query(ctx, selectItemSQL+` WHERE b.tgl_perolehan < $1 AND b.tgl_dihapus IS NULL ORDER BY k.nama, b.nama`, before)
+	if err != nil {
+		return nil, fmt.Errorf("querying items acquired before %v: %w", before, err)
+	}
+	defer rows.Close()
+
+	var items []entities.Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning rows item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (s *Storage) UpdateItem(ctx context.Context, item entities.Item, audit Audit) error {
+	sql := `
+		UPDATE barang SET
+			id_kategori = $1, sku = $2, nama = $3, jumlah = $4, satuan = $5,
+			harga_satuan = $6, umur_ekonomis = $7, metode_penyusutan = $8,
+			nilai_residu = $9, spesifikasi = $10, slug = $11, atribut = $12,
+			tgl_perolehan = $13
+		WHERE id = $14
+	`
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, sql,
+			item.IdKategori, item.SKU, item.Nama, item.Jumlah, item.Satuan,
+			item.HargaSatuan, item.UmurEkonomis, item.MetodePenyusutan,
+			item.NilaiResidu, item.Spesifikasi, item.Slug, item.Atribut,
+			item.TglPerolehan, item.Id,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrDuplicate
+			}
+			return fmt.Errorf("querying update item: %w", err)
+		}
+
+		if commandTag.RowsAffected() == 0 {
+			return fmt.Errorf("error updating item: %w", utils.ErrNotFound)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// DeleteItem moves the item and its units to the trash. The pictures stay
+// until the item is purged.
+func (s *Storage) DeleteItem(ctx context.Context, id uuid.UUID, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		trashed, err := trashRows(ctx, tx, []uuid.UUID{id}, time.Now(), trashItemSQL)
+		if err != nil {
+			return fmt.Errorf("querying delete item: %w", err)
+		}
+
+		if trashed == 0 {
+			return fmt.Errorf("error deleting item: %w", utils.ErrNotFound)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// Unit Area
+
+func (s *Storage) CreateUnits(ctx context.Context, units []entities.ItemUnit, audit Audit) error {
+	sql := `
+		INSERT INTO unit_barang (id, id_barang, id_ruangan, no_seri, kondisi, tgl_dibuat, tgl_update, tgl_perolehan)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for _, u := range units {
+			batch.Queue(sql, u.Id, u.IdBarang, u.IdRuangan, u.NoSeri, u.Kondisi, u.TglDibuat, u.TglUpdate, u.TglPerolehan)
+		}
+
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("querying create units: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+func (s *Storage) CountUnitsByItem(ctx context.Context, idBarang uuid.UUID) (int, error) {
+	sql := `SELECT COUNT(*) FROM unit_barang WHERE id_barang = $1 AND tgl_dihapus IS NULL`
+	total := -1
+
+	if err := s.db.QueryRow(ctx, sql, idBarang).Scan(&total); err != nil {
+		return 0, fmt.Errorf("querying count units: %w", err)
+	}
+
+	return total, nil
+}
+
+func (s *Storage) FindUnitSerials(ctx context.Context, idBarang uuid.UUID, serials []string) ([]string, error) {
+	sql := `SELECT no_seri FROM unit_barang WHERE id_barang = $1 AND no_seri = ANY($2)`
+
+	rows, err := s.db.Query(ctx, sql, idBarang, serials)
+	if err != nil {
+		return nil, fmt.Errorf("querying find unit serials: %w", err)
+	}
+	defer rows.Close()
+
+	found, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return found, nil
+}
+
+const selectUnitSQL = `
+	SELECT
+		ub.id, ub.no_seri, ub.kondisi, ub.tgl_dibuat, ub.tgl_update, ub.tgl_perolehan,
+		b.id, b.sku, b.nama, b.slug,
+		r.id, r.nama, r.slug
+	FROM unit_barang ub
+	LEFT JOIN barang b ON ub.id_barang = b.id
+	LEFT JOIN ruangan r ON ub.id_ruangan = r.id
+`
+
+func scanUnit(row pgx.Row) (entities.ItemUnit, error) {
+	var u entities.ItemUnit
+	var b entities.Item
+	var r entities.Room
+
+	err := row.Scan(
+		&u.Id, &u.NoSeri, &u.Kondisi, &u.TglDibuat, &u.TglUpdate, &u.TglPerolehan,
+		&b.Id, &b.SKU, &b.Nama, &b.Slug,
+		&r.Id, &r.Nama, &r.Slug,
+	)
+	if err != nil {
+		return entities.ItemUnit{}, err
+	}
+
+	u.IdBarang = b.Id
+	u.Barang = b
+	u.IdRuangan = r.Id
+	u.Ruangan = r
+
+	return u, nil
+}
+
+func (s *Storage) GetUnitsByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.ItemUnit, error) {
+	rows, err := s.db.Query(ctx, selectUnitSQL+` WHERE ub.id_barang = $1 AND ub.tgl_dihapus IS NULL ORDER BY ub.no_seri`, idBarang)
+	if err != nil {
+		return nil, fmt.Errorf("querying units by item: %w", err)
+	}
+	defer rows.Close()
+
+	var units []entities.ItemUnit
+	for rows.Next() {
+		u, err := scanUnit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning rows unit: %w", err)
+		}
+		units = append(units, u)
+	}
+
+	return units, rows.Err()
+}
+
+func (s *Storage) GetUnitsByIds(ctx context.Context, ids []uuid.UUID) ([]entities.ItemUnit, error) {
+	rows, err := s.db.Query(ctx, selectUnitSQL+` WHERE ub.id = ANY($1) AND ub.tgl_dihapus IS NULL ORDER BY r.nama, b.nama, ub.no_seri`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("querying units by ids: %w", err)
+	}
+	defer rows.Close()
+
+	var units []entities.ItemUnit
+	for rows.Next() {
+		u, err := scanUnit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning rows unit: %w", err)
+		}
+		units = append(units, u)
+	}
+
+	return units, rows.Err()
+}
+
+func (s *Storage) GetUnitsBySerial(ctx context.Context, serial string) ([]entities.ItemUnit, error) {
+	rows, err := s.db.Query(ctx, selectUnitSQL+` WHERE ub.no_seri = $1 AND ub.tgl_dihapus IS NULL ORDER BY b.nama`, serial)
+	if err != nil {
+		return nil, fmt.Errorf("querying units by serial: %w", err)
+	}
+	defer rows.Close()
+
+	var units []entities.ItemUnit
+	for rows.Next() {
+		u, err := scanUnit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning rows unit: %w", err)
+		}
+		units = append(units, u)
+	}
+
+	return units, rows.Err()
+}
+
+func (s *Storage) GetUnitById(ctx context.Context, id uuid.UUID) (entities.ItemUnit, error) {
+	unit, err := scanUnit(s.db.QueryRow(ctx, selectUnitSQL+` WHERE ub.id = $1 AND ub.tgl_dihapus IS NULL`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entities.ItemUnit{}, utils.ErrNotFound
+		}
+		return entities.ItemUnit{}, fmt.Errorf("querying get unit by id: %w", err)
+	}
+
+	return unit, nil
+}
+
+func (s *Storage) UpdateUnitSerial(ctx context.Context, unit entities.ItemUnit, audit Audit) error {
+	sql := `UPDATE unit_barang SET no_seri = $1, tgl_update = $2 WHERE id = $3`
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, sql, unit.NoSeri, unit.TglUpdate, unit.Id)
+		if err != nil {
+			return fmt.Errorf("querying update unit serial: %w", err)
+		}
+
+		if commandTag.RowsAffected() == 0 {
+			return fmt.Errorf("error updating unit: %w", utils.ErrNotFound)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+func (s *Storage) UpdateUnitCondition(ctx context.Context, id uuid.UUID, from, to entities.KondisiUnit, audit Audit) error {
+	sql := `UPDATE unit_barang SET kondisi = $1, tgl_update = $2 WHERE id = $3 AND kondisi = $4`
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, sql, to, time.Now(), id, from)
+		if err != nil {
+			return fmt.Errorf("querying update unit condition: %w", err)
+		}
+
+		if commandTag.RowsAffected() == 0 {
+			return ErrConditionChanged
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+func (s *Storage) DeleteUnit(ctx context.Context, id uuid.UUID, audit Audit) error {
+	sql := `UPDATE unit_barang SET tgl_dihapus = $2 WHERE id = $1 AND tgl_dihapus IS NULL`
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, sql, id, time.Now())
+		if err != nil {
+			return fmt.Errorf("querying delete unit: %w", err)
+		}
+
+		if commandTag.RowsAffected() == 0 {
+			return fmt.Errorf("error deleting unit: %w", utils.ErrNotFound)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// Picture Area
+
+func (s *Storage) SavePicture(ctx context.Context, picture entities.ItemPicture, r io.Reader, contentType string, audit Audit) error {
+	if err := s.objects.PutObject(ctx, picture.ObjectName, r, picture.FileSize, contentType); err != nil {
+		return fmt.Errorf("uploading picture object: %w", err)
+	}
+
+	sql := `
+		INSERT INTO gambar_barang (id_barang, nama_objek, nama_file, ukuran_file, tgl_upload)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, sql, picture.IdBarang, picture.ObjectName, picture.FileName, picture.FileSize, picture.TglUpload)
+		if err != nil {
+			return fmt.Errorf("querying save picture: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+	if err != nil {
+		if rmErr := s.objects.RemoveObject(context.WithoutCancel(ctx), picture.ObjectName); rmErr != nil {
+			log.Printf("cleaning up picture object %s: %v", picture.ObjectName, rmErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) GetPicturesByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.ItemPicture, error) {
+	sql := `
+		SELECT id, nama_objek, nama_file, ukuran_file, tgl_upload, id_barang
+		FROM gambar_barang WHERE id_barang = $1 ORDER BY tgl_upload
+	`
+
+	rows, err := s.db.Query(ctx, sql, idBarang)
+	if err != nil {
+		return nil, fmt.Errorf("querying pictures by item: %w", err)
+	}
+	defer rows.Close()
+
+	pictures, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.ItemPicture])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return pictures, nil
+}
+
+func (s *Storage) GetPictureById(ctx context.Context, id int) (entities.ItemPicture, error) {
+	sql := `
+		SELECT id, nama_objek, nama_file, ukuran_file, tgl_upload, id_barang
+		FROM gambar_barang WHERE id = $1
+	`
+	var p entities.ItemPicture
+
+	err := s.db.QueryRow(ctx, sql, id).Scan(&p.Id, &p.ObjectName, &p.FileName, &p.FileSize, &p.TglUpload, &p.IdBarang)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entities.ItemPicture{}, utils.ErrNotFound
+		}
+		return entities.ItemPicture{}, fmt.Errorf("querying get picture by id: %w", err)
+	}
+
+	return p, nil
+}
+
+func (s *Storage) OpenPicture(ctx context.Context, picture entities.ItemPicture) (io.ReadCloser, ObjectInfo, error) {
+	return s.objects.GetObject(ctx, picture.ObjectName)
+}
+
+func (s *Storage) DeletePicture(ctx context.Context, picture entities.ItemPicture, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		commandTag, err := tx.Exec(ctx, `DELETE FROM gambar_barang WHERE id = $1`, picture.Id)
+		if err != nil {
+			return fmt.Errorf("querying delete picture: %w", err)
+		}
+
+		if commandTag.RowsAffected() == 0 {
+			return fmt.Errorf("error deleting picture: %w", utils.ErrNotFound)
+		}
+
+		if err := audit(txAudit{tx}); err != nil {
+			return err
+		}
+
+		if err := s.objects.RemoveObject(ctx, picture.ObjectName); err != nil {
+			return fmt.Errorf("removing picture object: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Maintenance Area
+
+func (s *Storage) RecountCounters(ctx context.Context) (fixedLocations, fixedRooms int64, err error) {
+	err = pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `LOCK TABLE ruangan, unit_barang IN SHARE MODE`); err != nil {
+			return fmt.Errorf("locking tables: %w", err)
+		}
+
+		sqlLocations := `
+			UPDATE lokasi l SET jumlah_ruangan = c.total
+			FROM (
+				SELECT l2.id, COUNT(r.id) AS total
+				FROM lokasi l2 LEFT JOIN ruangan r ON r.id_lokasi = l2.id AND r.tgl_dihapus IS NULL
+				GROUP BY l2.id
+			) c
+			WHERE l.id = c.id AND l.jumlah_ruangan IS DISTINCT FROM c.total
+		`
+
+		commandTag, err := tx.Exec(ctx, sqlLocations)
+		if err != nil {
+			return fmt.Errorf("querying recount jumlah_ruangan: %w", err)
+		}
+		fixedLocations = commandTag.RowsAffected()
+
+		sqlRooms := `
+			UPDATE ruangan r SET jumlah_barang = c.total
+			FROM (
+				SELECT r2.id, COUNT(u.id) AS total
+				FROM ruangan r2 LEFT JOIN unit_barang u ON u.id_ruangan = r2.id AND u.tgl_dihapus IS NULL
+				GROUP BY r2.id
+			) c
+			WHERE r.id = c.id AND r.jumlah_barang IS DISTINCT FROM c.total
+		`
+
+		commandTag, err = tx.Exec(ctx, sqlRooms)
+		if err != nil {
+			return fmt.Errorf("querying recount jumlah_barang: %w", err)
+		}
+		fixedRooms = commandTag.RowsAffected()
+
+		return nil
+	})
+
+	return fixedLocations, fixedRooms, err
+}
+
+// Transfer Area
+
+func (s *Storage) TransferUnits(ctx context.Context, req entities.TransferRequest, build TransferBuilder, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var dest entities.Room
+		err := tx.QueryRow(ctx, `SELECT id, nama, slug FROM ruangan WHERE id = $1 AND tgl_dihapus IS NULL FOR SHARE`, req.Tujuan).Scan(
+			&dest.Id, &dest.Nama, &dest.Slug,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return utils.ErrNotFound
+			}
+			return fmt.Errorf("querying destination room: %w", err)
+		}
+
+		units, err := lockUnits(ctx, tx, req.Units)
+		if err != nil {
+			return err
+		}
+
+		lastMoved, err := lastTransferDates(ctx, tx, req.Units)
+		if err != nil {
+			return err
+		}
+
+		onLoan, err := activeLoans(ctx, tx, req.Units)
+		if err != nil {
+			return err
+		}
+
+		transfers, err := build(units, lastMoved, onLoan, dest)
+		if err != nil {
+			return err
+		}
+
+		commandTag, err := tx.Exec(ctx,
+			`UPDATE unit_barang SET id_ruangan = $1, tgl_update = $2 WHERE id = ANY($3)`,
+			dest.Id, time.Now(), req.Units,
+		)
+		if err != nil {
+			return fmt.Errorf("querying move units: %w", err)
+		}
+
+		if commandTag.RowsAffected() != int64(len(transfers)) {
+			return errors.New("failed to move every unit")
+		}
+
+		if err := insertTransfers(ctx, tx, transfers); err != nil {
+			return err
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// lockUnits reads the units with a row lock held until the transaction ends,
+// in id order so concurrent callers cannot deadlock.
+func lockUnits(ctx context.Context, tx pgx.Tx, ids []uuid.UUID) ([]entities.ItemUnit, error) {
+	sqlUnits := `
+		SELECT ub.id, ub.no_seri, ub.kondisi, ub.tgl_dibuat, ub.tgl_perolehan, ub.id_barang, ub.id_ruangan, r.nama
+		FROM unit_barang ub
+		JOIN ruangan r ON ub.id_ruangan = r.id
+		WHERE ub.id = ANY($1) AND ub.tgl_dihapus IS NULL
+		ORDER BY ub.id
+		FOR UPDATE OF ub
+	`
+
+	rows, err := tx.Query(ctx, sqlUnits, ids)
+	if err != nil {
+		return nil, fmt.Errorf("querying lock units: %w", err)
+	}
+	defer rows.Close()
+
+	var units []entities.ItemUnit
+	for rows.Next() {
+		var u entities.ItemUnit
+		if err := rows.Scan(&u.Id, &u.NoSeri, &u.Kondisi, &u.TglDibuat, &u.TglPerolehan, &u.IdBarang, &u.IdRuangan, &u.Ruangan.Nama); err != nil {
+			return nil, fmt.Errorf("error scanning rows unit: %w", err)
+		}
+		u.Ruangan.Id = u.IdRuangan
+		units = append(units, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading units: %w", err)
+	}
+
+	return units, nil
+}
+
+// lastTransferDates returns the latest tgl_mutasi of each unit that has been
+// transferred before.
+func lastTransferDates(ctx context.Context, tx pgx.Tx, ids []uuid.UUID) (map[uuid.UUID]time.Time, error) {
+	rows, err := tx.Query(ctx, `SELECT id_unit, MAX(tgl_mutasi) FROM mutasi WHERE id_unit = ANY($1) GROUP BY id_unit`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("querying last transfers: %w", err)
+	}
+	defer rows.Close()
+
+	lastMoved := make(map[uuid.UUID]time.Time)
+	for rows.Next() {
+		var id uuid.UUID
+		var last time.Time
+		if err := rows.Scan(&id, &last); err != nil {
+			return nil, fmt.Errorf("error scanning last transfers: %w", err)
+		}
+		lastMoved[id] = last
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading last transfers: %w", err)
+	}
+
+	return lastMoved, nil
+}
+
+// activeLoans returns the loan each unit is still out on. Call it after the
+// units are locked: as its own statement it sees a loan committed by whoever
+// held the lock before.
+func activeLoans(ctx context.Context, tx pgx.Tx, ids []uuid.UUID) (map[uuid.UUID]entities.Loan, error) {
+	sqlLoans := `
+		SELECT pu.id_unit, p.id, p.peminjam, p.identitas, p.tgl_pinjam, p.tgl_jatuh_tempo
+		FROM peminjaman_unit pu
+		JOIN peminjaman p ON pu.id_peminjaman = p.id
+		WHERE pu.id_unit = ANY($1) AND pu.tgl_kembali IS NULL
+	`
+
+	rows, err := tx.Query(ctx, sqlLoans, ids)
+	if err != nil {
+		return nil, fmt.Errorf("querying active loans: %w", err)
+	}
+	defer rows.Close()
+
+	onLoan := make(map[uuid.UUID]entities.Loan)
+	for rows.Next() {
+		var id uuid.UUID
+		var l entities.Loan
+		if err := rows.Scan(&id, &l.Id, &l.Peminjam, &l.Identitas, &l.TglPinjam, &l.TglJatuhTempo); err != nil {
+			return nil, fmt.Errorf("error scanning active loans: %w", err)
+		}
+		onLoan[id] = l
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading active loans: %w", err)
+	}
+
+	return onLoan, nil
+}
+
+func insertTransfers(ctx context.Context, tx pgx.Tx, transfers []entities.Transfer) error {
+	sqlInsert := `
+		INSERT INTO mutasi (
+			id, id_unit, id_ruangan_asal, id_ruangan_tujuan, nama_ruangan_asal,
+			nama_ruangan_tujuan, alasan, tgl_mutasi, tgl_dibuat
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	batch := &pgx.Batch{}
+	for _, t := range transfers {
+		batch.Queue(sqlInsert,
+			t.Id, t.IdUnit, t.IdRuanganAsal, t.IdRuanganTujuan, t.NamaRuanganAsal,
+			t.NamaRuanganTujuan, t.Alasan, t.TglMutasi, t.TglDibuat,
+		)
+	}
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("querying insert transfers: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetTransfersByUnit(ctx context.Context, idUnit uuid.UUID) ([]entities.Transfer, error) {
+	sql := `
+		SELECT
+			id, id_unit, id_ruangan_asal, id_ruangan_tujuan, nama_ruangan_asal,
+			nama_ruangan_tujuan, alasan, tgl_mutasi, tgl_dibuat
+		FROM mutasi WHERE id_unit = $1
+		ORDER BY tgl_mutasi, tgl_dibuat
+	`
+
+	rows, err := s.db.Query(ctx, sql, idUnit)
+	if err != nil {
+		return nil, fmt.Errorf("querying transfers by unit: %w", err)
+	}
+	defer rows.Close()
+
+	transfers, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.Transfer])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return transfers, nil
+}
+
+// Report Area
+
+// GetValuationLines reads items and units acquired by the cutoff
+// from a single snapshot. Each unit is placed in the room it occupied on that
+// date: the origin of its first later transfer, or its current room when it
+// has not moved since. The condition is the current one.
+func (s *Storage) GetValuationLines(ctx context.Context, cutoff time.Time) ([]entities.ValuationItem, []entities.ValuationUnit, error) {
+	var items []entities.ValuationItem
+	var units []entities.ValuationUnit
+
+	txOpts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	err := pgx.BeginTxFunc(ctx, s.db, txOpts, func(tx pgx.Tx) error {
+		sqlItems := `
+			SELECT b.id, b.harga_satuan, b.jumlah, k.kode, k.nama
+			FROM barang b
+			JOIN kategori k ON b.id_kategori = k.id
+			WHERE b.tgl_perolehan <= $1
+				AND (b.tgl_dihapus IS NULL OR b.tgl_dihapus::date > $1)
+			ORDER BY k.nama, b.nama
+		`
+
+		rows, err := tx.Query(ctx, sqlItems, cutoff)
+		if err != nil {
+			return fmt.Errorf("querying valuation items: %w", err)
+		}
+
+		for rows.Next() {
+			var i entities.ValuationItem
+			if err := rows.Scan(&i.IdBarang, &i.HargaSatuan, &i.Jumlah, &i.KodeKategori, &i.NamaKategori); err != nil {
+				rows.Close()
+				return fmt.Errorf("scanning valuation item: %w", err)
+			}
+			items = append(items, i)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		sqlUnits := `
+			SELECT ub.id_barang, ub.kondisi, COALESCE(l.kode, ''), COALESCE(l.nama, '')
+			FROM unit_barang ub
+			LEFT JOIN LATERAL (
+				SELECT true AS moved, m.id_ruangan_asal
+				FROM mutasi m
+				WHERE m.id_unit = ub.id AND m.tgl_mutasi > $1
+				ORDER BY m.tgl_mutasi, m.tgl_dibuat
+				LIMIT 1
+			) nxt ON true
+			LEFT JOIN ruangan r ON r.id = CASE WHEN nxt.moved THEN nxt.id_ruangan_asal ELSE ub.id_ruangan END
+			LEFT JOIN lokasi l ON r.id_lokasi = l.id
+			WHERE ub.tgl_perolehan <= $1
+				AND (ub.tgl_dihapus IS NULL OR ub.tgl_dihapus::date > $1)
+			ORDER BY l.nama NULLS LAST
+		`
+
+		rows, err = tx.Query(ctx, sqlUnits, cutoff)
+		if err != nil {
+			return fmt.Errorf("querying valuation units: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var u entities.ValuationUnit
+			if err := rows.Scan(&u.IdBarang, &u.Kondisi, &u.KodeLokasi, &u.NamaLokasi); err != nil {
+				return fmt.Errorf("scanning valuation unit: %w", err)
+			}
+			units = append(units, u)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return items, units, nil
+}
+
+// User Area
+
+func (s *Storage) CreateUser(ctx context.Context, user entities.User, audit Audit) error {
+	sql := `
+		INSERT INTO pengguna (id, username, nama, password_hash, peran, tgl_dibuat, tgl_update)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, sql,
+			user.Id, user.Username, user.Nama, user.PasswordHash,
+			user.Peran, user.TglDibuat, user.TglUpdate,
+		)
+		if err != nil {
+			return fmt.Errorf("querying create user: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+const selectUserSQL = `
+	SELECT id, username, nama, password_hash, peran, tgl_dibuat, tgl_update
+	FROM pengguna
+`
+
+func (s *Storage) GetUsers(ctx context.Context) ([]entities.User, error) {
+	rows, err := s.db.Query(ctx, selectUserSQL+` ORDER BY username`)
+	if err != nil {
+		return nil, fmt.Errorf("querying users: %w", err)
+	}
+	defer rows.Close()
+
+	users, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.User])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return users, nil
+}
+
+func (s *Storage) getUser(ctx context.Context, where string, arg any) (entities.User, error) {
+	rows, err := s.db.Query(ctx, selectUserSQL+where, arg)
+	if err != nil {
+		return entities.User{}, fmt.Errorf("querying user: %w", err)
+	}
+
+	user, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[entities.User])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entities.User{}, utils.ErrNotFound
+		}
+		return entities.User{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) GetUserById(ctx context.Context, id uuid.UUID) (entities.User, error) {
+	return s.getUser(ctx, ` WHERE id = $1`, id)
+}
+
+func (s *Storage) GetUserByUsername(ctx context.Context, username string) (entities.User, error) {
+	return s.getUser(ctx, ` WHERE username = $1`, username)
+}
+
+// lockAdmins locks every admin row so that concurrent demotions or deletions
+// cannot leave the system without an admin.
+func lockAdmins(ctx context.Context, tx pgx.Tx) (int, error) {
+	rows, err := tx.Query(ctx, `SELECT id FROM pengguna WHERE peran = 'admin' FOR UPDATE`)
+	if err != nil {
+		return 0, fmt.Errorf("locking admins: %w", err)
+	}
+
+	admins, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return 0, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return len(admins), nil
+}
+
+func (s *Storage) UpdateUser(ctx context.Context, user entities.User, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		admins, err := lockAdmins(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		var current entities.Peran
+		err = tx.QueryRow(ctx, `SELECT peran FROM pengguna WHERE id = $1 FOR UPDATE`, user.Id).Scan(&current)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return utils.ErrNotFound
+			}
+			return fmt.Errorf("querying user role: %w", err)
+		}
+
+		if current.IsAdmin() && !user.Peran.IsAdmin() && admins <= 1 {
+			return ErrLastAdmin
+		}
+
+		sql := `
+			UPDATE pengguna SET
+				nama = $1, password_hash = $2, peran = $3, tgl_update = $4
+			WHERE id = $5
+		`
+
+		_, err = tx.Exec(ctx, sql, user.Nama, user.PasswordHash, user.Peran, user.TglUpdate, user.Id)
+		if err != nil {
+			return fmt.Errorf("querying update user: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+func (s *Storage) DeleteUser(ctx context.Context, id uuid.UUID, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		admins, err := lockAdmins(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		var current entities.Peran
+		err = tx.QueryRow(ctx, `SELECT peran FROM pengguna WHERE id = $1 FOR UPDATE`, id).Scan(&current)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return utils.ErrNotFound
+			}
+			return fmt.Errorf("querying user role: %w", err)
+		}
+
+		if current.IsAdmin() && admins <= 1 {
+			return ErrLastAdmin
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM pengguna WHERE id = $1`, id); err != nil {
+			return fmt.Errorf("querying delete user: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+func (s *Storage) CreateSession(ctx context.Context, session entities.Session) error {
+	sql := `INSERT INTO sesi (id, id_pengguna, kedaluwarsa, tgl_dibuat) VALUES ($1, $2, $3, $4)`
+
+	_, err := s.db.Exec(ctx, sql, session.Id, session.IdPengguna, session.Kedaluwarsa, session.TglDibuat)
+	if err != nil {
+		return fmt.Errorf("querying create session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetSessionUser(ctx context.Context, id string, now time.Time) (entities.User, error) {
+	sql := `
+		SELECT p.id, p.username, p.nama, p.password_hash, p.peran, p.tgl_dibuat, p.tgl_update
+		FROM sesi s
+		JOIN pengguna p ON s.id_pengguna = p.id
+		WHERE s.id = $1 AND s.kedaluwarsa > $2
+	`
+
+	rows, err := s.db.Query(ctx, sql, id, now)
+	if err != nil {
+		return entities.User{}, fmt.Errorf("querying session user: %w", err)
+	}
+
+	user, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[entities.User])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entities.User{}, utils.ErrNotFound
+		}
+		return entities.User{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) DeleteSession(ctx context.Context, id string) error {
+	if _, err := s.db.Exec(ctx, `DELETE FROM sesi WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("querying delete session: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteUserSessions(ctx context.Context, idPengguna uuid.UUID) error {
+	if _, err := s.db.Exec(ctx, `DELETE FROM sesi WHERE id_pengguna = $1`, idPengguna); err != nil {
+		return fmt.Errorf("querying delete user sessions: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	commandTag, err := s.db.Exec(ctx, `DELETE FROM sesi WHERE kedaluwarsa <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("querying delete expired sessions: %w", err)
+	}
+	return commandTag.RowsAffected(), nil
+}
+
+// Audit Area
+
+func (a txAudit) CreateAuditLog(ctx context.Context, entry entities.AuditLog) error {
+	sql := `
+		INSERT INTO audit_log (id_pengguna, aktor, jenis_entitas, id_entitas, aksi, sebelum, sesudah, tgl_dibuat)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := a.tx.Exec(ctx, sql,
+		entry.IdPengguna, entry.Aktor, entry.JenisEntitas, entry.IdEntitas,
+		entry.Aksi, entry.Sebelum, entry.Sesudah, entry.TglDibuat,
+	)
+	if err != nil {
+		return fmt.Errorf("querying create audit log: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CountAuditLogs(ctx context.Context, where string, args []interface{}) (int, error) {
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("counting audit logs: %w", err)
+	}
+
+	return total, nil
+}
+
+func (s *Storage) GetAuditLogs(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.AuditLog, error) {
+	sql := `
+		SELECT id, id_pengguna, aktor, jenis_entitas, id_entitas, aksi, sebelum, sesudah, tgl_dibuat
+		FROM audit_log
+	` + where + sort + limit
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.AuditLog])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Stock Opname Area
+
+const selectOpnameSQL = `
+	SELECT
+		id, id_ruangan, id_lokasi, nama_cakupan, status, catatan,
+		id_pengguna, petugas, tgl_mulai, tgl_selesai
+	FROM stok_opname
+`
+
+// CreateOpname stores the session together with the units registered in its
+// scope at this moment, which are the units the count expects to find. Units
+// already marked hilang are left out.
+func (s *Storage) CreateOpname(ctx context.Context, op entities.StockOpname, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		sqlInsert := `
+			INSERT INTO stok_opname (
+				id, id_ruangan, id_lokasi, nama_cakupan, status, catatan,
+				id_pengguna, petugas, tgl_mulai
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`
+
+		_, err := tx.Exec(ctx, sqlInsert,
+			op.Id, op.IdRuangan, op.IdLokasi, op.NamaCakupan, op.Status, op.Catatan,
+			op.IdPengguna, op.Petugas, op.TglMulai,
+		)
+		if err != nil {
+			return fmt.Errorf("querying insert stok opname: %w", err)
+		}
+
+		// A location scope covers the rooms of every location under it.
+		sqlUnits := liveLocationSubtreeSQL + `
+			INSERT INTO stok_opname_unit (id_opname, id_unit, id_ruangan, diharapkan)
+			SELECT $2, ub.id, ub.id_ruangan, TRUE
+			FROM unit_barang ub
+			JOIN ruangan r ON ub.id_ruangan = r.id
+			WHERE ub.kondisi <> 'hilang' AND ub.tgl_dihapus IS NULL AND r.tgl_dihapus IS NULL
+			AND CASE WHEN $3::uuid IS NULL THEN r.id_lokasi IN (SELECT id FROM sub) ELSE r.id = $3 END
+		`
+
+		if _, err := tx.Exec(ctx, sqlUnits, []uuid.UUID{op.IdLokasi}, op.Id, op.IdRuangan); err != nil {
+			return fmt.Errorf("querying capture stok opname units: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+func (s *Storage) CountOpnames(ctx context.Context, where string, args []interface{}) (int, error) {
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM stok_opname`+where, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("counting stok opname: %w", err)
+	}
+
+	return total, nil
+}
+
+func (s *Storage) GetOpnames(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.StockOpname, error) {
+	rows, err := s.db.Query(ctx, selectOpnameSQL+where+sort+limit, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying stok opname: %w", err)
+	}
+	defer rows.Close()
+
+	opnames, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.StockOpname])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return opnames, nil
+}
+
+func (s *Storage) GetOpnameById(ctx context.Context, id uuid.UUID) (entities.StockOpname, error) {
+	rows, err := s.db.Query(ctx, selectOpnameSQL+` WHERE id = $1`, id)
+	if err != nil {
+		return entities.StockOpname{}, fmt.Errorf("querying stok opname by id: %w", err)
+	}
+
+	op, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[entities.StockOpname])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entities.StockOpname{}, utils.ErrNotFound
+		}
+		return entities.StockOpname{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return op, nil
+}
+
+// GetOpnameUnits lists the units of a session. Unit.Ruangan is the room the
+// unit was registered in when it joined the session.
+func (s *Storage) GetOpnameUnits(ctx context.Context, idOpname uuid.UUID) ([]entities.OpnameUnit, error) {
+	sql := `
+		SELECT
+			sou.id_opname, sou.id_unit, sou.id_ruangan, sou.diharapkan,
+			COALESCE(sou.kode_scan, ''), sou.tgl_scan, sou.catatan, sou.tindakan,
+			ub.no_seri, ub.kondisi, b.id, b.sku, b.nama, b.slug,
+			COALESCE(r.nama, ''), COALESCE(r.slug, '')
+		FROM stok_opname_unit sou
+		JOIN unit_barang ub ON sou.id_unit = ub.id
+		JOIN barang b ON ub.id_barang = b.id
+		LEFT JOIN ruangan r ON sou.id_ruangan = r.id
+		WHERE sou.id_opname = $1
+		ORDER BY b.nama, ub.no_seri
+	`
+
+	rows, err := s.db.Query(ctx, sql, idOpname)
+	if err != nil {
+		return nil, fmt.Errorf("querying stok opname units: %w", err)
+	}
+	defer rows.Close()
+
+	var units []entities.OpnameUnit
+	for rows.Next() {
+		var u entities.OpnameUnit
+		err := rows.Scan(
+			&u.IdOpname, &u.IdUnit, &u.IdRuangan, &u.Diharapkan,
+			&u.KodeScan, &u.TglScan, &u.Catatan, &u.Tindakan,
+			&u.Unit.NoSeri, &u.Unit.Kondisi, &u.Unit.Barang.Id, &u.Unit.Barang.SKU, &u.Unit.Barang.Nama, &u.Unit.Barang.Slug,
+			&u.Unit.Ruangan.Nama, &u.Unit.Ruangan.Slug,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning rows stok opname unit: %w", err)
+		}
+
+		u.Unit.Id = u.IdUnit
+		u.Unit.IdBarang = u.Unit.Barang.Id
+		u.Unit.IdRuangan = u.IdRuangan.UUID
+		u.Unit.Ruangan.Id = u.IdRuangan.UUID
+		units = append(units, u)
+	}
+
+	return units, rows.Err()
+}
+
+// RecordOpnameScan marks the unit as scanned in a running session. A unit
+// not captured at the start is added, as expected when it has been registered
+// in scope since and as unexpected otherwise. The returned flag tells whether
+// the unit had been scanned before.
+func (s *Storage) RecordOpnameScan(ctx context.Context, idOpname uuid.UUID, unit entities.ItemUnit, kode string, at time.Time) (entities.OpnameUnit, bool, error) {
+	var result entities.OpnameUnit
+	var scanned bool
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var status entities.StatusOpname
+		err := tx.QueryRow(ctx, `SELECT status FROM stok_opname WHERE id = $1 FOR SHARE`, idOpname).Scan(&status)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return utils.ErrNotFound
+			}
+			return fmt.Errorf("querying stok opname status: %w", err)
+		}
+
+		if status != entities.OpnameBerjalan {
+			return ErrOpnameClosed
+		}
+
+		var prev *time.Time
+		err = tx.QueryRow(ctx,
+			`SELECT tgl_scan FROM stok_opname_unit WHERE id_opname = $1 AND id_unit = $2 FOR UPDATE`,
+			idOpname, unit.Id,
+		).Scan(&prev)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("querying stok opname unit: %w", err)
+		}
+		scanned = prev != nil
+
+		sqlUpsert := `
+			WITH RECURSIVE sub AS (
+				SELECT id_lokasi AS id FROM stok_opname WHERE id = $1
+				UNION ALL
+				SELECT l.id FROM lokasi l JOIN sub ON l.id_induk = sub.id WHERE l.tgl_dihapus IS NULL
+			)
+			INSERT INTO stok_opname_unit (id_opname, id_unit, id_ruangan, diharapkan, kode_scan, tgl_scan)
+			VALUES ($1, $2, $3, EXISTS (
+				SELECT 1 FROM ruangan r JOIN stok_opname o ON o.id = $1
+				WHERE r.id = $3
+				AND CASE WHEN o.id_ruangan IS NULL THEN r.id_lokasi IN (SELECT id FROM sub) ELSE r.id = o.id_ruangan END
+			), $4, $5)
+			ON CONFLICT (id_opname, id_unit) DO UPDATE SET
+				kode_scan = COALESCE(stok_opname_unit.kode_scan, EXCLUDED.kode_scan),
+				tgl_scan = COALESCE(stok_opname_unit.tgl_scan, EXCLUDED.tgl_scan)
+			RETURNING id_opname, id_unit, id_ruangan, diharapkan, COALESCE(kode_scan, ''), tgl_scan, catatan, tindakan
+		`
+
+		err = tx.QueryRow(ctx, sqlUpsert, idOpname, unit.Id, unit.IdRuangan, kode, at).Scan(
+			&result.IdOpname, &result.IdUnit, &result.IdRuangan, &result.Diharapkan,
+			&result.KodeScan, &result.TglScan, &result.Catatan, &result.Tindakan,
+		)
+		if err != nil {
+			return fmt.Errorf("querying record stok opname scan: %w", err)
+		}
+
+		return nil
+	})
+
+	result.Unit = unit
+	return result, scanned, err
+}
+
+func (s *Storage) UpdateOpnameNote(ctx context.Context, idOpname, idUnit uuid.UUID, catatan string) error {
+	sql := `UPDATE stok_opname_unit SET catatan = $1 WHERE id_opname = $2 AND id_unit = $3`
+
+	commandTag, err := s.db.Exec(ctx, sql, catatan, idOpname, idUnit)
+	if err != nil {
+		return fmt.Errorf("querying update stok opname note: %w", err)
+	}
+
+	if commandTag.RowsAffected() == 0 {
+		return utils.ErrNotFound
+	}
+
+	return nil
+}
+
+// CloseOpname locks the session and its units, lets build decide which units
+// to mark hilang and which to move into the counted room, and applies that
+// together with closing the session.
+func (s *Storage) CloseOpname(ctx context.Context, id uuid.UUID, build OpnameCloser, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, selectOpnameSQL+` WHERE id = $1 FOR UPDATE`, id)
+		if err != nil {
+			return fmt.Errorf("querying lock stok opname: %w", err)
+		}
+
+		op, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[entities.StockOpname])
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return utils.ErrNotFound
+			}
+			return fmt.Errorf("collect row: %w", err)
+		}
+
+		if op.IsClosed() {
+			return ErrOpnameClosed
+		}
+
+		rows, err = tx.Query(ctx, `
+			SELECT id_opname, id_unit, id_ruangan, diharapkan, COALESCE(kode_scan, '') AS kode_scan,
+				tgl_scan, catatan, tindakan
+			FROM stok_opname_unit WHERE id_opname = $1
+		`, id)
+		if err != nil {
+			return fmt.Errorf("querying stok opname units: %w", err)
+		}
+
+		units, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.OpnameUnit])
+		if err != nil {
+			return fmt.Errorf("collect rows: %w", err)
+		}
+
+		ids := make([]uuid.UUID, len(units))
+		for i, u := range units {
+			ids[i] = u.IdUnit
+		}
+
+		locked, err := lockUnits(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+
+		current := make(map[uuid.UUID]entities.ItemUnit, len(locked))
+		for _, u := range locked {
+			current[u.Id] = u
+		}
+		for i := range units {
+			units[i].Unit = current[units[i].IdUnit]
+		}
+
+		lastMoved, err := lastTransferDates(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+
+		onLoan, err := activeLoans(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+
+		var dest entities.Room
+		if op.IsRoom() {
+			err := tx.QueryRow(ctx, `SELECT id, nama, slug FROM ruangan WHERE id = $1 AND tgl_dihapus IS NULL FOR SHARE`, op.IdRuangan.UUID).Scan(
+				&dest.Id, &dest.Nama, &dest.Slug,
+			)
+			if err != nil {
+				return fmt.Errorf("querying counted room: %w", err)
+			}
+		}
+
+		closing, err := build(op, units, lastMoved, onLoan, dest)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+
+		if len(closing.Hilang) > 0 {
+			lost := make([]uuid.UUID, len(closing.Hilang))
+			for i, u := range closing.Hilang {
+				lost[i] = u.Id
+			}
+
+			_, err := tx.Exec(ctx, `UPDATE unit_barang SET kondisi = $1, tgl_update = $2 WHERE id = ANY($3)`, entities.KondisiHilang, now, lost)
+			if err != nil {
+				return fmt.Errorf("querying mark units hilang: %w", err)
+			}
+
+			_, err = tx.Exec(ctx, `UPDATE stok_opname_unit SET tindakan = $1 WHERE id_opname = $2 AND id_unit = ANY($3)`, entities.TindakanTandaiHilang, id, lost)
+			if err != nil {
+				return fmt.Errorf("querying record stok opname action: %w", err)
+			}
+		}
+
+		if len(closing.Transfers) > 0 {
+			moved := make([]uuid.UUID, len(closing.Transfers))
+			for i, t := range closing.Transfers {
+				moved[i] = t.IdUnit
+			}
+
+			_, err := tx.Exec(ctx, `UPDATE unit_barang SET id_ruangan = $1, tgl_update = $2 WHERE id = ANY($3)`, dest.Id, now, moved)
+			if err != nil {
+				return fmt.Errorf("querying move units: %w", err)
+			}
+
+			if err := insertTransfers(ctx, tx, closing.Transfers); err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(ctx, `UPDATE stok_opname_unit SET tindakan = $1 WHERE id_opname = $2 AND id_unit = ANY($3)`, entities.TindakanDipindahkan, id, moved)
+			if err != nil {
+				return fmt.Errorf("querying record stok opname action: %w", err)
+			}
+		}
+
+		_, err = tx.Exec(ctx, `UPDATE stok_opname SET status = $1, tgl_selesai = $2 WHERE id = $3`, entities.OpnameSelesai, now, id)
+		if err != nil {
+			return fmt.Errorf("querying close stok opname: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// Loan Area
+
+// selectLoanSQL reads loans with their status worked out from their units, as
+// a subquery so list filters and sorting can use status and the counts.
+const selectLoanSQL = `
+	SELECT
+		id, peminjam, identitas, kontak, keperluan, tgl_pinjam, tgl_jatuh_tempo,
+		id_pengguna, petugas, tgl_dibuat, status, jumlah_unit, jumlah_kembali
+	FROM (
+		SELECT
+			p.id, p.peminjam, p.identitas, p.kontak, p.keperluan, p.tgl_pinjam, p.tgl_jatuh_tempo,
+			p.id_pengguna, p.petugas, p.tgl_dibuat,
+			CASE
+				WHEN COUNT(pu.tgl_kembali) = COUNT(pu.id_unit) THEN 'selesai'
+				WHEN p.tgl_jatuh_tempo < CURRENT_DATE THEN 'terlambat'
+				ELSE 'aktif'
+			END AS status,
+			COUNT(pu.id_unit)::int AS jumlah_unit,
+			COUNT(pu.tgl_kembali)::int AS jumlah_kembali
+		FROM peminjaman p
+		LEFT JOIN peminjaman_unit pu ON pu.id_peminjaman = p.id
+		GROUP BY p.id
+	) peminjaman
+`
+
+// CheckoutUnits locks the units, lets build check them against the loans they
+// are already out on and stores the new loan. The unique index on active loan
+// units backs the check up should a unit slip through.
+func (s *Storage) CheckoutUnits(ctx context.Context, req entities.LoanRequest, build LoanBuilder, audit Audit) error {
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		units, err := lockUnits(ctx, tx, req.Units)
+		if err != nil {
+			return err
+		}
+
+		onLoan, err := activeLoans(ctx, tx, req.Units)
+		if err != nil {
+			return err
+		}
+
+		loanUnits, err := build(units, onLoan)
+		if err != nil {
+			return err
+		}
+
+		l := req.Loan
+		sqlInsert := `
+			INSERT INTO peminjaman (
+				id, peminjam, identitas, kontak, keperluan, tgl_pinjam, tgl_jatuh_tempo,
+				id_pengguna, petugas, tgl_dibuat
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`
+
+		_, err = tx.Exec(ctx, sqlInsert,
+			l.Id, l.Peminjam, l.Identitas, l.Kontak, l.Keperluan, l.TglPinjam, l.TglJatuhTempo,
+			l.IdPengguna, l.Petugas, l.TglDibuat,
+		)
+		if err != nil {
+			return fmt.Errorf("querying insert peminjaman: %w", err)
+		}
+
+		batch := &pgx.Batch{}
+		for _, u := range loanUnits {
+			batch.Queue(`INSERT INTO peminjaman_unit (id_peminjaman, id_unit, kondisi_pinjam) VALUES ($1, $2, $3)`,
+				l.Id, u.IdUnit, u.KondisiPinjam,
+			)
+		}
+
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("querying insert peminjaman units: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrUnitOnLoan
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *Storage) CountLoans(ctx context.Context, where string, args []interface{}) (int, error) {
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM (`+selectLoanSQL+`) peminjaman`+where, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("counting peminjaman: %w", err)
+	}
+
+	return total, nil
+}
+
+func (s *Storage) GetLoans(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Loan, error) {
+	rows, err := s.db.Query(ctx, selectLoanSQL+where+sort+limit, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying peminjaman: %w", err)
+	}
+	defer rows.Close()
+
+	loans, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.Loan])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return loans, nil
+}
+
+func (s *Storage) GetLoanById(ctx context.Context, id uuid.UUID) (entities.Loan, error) {
+	rows, err := s.db.Query(ctx, selectLoanSQL+` WHERE id = $1`, id)
+	if err != nil {
+		return entities.Loan{}, fmt.Errorf("querying peminjaman by id: %w", err)
+	}
+
+	loan, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[entities.Loan])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entities.Loan{}, utils.ErrNotFound
+		}
+		return entities.Loan{}, fmt.Errorf("collect row: %w", err)
+	}
+
+	return loan, nil
+}
+
+// selectLoanUnitSQL joins the units of loans with the loan and the unit as it
+// is now. Deleted units are kept so a loan still shows everything it lent.
+const selectLoanUnitSQL = `
+	SELECT
+		pu.id_peminjaman, pu.id_unit, pu.kondisi_pinjam, pu.tgl_kembali,
+		pu.kondisi_kembali, pu.catatan_kembali,
+		p.peminjam, p.identitas, p.kontak, p.keperluan, p.tgl_pinjam, p.tgl_jatuh_tempo,
+		ub.no_seri, ub.kondisi, b.id, b.sku, b.nama, b.slug, r.id, r.nama, r.slug
+	FROM peminjaman_unit pu
+	JOIN peminjaman p ON pu.id_peminjaman = p.id
+	JOIN unit_barang ub ON pu.id_unit = ub.id
+	JOIN barang b ON ub.id_barang = b.id
+	JOIN ruangan r ON ub.id_ruangan = r.id
+`
+
+func scanLoanUnit(row pgx.Row) (entities.LoanUnit, error) {
+	var u entities.LoanUnit
+	var unit entities.ItemUnit
+	var l entities.Loan
+
+	err := row.Scan(
+		&u.IdPeminjaman, &u.IdUnit, &u.KondisiPinjam, &u.TglKembali,
+		&u.KondisiKembali, &u.CatatanKembali,
+		&l.Peminjam, &l.Identitas, &l.Kontak, &l.Keperluan, &l.TglPinjam, &l.TglJatuhTempo,
+		&unit.NoSeri, &unit.Kondisi, &unit.Barang.Id, &unit.Barang.SKU, &unit.Barang.Nama, &unit.Barang.Slug,
+		&unit.Ruangan.Id, &unit.Ruangan.Nama, &unit.Ruangan.Slug,
+	)
+	if err != nil {
+		return entities.LoanUnit{}, err
+	}
+
+	unit.Id = u.IdUnit
+	unit.IdBarang = unit.Barang.Id
+	unit.IdRuangan = unit.Ruangan.Id
+	l.Id = u.IdPeminjaman
+
+	u.Unit = unit
+	u.Peminjaman = l
+
+	return u, nil
+}
+
+func (s *Storage) queryLoanUnits(ctx context.Context, sql string, args ...any) ([]entities.LoanUnit, error) {
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying peminjaman units: %w", err)
+	}
+	defer rows.Close()
+
+	var units []entities.LoanUnit
+	for rows.Next() {
+		u, err := scanLoanUnit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning rows peminjaman unit: %w", err)
+		}
+		units = append(units, u)
+	}
+
+	return units, rows.Err()
+}
+
+func (s *Storage) GetLoanUnits(ctx context.Context, idPeminjaman uuid.UUID) ([]entities.LoanUnit, error) {
+	return s.queryLoanUnits(ctx, selectLoanUnitSQL+` WHERE pu.id_peminjaman = $1 ORDER BY b.nama, ub.no_seri`, idPeminjaman)
+}
+
+// GetOverdueLoanUnits lists the units still out past their due date, the
+// longest overdue first.
+func (s *Storage) GetOverdueLoanUnits(ctx context.Context, today time.Time) ([]entities.LoanUnit, error) {
+	return s.queryLoanUnits(ctx,
+		selectLoanUnitSQL+` WHERE pu.tgl_kembali IS NULL AND p.tgl_jatuh_tempo < $1 ORDER BY p.tgl_jatuh_tempo, p.peminjam, b.nama, ub.no_seri`,
+		today,
+	)
+}
+
+func (s *Storage) GetLoanUnitsByBorrower(ctx context.Context, identitas string) ([]entities.LoanUnit, error) {
+	return s.queryLoanUnits(ctx,
+		selectLoanUnitSQL+` WHERE p.identitas = $1 ORDER BY p.tgl_pinjam DESC, p.tgl_dibuat DESC, b.nama, ub.no_seri`,
+		identitas,
+	)
+}
+
+// ReturnUnits locks the loan and its units, lets build pick the units to
+// check in and records their return, setting each unit to the condition it
+// came back with.
+func (s *Storage) ReturnUnits(ctx context.Context, id uuid.UUID, build LoanReturner, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var locked uuid.UUID
+		if err := tx.QueryRow(ctx, `SELECT id FROM peminjaman WHERE id = $1 FOR UPDATE`, id).Scan(&locked); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return utils.ErrNotFound
+			}
+			return fmt.Errorf("querying lock peminjaman: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, selectLoanSQL+` WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("querying peminjaman by id: %w", err)
+		}
+
+		loan, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[entities.Loan])
+		if err != nil {
+			return fmt.Errorf("collect row: %w", err)
+		}
+
+		rows, err = tx.Query(ctx, selectLoanUnitSQL+` WHERE pu.id_peminjaman = $1 ORDER BY ub.id FOR UPDATE OF pu, ub`, id)
+		if err != nil {
+			return fmt.Errorf("querying lock peminjaman units: %w", err)
+		}
+
+		var units []entities.LoanUnit
+		for rows.Next() {
+			u, err := scanLoanUnit(rows)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("error scanning rows peminjaman unit: %w", err)
+			}
+			units = append(units, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("reading peminjaman units: %w", err)
+		}
+
+		returned, err := build(loan, units)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+
+		batch := &pgx.Batch{}
+		for _, u := range returned {
+			batch.Queue(`
+				UPDATE peminjaman_unit SET tgl_kembali = $1, kondisi_kembali = $2, catatan_kembali = $3
+				WHERE id_peminjaman = $4 AND id_unit = $5 AND tgl_kembali IS NULL
+			`, u.TglKembali, u.KondisiKembali, u.CatatanKembali, id, u.IdUnit)
+
+			if u.KondisiKembali != u.Unit.Kondisi {
+				batch.Queue(`UPDATE unit_barang SET kondisi = $1, tgl_update = $2 WHERE id = $3`, u.KondisiKembali, now, u.IdUnit)
+			}
+		}
+
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("querying return peminjaman units: %w", err)
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// Repair Area
+
+// selectRepairSQL reads tickets with their unit and the room they were opened
+// in, as a subquery so list filters can search the serial number and item.
+const selectRepairSQL = `
+	SELECT
+		id, id_unit, id_ruangan, masalah, vendor, biaya, status, kondisi_awal, kondisi_hasil,
+		catatan, tgl_mulai, tgl_selesai, id_pengguna, petugas, tgl_dibuat,
+		no_seri, kondisi_unit, id_barang, sku, nama_barang, slug_barang, nama_ruangan, slug_ruangan
+	FROM (
+		SELECT
+			p.id, p.id_unit, p.id_ruangan, p.masalah, p.vendor, p.biaya, p.status, p.kondisi_awal,
+			p.kondisi_hasil, p.catatan, p.tgl_mulai, p.tgl_selesai, p.id_pengguna, p.petugas, p.tgl_dibuat,
+			ub.no_seri, ub.kondisi AS kondisi_unit, b.id AS id_barang, b.sku, b.nama AS nama_barang,
+			b.slug AS slug_barang, COALESCE(r.nama, '') AS nama_ruangan, COALESCE(r.slug, '') AS slug_ruangan
+		FROM perbaikan p
+		JOIN unit_barang ub ON p.id_unit = ub.id
+		JOIN barang b ON ub.id_barang = b.id
+		LEFT JOIN ruangan r ON p.id_ruangan = r.id
+	) perbaikan
+`
+
+func scanRepair(row pgx.Row) (entities.Repair, error) {
+	var r entities.Repair
+	var unit entities.ItemUnit
+
+	err := row.Scan(
+		&r.Id, &r.IdUnit, &r.IdRuangan, &r.Masalah, &r.Vendor, &r.Biaya, &r.Status, &r.KondisiAwal, &r.KondisiHasil,
+		&r.Catatan, &r.TglMulai, &r.TglSelesai, &r.IdPengguna, &r.Petugas, &r.TglDibuat,
+		&unit.NoSeri, &unit.Kondisi, &unit.Barang.Id, &unit.Barang.SKU, &unit.Barang.Nama, &unit.Barang.Slug,
+		&unit.Ruangan.Nama, &unit.Ruangan.Slug,
+	)
+	if err != nil {
+		return entities.Repair{}, err
+	}
+
+	unit.Id = r.IdUnit
+	unit.IdBarang = unit.Barang.Id
+	unit.IdRuangan = r.IdRuangan.UUID
+	unit.Ruangan.Id = r.IdRuangan.UUID
+	r.Unit = unit
+
+	return r, nil
+}
+
+func (s *Storage) queryRepairs(ctx context.Context, sql string, args ...any) ([]entities.Repair, error) {
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying perbaikan: %w", err)
+	}
+	defer rows.Close()
+
+	var repairs []entities.Repair
+	for rows.Next() {
+		r, err := scanRepair(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning rows perbaikan: %w", err)
+		}
+		repairs = append(repairs, r)
+	}
+
+	return repairs, rows.Err()
+}
+
+// OpenRepair locks the unit, lets open check it and records the ticket,
+// putting the unit under repair. The unique index on open tickets backs the
+// check up.
+func (s *Storage) OpenRepair(ctx context.Context, idUnit uuid.UUID, open RepairOpener, audit Audit) error {
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		units, err := lockUnits(ctx, tx, []uuid.UUID{idUnit})
+		if err != nil {
+			return err
+		}
+		if len(units) == 0 {
+			return utils.ErrNotFound
+		}
+
+		onLoan, err := activeLoans(ctx, tx, []uuid.UUID{idUnit})
+		if err != nil {
+			return err
+		}
+
+		var berjalan bool
+		err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM perbaikan WHERE id_unit = $1 AND status = 'berjalan')`, idUnit).Scan(&berjalan)
+		if err != nil {
+			return fmt.Errorf("querying open perbaikan: %w", err)
+		}
+
+		r, err := open(units[0], onLoan, berjalan)
+		if err != nil {
+			return err
+		}
+
+		sqlInsert := `
+			INSERT INTO perbaikan (
+				id, id_unit, id_ruangan, masalah, vendor, biaya, status, kondisi_awal,
+				tgl_mulai, id_pengguna, petugas, tgl_dibuat
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`
+
+		_, err = tx.Exec(ctx, sqlInsert,
+			r.Id, r.IdUnit, r.IdRuangan, r.Masalah, r.Vendor, r.Biaya, r.Status, r.KondisiAwal,
+			r.TglMulai, r.IdPengguna, r.Petugas, r.TglDibuat,
+		)
+		if err != nil {
+			return fmt.Errorf("querying insert perbaikan: %w", err)
+		}
+
+		if r.KondisiAwal != entities.KondisiPerbaikan {
+			_, err = tx.Exec(ctx, `UPDATE unit_barang SET kondisi = $1, tgl_update = $2 WHERE id = $3`,
+				entities.KondisiPerbaikan, time.Now(), idUnit,
+			)
+			if err != nil {
+				return fmt.Errorf("querying update unit condition: %w", err)
+			}
+		}
+
+		return audit(txAudit{tx})
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrRepairOpen
+		}
+		return err
+	}
+
+	return nil
+}
+
+// CloseRepair locks the ticket and then its unit, lets finish decide the
+// outcome and records it, setting the unit to the condition it came back
+// with.
+func (s *Storage) CloseRepair(ctx context.Context, id uuid.UUID, finish RepairCloser, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var locked uuid.UUID
+		if err := tx.QueryRow(ctx, `SELECT id FROM perbaikan WHERE id = $1 FOR UPDATE`, id).Scan(&locked); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return utils.ErrNotFound
+			}
+			return fmt.Errorf("querying lock perbaikan: %w", err)
+		}
+
+		repair, err := scanRepair(tx.QueryRow(ctx, selectRepairSQL+` WHERE id = $1`, id))
+		if err != nil {
+			return fmt.Errorf("querying perbaikan by id: %w", err)
+		}
+
+		units, err := lockUnits(ctx, tx, []uuid.UUID{repair.IdUnit})
+		if err != nil {
+			return err
+		}
+
+		var unit entities.ItemUnit
+		if len(units) > 0 {
+			unit = units[0]
+		}
+
+		closed, err := finish(repair, unit)
+		if err != nil {
+			return err
+		}
+
+		sqlUpdate := `
+			UPDATE perbaikan SET status = $1, kondisi_hasil = $2, biaya = $3, tgl_selesai = $4, catatan = $5
+			WHERE id = $6 AND status = 'berjalan'
+		`
+
+		commandTag, err := tx.Exec(ctx, sqlUpdate,
+			closed.Status, closed.KondisiHasil, closed.Biaya, closed.TglSelesai, closed.Catatan, id,
+		)
+		if err != nil {
+			return fmt.Errorf("querying close perbaikan: %w", err)
+		}
+		if commandTag.RowsAffected() == 0 {
+			return errors.New("failed to close perbaikan")
+		}
+
+		if unit.Id != uuid.Nil && unit.Kondisi != closed.KondisiHasil {
+			_, err = tx.Exec(ctx, `UPDATE unit_barang SET kondisi = $1, tgl_update = $2 WHERE id = $3`,
+				closed.KondisiHasil, time.Now(), unit.Id,
+			)
+			if err != nil {
+				return fmt.Errorf("querying update unit condition: %w", err)
+			}
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+func (s *Storage) CountRepairs(ctx context.Context, where string, args []interface{}) (int, error) {
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM (`+selectRepairSQL+`) perbaikan`+where, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("counting perbaikan: %w", err)
+	}
+
+	return total, nil
+}
+
+func (s *Storage) GetRepairs(ctx context.Context, limit, sort, where string, args []interface{}) ([]entities.Repair, error) {
+	return s.queryRepairs(ctx, selectRepairSQL+where+sort+limit, args...)
+}
+
+func (s *Storage) GetRepairById(ctx context.Context, id uuid.UUID) (entities.Repair, error) {
+	repair, err := scanRepair(s.db.QueryRow(ctx, selectRepairSQL+` WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return entities.Repair{}, utils.ErrNotFound
+		}
+		return entities.Repair{}, fmt.Errorf("querying perbaikan by id: %w", err)
+	}
+
+	return repair, nil
+}
+
+// GetRepairsByItem lists the tickets of every unit of an item, deleted units
+// included, newest first.
+func (s *Storage) GetRepairsByItem(ctx context.Context, idBarang uuid.UUID) ([]entities.Repair, error) {
+	return s.queryRepairs(ctx,
+		selectRepairSQL+` WHERE id_barang = $1 ORDER BY tgl_mulai DESC, tgl_dibuat DESC`,
+		idBarang,
+	)
+}
+
+// GetRepairSpendLines reads the tickets started between dari and sampai with
+// the category of their item and the location they were opened in.
+func (s *Storage) GetRepairSpendLines(ctx context.Context, dari, sampai time.Time) ([]entities.RepairSpendLine, error) {
+	sql := `
+		SELECT p.biaya, p.status, k.kode, k.nama, COALESCE(l.kode, ''), COALESCE(l.nama, '')
+		FROM perbaikan p
+		JOIN unit_barang ub ON p.id_unit = ub.id
+		JOIN barang b ON ub.id_barang = b.id
+		JOIN kategori k ON b.id_kategori = k.id
+		LEFT JOIN ruangan r ON p.id_ruangan = r.id
+		LEFT JOIN lokasi l ON r.id_lokasi = l.id
+		WHERE p.tgl_mulai BETWEEN $1 AND $2
+		ORDER BY k.nama
+	`
+
+	rows, err := s.db.Query(ctx, sql, dari, sampai)
+	if err != nil {
+		return nil, fmt.Errorf("querying repair spend: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []entities.RepairSpendLine
+	for rows.Next() {
+		var l entities.RepairSpendLine
+		if err := rows.Scan(&l.Biaya, &l.Status, &l.KodeKategori, &l.NamaKategori, &l.KodeLokasi, &l.NamaLokasi); err != nil {
+			return nil, fmt.Errorf("scanning repair spend: %w", err)
+		}
+		lines = append(lines, l)
+	}
+
+	return lines, rows.Err()
+}
+
+// Import Area
+
+func (s *Storage) FindItemSKUs(ctx context.Context, skus []string) ([]string, error) {
+	sql := `SELECT sku FROM barang WHERE sku = ANY($1)`
+
+	rows, err := s.db.Query(ctx, sql, skus)
+	if err != nil {
+		return nil, fmt.Errorf("querying find item skus: %w", err)
+	}
+	defer rows.Close()
+
+	found, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return found, nil
+}
+
+// ImportRecords saves a whole import file in one transaction and fills in the
+// ids of the new categories. A row taken by someone else since the preview
+// fails the import with ErrDuplicate, a location or category deleted since then
+// with utils.ErrNotFound.
+func (s *Storage) ImportRecords(ctx context.Context, batch *entities.ImportBatch, audit Audit) error {
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		queued := &pgx.Batch{}
+
+		for _, c := range batch.Categories {
+			queued.Queue(`
+				INSERT INTO kategori (kode, nama, tgl_dibuat, tgl_update)
+				VALUES ($1, $2, $3, $4) RETURNING id
+			`, c.Kode, c.Nama, c.TglDibuat, c.TglUpdate)
+		}
+
+		for _, l := range batch.Locations {
+			queued.Queue(`
+				INSERT INTO lokasi (id, kode, nama, slug, tgl_dibuat, tgl_update)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`, l.Id, l.Kode, l.Nama, l.Slug, l.TglDibuat, l.TglUpdate)
+		}
+
+		for _, r := range batch.Rooms {
+			queued.Queue(`
+				INSERT INTO ruangan (id, id_lokasi, nama, penanggung_jawab, slug, tgl_dibuat, tgl_update)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, r.Id, r.LokasiId, r.Nama, r.PenanggungJawab, r.Slug, r.TglDibuat, r.TglUpdate)
+		}
+
+		for _, i := range batch.Items {
+			queued.Queue(`
+				INSERT INTO barang (
+					id, id_kategori, sku, nama, jumlah, satuan, harga_satuan, umur_ekonomis,
+					metode_penyusutan, nilai_residu, spesifikasi, slug, tgl_dibuat, atribut, tgl_perolehan
+				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			`,
+				i.Id, i.IdKategori, i.SKU, i.Nama, i.Jumlah, i.Satuan, i.HargaSatuan, i.UmurEkonomis,
+				i.MetodePenyusutan, i.NilaiResidu, i.Spesifikasi, i.Slug, i.TglDibuat, i.Atribut, i.TglPerolehan,
+			)
+		}
+
+		results := tx.SendBatch(ctx, queued)
+		for idx := range batch.Categories {
+			if err := results.QueryRow().Scan(&batch.Categories[idx].Id); err != nil {
+				results.Close()
+				return err
+			}
+		}
+
+		if err := results.Close(); err != nil {
+			return err
+		}
+
+		return audit(txAudit{tx})
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return ErrDuplicate
+			case "23503":
+				return utils.ErrNotFound
+			}
+		}
+		return fmt.Errorf("querying import records: %w", err)
+	}
+
+	return nil
+}
+
+// Export Area
+//
+// The Each methods run a list query without a limit and hand the rows to fn
+// one at a time as they arrive, so an export never holds the whole result.
+// An error from fn stops the query.
+
+func (s *Storage) EachCategory(ctx context.Context, sort, where string, args []interface{}, fn func(entities.Category) error) error {
+	rows, err := s.db.Query(ctx, `SELECT id, kode, nama, tgl_dibuat, tgl_update, id_induk, atribut FROM kategori`+where+sort, args...)
+	if err != nil {
+		return fmt.Errorf("querying categories: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		category, err := pgx.RowToStructByName[entities.Category](rows)
+		if err != nil {
+			return fmt.Errorf("error scanning rows category: %w", err)
+		}
+		if err := fn(category); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (s *Storage) EachLocation(ctx context.Context, sort, where string, args []interface{}, fn func(entities.Location) error) error {
+	rows, err := s.db.Query(ctx, `SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update, id_induk FROM lokasi`+where+sort, args...)
+	if err != nil {
+		return fmt.Errorf("querying locations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		loc, err := pgx.RowToStructByName[entities.Location](rows)
+		if err != nil {
+			return fmt.Errorf("error scanning rows location: %w", err)
+		}
+		if err := fn(loc); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (s *Storage) EachRoom(ctx context.Context, sort, where string, args []interface{}, fn func(entities.Room) error) error {
+	rows, err := s.db.Query(ctx, selectRoomsSQL+where+sort, args...)
+	if err != nil {
+		return fmt.Errorf("querying rooms: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		room, err := scanRoom(rows)
+		if err != nil {
+			return fmt.Errorf("error scanning rows room: %w", err)
+		}
+		if err := fn(room); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (s *Storage) EachItem(ctx context.Context, sort, where string, args []interface{}, fn func(entities.Item) error) error {
+	rows, err := s.db.Query(ctx, selectItemSQL+where+sort, args...)
+	if err != nil {
+		return fmt.Errorf("querying items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return fmt.Errorf("error scanning rows item: %w", err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Bulk Area
+
+func (s *Storage) bulkNames(ctx context.Context, sql string, ids any) ([]string, error) {
+	rows, err := s.db.Query(ctx, sql, ids)
+	if err != nil {
+		return nil, fmt.Errorf("querying names: %w", err)
+	}
+
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return names, nil
+}
+
+func (s *Storage) SummarizeCategoryDelete(ctx context.Context, ids []int) (entities.DeleteSummary, error) {
+	var summary entities.DeleteSummary
+
+	names, err := s.bulkNames(ctx, `SELECT nama FROM kategori WHERE id = ANY($1) AND tgl_dihapus IS NULL ORDER BY nama`, ids)
+	if err != nil {
+		return summary, err
+	}
+	summary.Nama = names
+
+	sql := liveCategorySubtreeSQL + `, b AS (SELECT id FROM barang WHERE id_kategori IN (SELECT id FROM sub) AND tgl_dihapus IS NULL)
+		SELECT
+			(SELECT COUNT(DISTINCT id) FROM sub WHERE id <> ALL($1)),
+			(SELECT COUNT(*) FROM b),
+			(SELECT COUNT(*) FROM unit_barang WHERE id_barang IN (SELECT id FROM b) AND tgl_dihapus IS NULL)
+	`
+
+	err = s.db.QueryRow(ctx, sql, ids).Scan(&summary.Kategori, &summary.Barang, &summary.Unit)
+	if err != nil {
+		return summary, fmt.Errorf("querying category delete summary: %w", err)
+	}
+
+	return summary, nil
+}
+
+func (s *Storage) SummarizeLocationDelete(ctx context.Context, ids []uuid.UUID) (entities.DeleteSummary, error) {
+	var summary entities.DeleteSummary
+
+	names, err := s.bulkNames(ctx, `SELECT nama FROM lokasi WHERE id = ANY($1) AND tgl_dihapus IS NULL ORDER BY nama`, ids)
+	if err != nil {
+		return summary, err
+	}
+	summary.Nama = names
+
+	sql := liveLocationSubtreeSQL + `, r AS (SELECT id FROM ruangan WHERE id_lokasi IN (SELECT id FROM sub) AND tgl_dihapus IS NULL)
+		SELECT
+			(SELECT COUNT(DISTINCT id) FROM sub WHERE id <> ALL($1)),
+			(SELECT COUNT(*) FROM r),
+			(SELECT COUNT(*) FROM unit_barang WHERE id_ruangan IN (SELECT id FROM r) AND tgl_dihapus IS NULL)
+	`
+
+	err = s.db.QueryRow(ctx, sql, ids).Scan(&summary.Lokasi, &summary.Ruangan, &summary.Unit)
+	if err != nil {
+		return summary, fmt.Errorf("querying location delete summary: %w", err)
+	}
+
+	return summary, nil
+}
+
+func (s *Storage) SummarizeRoomDelete(ctx context.Context, ids []uuid.UUID) (entities.DeleteSummary, error) {
+	var summary entities.DeleteSummary
+
+	names, err := s.bulkNames(ctx, `SELECT nama FROM ruangan WHERE id = ANY($1) AND tgl_dihapus IS NULL ORDER BY nama`, ids)
+	if err != nil {
+		return summary, err
+	}
+	summary.Nama = names
+
+	sql := `SELECT COUNT(*) FROM unit_barang WHERE id_ruangan = ANY($1) AND tgl_dihapus IS NULL`
+
+	err = s.db.QueryRow(ctx, sql, ids).Scan(&summary.Unit)
+	if err != nil {
+		return summary, fmt.Errorf("querying room delete summary: %w", err)
+	}
+
+	return summary, nil
+}
+
+// DeleteCategories moves the categories with their items and units to the
+// trash in one transaction. It fails with utils.ErrNotFound when any of them
+// is already gone.
+func (s *Storage) DeleteCategories(ctx context.Context, ids []int, audit func(rec AuditRecorder, deleted []entities.Category) error) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, kode, nama, tgl_dibuat, tgl_update, id_induk, atribut
+			FROM kategori WHERE id = ANY($1) AND tgl_dihapus IS NULL ORDER BY id FOR UPDATE
+		`, ids)
+		if err != nil {
+			return fmt.Errorf("querying lock categories: %w", err)
+		}
+
+		deleted, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.Category])
+		if err != nil {
+			return fmt.Errorf("collect rows: %w", err)
+		}
+
+		if len(deleted) != len(ids) {
+			return utils.ErrNotFound
+		}
+
+		if _, err := trashRows(ctx, tx, ids, time.Now(), trashCategorySQL); err != nil {
+			return fmt.Errorf("querying delete categories: %w", err)
+		}
+
+		return audit(txAudit{tx}, deleted)
+	})
+}
+
+func (s *Storage) DeleteLocations(ctx context.Context, ids []uuid.UUID, audit func(rec AuditRecorder, deleted []entities.Location) error) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, kode, nama, jumlah_ruangan, slug, tgl_dibuat, tgl_update, id_induk
+			FROM lokasi WHERE id = ANY($1) AND tgl_dihapus IS NULL ORDER BY id FOR UPDATE
+		`, ids)
+		if err != nil {
+			return fmt.Errorf("querying lock locations: %w", err)
+		}
+
+		deleted, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.Location])
+		if err != nil {
+			return fmt.Errorf("collect rows: %w", err)
+		}
+
+		if len(deleted) != len(ids) {
+			return utils.ErrNotFound
+		}
+
+		if _, err := trashRows(ctx, tx, ids, time.Now(), trashLocationSQL); err != nil {
+			return fmt.Errorf("querying delete locations: %w", err)
+		}
+
+		return audit(txAudit{tx}, deleted)
+	})
+}
+
+// lockRooms reads the live rooms with their location, locking the room rows
+// until the transaction ends.
+func lockRooms(ctx context.Context, tx pgx.Tx, ids []uuid.UUID) ([]entities.Room, error) {
+	rows, err := tx.Query(ctx, selectRoomsSQL+` WHERE r.id = ANY($1) AND r.tgl_dihapus IS NULL ORDER BY r.id FOR UPDATE OF r`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("querying lock rooms: %w", err)
+	}
+	defer rows.Close()
+
+	var rooms []entities.Room
+	for rows.Next() {
+		r, err := scanRoom(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning rows room: %w", err)
+		}
+		rooms = append(rooms, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading rooms: %w", err)
+	}
+
+	return rooms, nil
+}
+
+func (s *Storage) DeleteRooms(ctx context.Context, ids []uuid.UUID, audit func(rec AuditRecorder, deleted []entities.Room) error) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		deleted, err := lockRooms(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+
+		if len(deleted) != len(ids) {
+			return utils.ErrNotFound
+		}
+
+		if _, err := trashRows(ctx, tx, ids, time.Now(), trashRoomSQL); err != nil {
+			return fmt.Errorf("querying delete rooms: %w", err)
+		}
+
+		return audit(txAudit{tx}, deleted)
+	})
+}
+
+// MoveRooms puts the rooms under another location; check gets them as they
+// were before the move. The counter trigger keeps jumlah_ruangan of both
+// locations in step.
+func (s *Storage) MoveRooms(ctx context.Context, ids []uuid.UUID, idLokasi uuid.UUID, check RoomMoveChecker, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var dest entities.Location
+		err := tx.QueryRow(ctx, `SELECT id, kode, nama, slug FROM lokasi WHERE id = $1 AND tgl_dihapus IS NULL FOR SHARE`, idLokasi).Scan(
+			&dest.Id, &dest.Kode, &dest.Nama, &dest.Slug,
+		)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return utils.ErrNotFound
+			}
+			return fmt.Errorf("querying destination location: %w", err)
+		}
+
+		rooms, err := lockRooms(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+
+		if err := check(rooms, dest); err != nil {
+			return err
+		}
+
+		commandTag, err := tx.Exec(ctx,
+			`UPDATE ruangan SET id_lokasi = $1, tgl_update = $2 WHERE id = ANY($3)`,
+			dest.Id, time.Now(), ids,
+		)
+		if err != nil {
+			return fmt.Errorf("querying move rooms: %w", err)
+		}
+
+		if commandTag.RowsAffected() != int64(len(rooms)) {
+			return errors.New("failed to move every room")
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// ChangeUnitConditions sets one condition on every unit; check gets the units
+// as they were before the change.
+func (s *Storage) ChangeUnitConditions(ctx context.Context, ids []uuid.UUID, to entities.KondisiUnit, check UnitConditionChecker, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		units, err := lockUnits(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+
+		if err := check(units, to); err != nil {
+			return err
+		}
+
+		commandTag, err := tx.Exec(ctx,
+			`UPDATE unit_barang SET kondisi = $1, tgl_update = $2 WHERE id = ANY($3)`,
+			to, time.Now(), ids,
+		)
+		if err != nil {
+			return fmt.Errorf("querying update unit conditions: %w", err)
+		}
+
+		if commandTag.RowsAffected() != int64(len(units)) {
+			return errors.New("failed to update every unit")
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// Trash Area
+
+// The subtree statements select as sub the locations or categories in $1 with
+// every one under them, live ones or those deleted at $2.
+const (
+	liveLocationSubtreeSQL = `WITH RECURSIVE sub AS (
+		SELECT id FROM lokasi WHERE id = ANY($1) AND tgl_dihapus IS NULL
+		UNION ALL
+		SELECT l.id FROM lokasi l JOIN sub ON l.id_induk = sub.id WHERE l.tgl_dihapus IS NULL
+	) `
+	trashedLocationSubtreeSQL = `WITH RECURSIVE sub AS (
+		SELECT id FROM lokasi WHERE id = $1
+		UNION ALL
+		SELECT l.id FROM lokasi l JOIN sub ON l.id_induk = sub.id WHERE l.tgl_dihapus = $2
+	) `
+	liveCategorySubtreeSQL = `WITH RECURSIVE sub AS (
+		SELECT id FROM kategori WHERE id = ANY($1) AND tgl_dihapus IS NULL
+		UNION ALL
+		SELECT k.id FROM kategori k JOIN sub ON k.id_induk = sub.id WHERE k.tgl_dihapus IS NULL
+	) `
+	trashedCategorySubtreeSQL = `WITH RECURSIVE sub AS (
+		SELECT id FROM kategori WHERE id = $1
+		UNION ALL
+		SELECT k.id FROM kategori k JOIN sub ON k.id_induk = sub.id WHERE k.tgl_dihapus = $2
+	) `
+)
+
+// Deleting a record stamps tgl_dihapus on it and on its live children with
+// one timestamp. Each statement takes the ids as $1 and the timestamp as $2;
+// the record itself is updated last.
+var (
+	trashCategorySQL = []string{
+		liveCategorySubtreeSQL + `UPDATE unit_barang SET tgl_dihapus = $2 WHERE tgl_dihapus IS NULL
+			AND id_barang IN (SELECT id FROM barang WHERE id_kategori IN (SELECT id FROM sub) AND tgl_dihapus IS NULL)`,
+		liveCategorySubtreeSQL + `UPDATE barang SET tgl_dihapus = $2 WHERE id_kategori IN (SELECT id FROM sub) AND tgl_dihapus IS NULL`,
+		liveCategorySubtreeSQL + `UPDATE kategori SET tgl_dihapus = $2 WHERE id IN (SELECT id FROM sub) AND tgl_dihapus IS NULL`,
+	}
+	trashLocationSQL = []string{
+		liveLocationSubtreeSQL + `UPDATE unit_barang SET tgl_dihapus = $2 WHERE tgl_dihapus IS NULL
+			AND id_ruangan IN (SELECT id FROM ruangan WHERE id_lokasi IN (SELECT id FROM sub) AND tgl_dihapus IS NULL)`,
+		liveLocationSubtreeSQL + `UPDATE ruangan SET tgl_dihapus = $2 WHERE id_lokasi IN (SELECT id FROM sub) AND tgl_dihapus IS NULL`,
+		liveLocationSubtreeSQL + `UPDATE lokasi SET tgl_dihapus = $2 WHERE id IN (SELECT id FROM sub) AND tgl_dihapus IS NULL`,
+	}
+	trashRoomSQL = []string{
+		`UPDATE unit_barang SET tgl_dihapus = $2 WHERE id_ruangan = ANY($1) AND tgl_dihapus IS NULL`,
+		`UPDATE ruangan SET tgl_dihapus = $2 WHERE id = ANY($1) AND tgl_dihapus IS NULL`,
+	}
+	trashItemSQL = []string{
+		`UPDATE unit_barang SET tgl_dihapus = $2 WHERE id_barang = ANY($1) AND tgl_dihapus IS NULL`,
+		`UPDATE barang SET tgl_dihapus = $2 WHERE id = ANY($1) AND tgl_dihapus IS NULL`,
+	}
+)
+
+// trashRows runs the statements of a delete and returns how many of the
+// records themselves were moved to the trash.
+func trashRows(ctx context.Context, tx pgx.Tx, ids any, at time.Time, statements []string) (int64, error) {
+	var trashed int64
+	for _, sql := range statements {
+		commandTag, err := tx.Exec(ctx, sql, ids, at)
+		if err != nil {
+			return 0, err
+		}
+		trashed = commandTag.RowsAffected()
+	}
+
+	return trashed, nil
+}
+
+// trashTable holds the statements the trash page runs against one table.
+// lock reads a deleted record as entities.TrashState. restore takes the id as
+// $1 and the record's tgl_dihapus as $2, so only the children deleted along
+// with it come back, and only those whose other parent is live. pictures
+// lists the picture objects that go away on purge.
+type trashTable struct {
+	lock     string
+	restore  []string
+	pictures string
+	purge    string
+}
+
+var trashTables = map[entities.JenisSampah]trashTable{
+	entities.SampahKategori: {
+		lock: `
+			SELECT k.id::text, k.nama, k.tgl_dihapus,
+				(SELECT 'kategori ' || p.nama FROM kategori p WHERE p.id = k.id_induk AND p.tgl_dihapus IS NOT NULL),
+				0, 0
+			FROM kategori k WHERE k.id = $1 AND k.tgl_dihapus IS NOT NULL FOR UPDATE
+		`,
+		restore: []string{
+			trashedCategorySubtreeSQL + `UPDATE unit_barang SET tgl_dihapus = NULL WHERE tgl_dihapus = $2
+				AND id_barang IN (SELECT id FROM barang WHERE id_kategori IN (SELECT id FROM sub) AND tgl_dihapus = $2)
+				AND id_ruangan IN (SELECT id FROM ruangan WHERE tgl_dihapus IS NULL)`,
+			trashedCategorySubtreeSQL + `UPDATE barang SET tgl_dihapus = NULL WHERE id_kategori IN (SELECT id FROM sub) AND tgl_dihapus = $2`,
+			trashedCategorySubtreeSQL + `UPDATE kategori SET tgl_dihapus = NULL WHERE id IN (SELECT id FROM sub)`,
+		},
+		pictures: `
+			WITH RECURSIVE sub AS (
+				SELECT id FROM kategori WHERE id = $1
+				UNION ALL
+				SELECT k.id FROM kategori k JOIN sub ON k.id_induk = sub.id
+			)
+			SELECT g.nama_objek FROM gambar_barang g
+			JOIN barang b ON g.id_barang = b.id
+			WHERE b.id_kategori IN (SELECT id FROM sub)
+		`,
+		purge: `DELETE FROM kategori WHERE id = $1`,
+	},
+	entities.SampahLokasi: {
+		lock: `
+			SELECT l.id::text, l.nama, l.tgl_dihapus,
+				(SELECT 'lokasi ' || p.nama FROM lokasi p WHERE p.id = l.id_induk AND p.tgl_dihapus IS NOT NULL),
+				0, 0
+			FROM lokasi l WHERE l.id = $1 AND l.tgl_dihapus IS NOT NULL FOR UPDATE
+		`,
+		restore: []string{
+			trashedLocationSubtreeSQL + `UPDATE unit_barang SET tgl_dihapus = NULL WHERE tgl_dihapus = $2
+				AND id_ruangan IN (SELECT id FROM ruangan WHERE id_lokasi IN (SELECT id FROM sub) AND tgl_dihapus = $2)
+				AND id_barang IN (SELECT id FROM barang WHERE tgl_dihapus IS NULL)`,
+			trashedLocationSubtreeSQL + `UPDATE ruangan SET tgl_dihapus = NULL WHERE id_lokasi IN (SELECT id FROM sub) AND tgl_dihapus = $2`,
+			trashedLocationSubtreeSQL + `UPDATE lokasi SET tgl_dihapus = NULL WHERE id IN (SELECT id FROM sub)`,
+		},
+		purge: `DELETE FROM lokasi WHERE id = $1`,
+	},
+	entities.SampahRuangan: {
+		lock: `
+			SELECT r.id::text, r.nama, r.tgl_dihapus,
+				(SELECT 'lokasi ' || l.nama FROM lokasi l WHERE l.id = r.id_lokasi AND l.tgl_dihapus IS NOT NULL),
+				0, 0
+			FROM ruangan r WHERE r.id = $1 AND r.tgl_dihapus IS NOT NULL FOR UPDATE
+		`,
+		restore: []string{
+			`UPDATE unit_barang SET tgl_dihapus = NULL WHERE id_ruangan = $1 AND tgl_dihapus = $2
+				AND id_barang IN (SELECT id FROM barang WHERE tgl_dihapus IS NULL)`,
+			`UPDATE ruangan SET tgl_dihapus = NULL WHERE id = $1`,
+		},
+		purge: `DELETE FROM ruangan WHERE id = $1`,
+	},
+	entities.SampahBarang: {
+		lock: `
+			SELECT b.id::text, b.nama, b.tgl_dihapus,
+				(SELECT 'kategori ' || k.nama FROM kategori k WHERE k.id = b.id_kategori AND k.tgl_dihapus IS NOT NULL),
+				0, 0
+			FROM barang b WHERE b.id = $1 AND b.tgl_dihapus IS NOT NULL FOR UPDATE
+		`,
+		restore: []string{
+			`UPDATE unit_barang SET tgl_dihapus = NULL WHERE id_barang = $1 AND tgl_dihapus = $2
+				AND id_ruangan IN (SELECT id FROM ruangan WHERE tgl_dihapus IS NULL)`,
+			`UPDATE barang SET tgl_dihapus = NULL WHERE id = $1`,
+		},
+		pictures: `SELECT nama_objek FROM gambar_barang WHERE id_barang = $1`,
+		purge:    `DELETE FROM barang WHERE id = $1`,
+	},
+	// Locking the item row as well keeps two restores of its units from
+	// both passing the amount check.
+	entities.SampahUnit: {
+		lock: `
+			SELECT u.id::text, b.nama || ' ' || u.no_seri, u.tgl_dihapus,
+				CASE WHEN b.tgl_dihapus IS NOT NULL THEN 'barang ' || b.nama
+					ELSE (SELECT 'ruangan ' || r.nama FROM ruangan r WHERE r.id = u.id_ruangan AND r.tgl_dihapus IS NOT NULL)
+				END,
+				(SELECT COUNT(*) FROM unit_barang x WHERE x.id_barang = u.id_barang AND x.tgl_dihapus IS NULL),
+				b.jumlah
+			FROM unit_barang u
+			JOIN barang b ON u.id_barang = b.id
+			WHERE u.id = $1 AND u.tgl_dihapus IS NOT NULL
+			FOR UPDATE
+		`,
+		restore: []string{
+			`UPDATE unit_barang SET tgl_dihapus = NULL WHERE id = $1`,
+		},
+		purge: `DELETE FROM unit_barang WHERE id = $1`,
+	},
+}
+
+// GetTrash lists the deleted records, newest first. A record deleted together
+// with its parent is left out; it comes back when the parent is restored.
+func (s *Storage) GetTrash(ctx context.Context, jenis entities.JenisSampah) ([]entities.TrashEntry, error) {
+	sql := `
+		SELECT jenis, id, nama, keterangan, tgl_dihapus FROM (
+			SELECT 'kategori' AS jenis, k.id::text AS id, k.nama,
+				format('%s · %s subkategori · %s barang', k.kode,
+					(SELECT COUNT(*) FROM kategori c WHERE c.id_induk = k.id AND c.tgl_dihapus = k.tgl_dihapus),
+					(SELECT COUNT(*) FROM barang b WHERE b.id_kategori = k.id AND b.tgl_dihapus = k.tgl_dihapus)) AS keterangan,
+				k.tgl_dihapus
+			FROM kategori k
+			LEFT JOIN kategori p ON k.id_induk = p.id
+			WHERE k.tgl_dihapus IS NOT NULL AND p.tgl_dihapus IS DISTINCT FROM k.tgl_dihapus
+
+			UNION ALL
+
+			SELECT 'lokasi', l.id::text, l.nama,
+				format('%s · %s sublokasi · %s ruangan', l.kode,
+					(SELECT COUNT(*) FROM lokasi c WHERE c.id_induk = l.id AND c.tgl_dihapus = l.tgl_dihapus),
+					(SELECT COUNT(*) FROM ruangan r WHERE r.id_lokasi = l.id AND r.tgl_dihapus = l.tgl_dihapus)),
+				l.tgl_dihapus
+			FROM lokasi l
+			LEFT JOIN lokasi p ON l.id_induk = p.id
+			WHERE l.tgl_dihapus IS NOT NULL AND p.tgl_dihapus IS DISTINCT FROM l.tgl_dihapus
+
+			UNION ALL
+
+			SELECT 'ruangan', r.id::text, r.nama,
+				format('lokasi %s · %s unit', l.nama,
+					(SELECT COUNT(*) FROM unit_barang u WHERE u.id_ruangan = r.id AND u.tgl_dihapus = r.tgl_dihapus)),
+				r.tgl_dihapus
+			FROM ruangan r
+			JOIN lokasi l ON r.id_lokasi = l.id
+			WHERE r.tgl_dihapus IS NOT NULL AND l.tgl_dihapus IS DISTINCT FROM r.tgl_dihapus
+
+			UNION ALL
+
+			SELECT 'barang', b.id::text, b.nama,
+				format('%s · kategori %s · %s unit', b.sku, k.nama,
+					(SELECT COUNT(*) FROM unit_barang u WHERE u.id_barang = b.id AND u.tgl_dihapus = b.tgl_dihapus)),
+				b.tgl_dihapus
+			FROM barang b
+			JOIN kategori k ON b.id_kategori = k.id
+			WHERE b.tgl_dihapus IS NOT NULL AND k.tgl_dihapus IS DISTINCT FROM b.tgl_dihapus
+
+			UNION ALL
+
+			SELECT 'unit', u.id::text, b.nama || ' ' || u.no_seri,
+				format('%s · ruangan %s', b.sku, r.nama),
+				u.tgl_dihapus
+			FROM unit_barang u
+			JOIN barang b ON u.id_barang = b.id
+			JOIN ruangan r ON u.id_ruangan = r.id
+			WHERE u.tgl_dihapus IS NOT NULL
+				AND b.tgl_dihapus IS DISTINCT FROM u.tgl_dihapus
+				AND r.tgl_dihapus IS DISTINCT FROM u.tgl_dihapus
+		) t
+		WHERE $1 = '' OR t.jenis = $1
+		ORDER BY t.tgl_dihapus DESC, t.nama
+	`
+
+	rows, err := s.db.Query(ctx, sql, string(jenis))
+	if err != nil {
+		return nil, fmt.Errorf("querying trash: %w", err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByName[entities.TrashEntry])
+	if err != nil {
+		return nil, fmt.Errorf("collect rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+// lockTrash reads the deleted record for restore or purge and runs check on
+// it. It fails with utils.ErrNotFound when the record is not in the trash.
+func lockTrash(ctx context.Context, tx pgx.Tx, jenis entities.JenisSampah, id any, check TrashChecker) (entities.TrashState, error) {
+	table, ok := trashTables[jenis]
+	if !ok {
+		return entities.TrashState{}, utils.ErrNotFound
+	}
+
+	state := entities.TrashState{Entry: entities.TrashEntry{Jenis: jenis}}
+	var induk *string
+	err := tx.QueryRow(ctx, table.lock, id).Scan(
+		&state.Entry.Id, &state.Entry.Nama, &state.Entry.TglDihapus, &induk, &state.Unit, &state.Jumlah,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return state, utils.ErrNotFound
+		}
+		return state, fmt.Errorf("querying lock trash: %w", err)
+	}
+	if induk != nil {
+		state.Induk = *induk
+	}
+
+	return state, check(state)
+}
+
+// RestoreTrash brings the record back together with the children that were
+// deleted with it. check gets it as it was listed in the trash.
+func (s *Storage) RestoreTrash(ctx context.Context, jenis entities.JenisSampah, id any, check TrashChecker, audit Audit) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		state, err := lockTrash(ctx, tx, jenis, id, check)
+		if err != nil {
+			return err
+		}
+
+		for _, sql := range trashTables[jenis].restore {
+			if _, err := tx.Exec(ctx, sql, id, state.Entry.TglDihapus); err != nil {
+				return fmt.Errorf("querying restore %s: %w", jenis, err)
+			}
+		}
+
+		return audit(txAudit{tx})
+	})
+}
+
+// PurgeTrash deletes the record for good; its children go with it through
+// ON DELETE CASCADE. Picture objects are removed after the commit.
+func (s *Storage) PurgeTrash(ctx context.Context, jenis entities.JenisSampah, id any, check TrashChecker, audit Audit) error {
+	var objects []string
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := lockTrash(ctx, tx, jenis, id, check); err != nil {
+			return err
+		}
+
+		table := trashTables[jenis]
+		if table.pictures != "" {
+			rows, err := tx.Query(ctx, table.pictures, id)
+			if err != nil {
+				return fmt.Errorf("querying item pictures: %w", err)
+			}
+
+			objects, err = pgx.CollectRows(rows, pgx.RowTo[string])
+			if err != nil {
+				return fmt.Errorf("collect rows: %w", err)
+			}
+		}
+
+		if _, err := tx.Exec(ctx, table.purge, id); err != nil {
+			return fmt.Errorf("querying purge %s: %w", jenis, err)
+		}
+
+		return audit(txAudit{tx})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range objects {
+		if err := s.objects.RemoveObject(ctx, name); err != nil {
+			log.Printf("removing picture object %s: %v", name, err)
+		}
+	}
+
 	return nil
 }
+
+// PurgeExpiredTrash deletes every record that went to the trash before the
+// given time and returns how many were removed, not counting the children
+// removed with them. A child is never deleted later than its parent, so the
+// cascade only takes rows that have expired as well.
+func (s *Storage) PurgeExpiredTrash(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	var objects []string
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT g.nama_objek FROM gambar_barang g
+			JOIN barang b ON g.id_barang = b.id
+			WHERE b.tgl_dihapus < $1
+		`, before)
+		if err != nil {
+			return fmt.Errorf("querying item pictures: %w", err)
+		}
+
+		objects, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return fmt.Errorf("collect rows: %w", err)
+		}
+
+		for _, table := range []string{"kategori", "lokasi", "ruangan", "barang", "unit_barang"} {
+			commandTag, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE tgl_dihapus < $1`, before)
+			if err != nil {
+				return fmt.Errorf("querying purge %s: %w", table, err)
+			}
+			purged += commandTag.RowsAffected()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, name := range objects {
+		if err := s.objects.RemoveObject(ctx, name); err != nil {
+			log.Printf("removing picture object %s: %v", name, err)
+		}
+	}
+
+	return purged, nil
+}
@@ -1,10 +1,33 @@
 package utils
 
+import "errors"
+
+// Kinds of failure shared by the storage, service and server layers. Storage
+// and services wrap them with context; callers match them with errors.Is and
+// the server maps each kind to an HTTP status.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrInvalidId  = errors.New("invalid id")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+)
+
 type WebError struct {
 	Field   string
 	Message string
+	// Conflict marks errors caused by a value that is already taken, such as
+	// a duplicate code.
+	Conflict bool
 }
 
 func (e WebError) Error() string {
 	return e.Message
 }
+
+// Unwrap makes a WebError match ErrConflict or ErrValidation.
+func (e WebError) Unwrap() error {
+	if e.Conflict {
+		return ErrConflict
+	}
+	return ErrValidation
+}
@@ -4,28 +4,44 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 var MaxPageSize = 100
 
-type allowedFilter struct {
-	column   string
-	operator string
+// AllowedFilter maps a filter query parameter to a table qualified column.
+type AllowedFilter struct {
+	Column   string
+	Operator string
 }
 
-var filterConfigs = map[string]allowedFilter{
-	"dmin":   {column: "tgl_dibuat", operator: "gte"},
-	"dmax":   {column: "tgl_dibuat", operator: "lte"},
-	"status": {column: "status", operator: "in"},
-	"jr":     {column: "jumlah_ruangan", operator: "eq"},
+type FilterParam struct {
+	Name     string
+	Operator string
+}
+
+// FilterParams lists the query parameters accepted as list filters, sorted by
+// name.
+func FilterParams(filters map[string]AllowedFilter) []FilterParam {
+	params := make([]FilterParam, 0, len(filters))
+	for name, cfg := range filters {
+		params = append(params, FilterParam{Name: name, Operator: cfg.Operator})
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+	return params
 }
 
 type TableConfig struct {
 	QueryCols   []string
 	SortCols    []AllowedSort
 	DefaultSort string
+	// DeletedCol is the soft delete timestamp of the table, if it has one.
+	DeletedCol string
+	// Filters are the filter query parameters the list accepts, any other
+	// parameter is ignored.
+	Filters map[string]AllowedFilter
 }
 
 type AllowedSort struct {
@@ -64,6 +80,10 @@ type PaginationParams struct {
 	Query     string
 	Filters   []Filter
 	QueryCols []string
+	// DeletedCol leaves out rows whose soft delete timestamp is set.
+	DeletedCol string
+	// FilterValues holds the raw query, resolved into Filters by SetFilters.
+	FilterValues url.Values
 }
 
 func (p PaginationParams) getOffset() int {
@@ -78,6 +98,33 @@ func (p *PaginationParams) SetColumnSearch(cols ...string) {
 	p.QueryCols = cols
 }
 
+func (p *PaginationParams) ExcludeDeleted(col string) {
+	p.DeletedCol = col
+}
+
+// SetFilters resolves the filter values named in allowed, ignoring unknown
+// parameters and empty values.
+func (p *PaginationParams) SetFilters(allowed map[string]AllowedFilter) {
+	p.Filters = nil
+	for _, f := range FilterParams(allowed) {
+		var vals []string
+		for _, v := range p.FilterValues[f.Name] {
+			if v != "" {
+				vals = append(vals, v)
+			}
+		}
+		if len(vals) == 0 {
+			continue
+		}
+
+		p.Filters = append(p.Filters, Filter{
+			Field:    allowed[f.Name].Column,
+			Operator: f.Operator,
+			Value:    extractFilterValue(f.Operator, vals),
+		})
+	}
+}
+
 func PaginationFromRequest(r *http.Request) (PaginationParams, error) {
 	q := r.URL.Query()
 
@@ -96,18 +143,13 @@ func PaginationFromRequest(r *http.Request) (PaginationParams, error) {
 		return PaginationParams{}, fmt.Errorf("invalid sort direction: %w", err)
 	}
 
-	filters, err := buildFilters(q)
-	if err != nil {
-		return PaginationParams{}, fmt.Errorf("invalid filters: %w", err)
-	}
-
 	return PaginationParams{
-		Page:    page,
-		PerPage: perPage,
-		SortBy:  q.Get("sb"),
-		SortDir: sortDir,
-		Query:   q.Get("q"),
-		Filters: filters,
+		Page:         page,
+		PerPage:      perPage,
+		SortBy:       q.Get("sb"),
+		SortDir:      sortDir,
+		Query:        q.Get("q"),
+		FilterValues: q,
 	}, nil
 }
 
@@ -143,27 +185,12 @@ func validateSortDirection(sortDir string) (string, error) {
 	return sortDir, nil
 }
 
-func buildFilters(query url.Values) ([]Filter, error) {
-	var filters []Filter
-	for key, vals := range query {
-		if cfg, ok := filterConfigs[key]; ok {
-			if len(vals) == 0 {
-				continue
-			}
-			filters = append(filters, Filter{
-				Field:    cfg.column,
-				Operator: cfg.operator,
-				Value:    extractFilterValue(cfg.operator, vals),
-			})
-		}
-	}
-	return filters, nil
-}
-
-func FiltersToMap(filters []Filter) map[string]interface{} {
+// FiltersToMap returns the first value of each query parameter, for the list
+// templates to fill the filter inputs back in.
+func FiltersToMap(values url.Values) map[string]interface{} {
 	m := make(map[string]interface{})
-	for _, f := range filters {
-		m[f.Field] = f.Value
+	for key := range values {
+		m[key] = values.Get(key)
 	}
 	return m
 }
@@ -187,6 +214,10 @@ func BuildWhereClauses(params PaginationParams) (string, []interface{}) {
 	var arguments []interface{}
 	argIndex := 1
 
+	if params.DeletedCol != "" {
+		conditions = append(conditions, params.DeletedCol+" IS NULL")
+	}
+
 	for _, f := range params.Filters {
 		switch f.Operator {
 		case "in", "nin":
@@ -206,6 +237,18 @@ func BuildWhereClauses(params PaginationParams) (string, []interface{}) {
 				argIndex++
 			}
 			conditions = append(conditions, fmt.Sprintf("%s %s (%s)", f.Field, op, strings.Join(placeholders, ", ")))
+		case "attr":
+			// The value is kunci:nilai and matches a JSONB attribute
+			// regardless of case.
+			value, _ := f.Value.(string)
+			kunci, nilai, ok := strings.Cut(value, ":")
+			if kunci = strings.TrimSpace(kunci); !ok || kunci == "" {
+				continue
+			}
+
+			arguments = append(arguments, kunci, strings.TrimSpace(nilai))
+			conditions = append(conditions, fmt.Sprintf("lower(%s ->> $%d) = lower($%d)", f.Field, argIndex, argIndex+1))
+			argIndex += 2
 		default:
 			arguments = append(arguments, f.Value)
 			conditions = append(conditions, fmt.Sprintf("%s %s $%d", f.Field, mapOperator(f.Operator), argIndex))